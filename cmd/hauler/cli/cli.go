@@ -9,9 +9,11 @@ import (
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"hauler.dev/go/hauler/v2/internal/flags"
+	"hauler.dev/go/hauler/v2/pkg/audit"
 	"hauler.dev/go/hauler/v2/pkg/consts"
 	"hauler.dev/go/hauler/v2/pkg/content"
 	"hauler.dev/go/hauler/v2/pkg/log"
+	"hauler.dev/go/hauler/v2/pkg/store"
 )
 
 func New(ctx context.Context, ro *flags.CliRootOpts) *cobra.Command {
@@ -47,6 +49,13 @@ func New(ctx context.Context, ro *flags.CliRootOpts) *cobra.Command {
 			l.SetLevel(ro.LogLevel)
 			l.Debugf("running cli command [%s]", cmd.CommandPath())
 
+			// a store's history names the invocation that changed its index
+			cctx := cmd.Context()
+			if cctx == nil {
+				cctx = ctx
+			}
+			cmd.SetContext(store.ContextWithAuditID(cctx, audit.ID()))
+
 			if dir, set := content.SetDefaultDockerConfig(); set {
 				l.Debugf("defaulted $DOCKER_CONFIG to [%s] for registry credential resolution", dir)
 			}
@@ -69,6 +78,7 @@ func New(ctx context.Context, ro *flags.CliRootOpts) *cobra.Command {
 	cmd.AddCommand(cranecmd.NewCmdAuthLogin("hauler"))
 	cmd.AddCommand(cranecmd.NewCmdAuthLogout("hauler"))
 	addStore(cmd, ro)
+	addHaul(cmd, ro)
 	addVersion(cmd, ro)
 	addCompletion(cmd, ro)
 
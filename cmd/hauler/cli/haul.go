@@ -0,0 +1,48 @@
+package cli
+
+import (
+	"github.com/spf13/cobra"
+
+	"hauler.dev/go/hauler/v2/cmd/hauler/cli/store"
+	"hauler.dev/go/hauler/v2/internal/flags"
+)
+
+func addHaul(parent *cobra.Command, ro *flags.CliRootOpts) {
+	cmd := &cobra.Command{
+		Use:   "haul",
+		Short: "Interact with haul archives",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return cmd.Help()
+		},
+	}
+
+	cmd.AddCommand(
+		addHaulInspect(ro),
+	)
+
+	parent.AddCommand(cmd)
+}
+
+func addHaulInspect(ro *flags.CliRootOpts) *cobra.Command {
+	o := &flags.HaulInspectOpts{}
+
+	cmd := &cobra.Command{
+		Use:   "inspect <archive | chunk | url>",
+		Short: "List the contents of a haul without loading or extracting it",
+		Example: `  # list what a haul holds
+  hauler haul inspect haul.tar.zst
+
+  # a chunked haul, by any of its chunks
+  hauler haul inspect haul.tar.zst.001
+
+  # a remote haul, as json
+  hauler haul inspect https://example.com/haul.tar.zst -o json`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return store.HaulInspectCmd(cmd.Context(), o, args[0])
+		},
+	}
+	o.AddFlags(cmd)
+
+	return cmd
+}
@@ -3,6 +3,7 @@ package cli
 import (
 	"fmt"
 	"os"
+	"strconv"
 
 	"github.com/spf13/cobra"
 	"helm.sh/helm/v4/pkg/action"
@@ -33,9 +34,19 @@ func addStore(parent *cobra.Command, ro *flags.CliRootOpts) {
 		addStoreSave(rso, ro),
 		addStoreServe(rso, ro),
 		addStoreInfo(rso, ro),
+		addStoreDiff(rso, ro),
+		addStoreRepair(rso, ro),
 		addStoreCopy(rso, ro),
+		addStoreMerge(rso, ro),
+		addStoreTag(rso, ro),
+		addStoreLabel(rso, ro),
 		addStoreAdd(rso, ro),
 		addStoreRemove(rso, ro),
+		addStoreGC(rso, ro),
+		addStoreHistory(rso, ro),
+		addStoreRollback(rso, ro),
+		addStoreVerify(rso, ro),
+		addStoreSign(rso, ro),
 		addStoreCreate(rso, ro),
 	)
 
@@ -46,10 +57,15 @@ func addStoreExtract(rso *flags.StoreRootOpts, ro *flags.CliRootOpts) *cobra.Com
 	o := &flags.ExtractOpts{StoreRootOpts: rso}
 
 	cmd := &cobra.Command{
-		Use:     "extract",
+		Use:     "extract [<artifact-ref> | --select <selector>]",
 		Short:   "Extract artifacts from the content store to disk",
 		Aliases: []string{"x"},
-		Args:    cobra.ExactArgs(1),
+		Example: `  # extract a file
+  hauler store extract hauler/rke2-install.sh:latest
+
+  # extract every chart from a single repository
+  hauler store extract --select 'type=chart,ref=hauler/rancher*' -o charts`,
+		Args: cobra.MaximumNArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			ctx := cmd.Context()
 
@@ -57,8 +73,16 @@ func addStoreExtract(rso *flags.StoreRootOpts, ro *flags.CliRootOpts) *cobra.Com
 			if err != nil {
 				return err
 			}
+			if err := o.LockShared(ctx, s, cmd.CommandPath()); err != nil {
+				return err
+			}
+			defer s.Unlock()
 
-			return store.ExtractCmd(ctx, o, s, args[0])
+			var ref string
+			if len(args) > 0 {
+				ref = args[0]
+			}
+			return store.ExtractCmd(ctx, o, s, ref)
 		},
 	}
 	o.AddFlags(cmd)
@@ -92,13 +116,13 @@ func addStoreSync(rso *flags.StoreRootOpts, ro *flags.CliRootOpts) *cobra.Comman
 				o.InsecureSkipTLSVerify = true
 			}
 
-			// --dry-run requires --products
-			if o.DryRun && len(o.Products) == 0 {
+			// --dry-run requires --products, unless it's previewing a --prune
+			if o.DryRun && !o.Prune && len(o.Products) == 0 {
 				return fmt.Errorf("--dry-run requires --products")
 			}
 			// suppress log output during dry-run so YAML is the only stdout content
 			// must be set before any log calls to keep stdout clean for piping
-			if o.DryRun {
+			if o.DryRun && !o.Prune {
 				log.FromContext(cmd.Context()).SetLevel("fatal")
 			}
 			// warn if products or product-registry flag is used by the user
@@ -136,14 +160,34 @@ func addStoreSync(rso *flags.StoreRootOpts, ro *flags.CliRootOpts) *cobra.Comman
 		RunE: func(cmd *cobra.Command, args []string) error {
 			ctx := cmd.Context()
 
-			if o.DryRun {
+			if o.DryRun && !o.Prune {
 				return store.SyncCmd(ctx, o, nil, rso, ro)
 			}
+			if o.DryRun {
+				// a dry run never creates the store... one that doesn't exist
+				// yet has nothing to prune
+				dir, err := flags.ResolveStoreDir(ctx, ro, o.StoreDir)
+				if err != nil {
+					return err
+				}
+				if _, err := os.Stat(dir); os.IsNotExist(err) {
+					return store.SyncCmd(ctx, o, nil, rso, ro)
+				}
+			}
 
 			s, err := o.Store(ctx, ro)
 			if err != nil {
 				return err
 			}
+			// a --prune dry run only reads the store, to report what would go
+			lock := o.LockExclusive
+			if o.DryRun {
+				lock = o.LockShared
+			}
+			if err := lock(ctx, s, cmd.CommandPath()); err != nil {
+				return err
+			}
+			defer s.Unlock()
 
 			return store.SyncCmd(ctx, o, s, rso, ro)
 		},
@@ -159,7 +203,15 @@ func addStoreLoad(rso *flags.StoreRootOpts, ro *flags.CliRootOpts) *cobra.Comman
 	cmd := &cobra.Command{
 		Use:   "load",
 		Short: "Load a content store from a store archive",
-		Args:  cobra.ExactArgs(0),
+		Example: `  # load a haul
+  hauler store load --filename haul.tar.zst
+
+  # verify a signed haul, chunk by chunk, before loading it
+  hauler store load --filename haul.tar.zst --verify-key cosign.pub
+
+  # load a haul streamed in on stdin
+  age -d -i key.txt haul.tar.zst.age | hauler store load --filename -`,
+		Args: cobra.ExactArgs(0),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			ctx := cmd.Context()
 
@@ -167,6 +219,10 @@ func addStoreLoad(rso *flags.StoreRootOpts, ro *flags.CliRootOpts) *cobra.Comman
 			if err != nil {
 				return err
 			}
+			if err := o.LockExclusive(ctx, s, cmd.CommandPath()); err != nil {
+				return err
+			}
+			defer s.Unlock()
 
 			return store.LoadCmd(ctx, o, s, rso, ro)
 		},
@@ -198,6 +254,11 @@ func addStoreServeRegistry(rso *flags.StoreRootOpts, ro *flags.CliRootOpts) *cob
 	cmd := &cobra.Command{
 		Use:   "registry",
 		Short: "Serve the OCI Compliant Registry",
+		Long: `Serve the content store as an OCI compliant registry.
+
+With --readonly (the default), the registry is served directly from the store,
+which stays locked for as long as it runs: commands that write to the store
+(add, sync, remove, load...) wait for it to stop, up to their --lock-timeout.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			ctx := cmd.Context()
 
@@ -205,6 +266,10 @@ func addStoreServeRegistry(rso *flags.StoreRootOpts, ro *flags.CliRootOpts) *cob
 			if err != nil {
 				return err
 			}
+			if err := o.LockShared(ctx, s, cmd.CommandPath()); err != nil {
+				return err
+			}
+			defer s.Unlock()
 
 			return store.ServeRegistryCmd(ctx, o, s, rso, ro)
 		},
@@ -228,6 +293,10 @@ func addStoreServeFiles(rso *flags.StoreRootOpts, ro *flags.CliRootOpts) *cobra.
 			if err != nil {
 				return err
 			}
+			if err := o.LockShared(ctx, s, cmd.CommandPath()); err != nil {
+				return err
+			}
+			defer s.Unlock()
 
 			return store.ServeFilesCmd(ctx, o, s, ro)
 		},
@@ -244,14 +313,45 @@ func addStoreSave(rso *flags.StoreRootOpts, ro *flags.CliRootOpts) *cobra.Comman
 	cmd := &cobra.Command{
 		Use:   "save",
 		Short: "Save a content store to a store archive",
-		Args:  cobra.ExactArgs(0),
+		Example: `  # save the whole store
+  hauler store save --filename haul.tar.zst
+
+  # save only the charts and files
+  hauler store save --filename extras.tar.zst --select 'type~^(chart|file)$'
+
+  # save and sign the haul's checksums
+  hauler store save --filename haul.tar.zst --sign-key cosign.key
+
+  # save an uncompressed tar, for stores whose layers are compressed already
+  hauler store save --filename haul.tar
+
+  # save with the strongest zstd compression on 8 threads
+  hauler store save --filename haul.tar.zst --compression-level 19 --threads 8
+
+  # stream the haul to another host, without writing it locally
+  hauler store save --filename - | ssh airgap 'hauler store load --filename -'
+
+  # stream the haul through age into an encrypted file
+  hauler store save --filename - | age -r <recipient> > haul.tar.zst.age`,
+		Args: cobra.ExactArgs(0),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			ctx := cmd.Context()
 
+			// stdout carries the haul itself with `-f -`, so the logs move
+			// aside, along with the error main logs if the save fails
+			if o.FileName == "-" {
+				ctx = log.NewLogger(os.Stderr).WithContext(ctx)
+				cmd.SetContext(ctx)
+			}
+
 			s, err := o.Store(ctx, ro)
 			if err != nil {
 				return err
 			}
+			if err := o.LockShared(ctx, s, cmd.CommandPath()); err != nil {
+				return err
+			}
+			defer s.Unlock()
 
 			return store.SaveCmd(ctx, o, s, rso, ro)
 		},
@@ -267,8 +367,16 @@ func addStoreInfo(rso *flags.StoreRootOpts, ro *flags.CliRootOpts) *cobra.Comman
 	var allowedValues = []string{"image", "chart", "file", "sigs", "atts", "sbom", "referrer", "all"}
 
 	cmd := &cobra.Command{
-		Use:     "info",
-		Short:   "Print out information about the store",
+		Use:   "info",
+		Short: "Print out information about the store",
+		Example: `  # list every artifact in the store
+  hauler store info
+
+  # list the arm64 images over 100M
+  hauler store info --select 'type=image,platform=linux/arm64*,size>100M'
+
+  # show what each artifact shares with the rest, and the size of the haul to expect
+  hauler store info --sizes`,
 		Args:    cobra.ExactArgs(0),
 		Aliases: []string{"i", "list", "ls"},
 		PreRunE: func(cmd *cobra.Command, args []string) error {
@@ -290,6 +398,10 @@ func addStoreInfo(rso *flags.StoreRootOpts, ro *flags.CliRootOpts) *cobra.Comman
 			if err != nil {
 				return err
 			}
+			if err := o.LockShared(ctx, s, cmd.CommandPath()); err != nil {
+				return err
+			}
+			defer s.Unlock()
 
 			for _, allowed := range allowedValues {
 				if o.TypeFilter == allowed {
@@ -304,6 +416,404 @@ func addStoreInfo(rso *flags.StoreRootOpts, ro *flags.CliRootOpts) *cobra.Comman
 	return cmd
 }
 
+func addStoreDiff(rso *flags.StoreRootOpts, ro *flags.CliRootOpts) *cobra.Command {
+	o := &flags.DiffOpts{StoreRootOpts: rso}
+
+	cmd := &cobra.Command{
+		Use:   "diff <from> <to>",
+		Short: "Compare the contents of two stores, hauls, or manifests",
+		Long: `Compare two sets of content and report what moving from <from> to <to> would
+add, remove, retag, or change, and the size of the blobs <to> holds that <from>
+does not. Each side can be:
+
+  - a store directory, or the StoreID of a store used before
+  - a haul archive, local or remote (chunked hauls are joined first)
+  - a hauler manifest (.yaml or .yml)
+
+A manifest is read without contacting any registry, so only images pinned by
+digest can show a digest change against it, and a chart with no version matches
+any stored version of that chart. Signatures, attestations, sboms, and referrers
+are only compared between stores and hauls.`,
+		Example: `  # compare the local store with a haul before loading it
+  hauler store diff store haul.tar.zst
+
+  # check what a manifest would add to a store
+  hauler store diff store hauler-manifest.yaml -o json`,
+		Args: cobra.ExactArgs(2),
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if o.OutputFormat == "json" {
+				log.FromContext(cmd.Context()).SetLevel("fatal")
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+
+			if o.OutputFormat != "table" && o.OutputFormat != "json" {
+				return fmt.Errorf("output must be one of [table json]")
+			}
+
+			return store.DiffCmd(ctx, o, args[0], args[1], ro)
+		},
+	}
+	o.AddFlags(cmd)
+
+	return cmd
+}
+
+func addStoreRepair(rso *flags.StoreRootOpts, ro *flags.CliRootOpts) *cobra.Command {
+	o := &flags.RepairOpts{StoreRootOpts: rso}
+
+	cmd := &cobra.Command{
+		Use:   "repair",
+		Short: "Re-fetch missing or corrupt blobs in the content store",
+		Long: `Check every artifact in the store the way 'hauler store info --check' does, and
+rewrite each missing or corrupt blob from the first source that serves its exact
+digest: the hauls given with --haul, in order, then the registry the artifact was
+pulled from (or the same repository on --mirror). Only the damaged blobs are
+rewritten. Blobs that cannot be repaired are reported and fail the command.`,
+		Args: cobra.ExactArgs(0),
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			// same as `info --check -o json`: keep stdout parseable
+			if o.OutputFormat == "json" {
+				log.FromContext(cmd.Context()).SetLevel("fatal")
+			}
+			if o.CaFile == "" {
+				o.CaFile = os.Getenv(consts.CaFile)
+			}
+			if !cmd.Flags().Changed("insecure-skip-tls-verify") && os.Getenv(consts.InsecureSkipTLSVerify) == "true" {
+				o.InsecureSkipTLSVerify = true
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+
+			if o.OutputFormat != "table" && o.OutputFormat != "json" {
+				return fmt.Errorf("output must be one of [table json]")
+			}
+
+			s, err := o.Store(ctx, ro)
+			if err != nil {
+				return err
+			}
+			if err := o.LockExclusive(ctx, s, cmd.CommandPath()); err != nil {
+				return err
+			}
+			defer s.Unlock()
+
+			return store.RepairCmd(ctx, o, s, ro)
+		},
+	}
+	o.AddFlags(cmd)
+
+	return cmd
+}
+
+func addStoreGC(rso *flags.StoreRootOpts, ro *flags.CliRootOpts) *cobra.Command {
+	o := &flags.GCOpts{StoreRootOpts: rso}
+
+	cmd := &cobra.Command{
+		Use:   "gc",
+		Short: "Delete unreferenced blobs, orphaned signatures and referrers, and stale temp files from the content store",
+		Long: `Delete everything in the store that no artifact needs anymore:
+
+  - signature, attestation, sbom, and referrer entries whose subject image is gone
+  - blobs that no remaining artifact references
+  - temp files left by interrupted writes, once older than --temp-grace
+
+Use --dry-run to list what would be deleted, and its size, first. Collection
+stops without deleting anything if a manifest in the store cannot be read; run
+'hauler store repair' first.
+
+Blobs still referenced by a generation of the store's history are kept so
+'hauler store rollback' can restore it, and counted as held. Run
+'hauler store history --trim' first to drop the history and collect them too.`,
+		Args: cobra.ExactArgs(0),
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if o.OutputFormat == "json" {
+				log.FromContext(cmd.Context()).SetLevel("fatal")
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+
+			if o.OutputFormat != "table" && o.OutputFormat != "json" {
+				return fmt.Errorf("output must be one of [table json]")
+			}
+
+			s, err := o.Store(ctx, ro)
+			if err != nil {
+				return err
+			}
+			lock := o.LockExclusive
+			if o.DryRun {
+				lock = o.LockShared
+			}
+			if err := lock(ctx, s, cmd.CommandPath()); err != nil {
+				return err
+			}
+			defer s.Unlock()
+
+			return store.GCCmd(ctx, o, s, ro)
+		},
+	}
+	o.AddFlags(cmd)
+
+	return cmd
+}
+
+func addStoreHistory(rso *flags.StoreRootOpts, ro *flags.CliRootOpts) *cobra.Command {
+	o := &flags.HistoryOpts{StoreRootOpts: rso}
+
+	cmd := &cobra.Command{
+		Use:   "history",
+		Short: "List the generations of the content store index kept for rollback",
+		Long: `Every command that changes the store's index first keeps a copy of it as a new
+generation, recording the command and its audit id. The newest --history-limit
+generations are kept (20 by default), and 'hauler store rollback <generation>'
+restores one. Blobs a kept generation references are left alone by
+'hauler store gc', 'hauler store remove', and 'hauler store sync --prune'.
+
+Lowering --history-limit, or setting it to 0 to stop recording, never drops
+generations already kept; use --trim to drop them.`,
+		Example: `  # list the store's history
+  hauler store history
+
+  # drop the history so gc can free the blobs only it still references
+  hauler store history --trim
+
+  # undo the last change to the store
+  hauler store rollback $(hauler store history -o json | jq '.generations[0].generation')`,
+		Args: cobra.ExactArgs(0),
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if o.OutputFormat == "json" {
+				log.FromContext(cmd.Context()).SetLevel("fatal")
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+
+			if o.OutputFormat != "table" && o.OutputFormat != "json" {
+				return fmt.Errorf("output must be one of [table json]")
+			}
+			if o.Keep < 0 {
+				return fmt.Errorf("invalid --keep %d: must be 0 or more", o.Keep)
+			}
+
+			s, err := o.Store(ctx, ro)
+			if err != nil {
+				return err
+			}
+			lock := o.LockShared
+			if o.Trim {
+				lock = o.LockExclusive
+			}
+			if err := lock(ctx, s, cmd.CommandPath()); err != nil {
+				return err
+			}
+			defer s.Unlock()
+
+			return store.HistoryCmd(ctx, o, s)
+		},
+	}
+	o.AddFlags(cmd)
+
+	return cmd
+}
+
+func addStoreRollback(rso *flags.StoreRootOpts, ro *flags.CliRootOpts) *cobra.Command {
+	o := &flags.RollbackOpts{StoreRootOpts: rso}
+
+	cmd := &cobra.Command{
+		Use:   "rollback <generation>",
+		Short: "Restore the content store index to a generation from its history",
+		Long: `Replace the store's index with a generation listed by 'hauler store history':
+artifacts added since are dropped from the index, and artifacts removed since are
+restored. Dropped artifacts' blobs stay in the store until 'hauler store gc' and
+the generation leaves the history. The rollback is itself kept as a generation,
+so it can be undone the same way.`,
+		Example: `  # preview a rollback
+  hauler store rollback 12 --dry-run
+
+  # restore the index as it was before generation 12's command ran
+  hauler store rollback 12`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+
+			gen, err := strconv.Atoi(args[0])
+			if err != nil || gen <= 0 {
+				return fmt.Errorf("invalid generation [%s]... want a number from 'hauler store history'", args[0])
+			}
+
+			s, err := o.Store(ctx, ro)
+			if err != nil {
+				return err
+			}
+			lock := o.LockExclusive
+			if o.DryRun {
+				lock = o.LockShared
+			}
+			if err := lock(ctx, s, cmd.CommandPath()); err != nil {
+				return err
+			}
+			defer s.Unlock()
+
+			return store.RollbackCmd(ctx, o, s, gen, ro)
+		},
+	}
+	o.AddFlags(cmd)
+
+	return cmd
+}
+
+func addStoreMerge(rso *flags.StoreRootOpts, ro *flags.CliRootOpts) *cobra.Command {
+	o := &flags.MergeOpts{StoreRootOpts: rso}
+
+	cmd := &cobra.Command{
+		Use:   "merge",
+		Short: "Merge the contents of other stores into the content store",
+		Long: `Copy every artifact of each --from store, and the blobs it needs, straight into
+the content store... no haul is saved or extracted along the way, and blobs the
+store already holds are not copied again. Each source's store audit log is
+appended onto the content store's.
+
+Sources are merged in order. When a reference is already stored with a different
+digest, --conflict decides what happens to it, along with the signatures,
+attestations, sboms, and referrers saved for it:
+
+  fail           refuse the merge before anything is written (default)
+  keep-existing  leave the stored artifact as is
+  overwrite      replace the stored artifact with the source's
+  rename         store the source's artifact under its tag suffixed with the
+                 source's short store id... i.e. app:v1-ec520cf6`,
+		Example: `  # combine two team stores into the default store
+  hauler store merge --from /data/team-a --from /data/team-b
+
+  # merge a store by id, keeping both versions of any conflicting tag
+  hauler store merge --from ec520cf6 --conflict rename`,
+		Args: cobra.ExactArgs(0),
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if o.OutputFormat == "json" {
+				log.FromContext(cmd.Context()).SetLevel("fatal")
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+
+			if o.OutputFormat != "table" && o.OutputFormat != "json" {
+				return fmt.Errorf("output must be one of [table json]")
+			}
+
+			s, err := o.Store(ctx, ro)
+			if err != nil {
+				return err
+			}
+			if err := o.LockExclusive(ctx, s, cmd.CommandPath()); err != nil {
+				return err
+			}
+			defer s.Unlock()
+
+			return store.MergeCmd(ctx, o, s, ro)
+		},
+	}
+	o.AddFlags(cmd)
+
+	return cmd
+}
+
+func addStoreTag(rso *flags.StoreRootOpts, ro *flags.CliRootOpts) *cobra.Command {
+	o := &flags.TagOpts{StoreRootOpts: rso}
+
+	cmd := &cobra.Command{
+		Use:   "tag <src-ref> <dst-ref>",
+		Short: "Store an artifact in the content store under another reference",
+		Long: `Give an image, image index, chart, or file already in the store another reference,
+the same way --rewrite does when it is added. Its signatures, attestations, sboms,
+and referrers follow it to the new reference, and its original reference is kept
+for 'hauler store create manifest'. Without --move both references are kept.
+
+Use --file to tag many artifacts at once from a mapping file with one
+'<src-ref> <dst-ref>' pair per line. Every mapping is checked before any is
+applied.`,
+		Example: `  # alias an image under an internal name
+  hauler store tag ghcr.io/hauler-dev/library/busybox:stable platform/busybox:stable
+
+  # rename a chart, dropping the old reference
+  hauler store tag hauler/rancher:2.8.5 rancher/rancher:2.8.5 --move
+
+  # retag everything listed in a mapping file
+  hauler store tag --file retag.txt`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if o.File != "" {
+				return cobra.ExactArgs(0)(cmd, args)
+			}
+			return cobra.ExactArgs(2)(cmd, args)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+
+			s, err := o.Store(ctx, ro)
+			if err != nil {
+				return err
+			}
+			if err := o.LockExclusive(ctx, s, cmd.CommandPath()); err != nil {
+				return err
+			}
+			defer s.Unlock()
+
+			return store.TagCmd(ctx, o, s, args, ro)
+		},
+	}
+	o.AddFlags(cmd)
+
+	return cmd
+}
+
+func addStoreLabel(rso *flags.StoreRootOpts, ro *flags.CliRootOpts) *cobra.Command {
+	o := &flags.LabelOpts{StoreRootOpts: rso}
+
+	cmd := &cobra.Command{
+		Use:   "label [<artifact-ref> | --select <selector>] <key>=<value>... <key>-...",
+		Short: "Set or unset labels on artifacts in the content store",
+		Long: `Set labels with key=value and unset them with key-, on an artifact named by its
+reference or on every artifact matching --select. Labels are the same ones --label
+sets on 'hauler store add', are shown by 'hauler store info', are kept by 'hauler
+store create manifest', and can be matched with --select label.<key>. An artifact's
+signatures, attestations, sboms, and referrers are matched through its labels.`,
+		Example: `  # label an image
+  hauler store label ghcr.io/hauler-dev/library/busybox:stable team=platform ticket=OPS-1234
+
+  # unset a label
+  hauler store label ghcr.io/hauler-dev/library/busybox:stable ticket-
+
+  # label every chart from a single repository
+  hauler store label --select 'type=chart,ref=hauler/rancher*' team=rancher`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+
+			s, err := o.Store(ctx, ro)
+			if err != nil {
+				return err
+			}
+			if err := o.LockExclusive(ctx, s, cmd.CommandPath()); err != nil {
+				return err
+			}
+			defer s.Unlock()
+
+			return store.LabelCmd(ctx, o, s, args, ro)
+		},
+	}
+	o.AddFlags(cmd)
+
+	return cmd
+}
+
 func addStoreCopy(rso *flags.StoreRootOpts, ro *flags.CliRootOpts) *cobra.Command {
 	o := &flags.CopyOpts{StoreRootOpts: rso}
 
@@ -312,8 +822,31 @@ func addStoreCopy(rso *flags.StoreRootOpts, ro *flags.CliRootOpts) *cobra.Comman
 		Short: "Copy all store content to another location",
 		Example: `  # supported copy target prefixes
   registry://  | reg:// | oci:// - Pushes the store to an OCI registry
-  directory:// | dir://          - Extracts the store to a directory`,
+  directory:// | dir://          - Extracts the store to a directory
+
+  # copy only the images from docker hub, with their signatures, to a registry
+  hauler store copy registry://registry.example.com --select 'registry=docker.io,type=image'
+
+  # preview what would be pushed
+  hauler store copy registry://registry.example.com --select 'ref=*/rancher/*' --dry-run`,
 		Args: cobra.ExactArgs(1),
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			n, err := flags.ResolveConcurrency(cmd.Flags().Changed("concurrency"), o.Concurrency)
+			if err != nil {
+				return err
+			}
+			o.Concurrency = n
+
+			// same precedence as sync: an explicit --blob-concurrency wins,
+			// otherwise the ceiling is derived from --concurrency
+			bc, err := flags.SyncBlobConcurrency(rso.BlobConcurrency, n)
+			if err != nil {
+				return err
+			}
+			rso.BlobConcurrency = bc
+
+			return nil
+		},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			ctx := cmd.Context()
 
@@ -321,6 +854,10 @@ func addStoreCopy(rso *flags.StoreRootOpts, ro *flags.CliRootOpts) *cobra.Comman
 			if err != nil {
 				return err
 			}
+			if err := o.LockShared(ctx, s, cmd.CommandPath()); err != nil {
+				return err
+			}
+			defer s.Unlock()
 
 			return store.CopyCmd(ctx, o, s, args[0], ro)
 		},
@@ -361,7 +898,10 @@ func addStoreAddFile(rso *flags.StoreRootOpts, ro *flags.CliRootOpts) *cobra.Com
   hauler store add file https://get.rke2.io/install.sh
 
   # fetch remote file and assign new name
-  hauler store add file https://get.hauler.dev --name hauler-install.sh`,
+  hauler store add file https://get.hauler.dev --name hauler-install.sh
+
+  # fetch remote file and label it
+  hauler store add file https://get.rke2.io/install.sh --label team=platform --label ticket=OPS-1234`,
 		Args: cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			ctx := cmd.Context()
@@ -370,6 +910,10 @@ func addStoreAddFile(rso *flags.StoreRootOpts, ro *flags.CliRootOpts) *cobra.Com
 			if err != nil {
 				return err
 			}
+			if err := o.LockExclusive(ctx, s, cmd.CommandPath()); err != nil {
+				return err
+			}
+			defer s.Unlock()
 
 			return store.AddFileCmd(ctx, o, s, args[0], ro)
 		},
@@ -405,7 +949,10 @@ func addStoreAddImage(rso *flags.StoreRootOpts, ro *flags.CliRootOpts) *cobra.Co
   hauler store add image busybox --rewrite custom-path/busybox:latest
 
   # add image from local Docker daemon
-  hauler store add image my-local-app:latest --local`,
+  hauler store add image my-local-app:latest --local
+
+  # fetch image and label it
+  hauler store add image busybox --label team=platform --label ticket=OPS-1234`,
 		Args: cobra.ExactArgs(1),
 		PreRunE: func(cmd *cobra.Command, args []string) error {
 			// Check for ca-file & insecure-skip-tls-verify env variables
@@ -425,6 +972,10 @@ func addStoreAddImage(rso *flags.StoreRootOpts, ro *flags.CliRootOpts) *cobra.Co
 			if err != nil {
 				return err
 			}
+			if err := o.LockExclusive(ctx, s, cmd.CommandPath()); err != nil {
+				return err
+			}
+			defer s.Unlock()
 
 			return store.AddImageCmd(ctx, o, s, args[0], rso, ro)
 		},
@@ -459,7 +1010,10 @@ func addStoreAddChart(rso *flags.StoreRootOpts, ro *flags.CliRootOpts) *cobra.Co
   hauler store add chart rancher --repo https://releases.rancher.com/server-charts/latest --version 2.10.1
 
   # fetch remote helm chart and rewrite path
-  hauler store add chart hauler-helm --repo oci://ghcr.io/hauler-dev --rewrite custom-path/hauler-chart:latest`,
+  hauler store add chart hauler-helm --repo oci://ghcr.io/hauler-dev --rewrite custom-path/hauler-chart:latest
+
+  # fetch remote oci chart with its images and label them all
+  hauler store add chart hauler-helm --repo oci://ghcr.io/hauler-dev --add-images --label team=platform`,
 		Args: cobra.ExactArgs(1),
 		PreRunE: func(cmd *cobra.Command, args []string) error {
 			n, err := flags.ResolveConcurrency(cmd.Flags().Changed("concurrency"), o.Concurrency)
@@ -486,6 +1040,10 @@ func addStoreAddChart(rso *flags.StoreRootOpts, ro *flags.CliRootOpts) *cobra.Co
 			if err != nil {
 				return err
 			}
+			if err := o.LockExclusive(ctx, s, cmd.CommandPath()); err != nil {
+				return err
+			}
+			defer s.Unlock()
 
 			return store.AddChartCmd(ctx, o, s, args[0], rso, ro)
 		},
@@ -533,6 +1091,10 @@ func addStoreCreateManifest(rso *flags.StoreRootOpts, ro *flags.CliRootOpts) *co
 			if err != nil {
 				return err
 			}
+			if err := o.LockShared(ctx, s, cmd.CommandPath()); err != nil {
+				return err
+			}
+			defer s.Unlock()
 
 			return store.CreateManifestCmd(ctx, o, s)
 		},
@@ -545,7 +1107,7 @@ func addStoreCreateManifest(rso *flags.StoreRootOpts, ro *flags.CliRootOpts) *co
 func addStoreRemove(rso *flags.StoreRootOpts, ro *flags.CliRootOpts) *cobra.Command {
 	o := &flags.RemoveOpts{}
 	cmd := &cobra.Command{
-		Use:   "remove <artifact-ref>",
+		Use:   "remove [<artifact-ref> | --select <selector>]",
 		Short: "Remove an artifact from the content store",
 		Example: `  # remove an image using full store reference
   hauler store info
@@ -566,8 +1128,14 @@ func addStoreRemove(rso *flags.StoreRootOpts, ro *flags.CliRootOpts) *cobra.Comm
   hauler store remove busybox
 
   # force remove without verification
-  hauler store remove busybox:latest --force`,
-		Args: cobra.ExactArgs(1),
+  hauler store remove busybox:latest --force
+
+  # preview removing every docker hub image added more than 30 days ago
+  hauler store remove --select 'registry=docker.io,type=image,added<30d' --dry-run
+
+  # remove every artifact except the ones labeled to keep
+  hauler store remove --select '!label.keep'`,
+		Args: cobra.MaximumNArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			ctx := cmd.Context()
 
@@ -575,8 +1143,92 @@ func addStoreRemove(rso *flags.StoreRootOpts, ro *flags.CliRootOpts) *cobra.Comm
 			if err != nil {
 				return err
 			}
+			if err := rso.LockExclusive(ctx, s, cmd.CommandPath()); err != nil {
+				return err
+			}
+			defer s.Unlock()
+
+			var ref string
+			if len(args) > 0 {
+				ref = args[0]
+			}
+			return store.RemoveCmd(ctx, o, s, ref, ro, rso)
+		},
+	}
+	o.AddFlags(cmd)
+
+	return cmd
+}
+
+func addStoreVerify(rso *flags.StoreRootOpts, ro *flags.CliRootOpts) *cobra.Command {
+	o := &flags.VerifyOpts{StoreRootOpts: rso}
+
+	cmd := &cobra.Command{
+		Use:   "verify [image-ref...]",
+		Short: "Verify the signatures of images in the content store, offline",
+		Long: `Verify the cosign signatures of images in the content store against the
+signatures, attestations, and sigstore bundles saved alongside them... without
+any network access. Verify with a public key (--key), or keylessly against a
+sigstore trusted root (--trusted-root) plus the expected certificate identity.`,
+		Example: `  # verify every image in the store against a public key
+  hauler store verify --key cosign.pub
+
+  # verify images with 'rancher' in the reference
+  hauler store verify rancher --key cosign.pub
+
+  # verify keyless signatures against a bundled trusted root
+  hauler store verify --trusted-root trusted_root.json \
+    --certificate-identity-regexp 'https://github.com/rancher/.*' \
+    --certificate-oidc-issuer https://token.actions.githubusercontent.com`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+
+			s, err := o.Store(ctx, ro)
+			if err != nil {
+				return err
+			}
+			if err := o.LockShared(ctx, s, cmd.CommandPath()); err != nil {
+				return err
+			}
+			defer s.Unlock()
+
+			return store.VerifyCmd(ctx, o, s, args, ro)
+		},
+	}
+	o.AddFlags(cmd)
+
+	return cmd
+}
+
+func addStoreSign(rso *flags.StoreRootOpts, ro *flags.CliRootOpts) *cobra.Command {
+	o := &flags.SignOpts{StoreRootOpts: rso}
+
+	cmd := &cobra.Command{
+		Use:   "sign image-ref...",
+		Short: "Sign images in the content store with a cosign key",
+		Long: `Sign images already in the content store with a cosign private key, without
+any network access. Signatures are stored alongside each image like pulled
+signatures, so 'hauler store copy registry://' pushes them next to the image.
+Useful for images moved with --rewrite or added from the local docker daemon.`,
+		Example: `  # sign every image with 'myorg/app' in the reference
+  hauler store sign myorg/app --key cosign.key
+
+  # also attach a sigstore bundle as an OCI 1.1 referrer
+  hauler store sign myorg/app --key cosign.key --referrer`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+
+			s, err := o.Store(ctx, ro)
+			if err != nil {
+				return err
+			}
+			if err := o.LockExclusive(ctx, s, cmd.CommandPath()); err != nil {
+				return err
+			}
+			defer s.Unlock()
 
-			return store.RemoveCmd(ctx, o, s, args[0], ro, rso)
+			return store.SignCmd(ctx, o, s, args, ro)
 		},
 	}
 	o.AddFlags(cmd)
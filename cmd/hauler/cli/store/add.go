@@ -56,10 +56,16 @@ func AddFileCmd(ctx context.Context, o *flags.AddFileOpts, s *store.Layout, refe
 		}
 	}()
 
+	labels, err := store.ParseLabels(o.Labels)
+	if err != nil {
+		return err
+	}
+
 	cfg := v1.File{
 		Path:                  reference,
 		CaFile:                o.CaFile,
 		InsecureSkipTLSVerify: o.InsecureSkipTLSVerify,
+		Labels:                labels,
 	}
 	if len(o.Name) > 0 {
 		cfg.Name = o.Name
@@ -81,6 +87,14 @@ func storeFile(ctx context.Context, s *store.Layout, fi v1.File, ro *flags.CliRo
 		return err
 	}
 
+	rep := syncReportFromContext(ctx).begin("file", fi.Path, s)
+	defer rep.done()
+
+	if err := validateLabels(fi.Labels); err != nil {
+		rep.fail(reportFetchFailed, err)
+		return err
+	}
+
 	copts := getter.ClientOptions{
 		NameOverride:          fi.Name,
 		InsecureSkipTLSVerify: fi.InsecureSkipTLSVerify,
@@ -90,6 +104,7 @@ func storeFile(ctx context.Context, s *store.Layout, fi v1.File, ro *flags.CliRo
 	f := file.NewFile(fi.Path, file.WithClient(getter.NewClient(copts)), file.WithContext(ctx))
 	ref, err := reference.NewTagged(f.Name(fi.Path), consts.DefaultTag)
 	if err != nil {
+		rep.fail(reportFetchFailed, err)
 		if ignoreErrors {
 			log.BaseFromContext(ctx).Warnf("unable to derive a store reference for file [%s]: %v... skipping...", fi.Path, err)
 			return nil
@@ -99,14 +114,50 @@ func storeFile(ctx context.Context, s *store.Layout, fi v1.File, ro *flags.CliRo
 	}
 
 	log.BaseFromContext(ctx).Debugf("adding file [%s] to the store as [%s]", fi.Path, ref.Name())
+	syncPruneFromContext(ctx).keepName(s, ref.Name())
+	previous := rep.indexedDigest(s, ref.Name())
+
+	if err := admissionFromContext(ctx).admitFile(ctx, s, fi.Path); err != nil {
+		return failAdmission(ctx, rep, "file", audit.SanitizeURL(fi.Path), err, ignoreErrors)
+	}
+
+	// Only a sync under --lock/--locked needs the content digest up front.
+	// Layers() fetches and memoizes, so AddArtifact below reuses the content.
+	lk := syncLockFromContext(ctx)
+	var contentDigest string
+	if lk != nil {
+		err = retry.Operation(ctx, rso, ro, func() error {
+			rep.attempt()
+			var digestErr error
+			contentDigest, digestErr = artifactDigest(f)
+			return digestErr
+		})
+		if err == nil {
+			err = lk.checkFile(fi, contentDigest)
+		}
+		if err != nil {
+			rep.fail(reportFetchFailed, err)
+			if ignoreErrors {
+				log.BaseFromContext(ctx).Warnf("unable to pin file [%s]: %v... skipping...", fi.Path, err)
+				return nil
+			} else if errors.Is(err, context.Canceled) {
+				log.BaseFromContext(ctx).Debugf("unable to pin file [%s]: %v", fi.Path, err)
+				return err
+			}
+			log.BaseFromContext(ctx).Errorf("unable to pin file [%s]: %v", fi.Path, err)
+			return err
+		}
+	}
 
 	var desc ocispec.Descriptor
 	err = retry.Operation(ctx, rso, ro, func() error {
+		rep.attempt()
 		var addErr error
 		desc, addErr = s.AddArtifact(ctx, f, ref.Name())
 		return addErr
 	})
 	if err != nil {
+		rep.fail(reportFetchFailed, err)
 		if ignoreErrors {
 			log.BaseFromContext(ctx).Warnf("unable to add file [%s] to store: %v... skipping...", fi.Path, err)
 			return nil
@@ -133,9 +184,11 @@ func storeFile(ctx context.Context, s *store.Layout, fi v1.File, ro *flags.CliRo
 	// local ones) so `store create manifest` can recover it later; nothing else in
 	// the store retains it.
 	desc.Annotations[consts.OriginalRefAnnotation] = resolvedPath
+	store.ApplyLabels(desc.Annotations, fi.Labels, nil)
 	if err := s.OCI.AddIndex(desc); err != nil {
 		return err
 	}
+	lk.recordFile(fi, contentDigest)
 
 	if auditLevel(ro) != "none" {
 		e := audit.Entry{
@@ -175,6 +228,11 @@ func storeFile(ctx context.Context, s *store.Layout, fi v1.File, ro *flags.CliRo
 		stats.Layers.Store(1)
 		stats.Bytes.Store(size)
 	}
+	var written int64
+	if stats != nil {
+		written = stats.Bytes.Load()
+	}
+	rep.stored(ref.Name(), desc.Digest.String(), previous, written)
 
 	log.BaseFromContext(ctx).Infof("%s", formatAddedLine(ref.Name(), stats, time.Since(start)))
 
@@ -198,6 +256,18 @@ func AddImageCmd(ctx context.Context, o *flags.AddImageOpts, s *store.Layout, re
 		}
 	}()
 
+	labels, err := store.ParseLabels(o.Labels)
+	if err != nil {
+		return err
+	}
+
+	adm, err := newAdmission(ctx, o.Policy, "store add image", rso, ro)
+	if err != nil {
+		return err
+	}
+	defer adm.Close()
+	ctx = withAdmission(ctx, adm)
+
 	cfg := v1.Image{
 		Name:                         reference,
 		Key:                          o.Key,
@@ -213,6 +283,7 @@ func AddImageCmd(ctx context.Context, o *flags.AddImageOpts, s *store.Layout, re
 		Local:                        o.Local,
 		CaFile:                       o.CaFile,
 		InsecureSkipTLSVerify:        o.InsecureSkipTLSVerify,
+		Labels:                       labels,
 	}
 
 	if o.Local {
@@ -351,6 +422,11 @@ func AddChartCmd(ctx context.Context, o *flags.AddChartOpts, s *store.Layout, ch
 		}
 	}()
 
+	labels, err := store.ParseLabels(o.Labels)
+	if err != nil {
+		return err
+	}
+
 	l.Infof("adding chart [%s] to the store", chartName)
 
 	// The job owns its *action.ChartPathOptions rather than the caller's, so
@@ -365,6 +441,7 @@ func AddChartCmd(ctx context.Context, o *flags.AddChartOpts, s *store.Layout, ch
 			Name:    chartName,
 			RepoURL: o.ChartOpts.RepoURL,
 			Version: o.ChartOpts.Version,
+			Labels:  labels,
 		},
 		opts:    opts,
 		rewrite: o.Rewrite,
@@ -400,8 +477,17 @@ func storeLocalImage(ctx context.Context, s *store.Layout, i v1.Image, _ *flags.
 
 	l.Debugf("resolving image [%s] from local Docker daemon (rewrite=%q)", i.Name, rewrite)
 
+	rep := syncReportFromContext(ctx).begin("image", i.Name, s)
+	defer rep.done()
+
+	if err := validateLabels(i.Labels); err != nil {
+		rep.fail(reportFetchFailed, err)
+		return err
+	}
+
 	r, err := name.ParseReference(i.Name)
 	if err != nil {
+		rep.fail(reportFetchFailed, err)
 		if ignoreErrors {
 			l.Warnf("unable to parse image [%s]: %v... skipping...", i.Name, err)
 			return nil
@@ -409,9 +495,17 @@ func storeLocalImage(ctx context.Context, s *store.Layout, i v1.Image, _ *flags.
 		l.Errorf("unable to parse image [%s]: %v", i.Name, err)
 		return err
 	}
+	syncPruneFromContext(ctx).keepImage(s, r)
+	previous := rep.indexedDigest(s, strings.TrimPrefix(r.Name(), r.Context().RegistryStr()+"/"))
+
+	if err := admissionFromContext(ctx).admitLocalImage(ctx, s, r); err != nil {
+		return failAdmission(ctx, rep, "image", r.Name(), err, ignoreErrors)
+	}
 
+	rep.attempt()
 	localDigest, err := s.AddLocalImage(ctx, r.Name())
 	if err != nil {
+		rep.fail(reportFetchFailed, err)
 		if ignoreErrors {
 			l.Warnf("unable to add image [%s] from Docker daemon to store: %v... skipping...", r.Name(), err)
 			return nil
@@ -420,21 +514,17 @@ func storeLocalImage(ctx context.Context, s *store.Layout, i v1.Image, _ *flags.
 		return err
 	}
 
+	// labels go on before any rewrite, which carries them to the new name
+	if err := labelImage(s, r, i.Labels); err != nil {
+		return err
+	}
+
 	if rewrite != "" {
-		rawRewrite := rewrite
-		rewrite = strings.TrimPrefix(rewrite, "/")
-		if !strings.Contains(rewrite, ":") {
-			if tag, ok := r.(name.Tag); ok {
-				rewrite = rewrite + ":" + tag.TagStr()
-			} else {
-				return fmt.Errorf("cannot rewrite digest reference [%s] without an explicit tag in the rewrite", r.Name())
-			}
-		}
-		newRef, err := name.ParseReference(rewrite)
+		newRef, err := imageRewriteRef(r, rewrite)
 		if err != nil {
-			return fmt.Errorf("unable to parse rewrite name [%s]: %w", rewrite, err)
+			return err
 		}
-		if err := rewriteReference(ctx, s, r, newRef, rawRewrite); err != nil {
+		if err := rewriteReference(ctx, s, r, newRef, rewrite); err != nil {
 			return err
 		}
 	}
@@ -468,6 +558,8 @@ func storeLocalImage(ctx context.Context, s *store.Layout, i v1.Image, _ *flags.
 		l.Debugf("generated audit id of [none]")
 	}
 
+	rep.stored(r.Name(), localDigest, previous, 0)
+
 	l.Infof("%s", formatAddedLine(r.Name()+" from local Docker daemon", nil, time.Since(start)))
 	return nil
 }
@@ -494,8 +586,14 @@ func storeImage(ctx context.Context, s *store.Layout, i v1.Image, platform strin
 
 	log.BaseFromContext(ctx).Debugf("resolving image [%s] (verified=%t, platform=%q, excludeExtras=%t, insecureSkipTLSVerify=%t, caFile=%q, rewrite=%q, digest=%q)", i.Name, verified, platform, excludeExtras, insecureSkipTLSVerify, caFile, rewrite, pinnedDigest)
 
+	rep, owned := reportEntryFor(ctx, "image", i.Name, s)
+	if owned {
+		defer rep.done()
+	}
+
 	r, err := name.ParseReference(i.Name)
 	if err != nil {
+		rep.fail(reportFetchFailed, err)
 		if ignoreErrors {
 			log.BaseFromContext(ctx).Warnf("unable to parse image [%s]: %v... skipping...", i.Name, err)
 			return nil
@@ -504,6 +602,14 @@ func storeImage(ctx context.Context, s *store.Layout, i v1.Image, platform strin
 			return err
 		}
 	}
+	syncPruneFromContext(ctx).keepImage(s, r)
+	previous := rep.indexedDigest(s, strings.TrimPrefix(r.Name(), r.Context().RegistryStr()+"/"))
+
+	pinnedDigest, admitted, err := admissionFromContext(ctx).admitImage(ctx, s, r, i, platform, pinnedDigest)
+	if err != nil {
+		return failAdmission(ctx, rep, "image", r.Name(), err, ignoreErrors)
+	}
+	verified = verified || admitted
 
 	// fetch image along with any associated signatures and attestations.
 	// A fresh store.ImageStats is built inside the closure on every attempt,
@@ -514,6 +620,7 @@ func storeImage(ctx context.Context, s *store.Layout, i v1.Image, platform strin
 	var imageDigest string
 	var stats *store.ImageStats
 	err = retry.Operation(ctx, rso, ro, func() error {
+		rep.attempt()
 		attemptStats := &store.ImageStats{}
 		var addErr error
 		imageDigest, addErr = s.AddImage(store.WithImageStats(ctx, attemptStats), r.Name(), platform, excludeExtras, pinnedDigest, insecureSkipTLSVerify, caFile)
@@ -523,6 +630,7 @@ func storeImage(ctx context.Context, s *store.Layout, i v1.Image, platform strin
 		return addErr
 	})
 	if err != nil {
+		rep.fail(reportFetchFailed, err)
 		if ignoreErrors {
 			log.BaseFromContext(ctx).Warnf("unable to add image [%s] to store: %v... skipping...", r.Name(), err)
 			return nil
@@ -540,22 +648,18 @@ func storeImage(ctx context.Context, s *store.Layout, i v1.Image, platform strin
 		}
 	}
 
+	// labels go on before any rewrite, which carries them to the new name
+	if err := labelImage(s, r, i.Labels); err != nil {
+		return err
+	}
+
 	if rewrite != "" {
-		rawRewrite := rewrite
-		rewrite = strings.TrimPrefix(rewrite, "/")
-		if !strings.Contains(rewrite, ":") {
-			if tag, ok := r.(name.Tag); ok {
-				rewrite = rewrite + ":" + tag.TagStr()
-			} else {
-				return fmt.Errorf("cannot rewrite digest reference [%s] without an explicit tag in the rewrite", r.Name())
-			}
-		}
 		// rename image name in store
-		newRef, err := name.ParseReference(rewrite)
+		newRef, err := imageRewriteRef(r, rewrite)
 		if err != nil {
-			return fmt.Errorf("unable to parse rewrite name [%s]: %w", rewrite, err)
+			return err
 		}
-		if err := rewriteReference(ctx, s, r, newRef, rawRewrite); err != nil {
+		if err := rewriteReference(ctx, s, r, newRef, rewrite); err != nil {
 			return err
 		}
 	}
@@ -599,11 +703,72 @@ func storeImage(ctx context.Context, s *store.Layout, i v1.Image, platform strin
 		l.Debugf("generated audit id of [none]")
 	}
 
+	// a pinned fetch stored exactly pinnedDigest; under a platform filter
+	// imageDigest is the selected child, which a later pin can't name
+	locked := pinnedDigest
+	if locked == "" {
+		locked = imageDigest
+	}
+	syncLockFromContext(ctx).recordImage(i.Name, platform, locked)
+
+	var written int64
+	if stats != nil {
+		written = stats.Bytes.Load()
+	}
+	rep.stored(r.Name(), imageDigest, previous, written)
+
 	log.BaseFromContext(ctx).Infof("%s", formatAddedLine(r.Name(), stats, time.Since(start)))
 	return nil
 }
 
 func rewriteReference(ctx context.Context, s *store.Layout, oldRef name.Reference, newRef name.Reference, rawRewrite string) error {
+	oldTotal, oldTotalReg, newTotal, newTotalReg := rewriteNames(oldRef, newRef, rawRewrite)
+
+	log.BaseFromContext(ctx).Infof("rewriting [%s] to [%s]", oldTotalReg, newTotalReg)
+
+	//find and update reference
+	matched, err := s.OCI.UpdateAnnotations(
+		func(d ocispec.Descriptor) bool {
+			return d.Annotations[ocispec.AnnotationRefName] == oldTotal && d.Annotations[consts.ContainerdImageNameKey] == oldTotalReg
+		},
+		func(a map[string]string) {
+			a[ocispec.AnnotationRefName] = newTotal
+			a[consts.ContainerdImageNameKey] = newTotalReg
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	if matched == 0 {
+		return fmt.Errorf("could not find image [%s] in store", oldRef.Name())
+	}
+	syncPruneFromContext(ctx).keepName(s, newTotal)
+
+	return nil
+}
+
+// imageRewriteRef parses an image's --rewrite. A rewrite that omits a tag
+// inherits r's, which a digest reference doesn't have.
+func imageRewriteRef(r name.Reference, rewrite string) (name.Reference, error) {
+	rewrite = strings.TrimPrefix(rewrite, "/")
+	if !strings.Contains(rewrite, ":") {
+		tag, ok := r.(name.Tag)
+		if !ok {
+			return nil, fmt.Errorf("cannot rewrite digest reference [%s] without an explicit tag in the rewrite", r.Name())
+		}
+		rewrite = rewrite + ":" + tag.TagStr()
+	}
+	newRef, err := name.ParseReference(rewrite)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse rewrite name [%s]: %w", rewrite, err)
+	}
+	return newRef, nil
+}
+
+// rewriteNames computes the store reference ("repo:tag") and registry-qualified
+// name an image has before and after a --rewrite to newRef.
+func rewriteNames(oldRef name.Reference, newRef name.Reference, rawRewrite string) (oldTotal, oldTotalReg, newTotal, newTotalReg string) {
 	//TODO: improve string manipulation
 	oldRefContext := oldRef.Context()
 	newRefContext := newRef.Context()
@@ -636,32 +801,12 @@ func rewriteReference(ctx context.Context, s *store.Layout, oldRef name.Referenc
 			newRepo = strings.TrimPrefix(newRepo, "library/")
 		}
 	}
-	oldTotal := oldRepo + ":" + oldTag
-	newTotal := newRepo + ":" + newTag
-	oldTotalReg := oldRegistry + "/" + oldTotal
-	newTotalReg := newRegistry + "/" + newTotal
-
-	log.BaseFromContext(ctx).Infof("rewriting [%s] to [%s]", oldTotalReg, newTotalReg)
-
-	//find and update reference
-	matched, err := s.OCI.UpdateAnnotations(
-		func(d ocispec.Descriptor) bool {
-			return d.Annotations[ocispec.AnnotationRefName] == oldTotal && d.Annotations[consts.ContainerdImageNameKey] == oldTotalReg
-		},
-		func(a map[string]string) {
-			a[ocispec.AnnotationRefName] = newTotal
-			a[consts.ContainerdImageNameKey] = newTotalReg
-		},
-	)
-	if err != nil {
-		return err
-	}
-
-	if matched == 0 {
-		return fmt.Errorf("could not find image [%s] in store", oldRef.Name())
-	}
+	oldTotal = oldRepo + ":" + oldTag
+	newTotal = newRepo + ":" + newTag
+	oldTotalReg = oldRegistry + "/" + oldTotal
+	newTotalReg = newRegistry + "/" + newTotal
 
-	return nil
+	return oldTotal, oldTotalReg, newTotal, newTotalReg
 }
 
 // imageregex parses image references starting with "image:" and with optional spaces or optional quotes
@@ -1144,28 +1289,70 @@ func fetchChart(ctx context.Context, s *store.Layout, j chartJob, tempRoot strin
 
 	log.BaseFromContext(ctx).Debugf("adding chart [%s] to the store", displayName)
 
-	chrt, err := chart.NewChart(j.cfg.Name, j.opts.ChartOpts)
+	rep := syncReportFromContext(ctx).begin("chart", j.cfg.Name, s)
+	defer rep.done()
+
+	if err := validateLabels(j.cfg.Labels); err != nil {
+		rep.fail(reportFetchFailed, err)
+		return nil, nil, err
+	}
+
+	// --locked swaps the manifest's version (possibly a constraint) for the
+	// exact version it resolved to, on a copy so sibling jobs are untouched
+	lk := syncLockFromContext(ctx)
+	chartOpts := j.opts.ChartOpts
+	version, err := lk.lockedChartVersion(j)
+	if err != nil {
+		rep.fail(reportFetchFailed, err)
+		return nil, nil, err
+	}
+	if version != chartOpts.Version {
+		pinned := *chartOpts
+		pinned.Version = version
+		chartOpts = &pinned
+	}
+
+	chrt, err := chart.NewChart(j.cfg.Name, chartOpts)
 	if err != nil {
+		rep.fail(reportFetchFailed, err)
 		return nil, nil, err
 	}
 
 	c, err := chrt.Load()
 	if err != nil {
+		rep.fail(reportFetchFailed, err)
 		return nil, nil, err
 	}
 
 	ref, err := reference.NewTagged(c.Name(), c.Metadata.Version)
 	if err != nil {
+		rep.fail(reportFetchFailed, err)
 		return nil, nil, err
 	}
+	syncPruneFromContext(ctx).keepName(s, ref.Name())
+	previous := rep.indexedDigest(s, ref.Name())
+
+	var chartDigest string
+	if lk != nil {
+		if chartDigest, err = artifactDigest(chrt); err != nil {
+			rep.fail(reportFetchFailed, err)
+			return nil, nil, err
+		}
+		if err := lk.checkChart(j, chartDigest); err != nil {
+			rep.fail(reportFetchFailed, err)
+			return nil, nil, err
+		}
+	}
 
 	var chartDesc ocispec.Descriptor
 	err = retry.Operation(ctx, rso, ro, func() error {
+		rep.attempt()
 		var addErr error
 		chartDesc, addErr = s.AddArtifact(ctx, chrt, ref.Name())
 		return addErr
 	})
 	if err != nil {
+		rep.fail(reportFetchFailed, err)
 		if ignoreErrors {
 			log.BaseFromContext(ctx).Warnf("unable to add chart [%s] to store: %v... skipping...", ref.Name(), err)
 			return nil, nil, nil
@@ -1186,15 +1373,20 @@ func fetchChart(ctx context.Context, s *store.Layout, j chartJob, tempRoot strin
 	// --rewrite is ever applied. This must happen before rewriteChartReference so its
 	// retag of AnnotationRefName isn't clobbered by re-adding a pre-rewrite chartDesc.
 	chartDesc.Annotations[consts.OriginalRefAnnotation] = encodeOriginalChartRef(j.cfg.RepoURL, ref.Name())
+	store.ApplyLabels(chartDesc.Annotations, j.cfg.Labels, nil)
 	if err := s.OCI.AddIndex(chartDesc); err != nil {
+		rep.fail(reportFetchFailed, err)
 		return nil, nil, err
 	}
 
 	if j.rewrite != "" {
 		if err := rewriteChartReference(ctx, s, ref, j.rewrite); err != nil {
+			rep.fail(reportFetchFailed, err)
 			return nil, nil, err
 		}
 	}
+	lk.recordChart(j, c.Metadata.Version, chartDigest)
+	rep.stored(ref.Name(), chartDesc.Digest.String(), previous, 0)
 
 	if auditLevel(ro) != "none" {
 		e := audit.Entry{
@@ -1403,6 +1595,7 @@ func fetchChart(ctx context.Context, s *store.Layout, j chartJob, tempRoot strin
 					Name:                  relocated,
 					CaFile:                j.opts.ChartOpts.CaFile,
 					InsecureSkipTLSVerify: j.opts.ChartOpts.InsecureSkipTLSVerify,
+					Labels:                j.cfg.Labels,
 				},
 				platform:      j.opts.Platform,
 				excludeExtras: j.opts.ExcludeExtras,
@@ -1437,11 +1630,11 @@ func fetchChart(ctx context.Context, s *store.Layout, j chartJob, tempRoot strin
 				// resolve from a repository.
 				subchartPath := filepath.Join(chartPath, "charts", dep.Name)
 
-				depCfg = v1.Chart{Name: subchartPath}
+				depCfg = v1.Chart{Name: subchartPath, Labels: j.cfg.Labels}
 				depChartOpts.RepoURL = ""
 				depChartOpts.Version = ""
 			} else {
-				depCfg = v1.Chart{Name: dep.Name, RepoURL: dep.Repository, Version: dep.Version}
+				depCfg = v1.Chart{Name: dep.Name, RepoURL: dep.Repository, Version: dep.Version, Labels: j.cfg.Labels}
 				depChartOpts.RepoURL = dep.Repository
 				depChartOpts.Version = dep.Version
 			}
@@ -1476,12 +1669,42 @@ func fetchChart(ctx context.Context, s *store.Layout, j chartJob, tempRoot strin
 // rewriteChartReference retags a stored chart's index entry from ref to
 // rewrite. A rewrite that omits a tag inherits ref's.
 func rewriteChartReference(ctx context.Context, s *store.Layout, ref name.Reference, rewrite string) error {
+	oldTotal, newTotal, err := chartRewriteNames(ref, rewrite)
+	if err != nil {
+		return err
+	}
+
+	log.BaseFromContext(ctx).Debugf("rewriting [%s] to [%s]", oldTotal, newTotal)
+
+	matched, err := s.OCI.UpdateAnnotations(
+		func(d ocispec.Descriptor) bool {
+			return d.Annotations[ocispec.AnnotationRefName] == oldTotal
+		},
+		func(a map[string]string) {
+			a[ocispec.AnnotationRefName] = newTotal
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	if matched == 0 {
+		return fmt.Errorf("could not find chart [%s] in store", ref.Name())
+	}
+	syncPruneFromContext(ctx).keepName(s, newTotal)
+
+	return nil
+}
+
+// chartRewriteNames computes the store reference ("repo:tag") a chart has
+// before and after a --rewrite. A rewrite that omits a tag inherits ref's.
+func chartRewriteNames(ref name.Reference, rewrite string) (oldTotal, newTotal string, err error) {
 	rewrite = strings.TrimPrefix(rewrite, "/")
 	rawRewrite := rewrite
 	newRef, err := name.ParseReference(rewrite)
 	if err != nil {
 		// error... don't continue with a bad reference
-		return fmt.Errorf("unable to parse rewrite name [%s]: %w", rewrite, err)
+		return "", "", fmt.Errorf("unable to parse rewrite name [%s]: %w", rewrite, err)
 	}
 
 	// if rewrite omits a tag... keep the existing tag
@@ -1493,11 +1716,10 @@ func rewriteChartReference(ctx context.Context, s *store.Layout, ref name.Refere
 		rewrite = strings.Join([]string{rewrite, oldTag}, ":")
 		newRef, err = name.ParseReference(rewrite)
 		if err != nil {
-			return fmt.Errorf("unable to parse rewrite name [%s]: %w", rewrite, err)
+			return "", "", fmt.Errorf("unable to parse rewrite name [%s]: %w", rewrite, err)
 		}
 	}
 
-	// rename chart name in store
 	oldRepo := ref.Context().RepositoryStr()
 	newRepo := newRef.Context().RepositoryStr()
 	rewriteRepo := rawRewrite
@@ -1512,28 +1734,10 @@ func rewriteChartReference(ctx context.Context, s *store.Layout, ref name.Refere
 		newTag = tag.TagStr()
 	}
 
-	oldTotal := oldRepo + ":" + oldTag
-	newTotal := newRepo + ":" + newTag
-
-	log.BaseFromContext(ctx).Debugf("rewriting [%s] to [%s]", oldTotal, newTotal)
+	oldTotal = oldRepo + ":" + oldTag
+	newTotal = newRepo + ":" + newTag
 
-	matched, err := s.OCI.UpdateAnnotations(
-		func(d ocispec.Descriptor) bool {
-			return d.Annotations[ocispec.AnnotationRefName] == oldTotal
-		},
-		func(a map[string]string) {
-			a[ocispec.AnnotationRefName] = newTotal
-		},
-	)
-	if err != nil {
-		return err
-	}
-
-	if matched == 0 {
-		return fmt.Errorf("could not find chart [%s] in store", ref.Name())
-	}
-
-	return nil
+	return oldTotal, newTotal, nil
 }
 
 // encodeOriginalChartRef combines a chart's source repoURL with its pre-rewrite
@@ -0,0 +1,490 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	gcrv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+
+	"hauler.dev/go/hauler/v2/internal/flags"
+	v1 "hauler.dev/go/hauler/v2/pkg/apis/hauler.cattle.io/v1"
+	"hauler.dev/go/hauler/v2/pkg/audit"
+	"hauler.dev/go/hauler/v2/pkg/consts"
+	"hauler.dev/go/hauler/v2/pkg/content"
+	"hauler.dev/go/hauler/v2/pkg/cosign"
+	"hauler.dev/go/hauler/v2/pkg/log"
+	"hauler.dev/go/hauler/v2/pkg/policy"
+	"hauler.dev/go/hauler/v2/pkg/retry"
+	"hauler.dev/go/hauler/v2/pkg/store"
+)
+
+// admission enforces an admission policy on everything one `store add image`,
+// `store sync`, or `store load` run brings into a store. Like syncLock it
+// rides on the context, and every method admits everything on a nil
+// *admission, which is what every caller sees when no policy is configured.
+//
+// A rejection is never downgraded by --ignore-errors the way a verification
+// failure is: the artifact is skipped with a warning instead of failing the
+// run, but it is not stored.
+type admission struct {
+	policy  *policy.Policy
+	path    string
+	command string
+	cache   *cosign.Cache
+	rso     *flags.StoreRootOpts
+	ro      *flags.CliRootOpts
+}
+
+// newAdmission loads the policy at policyPath (or the hauler directory's
+// default policy), returning nil when there is none to enforce. command names
+// the run in the audit entries of anything it rejects.
+func newAdmission(ctx context.Context, policyPath, command string, rso *flags.StoreRootOpts, ro *flags.CliRootOpts) (*admission, error) {
+	path, err := flags.ResolvePolicyPath(ro, policyPath)
+	if err != nil || path == "" {
+		return nil, err
+	}
+	p, err := policy.Load(path)
+	if err != nil {
+		return nil, err
+	}
+	log.FromContext(ctx).Infof("enforcing admission policy [%s]", path)
+	return &admission{
+		policy:  p,
+		path:    path,
+		command: command,
+		cache:   cosign.NewCache(rso, ro),
+		rso:     rso,
+		ro:      ro,
+	}, nil
+}
+
+// Close releases the verifiers built for the policy's signature rules. Like
+// cosign.Cache.Close, it must not run while any admit call is in flight.
+func (a *admission) Close() {
+	if a != nil {
+		a.cache.Close()
+	}
+}
+
+type admissionKey struct{}
+
+func withAdmission(ctx context.Context, a *admission) context.Context {
+	return context.WithValue(ctx, admissionKey{}, a)
+}
+
+func admissionFromContext(ctx context.Context) *admission {
+	a, _ := ctx.Value(admissionKey{}).(*admission)
+	return a
+}
+
+// admitImage checks a remote image against the policy before storeImage
+// fetches it, returning the digest to fetch and whether a signature rule
+// verified it. Whenever a signature or the content is checked, the image is
+// pinned first, so the bytes stored are exactly the bytes admitted.
+//
+// A *policy.Violation rejects the image; any other error is a failure to
+// reach the registry, which callers treat like a failed fetch.
+func (a *admission) admitImage(ctx context.Context, s *store.Layout, r name.Reference, i v1.Image, platform, pinned string) (string, bool, error) {
+	if a == nil {
+		return pinned, false, nil
+	}
+	if err := a.policy.CheckImage(r); err != nil {
+		return "", false, a.reject(ctx, s, "image", r.Name(), err)
+	}
+
+	rule := a.policy.SignatureRule(r)
+	if rule == nil && !a.policy.NeedsContent() {
+		return pinned, false, nil
+	}
+	if pinned == "" {
+		var err error
+		if pinned, err = pinDigest(ctx, r, a.rso, a.ro); err != nil {
+			return "", false, fmt.Errorf("unable to resolve image digest: %w", err)
+		}
+	}
+
+	if rule != nil {
+		cfg := rule.Config()
+		cfg.InsecureSkipTLSVerify = i.InsecureSkipTLSVerify
+		cfg.CaFile = i.CaFile
+		v, err := a.cache.Get(ctx, cfg)
+		if err != nil {
+			return "", false, fmt.Errorf("unable to configure signature verification for policy rule [%s]: %w", rule.Match, err)
+		}
+		if err := v.Verify(ctx, r.Context().Digest(pinned).Name()); err != nil {
+			if errors.Is(err, context.Canceled) {
+				return "", false, err
+			}
+			return "", false, a.reject(ctx, s, "image", r.Name(), &policy.Violation{
+				Reason: fmt.Sprintf("image [%s] has no signature satisfying policy rule [%s]: %v", r.Name(), rule.Match, flattenVerifyError(err)),
+			})
+		}
+		log.BaseFromContext(ctx).Infof("✓ signature verified for image [%s] by policy rule [%s]", r.Name(), rule.Match)
+	}
+
+	if a.policy.NeedsContent() {
+		c, err := inspectRemoteImage(ctx, r.Context().Digest(pinned), platform, i, a.rso, a.ro)
+		if err != nil {
+			return "", false, fmt.Errorf("unable to inspect image for policy: %w", err)
+		}
+		if err := a.policy.CheckContent(r, c); err != nil {
+			return "", false, a.reject(ctx, s, "image", r.Name(), err)
+		}
+	}
+	return pinned, rule != nil, nil
+}
+
+// admitLocalImage checks an image about to be copied out of the Docker
+// daemon. The daemon holds no signatures, so an image a signature rule covers
+// is always rejected.
+func (a *admission) admitLocalImage(ctx context.Context, s *store.Layout, r name.Reference) error {
+	if a == nil {
+		return nil
+	}
+	if err := a.policy.CheckImage(r); err != nil {
+		return a.reject(ctx, s, "image", r.Name(), err)
+	}
+	if rule := a.policy.SignatureRule(r); rule != nil {
+		return a.reject(ctx, s, "image", r.Name(), &policy.Violation{
+			Reason: fmt.Sprintf("image [%s] requires a signature by policy rule [%s], and images from the Docker daemon carry none", r.Name(), rule.Match),
+		})
+	}
+	if !a.policy.NeedsContent() {
+		return nil
+	}
+	img, err := store.LocalImage(ctx, r)
+	if err != nil {
+		return err
+	}
+	c, err := policy.InspectImage(img)
+	if err != nil {
+		return fmt.Errorf("unable to inspect image for policy: %w", err)
+	}
+	if err := a.policy.CheckContent(r, c); err != nil {
+		return a.reject(ctx, s, "image", r.Name(), err)
+	}
+	return nil
+}
+
+// admitFile checks a file's source before it is fetched.
+func (a *admission) admitFile(ctx context.Context, s *store.Layout, path string) error {
+	if a == nil {
+		return nil
+	}
+	if err := a.policy.CheckFile(path); err != nil {
+		return a.reject(ctx, s, "file", audit.SanitizeURL(path), err)
+	}
+	return nil
+}
+
+// failAdmission reports an artifact the policy rejected, or that couldn't be
+// checked against it, in the shape storeImage reports a failed fetch, and
+// returns what the store* caller should. A rejection is reported as
+// rejected-by-policy; --ignore-errors skips it, and never stores it.
+func failAdmission(ctx context.Context, rep *reportEntry, kind, reference string, err error, ignoreErrors bool) error {
+	l := log.BaseFromContext(ctx)
+
+	if errors.Is(err, context.Canceled) {
+		rep.fail(reportFetchFailed, err)
+		l.Debugf("unable to admit %s [%s]: %v", kind, reference, err)
+		return err
+	}
+	if !policy.IsViolation(err) {
+		rep.fail(reportFetchFailed, err)
+		if ignoreErrors {
+			l.Warnf("unable to check %s [%s] against the admission policy: %v... skipping...", kind, reference, err)
+			return nil
+		}
+		l.Errorf("unable to check %s [%s] against the admission policy: %v", kind, reference, err)
+		return err
+	}
+
+	rep.fail(reportRejected, err)
+	if ignoreErrors {
+		l.Warnf("rejected %s [%s]: %v... skipping...", kind, reference, err)
+		return nil
+	}
+	l.Errorf("rejected %s [%s]: %v", kind, reference, err)
+	return err
+}
+
+// reject records a violation in the audit log and returns it unchanged, so
+// callers can return a.reject(...) directly. Anything that isn't a violation
+// passes through unrecorded.
+func (a *admission) reject(ctx context.Context, s *store.Layout, typ, reference string, err error) error {
+	var v *policy.Violation
+	if !errors.As(err, &v) {
+		return err
+	}
+	l := log.FromContext(ctx)
+
+	if auditLevel(a.ro) == "none" {
+		l.Debugf("generated audit id of [none]")
+		return err
+	}
+	e := audit.Entry{
+		StoreID:   s.StoreID,
+		Store:     s.Root,
+		Type:      typ,
+		Command:   a.command,
+		Reference: reference,
+		Rejected:  v.Reason,
+	}
+	if auditLevel(a.ro) == "verbose" {
+		sys := audit.BuildSystem()
+		g := audit.BuildGlobal(a.ro, a.rso)
+		e.System = &sys
+		e.Global = &g
+		e.Flags = map[string]any{
+			"policy": a.path,
+		}
+	}
+	if aerr := audit.Append(a.ro.HaulerDir, e); aerr != nil {
+		l.Warnf("failed to write audit entry: %v", aerr)
+	}
+	l.Debugf("generated audit id of [%s]", audit.ID())
+	return err
+}
+
+// inspectRemoteImage reads what storing ref under platform would write,
+// without fetching any layers: sizes come from the manifests.
+func inspectRemoteImage(ctx context.Context, ref name.Digest, platform string, i v1.Image, rso *flags.StoreRootOpts, ro *flags.CliRootOpts) (policy.Content, error) {
+	tr, err := content.BuildTransport(i.InsecureSkipTLSVerify, i.CaFile)
+	if err != nil {
+		return policy.Content{}, err
+	}
+	opts := []remote.Option{
+		remote.WithAuthFromKeychain(authn.DefaultKeychain),
+		remote.WithContext(ctx),
+		remote.WithTransport(tr),
+	}
+
+	var c policy.Content
+	err = retry.Operation(ctx, rso, ro, func() error {
+		desc, err := remote.Get(ref, opts...)
+		if err != nil {
+			return err
+		}
+		if idx, idxErr := desc.ImageIndex(); idxErr == nil && platform == "" {
+			c, err = policy.InspectIndex(idx)
+			return err
+		}
+		imgOpts := opts
+		if platform != "" {
+			p, err := gcrv1.ParsePlatform(platform)
+			if err != nil {
+				return err
+			}
+			imgOpts = append(imgOpts, remote.WithPlatform(*p))
+		}
+		img, err := remote.Image(ref, imgOpts...)
+		if err != nil {
+			return err
+		}
+		c, err = policy.InspectImage(img)
+		return err
+	})
+	return c, err
+}
+
+// admitHaul checks the images and files of a haul unpacked into tempDir
+// before any of it is copied into dest. Signatures are verified offline
+// against what the haul carries, as `store verify` does. Without
+// --ignore-errors the first haul holding a violation fails the load;
+// with it, each violating image or file is dropped from the haul, along with
+// its signatures, attestations, SBOMs, and referrers.
+func (a *admission) admitHaul(ctx context.Context, tempDir, dest string) error {
+	if a == nil {
+		return nil
+	}
+	l := log.FromContext(ctx)
+
+	hs, err := store.NewLayout(tempDir)
+	if err != nil {
+		return err
+	}
+
+	type candidate struct {
+		refName string
+		typ     string
+		ref     name.Reference
+		source  string
+		desc    ocispec.Descriptor
+	}
+	var candidates []candidate
+	if err := hs.Walk(func(_ string, desc ocispec.Descriptor) error {
+		refName := desc.Annotations[ocispec.AnnotationRefName]
+		if refName == "" {
+			return nil
+		}
+		switch typ := artifactType(ctx, hs, desc); typ {
+		case "image":
+			full := desc.Annotations[consts.ContainerdImageNameKey]
+			if full == "" {
+				full = refName
+			}
+			r, err := name.ParseReference(full)
+			if err != nil {
+				return fmt.Errorf("unable to parse image [%s] in haul: %w", full, err)
+			}
+			candidates = append(candidates, candidate{refName: refName, typ: typ, ref: r, desc: desc})
+		case "file":
+			candidates = append(candidates, candidate{refName: refName, typ: typ, source: desc.Annotations[consts.OriginalRefAnnotation], desc: desc})
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	var (
+		host     string
+		stop     func()
+		lp       layout.Path
+		rejected = map[string]error{}
+	)
+	defer func() {
+		if stop != nil {
+			stop()
+		}
+	}()
+
+	for _, c := range candidates {
+		var verr error
+		switch c.typ {
+		case "file":
+			verr = a.policy.CheckFile(c.source)
+		case "image":
+			if verr = a.policy.CheckImage(c.ref); verr != nil {
+				break
+			}
+			if rule := a.policy.SignatureRule(c.ref); rule != nil {
+				if host == "" {
+					if host, stop, err = serveStoreLoopback(ctx, hs); err != nil {
+						return err
+					}
+				}
+				if verr = a.verifyHaulImage(ctx, host, rule, c.refName, c.desc); verr != nil {
+					break
+				}
+			}
+			if a.policy.NeedsContent() {
+				if lp == "" {
+					if lp, err = layout.FromPath(tempDir); err != nil {
+						return err
+					}
+				}
+				ic, err := inspectLayoutImage(lp, c.desc)
+				if err != nil {
+					return fmt.Errorf("unable to inspect image [%s] in haul for policy: %w", c.ref.Name(), err)
+				}
+				verr = a.policy.CheckContent(c.ref, ic)
+			}
+		}
+		if verr != nil {
+			rejected[c.refName] = verr
+		}
+	}
+	if len(rejected) == 0 {
+		return nil
+	}
+
+	ds, err := store.NewLayout(dest)
+	if err != nil {
+		return err
+	}
+	for _, c := range candidates {
+		if verr, ok := rejected[c.refName]; ok {
+			reference := c.refName
+			if c.typ == "file" && c.source != "" {
+				reference = audit.SanitizeURL(c.source)
+			}
+			a.reject(ctx, ds, c.typ, reference, verr) //nolint:errcheck // returns verr
+		}
+	}
+
+	if !flags.ShouldIgnoreErrors(a.ro) {
+		for _, c := range candidates {
+			if verr, ok := rejected[c.refName]; ok {
+				if len(rejected) > 1 {
+					return fmt.Errorf("haul rejected by admission policy ([%d] artifacts in violation, first): %w", len(rejected), verr)
+				}
+				return fmt.Errorf("haul rejected by admission policy: %w", verr)
+			}
+		}
+	}
+
+	var drop []string
+	if err := hs.Walk(func(reference string, desc ocispec.Descriptor) error {
+		if _, ok := rejected[desc.Annotations[ocispec.AnnotationRefName]]; ok {
+			drop = append(drop, reference)
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+	for _, reference := range drop {
+		if err := hs.RemoveArtifact(ctx, reference, ocispec.Descriptor{}); err != nil {
+			return err
+		}
+	}
+	for refName, verr := range rejected {
+		l.Warnf("skipping [%s] from haul: %v", refName, verr)
+	}
+	return nil
+}
+
+// verifyHaulImage checks desc's signatures as the haul carries them, served
+// from host. Nothing an offline check reads can change between attempts, so
+// it is never retried.
+func (a *admission) verifyHaulImage(ctx context.Context, host string, rule *policy.SignatureRule, refName string, desc ocispec.Descriptor) error {
+	cfg := rule.Config()
+	cfg.Offline = true
+	vrso := *a.rso
+	vrso.Retries = 1
+	v, err := cosign.NewVerifier(ctx, cfg, &vrso, a.ro)
+	if err != nil {
+		return fmt.Errorf("unable to configure signature verification for policy rule [%s]: %w", rule.Match, err)
+	}
+	defer v.Close()
+
+	r, err := name.ParseReference(host+"/"+refName, name.WithDefaultRegistry(""))
+	if err == nil {
+		err = v.Verify(ctx, r.Context().Digest(desc.Digest.String()).Name())
+	}
+	if err != nil {
+		return &policy.Violation{
+			Reason: fmt.Sprintf("image [%s] has no signature satisfying policy rule [%s]: %v", refName, rule.Match, flattenVerifyError(err)),
+		}
+	}
+	return nil
+}
+
+// inspectLayoutImage reads the Content of an image or index in an OCI layout.
+func inspectLayoutImage(lp layout.Path, desc ocispec.Descriptor) (policy.Content, error) {
+	h, err := gcrv1.NewHash(desc.Digest.String())
+	if err != nil {
+		return policy.Content{}, err
+	}
+	if desc.MediaType == consts.OCIImageIndexSchema || desc.MediaType == consts.DockerManifestListSchema2 {
+		root, err := lp.ImageIndex()
+		if err != nil {
+			return policy.Content{}, err
+		}
+		idx, err := root.ImageIndex(h)
+		if err != nil {
+			return policy.Content{}, err
+		}
+		return policy.InspectIndex(idx)
+	}
+	img, err := lp.Image(h)
+	if err != nil {
+		return policy.Content{}, err
+	}
+	return policy.InspectImage(img)
+}
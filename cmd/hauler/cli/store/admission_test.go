@@ -0,0 +1,222 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+
+	"hauler.dev/go/hauler/v2/internal/flags"
+	v1 "hauler.dev/go/hauler/v2/pkg/apis/hauler.cattle.io/v1"
+	"hauler.dev/go/hauler/v2/pkg/consts"
+	"hauler.dev/go/hauler/v2/pkg/policy"
+	"hauler.dev/go/hauler/v2/pkg/store"
+)
+
+// rejectedAuditEntries returns the reasons of every rejection in
+// <haulerDir>/audit.log.
+func rejectedAuditEntries(t *testing.T, haulerDir string) []string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(haulerDir, "audit.log"))
+	if err != nil {
+		t.Fatalf("reading audit.log: %v", err)
+	}
+	var reasons []string
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		var e struct {
+			Rejected string `json:"rejected"`
+		}
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			t.Fatalf("unmarshaling audit.log line: %v", err)
+		}
+		if e.Rejected != "" {
+			reasons = append(reasons, e.Rejected)
+		}
+	}
+	return reasons
+}
+
+func TestAddImageCmd_PolicyDeny(t *testing.T) {
+	host, _ := newLocalhostRegistry(t)
+	seedImage(t, host, "policy/denied", "v1")
+	pol := writeTempFile(t, "policy.yaml", fmt.Sprintf("images:\n  deny: [%s/policy/denied]\n", host))
+
+	for _, ignoreErrors := range []bool{false, true} {
+		s := newTestStore(t)
+		rso := defaultRootOpts(s.Root)
+		ro := defaultCliOpts()
+		ro.AuditLevel = "standard"
+		ro.HaulerDir = t.TempDir()
+		ro.IgnoreErrors = ignoreErrors
+
+		o := &flags.AddImageOpts{StoreRootOpts: rso, Policy: pol}
+		err := AddImageCmd(newTestContext(t), o, s, host+"/policy/denied:v1", rso, ro)
+		if ignoreErrors && err != nil {
+			t.Errorf("AddImageCmd --ignore-errors = %v, want the rejection skipped", err)
+		}
+		if !ignoreErrors && !policy.IsViolation(err) {
+			t.Errorf("AddImageCmd = %v, want a policy violation", err)
+		}
+		if n := countArtifactsInStore(t, s); n != 0 {
+			t.Errorf("ignoreErrors=%t: store holds %d artifacts, want the rejected image left out", ignoreErrors, n)
+		}
+		if reasons := rejectedAuditEntries(t, ro.HaulerDir); len(reasons) != 1 || !strings.Contains(reasons[0], "denied pattern") {
+			t.Errorf("ignoreErrors=%t: audit rejections = %v, want one naming the denied pattern", ignoreErrors, reasons)
+		}
+	}
+}
+
+func TestAddImageCmd_PolicyDefaultFile(t *testing.T) {
+	host, _ := newLocalhostRegistry(t)
+	seedImage(t, host, "policy/other", "v1")
+
+	s := newTestStore(t)
+	rso := defaultRootOpts(s.Root)
+	ro := defaultCliOpts()
+	ro.HaulerDir = t.TempDir()
+	content := fmt.Sprintf("images:\n  allow: [%s/policy/allowed]\n", host)
+	if err := os.WriteFile(filepath.Join(ro.HaulerDir, consts.DefaultPolicyFileName), []byte(content), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	o := &flags.AddImageOpts{StoreRootOpts: rso}
+	err := AddImageCmd(newTestContext(t), o, s, host+"/policy/other:v1", rso, ro)
+	if !policy.IsViolation(err) || !strings.Contains(err.Error(), "no allowed pattern") {
+		t.Errorf("AddImageCmd = %v, want the hauler dir's policy to reject it", err)
+	}
+}
+
+func TestSyncCmd_PolicySignatureRule(t *testing.T) {
+	host, rOpts := newLocalhostRegistry(t)
+	_, keyPath := seedSignedImage(t, host, "policy/signed", "v1", rOpts...)
+	seedImage(t, host, "policy/unsigned", "v1", rOpts...)
+
+	s := newTestStore(t)
+	o := newSyncOpts(s.Root)
+	o.FileName = []string{writeTempFile(t, "hauler-manifest.yaml", fmt.Sprintf(`apiVersion: content.hauler.cattle.io/v1
+kind: Images
+metadata:
+  name: policy
+spec:
+  images:
+    - name: %[1]s/policy/signed:v1
+    - name: %[1]s/policy/unsigned:v1
+`, host))}
+	o.Policy = writeTempFile(t, "policy.yaml", fmt.Sprintf("images:\n  signatures:\n    - match: %s/policy\n      key: %s\n", host, keyPath))
+	o.ExcludeExtras = true
+	o.Report = filepath.Join(t.TempDir(), "report.json")
+	o.ReportFormat = "json"
+	ro := defaultCliOpts()
+	ro.HaulerDir = t.TempDir()
+	ro.IgnoreErrors = true
+
+	if err := SyncCmd(newTestContext(t), o, s, o.StoreRootOpts, ro); err != nil {
+		t.Fatalf("SyncCmd: %v", err)
+	}
+	if n := countArtifactsInStore(t, s); n != 1 {
+		t.Errorf("store holds %d artifacts, want only the signed image", n)
+	}
+
+	data, err := os.ReadFile(o.Report)
+	if err != nil {
+		t.Fatalf("ReadFile(report): %v", err)
+	}
+	var r struct {
+		Summary reportSummary  `json:"summary"`
+		Items   []*reportEntry `json:"items"`
+	}
+	if err := json.Unmarshal(data, &r); err != nil {
+		t.Fatalf("Unmarshal(report): %v", err)
+	}
+	if r.Summary.Stored != 1 || r.Summary.Rejected != 1 {
+		t.Errorf("report summary = %+v, want one stored and one rejected", r.Summary)
+	}
+	for _, e := range r.Items {
+		if strings.Contains(e.Name, "unsigned") && e.Status != reportRejected {
+			t.Errorf("unsigned image status = %q, want %q", e.Status, reportRejected)
+		}
+	}
+}
+
+func TestSyncCmd_PolicyFileHost(t *testing.T) {
+	fileURL := seedFileInHTTPServer(t, "install.sh", "#!/bin/sh\n")
+
+	s := newTestStore(t)
+	o := newSyncOpts(s.Root)
+	o.FileName = []string{writeTempFile(t, "hauler-manifest.yaml", fmt.Sprintf(`apiVersion: content.hauler.cattle.io/v1
+kind: Files
+metadata:
+  name: policy
+spec:
+  files:
+    - path: %s
+`, fileURL))}
+	o.Policy = writeTempFile(t, "policy.yaml", "files:\n  allowedHosts: [github.com]\n")
+	ro := defaultCliOpts()
+	ro.HaulerDir = t.TempDir()
+
+	err := SyncCmd(newTestContext(t), o, s, o.StoreRootOpts, ro)
+	if !policy.IsViolation(err) || !strings.Contains(err.Error(), "allowed hosts") {
+		t.Errorf("SyncCmd = %v, want the file host rejected", err)
+	}
+	if n := countArtifactsInStore(t, s); n != 0 {
+		t.Errorf("store holds %d artifacts, want none", n)
+	}
+}
+
+// A haul is checked as a whole before anything reaches the target store; under
+// --ignore-errors only the violating images are dropped from it.
+func TestAdmitHaul(t *testing.T) {
+	ctx := newTestContext(t)
+	host, _ := newLocalhostRegistry(t)
+	seedImage(t, host, "haul/kept", "v1")
+	seedImage(t, host, "haul/denied", "v1")
+
+	haul := newTestStore(t)
+	rso := defaultRootOpts(haul.Root)
+	ro := defaultCliOpts()
+	for _, ref := range []string{host + "/haul/kept:v1", host + "/haul/denied:v1"} {
+		if err := storeImage(ctx, haul, v1.Image{Name: ref}, "", false, rso, ro, "", "", false); err != nil {
+			t.Fatalf("storeImage(%s): %v", ref, err)
+		}
+	}
+
+	dest := t.TempDir()
+	ro.HaulerDir = t.TempDir()
+	ro.AuditLevel = "standard"
+	a, err := newAdmission(ctx, writeTempFile(t, "policy.yaml", fmt.Sprintf("images:\n  deny: [%s/haul/denied]\n", host)), "store load", rso, ro)
+	if err != nil {
+		t.Fatalf("newAdmission: %v", err)
+	}
+	defer a.Close()
+
+	if err := a.admitHaul(ctx, haul.Root, dest); !policy.IsViolation(err) {
+		t.Fatalf("admitHaul = %v, want the haul rejected", err)
+	}
+
+	ro.IgnoreErrors = true
+	if err := a.admitHaul(ctx, haul.Root, dest); err != nil {
+		t.Fatalf("admitHaul --ignore-errors: %v", err)
+	}
+	reopened, err := store.NewLayout(haul.Root)
+	if err != nil {
+		t.Fatalf("NewLayout: %v", err)
+	}
+	var names []string
+	if err := reopened.Walk(func(_ string, desc ocispec.Descriptor) error {
+		names = append(names, desc.Annotations[ocispec.AnnotationRefName])
+		return nil
+	}); err != nil {
+		t.Fatalf("Walk: %v", err)
+	}
+	if len(names) != 1 || !strings.Contains(names[0], "haul/kept") {
+		t.Errorf("haul holds %v, want only haul/kept", names)
+	}
+	if reasons := rejectedAuditEntries(t, ro.HaulerDir); len(reasons) != 2 {
+		t.Errorf("audit rejections = %v, want one per admitHaul call", reasons)
+	}
+}
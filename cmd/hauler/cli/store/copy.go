@@ -12,6 +12,7 @@ import (
 	"github.com/containerd/containerd/v2/core/remotes"
 	"github.com/containerd/errdefs"
 	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"golang.org/x/sync/errgroup"
 
 	"hauler.dev/go/hauler/v2/internal/flags"
 	"hauler.dev/go/hauler/v2/internal/mapper"
@@ -35,18 +36,31 @@ func CopyCmd(ctx context.Context, o *flags.CopyOpts, s *store.Layout, targetRef
 
 	ignoreErrors := flags.ShouldIgnoreErrors(ro)
 
+	// nil unless --select narrows the copy... an artifact selected brings the
+	// signatures, attestations, sboms, and referrers saved for it along
+	selected, err := selectedKeys(ctx, s, o.Select, true)
+	if err != nil {
+		return err
+	}
+
 	components := strings.SplitN(targetRef, "://", 2)
 	switch components[0] {
 	case "directory", "dir":
 		l.Debugf("identified [directory] target reference of [%s]", components[1])
 
 		// Create destination directory if it doesn't exist
-		if err := os.MkdirAll(components[1], 0755); err != nil {
-			return fmt.Errorf("failed to create destination directory: %w", err)
+		if !o.DryRun {
+			if err := os.MkdirAll(components[1], 0755); err != nil {
+				return fmt.Errorf("failed to create destination directory: %w", err)
+			}
 		}
 
 		// For directory targets, extract files and charts (not images)
+		var planned []store.Selected
 		err := s.Walk(func(reference string, desc ocispec.Descriptor) error {
+			if selected != nil && !selected[reference] {
+				return nil
+			}
 			// Skip cosign sig/att/sbom artifacts — they're registry-only metadata,
 			// not extractable as files or charts.
 			kind := desc.Annotations[consts.KindAnnotationName]
@@ -107,6 +121,11 @@ func CopyCmd(ctx context.Context, o *flags.CopyOpts, s *store.Layout, targetRef
 						continue
 					}
 
+					if o.DryRun {
+						planned = append(planned, store.Selected{Key: reference, Desc: manifestDesc, Type: artifactType(ctx, s, desc)})
+						continue
+					}
+
 					// Create mapper and extract
 					mapperStore, err := mapper.FromManifest(m, components[1])
 					if err != nil {
@@ -147,6 +166,12 @@ func CopyCmd(ctx context.Context, o *flags.CopyOpts, s *store.Layout, targetRef
 					return nil
 				}
 
+				if o.DryRun {
+					rc.Close()
+					planned = append(planned, store.Selected{Key: reference, Desc: desc, Type: artifactType(ctx, s, desc)})
+					return nil
+				}
+
 				// Create a mapper store based on the manifest type
 				mapperStore, err := mapper.FromManifest(m, components[1])
 				if err != nil {
@@ -175,6 +200,10 @@ func CopyCmd(ctx context.Context, o *flags.CopyOpts, s *store.Layout, targetRef
 		if err != nil {
 			return err
 		}
+		if o.DryRun {
+			logSelection(ctx, "copy", "copied", planned)
+			return nil
+		}
 
 	case "registry", "reg", "oci":
 		l.Debugf("identified [registry] target reference of [%s]", components[1])
@@ -207,11 +236,8 @@ func CopyCmd(ctx context.Context, o *flags.CopyOpts, s *store.Layout, targetRef
 			consts.KindAnnotationSboms: ".sbom",
 		}
 
-		var fatalErr error
+		var jobs []copyJob
 		err := s.Walk(func(reference string, desc ocispec.Descriptor) error {
-			if fatalErr != nil {
-				return nil
-			}
 			baseRef := desc.Annotations[ocispec.AnnotationRefName]
 			if baseRef == "" {
 				return nil
@@ -219,6 +245,9 @@ func CopyCmd(ctx context.Context, o *flags.CopyOpts, s *store.Layout, targetRef
 				l.Warnf("skipping file artifact [%s]: invalid filename for registry serve", baseRef)
 				return nil
 			}
+			if selected != nil && !selected[reference] {
+				return nil
+			}
 			if o.Only != "" && !strings.Contains(baseRef, o.Only) {
 				l.Debugf("skipping [%s] (not matching --only filter)", baseRef)
 				return nil
@@ -246,36 +275,31 @@ func CopyCmd(ctx context.Context, o *flags.CopyOpts, s *store.Layout, targetRef
 			toRef, err := content.RewriteRefToRegistry(destRef, components[1])
 			if err != nil {
 				if !ignoreErrors {
-					fatalErr = fmt.Errorf("rewriting ref [%s]: %w", baseRef, err)
-					return nil
+					return fmt.Errorf("rewriting ref [%s]: %w", baseRef, err)
 				}
 				l.Warnf("failed to rewrite ref [%s]: %v", baseRef, err)
 				return nil
 			}
-			l.Infof("%s", destRef)
-			// A fresh target per artifact gives each push its own in-memory status
-			// tracker. Containerd's tracker keys blobs by digest only (not repo),
-			// so a shared tracker would mark shared blobs as "already exists" after
-			// the first image, skipping the per-repository blob link creation that
-			// Docker Distribution requires for manifest validation.
-			target := content.NewRegistryTarget(components[1], registryOpts, registryClient)
-			var pushed ocispec.Descriptor
-			if err := retry.Operation(ctx, o.StoreRootOpts, ro, func() error {
-				var copyErr error
-				pushed, copyErr = s.Copy(ctx, reference, target, toRef)
-				return copyErr
-			}); err != nil {
-				if !ignoreErrors {
-					fatalErr = err
-				}
-				return nil
-			}
-			l.Infof("%s: digest: %s size: %d", toRef, pushed.Digest, pushed.Size)
+			jobs = append(jobs, copyJob{reference: reference, destRef: destRef, toRef: toRef})
 			return nil
 		})
-		if fatalErr != nil {
-			return fatalErr
+		if err != nil {
+			return err
 		}
+
+		if o.DryRun {
+			for _, j := range jobs {
+				l.Infof("would copy [%s] to [%s]", j.reference, j.toRef)
+			}
+			l.Infof("dry run... [%d] artifact(s) would be copied", len(jobs))
+			return nil
+		}
+
+		newTarget := func() content.Target {
+			return s.OCI.LimitTarget(content.NewRegistryTarget(components[1], registryOpts, registryClient))
+		}
+		err = runCopyJobs(ctx, s, jobs, newTarget, o.Concurrency, o.StoreRootOpts, ro, newProgressRenderer(o.NoProgress, ro.LogLevel))
+		l.Debugf("%s", formatIOStats(s.OCI.Stats().Snapshot(), s.OCI.BlobConcurrency()))
 		if err != nil {
 			return err
 		}
@@ -288,6 +312,79 @@ func CopyCmd(ctx context.Context, o *flags.CopyOpts, s *store.Layout, targetRef
 	return nil
 }
 
+// copyJob is a single artifact to push to a registry: its store key and the
+// destination it was rewritten to.
+type copyJob struct {
+	reference string
+	destRef   string
+	toRef     string
+}
+
+// runCopyJobs pushes every job concurrently, bounded by concurrency, with the
+// same fail-fast and --ignore-errors semantics as sync's runRemoteImageJobsWith:
+// with --ignore-errors a failed push is logged by retry.Operation and
+// dropped, otherwise the first failure cancels the group and is returned.
+//
+// newTarget is called once per job. A fresh target per artifact gives each
+// push its own in-memory status tracker. Containerd's tracker keys blobs by
+// digest only (not repo), so a shared tracker would mark shared blobs as
+// "already exists" after the first image, skipping the per-repository blob
+// link creation that Docker Distribution requires for manifest validation.
+// What the targets do share is the store's blob semaphore (see
+// content.OCI.LimitTarget), so blob fan-out stays under one ceiling no
+// matter how many artifacts are in flight.
+func runCopyJobs(ctx context.Context, s *store.Layout, jobs []copyJob, newTarget func() content.Target, concurrency int, rso *flags.StoreRootOpts, ro *flags.CliRootOpts, progress *log.Renderer) error {
+	l := log.FromContext(ctx)
+
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	// see runImageJobs for why every job logs through the Renderer while a
+	// progress session is live
+	baseLogger := l
+	if progress != nil && len(jobs) > 0 {
+		baseLogger = log.NewLogger(progress)
+		progress.Start()
+		defer progress.Stop()
+	}
+
+	ignoreErrors := flags.ShouldIgnoreErrors(ro)
+
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(concurrency)
+	for _, j := range jobs {
+		g.Go(func() error {
+			// Began must be called after g.Go's semaphore acquisition;
+			// see runRemoteImageJobsWith.
+			if progress != nil {
+				progress.Began(j.destRef)
+				defer progress.Finished(j.destRef)
+			}
+			jl := baseLogger.With(log.Fields{"artifact": j.destRef})
+			jctx := jl.WithContext(gctx)
+			jctx = log.WithBaseLogger(jctx, baseLogger)
+
+			baseLogger.Infof("%s", j.destRef)
+			target := newTarget()
+			var pushed ocispec.Descriptor
+			if err := retry.Operation(jctx, rso, ro, func() error {
+				var copyErr error
+				pushed, copyErr = s.Copy(jctx, j.reference, target, j.toRef)
+				return copyErr
+			}); err != nil {
+				if ignoreErrors {
+					return nil
+				}
+				return err
+			}
+			baseLogger.Infof("%s: digest: %s size: %d", j.toRef, pushed.Digest, pushed.Size)
+			return nil
+		})
+	}
+	return g.Wait()
+}
+
 // repoFromBaseRef strips any digest and/or tag from a stored ref name, yielding
 // just the repository path. AnnotationRefName never contains a registry host, so
 // the only colons come from a tag or the digest algorithm separator. A digest-only
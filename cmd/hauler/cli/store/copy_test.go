@@ -162,6 +162,56 @@ func TestCopyCmd_Registry(t *testing.T) {
 	}
 }
 
+// TestCopyCmd_Registry_Concurrent pushes several images through the worker
+// pool and checks every push drew on the store's one shared blob ceiling.
+func TestCopyCmd_Registry_Concurrent(t *testing.T) {
+	ctx := newTestContext(t)
+
+	srcHost, _ := newLocalhostRegistry(t)
+	s, err := store.NewLayout(t.TempDir(), store.WithBlobConcurrency(2))
+	if err != nil {
+		t.Fatalf("NewLayout: %v", err)
+	}
+	rso := defaultRootOpts(s.Root)
+	ro := defaultCliOpts()
+
+	repos := []string{"test/pool-a", "test/pool-b", "test/pool-c", "test/pool-d", "test/pool-e"}
+	for _, repo := range repos {
+		seedImage(t, srcHost, repo, "v1")
+		if err := storeImage(ctx, s, v1.Image{Name: srcHost + "/" + repo + ":v1"}, "", false, rso, ro, "", "", false); err != nil {
+			t.Fatalf("storeImage %s: %v", repo, err)
+		}
+	}
+	before := s.OCI.Stats().Snapshot()
+
+	dstHost, dstOpts := newTestRegistry(t)
+	o := &flags.CopyOpts{
+		StoreRootOpts: defaultRootOpts(s.Root),
+		PlainHTTP:     true,
+		Concurrency:   3,
+		NoProgress:    true,
+	}
+	if err := CopyCmd(ctx, o, s, "registry://"+dstHost, ro); err != nil {
+		t.Fatalf("CopyCmd registry: %v", err)
+	}
+
+	for _, repo := range repos {
+		ref, _ := name.NewTag(dstHost+"/"+repo+":v1", name.Insecure)
+		if _, err := remote.Get(ref, dstOpts...); err != nil {
+			t.Errorf("%s not found in target registry after copy: %v", repo, err)
+		}
+	}
+
+	after := s.OCI.Stats().Snapshot()
+	// 5 images x (2 layers + config + manifest)
+	if pushed := after.BlobsWritten - before.BlobsWritten; pushed != 20 {
+		t.Errorf("pushed %d blobs through the shared limiter, want 20", pushed)
+	}
+	if after.BlobPeakInFlight > 2 {
+		t.Errorf("peak in-flight blobs = %d, want <= 2", after.BlobPeakInFlight)
+	}
+}
+
 // TestCopyCmd_Registry_OnlyFilter seeds two images in distinct repos, copies
 // with --only=repo1, and asserts only repo1 reaches the target.
 func TestCopyCmd_Registry_OnlyFilter(t *testing.T) {
@@ -25,21 +25,24 @@ import (
 // callers unmarshal rather than marshal) so the generated manifest stays readable
 // instead of listing every unset flag.
 type manifestImage struct {
-	Name     string `yaml:"name"`
-	Platform string `yaml:"platform,omitempty"`
-	Rewrite  string `yaml:"rewrite,omitempty"`
+	Name     string            `yaml:"name"`
+	Platform string            `yaml:"platform,omitempty"`
+	Rewrite  string            `yaml:"rewrite,omitempty"`
+	Labels   map[string]string `yaml:"labels,omitempty"`
 }
 
 type manifestChart struct {
-	Name    string `yaml:"name"`
-	RepoURL string `yaml:"repoURL,omitempty"`
-	Version string `yaml:"version,omitempty"`
-	Rewrite string `yaml:"rewrite,omitempty"`
+	Name    string            `yaml:"name"`
+	RepoURL string            `yaml:"repoURL,omitempty"`
+	Version string            `yaml:"version,omitempty"`
+	Rewrite string            `yaml:"rewrite,omitempty"`
+	Labels  map[string]string `yaml:"labels,omitempty"`
 }
 
 type manifestFile struct {
-	Path string `yaml:"path"`
-	Name string `yaml:"name,omitempty"`
+	Path   string            `yaml:"path"`
+	Name   string            `yaml:"name,omitempty"`
+	Labels map[string]string `yaml:"labels,omitempty"`
 }
 
 type manifestMetadata struct {
@@ -115,7 +118,7 @@ func CreateManifestCmd(ctx context.Context, o *flags.CreateManifestOpts, s *stor
 				name = orig
 			}
 
-			img := manifestImage{Name: name, Rewrite: rewrite}
+			img := manifestImage{Name: name, Rewrite: rewrite, Labels: itemLabels(desc)}
 			if kind == consts.KindAnnotationImage {
 				// Only a single-platform manifest has an unambiguous platform to pin.
 				// A stored multi-arch index is left unset so a future sync re-pulls
@@ -176,7 +179,7 @@ func CreateManifestCmd(ctx context.Context, o *flags.CreateManifestOpts, s *stor
 				}
 			}
 
-			charts = append(charts, manifestChart{Name: name, RepoURL: repoURL, Version: version, Rewrite: rewrite})
+			charts = append(charts, manifestChart{Name: name, RepoURL: repoURL, Version: version, Rewrite: rewrite, Labels: itemLabels(desc)})
 			if repoURL == "" {
 				chartsMissingRepoURL = true
 			}
@@ -186,7 +189,7 @@ func CreateManifestCmd(ctx context.Context, o *flags.CreateManifestOpts, s *stor
 			if orig, ok := desc.Annotations[consts.OriginalRefAnnotation]; ok && orig != "" {
 				path = orig
 			}
-			files = append(files, manifestFile{Path: path, Name: name})
+			files = append(files, manifestFile{Path: path, Name: name, Labels: itemLabels(desc)})
 
 		default:
 			l.Warnf("skipping unrecognized artifact [%s] with config media type [%s]", refName, m.Config.MediaType)
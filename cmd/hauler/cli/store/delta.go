@@ -0,0 +1,404 @@
+package store
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	mholt "github.com/mholt/archives"
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+
+	"hauler.dev/go/hauler/v2/pkg/archives"
+	"hauler.dev/go/hauler/v2/pkg/consts"
+	"hauler.dev/go/hauler/v2/pkg/log"
+	"hauler.dev/go/hauler/v2/pkg/store"
+)
+
+// contentList describes everything a haul makes available once loaded... it's
+// what `store save --since` compares against, and what `--content-list`
+// exports so a baseline doesn't have to be carried around as a full haul
+type contentList struct {
+	StoreID   string               `json:"store-id,omitempty"`
+	Index     digest.Digest        `json:"index"`
+	Manifests []ocispec.Descriptor `json:"manifests"`
+	Blobs     []digest.Digest      `json:"blobs"`
+}
+
+// deltaManifest is written to the root of a delta haul. Required lists the
+// blobs the delta references but leaves out because the baseline carries them
+type deltaManifest struct {
+	Baseline struct {
+		StoreID string        `json:"store-id,omitempty"`
+		Index   digest.Digest `json:"index"`
+	} `json:"baseline"`
+	Required []digest.Digest `json:"required"`
+}
+
+// identifies an index entry across stores... the same reference and kind at
+// the same digest is the same content, wherever it came from
+func entryKey(desc ocispec.Descriptor) string {
+	return desc.Annotations[ocispec.AnnotationRefName] + "|" + desc.Annotations[consts.KindAnnotationName] + "|" + desc.Digest.String()
+}
+
+// readContentList reads a baseline from either an exported content list or a
+// previously saved haul (chunked or not)
+func readContentList(ctx context.Context, path string, tempDir string) (*contentList, error) {
+	path = resolveHaulPath(path)
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open baseline [%s]: %w", path, err)
+	}
+	peek, _ := bufio.NewReader(f).Peek(1)
+	f.Close()
+
+	if len(peek) == 1 && peek[0] == '{' {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		var cl contentList
+		if err := json.Unmarshal(data, &cl); err != nil {
+			return nil, fmt.Errorf("failed to parse content list [%s]: %w", path, err)
+		}
+		return &cl, nil
+	}
+
+	joined, err := archives.JoinChunks(ctx, path, tempDir)
+	if err != nil {
+		return nil, err
+	}
+
+	cl := &contentList{}
+	blobs := map[digest.Digest]bool{}
+	blobPrefix := ocispec.ImageBlobsDir + "/"
+
+	err = archives.Walk(ctx, joined, func(name string, f mholt.FileInfo) error {
+		switch {
+		case name == ocispec.ImageIndexFile:
+			data, err := readArchiveEntry(f)
+			if err != nil {
+				return err
+			}
+			var idx ocispec.Index
+			if err := json.Unmarshal(data, &idx); err != nil {
+				return fmt.Errorf("failed to parse baseline index: %w", err)
+			}
+			cl.Index = digest.FromBytes(data)
+			cl.Manifests = idx.Manifests
+
+		case name == consts.DefaultStoreMetadataName:
+			data, err := readArchiveEntry(f)
+			if err != nil {
+				return err
+			}
+			var meta struct {
+				StoreID string `json:"store-id"`
+			}
+			if json.Unmarshal(data, &meta) == nil {
+				cl.StoreID = meta.StoreID
+			}
+
+		case name == consts.DefaultDeltaManifestName:
+			// a delta baseline only works on top of its own baseline, so
+			// whatever it required is available to the next delta too
+			data, err := readArchiveEntry(f)
+			if err != nil {
+				return err
+			}
+			var dm deltaManifest
+			if err := json.Unmarshal(data, &dm); err != nil {
+				return fmt.Errorf("failed to parse baseline delta manifest: %w", err)
+			}
+			for _, d := range dm.Required {
+				blobs[d] = true
+			}
+
+		case strings.HasPrefix(name, blobPrefix):
+			d := digest.Digest(strings.Replace(strings.TrimPrefix(name, blobPrefix), "/", ":", 1))
+			if d.Validate() == nil {
+				blobs[d] = true
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read baseline haul [%s]: %w", path, err)
+	}
+	if cl.Index == "" {
+		return nil, fmt.Errorf("baseline [%s] is not a haul or content list... missing %s", path, ocispec.ImageIndexFile)
+	}
+
+	cl.Blobs = sortedDigests(blobs)
+	return cl, nil
+}
+
+func readArchiveEntry(f mholt.FileInfo) ([]byte, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(rc)
+}
+
+// storeContentList builds the content list of an oci layout on disk
+func storeContentList(dir string, storeID string) (*contentList, error) {
+	data, err := os.ReadFile(filepath.Join(dir, ocispec.ImageIndexFile))
+	if err != nil {
+		return nil, err
+	}
+	var idx ocispec.Index
+	if err := json.Unmarshal(data, &idx); err != nil {
+		return nil, err
+	}
+
+	blobs := map[digest.Digest]bool{}
+	algDir := filepath.Join(dir, ocispec.ImageBlobsDir, digest.Canonical.String())
+	entries, err := os.ReadDir(algDir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	for _, e := range entries {
+		if d := digest.NewDigestFromEncoded(digest.Canonical, e.Name()); !e.IsDir() && d.Validate() == nil {
+			blobs[d] = true
+		}
+	}
+
+	return &contentList{
+		StoreID:   storeID,
+		Index:     digest.FromBytes(data),
+		Manifests: idx.Manifests,
+		Blobs:     sortedDigests(blobs),
+	}, nil
+}
+
+func writeContentList(path string, cl *contentList) error {
+	data, err := json.MarshalIndent(cl, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// writeDelta stages a delta layout of s against base in stageDir: the index
+// entries base doesn't have, the blobs they reference that base doesn't carry,
+// and a delta manifest naming the baseline and the blobs it must provide. It
+// returns the content list of baseline and delta combined. A non-nil selected
+// limits the delta to the index entries it holds.
+func writeDelta(ctx context.Context, s *store.Layout, base *contentList, selected map[string]bool, stageDir string) (*contentList, error) {
+	l := log.FromContext(ctx)
+
+	baseBlobs := make(map[digest.Digest]bool, len(base.Blobs))
+	for _, d := range base.Blobs {
+		baseBlobs[d] = true
+	}
+	baseEntries := make(map[string]bool, len(base.Manifests))
+	for _, desc := range base.Manifests {
+		baseEntries[entryKey(desc)] = true
+	}
+
+	var added []ocispec.Descriptor
+	included := map[digest.Digest]bool{}
+	required := map[digest.Digest]bool{}
+
+	err := s.Walk(func(reference string, desc ocispec.Descriptor) error {
+		if baseEntries[entryKey(desc)] || (selected != nil && !selected[reference]) {
+			return nil
+		}
+		added = append(added, desc)
+		return s.WalkBlobs(ctx, desc, func(d ocispec.Descriptor) error {
+			if baseBlobs[d.Digest] {
+				required[d.Digest] = true
+				return nil
+			}
+			included[d.Digest] = true
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(added) == 0 {
+		l.Warnf("no new content since baseline [%s]... writing an empty delta haul", base.Index)
+	}
+
+	if err := stageLayout(s, added, included, stageDir); err != nil {
+		return nil, err
+	}
+
+	var dm deltaManifest
+	dm.Baseline.StoreID = base.StoreID
+	dm.Baseline.Index = base.Index
+	dm.Required = sortedDigests(required)
+	dmData, err := json.MarshalIndent(dm, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(stageDir, consts.DefaultDeltaManifestName), dmData, 0644); err != nil {
+		return nil, err
+	}
+
+	l.Infof("delta against baseline [%s]: [%d] new entries, [%d] new blobs, [%d] blobs from baseline", base.Index, len(added), len(included), len(required))
+
+	// loading a delta replaces any baseline entry with the same reference and kind
+	addedKeys := make(map[string]bool, len(added))
+	for _, desc := range added {
+		addedKeys[desc.Annotations[ocispec.AnnotationRefName]+"|"+desc.Annotations[consts.KindAnnotationName]] = true
+	}
+	merged := &contentList{StoreID: s.StoreID}
+	for _, desc := range base.Manifests {
+		if !addedKeys[desc.Annotations[ocispec.AnnotationRefName]+"|"+desc.Annotations[consts.KindAnnotationName]] {
+			merged.Manifests = append(merged.Manifests, desc)
+		}
+	}
+	merged.Manifests = append(merged.Manifests, added...)
+	mergedIdx, err := json.Marshal(ocispec.Index{Manifests: merged.Manifests})
+	if err != nil {
+		return nil, err
+	}
+	merged.Index = digest.FromBytes(mergedIdx)
+	for d := range included {
+		baseBlobs[d] = true
+	}
+	merged.Blobs = sortedDigests(baseBlobs)
+
+	return merged, nil
+}
+
+// stageLayout writes an oci layout of s's entries in stageDir: the blobs
+// listed, linked in from s, an index of the entries, and s's store metadata.
+func stageLayout(s *store.Layout, entries []ocispec.Descriptor, blobs map[digest.Digest]bool, stageDir string) error {
+	for d := range blobs {
+		src := filepath.Join(s.Root, ocispec.ImageBlobsDir, d.Algorithm().String(), d.Encoded())
+		dst := filepath.Join(stageDir, ocispec.ImageBlobsDir, d.Algorithm().String(), d.Encoded())
+		if err := linkOrCopy(src, dst); err != nil {
+			return fmt.Errorf("failed to stage blob %s: %w", d, err)
+		}
+	}
+
+	// entries are written in a stable order so the same content always has
+	// the same index digest
+	sort.Slice(entries, func(i, j int) bool { return entryKey(entries[i]) < entryKey(entries[j]) })
+	idx := ocispec.Index{
+		MediaType: ocispec.MediaTypeImageIndex,
+		Manifests: entries,
+	}
+	idx.SchemaVersion = 2
+	idxData, err := json.MarshalIndent(idx, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(stageDir, ocispec.ImageIndexFile), idxData, 0644); err != nil {
+		return err
+	}
+
+	layoutData, err := json.Marshal(ocispec.ImageLayout{Version: ocispec.ImageLayoutVersion})
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(stageDir, ocispec.ImageLayoutFile), layoutData, 0644); err != nil {
+		return err
+	}
+
+	if err := linkOrCopy(filepath.Join(s.Root, consts.DefaultStoreMetadataName), filepath.Join(stageDir, consts.DefaultStoreMetadataName)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// prepareDelta checks an unarchived haul in tempDir for a delta manifest and,
+// if it is one, links the baseline blobs it relies on in from dest so the
+// regular CopyAll path can traverse it. A delta whose baseline blobs aren't in
+// dest is refused outright rather than half loaded.
+func prepareDelta(ctx context.Context, tempDir string, dest string) error {
+	l := log.FromContext(ctx)
+
+	data, err := os.ReadFile(filepath.Join(tempDir, consts.DefaultDeltaManifestName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	var dm deltaManifest
+	if err := json.Unmarshal(data, &dm); err != nil {
+		return fmt.Errorf("failed to parse delta manifest: %w", err)
+	}
+
+	l.Infof("detected delta haul against baseline [%s]", dm.Baseline.Index)
+
+	var missing []string
+	for _, d := range dm.Required {
+		if err := d.Validate(); err != nil {
+			return fmt.Errorf("invalid blob in delta manifest: %w", err)
+		}
+		src := filepath.Join(dest, ocispec.ImageBlobsDir, d.Algorithm().String(), d.Encoded())
+		if _, err := os.Stat(src); err != nil {
+			missing = append(missing, d.String())
+			continue
+		}
+		dst := filepath.Join(tempDir, ocispec.ImageBlobsDir, d.Algorithm().String(), d.Encoded())
+		if _, err := os.Stat(dst); err == nil {
+			continue
+		}
+		if err := linkOrCopy(src, dst); err != nil {
+			return fmt.Errorf("failed to stage baseline blob %s: %w", d, err)
+		}
+	}
+
+	if len(missing) > 0 {
+		baseline := string(dm.Baseline.Index)
+		if dm.Baseline.StoreID != "" {
+			baseline = fmt.Sprintf("%s (store %s)", baseline, dm.Baseline.StoreID)
+		}
+		return fmt.Errorf("delta haul requires [%d] blob(s) from baseline [%s] that are missing from store [%s]... load the baseline haul first: %s",
+			len(missing), baseline, dest, strings.Join(missing, ", "))
+	}
+
+	return nil
+}
+
+// hardlinks src to dst, falling back to a copy across filesystems
+func linkOrCopy(src, dst string) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	if err := os.Link(src, dst); err == nil {
+		return nil
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+func sortedDigests(set map[digest.Digest]bool) []digest.Digest {
+	out := make([]digest.Digest, 0, len(set))
+	for d := range set {
+		out = append(out, d)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i] < out[j] })
+	return out
+}
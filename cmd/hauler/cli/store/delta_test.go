@@ -0,0 +1,223 @@
+package store
+
+// delta_test.go covers `store save --since` and loading the resulting delta.
+// Like save_test.go, these call SaveCmd, so none of them may use t.Parallel().
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+
+	"hauler.dev/go/hauler/v2/internal/flags"
+	"hauler.dev/go/hauler/v2/pkg/archives"
+	"hauler.dev/go/hauler/v2/pkg/consts"
+	"hauler.dev/go/hauler/v2/pkg/store"
+)
+
+// seedDeltaStore fills a store with test/base:v1, saves it as the baseline
+// haul, then adds test/base:v2 (v1 plus one layer) and test/other:v1. It
+// returns the store and the baseline haul path.
+func seedDeltaStore(t *testing.T, ctx context.Context) (*store.Layout, string) {
+	t.Helper()
+	host, opts := newLocalhostRegistry(t)
+	base := seedImage(t, host, "test/base", "v1", opts...)
+
+	s := newTestStore(t)
+	if _, err := s.AddImage(ctx, host+"/test/base:v1", "", true, "", false, ""); err != nil {
+		t.Fatalf("AddImage v1: %v", err)
+	}
+
+	baseline := filepath.Join(t.TempDir(), "baseline.tar.zst")
+	if err := SaveCmd(ctx, newSaveOpts(s.Root, baseline), s, defaultRootOpts(s.Root), defaultCliOpts()); err != nil {
+		t.Fatalf("SaveCmd baseline: %v", err)
+	}
+
+	extra, err := random.Layer(256, "application/vnd.oci.image.layer.v1.tar")
+	if err != nil {
+		t.Fatalf("random.Layer: %v", err)
+	}
+	next, err := mutate.AppendLayers(base, extra)
+	if err != nil {
+		t.Fatalf("AppendLayers: %v", err)
+	}
+	ref, _ := name.NewTag(host+"/test/base:v2", name.Insecure)
+	if err := remote.Write(ref, next, opts...); err != nil {
+		t.Fatalf("remote.Write v2: %v", err)
+	}
+	seedImage(t, host, "test/other", "v1", opts...)
+
+	for _, r := range []string{host + "/test/base:v2", host + "/test/other:v1"} {
+		if _, err := s.AddImage(ctx, r, "", true, "", false, ""); err != nil {
+			t.Fatalf("AddImage %s: %v", r, err)
+		}
+	}
+	return s, baseline
+}
+
+func readDeltaManifest(t *testing.T, ctx context.Context, haul string) (deltaManifest, []string) {
+	t.Helper()
+	dir := t.TempDir()
+	if err := archives.Unarchive(ctx, haul, dir); err != nil {
+		t.Fatalf("Unarchive: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, consts.DefaultDeltaManifestName))
+	if err != nil {
+		t.Fatalf("reading delta manifest: %v", err)
+	}
+	var dm deltaManifest
+	if err := json.Unmarshal(data, &dm); err != nil {
+		t.Fatalf("parsing delta manifest: %v", err)
+	}
+	blobs, _ := os.ReadDir(filepath.Join(dir, "blobs", "sha256"))
+	var names []string
+	for _, b := range blobs {
+		names = append(names, b.Name())
+	}
+	return dm, names
+}
+
+func TestSaveCmd_Since(t *testing.T) {
+	ctx := newTestContext(t)
+	s, baseline := seedDeltaStore(t, ctx)
+
+	base, err := readContentList(ctx, baseline, t.TempDir())
+	if err != nil {
+		t.Fatalf("readContentList: %v", err)
+	}
+
+	delta := filepath.Join(t.TempDir(), "delta.tar.zst")
+	o := newSaveOpts(s.Root, delta)
+	o.Since = baseline
+	if err := SaveCmd(ctx, o, s, defaultRootOpts(s.Root), defaultCliOpts()); err != nil {
+		t.Fatalf("SaveCmd --since: %v", err)
+	}
+
+	dm, blobs := readDeltaManifest(t, ctx, delta)
+	if dm.Baseline.Index != base.Index || dm.Baseline.StoreID != s.StoreID {
+		t.Errorf("baseline identity = %+v, want index %s store %s", dm.Baseline, base.Index, s.StoreID)
+	}
+	// v2 shares v1's layers, which the delta must name rather than carry
+	if len(dm.Required) == 0 {
+		t.Fatal("expected the delta to require baseline blobs")
+	}
+	inBaseline := map[string]bool{}
+	for _, d := range base.Blobs {
+		inBaseline[d.Encoded()] = true
+	}
+	for _, b := range blobs {
+		if inBaseline[b] {
+			t.Errorf("delta carries blob %s that is already in the baseline", b)
+		}
+	}
+}
+
+func TestSaveCmd_Since_ContentList(t *testing.T) {
+	ctx := newTestContext(t)
+	s, baseline := seedDeltaStore(t, ctx)
+
+	// exporting the list from a full save and using it as the baseline must
+	// produce the same delta as pointing at the haul itself
+	list := filepath.Join(t.TempDir(), "content.json")
+	o := newSaveOpts(s.Root, filepath.Join(t.TempDir(), "full.tar.zst"))
+	o.ContentList = list
+	if err := SaveCmd(ctx, o, s, defaultRootOpts(s.Root), defaultCliOpts()); err != nil {
+		t.Fatalf("SaveCmd --content-list: %v", err)
+	}
+
+	fromHaul, err := readContentList(ctx, baseline, t.TempDir())
+	if err != nil {
+		t.Fatalf("readContentList haul: %v", err)
+	}
+	fromList, err := readContentList(ctx, list, t.TempDir())
+	if err != nil {
+		t.Fatalf("readContentList list: %v", err)
+	}
+	if len(fromList.Manifests) != 3 {
+		t.Errorf("content list has %d manifests, want 3", len(fromList.Manifests))
+	}
+	if len(fromList.Blobs) <= len(fromHaul.Blobs) {
+		t.Errorf("content list has %d blobs, baseline has %d... expected more", len(fromList.Blobs), len(fromHaul.Blobs))
+	}
+
+	// nothing was added after the export, so a delta against it is empty
+	delta := filepath.Join(t.TempDir(), "delta.tar.zst")
+	o = newSaveOpts(s.Root, delta)
+	o.Since = list
+	if err := SaveCmd(ctx, o, s, defaultRootOpts(s.Root), defaultCliOpts()); err != nil {
+		t.Fatalf("SaveCmd --since list: %v", err)
+	}
+	dm, blobs := readDeltaManifest(t, ctx, delta)
+	if len(dm.Required) != 0 || len(blobs) != 0 {
+		t.Errorf("expected an empty delta, got %d required and %d carried blobs", len(dm.Required), len(blobs))
+	}
+}
+
+func TestSaveCmd_Since_RejectsContainerd(t *testing.T) {
+	ctx := newTestContext(t)
+	s := newTestStore(t)
+
+	o := newSaveOpts(s.Root, filepath.Join(t.TempDir(), "delta.tar.zst"))
+	o.Since = filepath.Join(t.TempDir(), "baseline.tar.zst")
+	o.ContainerdCompatibility = true
+	if err := SaveCmd(ctx, o, s, defaultRootOpts(s.Root), defaultCliOpts()); err == nil {
+		t.Fatal("expected --since with --containerd to fail")
+	}
+}
+
+func TestLoadCmd_Delta(t *testing.T) {
+	ctx := newTestContext(t)
+	s, baseline := seedDeltaStore(t, ctx)
+
+	delta := filepath.Join(t.TempDir(), "delta.tar.zst")
+	o := newSaveOpts(s.Root, delta)
+	o.Since = baseline
+	if err := SaveCmd(ctx, o, s, defaultRootOpts(s.Root), defaultCliOpts()); err != nil {
+		t.Fatalf("SaveCmd --since: %v", err)
+	}
+
+	load := func(storeDir string, hauls ...string) error {
+		pre, err := store.NewLayout(storeDir)
+		if err != nil {
+			t.Fatalf("NewLayout: %v", err)
+		}
+		lo := &flags.LoadOpts{StoreRootOpts: defaultRootOpts(storeDir), FileName: hauls}
+		return LoadCmd(ctx, lo, pre, defaultRootOpts(storeDir), defaultCliOpts())
+	}
+
+	t.Run("refused without baseline", func(t *testing.T) {
+		dir := t.TempDir()
+		err := load(dir, delta)
+		if err == nil || !strings.Contains(err.Error(), "load the baseline haul first") {
+			t.Fatalf("expected missing baseline error, got %v", err)
+		}
+		empty, _ := store.NewLayout(dir)
+		if n := countArtifactsInStore(t, empty); n != 0 {
+			t.Errorf("refused delta still loaded %d artifacts", n)
+		}
+	})
+
+	t.Run("merges on top of baseline", func(t *testing.T) {
+		dir := t.TempDir()
+		if err := load(dir, baseline, delta); err != nil {
+			t.Fatalf("LoadCmd baseline + delta: %v", err)
+		}
+		loaded, err := store.NewLayout(dir)
+		if err != nil {
+			t.Fatalf("NewLayout: %v", err)
+		}
+		for _, ref := range []string{"test/base:v1", "test/base:v2", "test/other:v1"} {
+			assertArtifactInStore(t, loaded, ref)
+		}
+		if got, want := storedDigest(t, loaded, "test/base:v2"), storedDigest(t, s, "test/base:v2"); got != want {
+			t.Errorf("test/base:v2 digest = %s, want %s", got, want)
+		}
+	})
+}
@@ -0,0 +1,508 @@
+package store
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/olekukonko/tablewriter"
+	"github.com/olekukonko/tablewriter/tw"
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"k8s.io/apimachinery/pkg/util/yaml"
+
+	"hauler.dev/go/hauler/v2/internal/flags"
+	v1 "hauler.dev/go/hauler/v2/pkg/apis/hauler.cattle.io/v1"
+	"hauler.dev/go/hauler/v2/pkg/consts"
+	"hauler.dev/go/hauler/v2/pkg/content"
+	"hauler.dev/go/hauler/v2/pkg/getter"
+	"hauler.dev/go/hauler/v2/pkg/log"
+	"hauler.dev/go/hauler/v2/pkg/reference"
+	"hauler.dev/go/hauler/v2/pkg/store"
+)
+
+type diffArtifact struct {
+	Reference string `json:"reference"`
+	Type      string `json:"type"`
+	Digest    string `json:"digest,omitempty"`
+
+	// anyTag marks a manifest chart with no version: it matches the chart
+	// under any tag, since only a registry knows which version sync would get
+	anyTag bool
+}
+
+type diffRetag struct {
+	From   string `json:"from"`
+	To     string `json:"to"`
+	Type   string `json:"type"`
+	Digest string `json:"digest"`
+}
+
+type diffChange struct {
+	Reference string `json:"reference"`
+	Type      string `json:"type"`
+	From      string `json:"from"`
+	To        string `json:"to"`
+}
+
+type diffBlobs struct {
+	Count int   `json:"count"`
+	Size  int64 `json:"size"`
+}
+
+type diffOutput struct {
+	From     string         `json:"from"`
+	To       string         `json:"to"`
+	Added    []diffArtifact `json:"added"`
+	Removed  []diffArtifact `json:"removed"`
+	Retagged []diffRetag    `json:"retagged"`
+	Changed  []diffChange   `json:"changed"`
+	// NewBlobs is left out when either side is a manifest, which names
+	// artifacts but holds no blobs
+	NewBlobs *diffBlobs `json:"new-blobs,omitempty"`
+}
+
+// diffSide is one side of a diff, read from a store, haul, or manifest.
+type diffSide struct {
+	name      string
+	artifacts map[string]diffArtifact
+	blobs     map[digest.Digest]int64 // nil for a manifest
+}
+
+func (d *diffSide) add(a diffArtifact, kind string) {
+	key := a.Type + "|" + a.Reference
+	if a.Type == "referrer" {
+		// an image can have any number of referrers, one per kind
+		key += "|" + kind
+	}
+	d.artifacts[key] = a
+}
+
+// find returns the artifact in d matching a, by key or, for an unversioned
+// manifest chart, by repository.
+func (d *diffSide) find(key string, a diffArtifact) (diffArtifact, bool) {
+	if b, ok := d.artifacts[key]; ok {
+		return b, true
+	}
+	for _, b := range d.artifacts {
+		if b.Type != a.Type || !(a.anyTag || b.anyTag) {
+			continue
+		}
+		if diffRepository(a.Reference) == diffRepository(b.Reference) {
+			return b, true
+		}
+	}
+	return diffArtifact{}, false
+}
+
+func diffRepository(ref string) string {
+	if i := strings.LastIndex(ref, ":"); i > strings.LastIndex(ref, "/") {
+		return ref[:i]
+	}
+	return ref
+}
+
+// DiffCmd compares two sides -- each a store directory, StoreID, haul
+// archive, or hauler manifest -- and reports what a transfer from a to b would
+// add, remove, retag, or change, and how many bytes of blobs b holds that a
+// doesn't.
+func DiffCmd(ctx context.Context, o *flags.DiffOpts, a, b string, ro *flags.CliRootOpts) error {
+	l := log.FromContext(ctx)
+
+	tempDir, err := os.MkdirTemp(o.TempOverride, consts.DefaultHaulerTempDirName)
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tempDir)
+
+	from, err := openDiffSide(ctx, o, a, filepath.Join(tempDir, "a"), ro)
+	if err != nil {
+		return err
+	}
+	to, err := openDiffSide(ctx, o, b, filepath.Join(tempDir, "b"), ro)
+	if err != nil {
+		return err
+	}
+
+	// a manifest only names the content it pulls; what comes along with each
+	// image is only known once it's been pulled, so leave it out of both sides
+	if from.blobs == nil || to.blobs == nil {
+		for _, side := range []*diffSide{from, to} {
+			for key, art := range side.artifacts {
+				if isRelatedType(art.Type) {
+					delete(side.artifacts, key)
+				}
+			}
+		}
+	}
+
+	out := diffSides(from, to)
+
+	if o.OutputFormat == "json" {
+		data, err := json.MarshalIndent(out, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	if len(out.Added)+len(out.Removed)+len(out.Retagged)+len(out.Changed) == 0 {
+		l.Infof("no differences between [%s] and [%s]", out.From, out.To)
+		return nil
+	}
+	return buildDiffTable(out)
+}
+
+func diffSides(from, to *diffSide) *diffOutput {
+	out := &diffOutput{
+		From:     from.name,
+		To:       to.name,
+		Added:    []diffArtifact{},
+		Removed:  []diffArtifact{},
+		Retagged: []diffRetag{},
+		Changed:  []diffChange{},
+	}
+
+	for key, x := range from.artifacts {
+		y, ok := to.find(key, x)
+		switch {
+		case !ok:
+			out.Removed = append(out.Removed, x)
+		case x.Digest != "" && y.Digest != "" && x.Digest != y.Digest:
+			out.Changed = append(out.Changed, diffChange{Reference: x.Reference, Type: x.Type, From: x.Digest, To: y.Digest})
+		}
+	}
+	for key, y := range to.artifacts {
+		if _, ok := from.find(key, y); !ok {
+			out.Added = append(out.Added, y)
+		}
+	}
+
+	sortDiffArtifacts(out.Removed)
+	sortDiffArtifacts(out.Added)
+	sort.Slice(out.Changed, func(i, j int) bool { return out.Changed[i].Reference < out.Changed[j].Reference })
+
+	// the same content under a new reference is a retag, not a removal and
+	// an addition
+	var added []diffArtifact
+	for _, y := range out.Added {
+		retagged := false
+		for i, x := range out.Removed {
+			if y.Digest != "" && x.Digest == y.Digest && x.Type == y.Type {
+				out.Retagged = append(out.Retagged, diffRetag{From: x.Reference, To: y.Reference, Type: y.Type, Digest: y.Digest})
+				out.Removed = append(out.Removed[:i], out.Removed[i+1:]...)
+				retagged = true
+				break
+			}
+		}
+		if !retagged {
+			added = append(added, y)
+		}
+	}
+	if added == nil {
+		added = []diffArtifact{}
+	}
+	out.Added = added
+
+	if from.blobs != nil && to.blobs != nil {
+		out.NewBlobs = &diffBlobs{}
+		for d, size := range to.blobs {
+			if _, ok := from.blobs[d]; !ok {
+				out.NewBlobs.Count++
+				out.NewBlobs.Size += size
+			}
+		}
+	}
+	return out
+}
+
+func sortDiffArtifacts(arts []diffArtifact) {
+	sort.Slice(arts, func(i, j int) bool {
+		if arts[i].Reference != arts[j].Reference {
+			return arts[i].Reference < arts[j].Reference
+		}
+		return arts[i].Type < arts[j].Type
+	})
+}
+
+// openDiffSide reads arg as a store directory, a hauler manifest (.yaml or
+// .yml), a haul archive (local, remote, or chunked, unpacked into dir), or
+// failing all of those a StoreID.
+func openDiffSide(ctx context.Context, o *flags.DiffOpts, arg, dir string, ro *flags.CliRootOpts) (*diffSide, error) {
+	remote := strings.HasPrefix(arg, "http://") || strings.HasPrefix(arg, "https://")
+
+	fi, err := os.Stat(arg)
+	switch {
+	case remote:
+	case err == nil && fi.IsDir():
+		return readDiffStore(ctx, o, arg, ro)
+	case err == nil && isManifestPath(arg):
+		return readDiffManifest(ctx, arg)
+	case errors.Is(err, os.ErrNotExist):
+		abs, rerr := flags.ResolveStoreDir(ctx, ro, arg)
+		if rerr != nil {
+			return nil, rerr
+		}
+		if _, serr := os.Stat(abs); serr != nil {
+			return nil, fmt.Errorf("no store, haul, or manifest found at [%s]", arg)
+		}
+		return readDiffStore(ctx, o, abs, ro)
+	case err != nil:
+		return nil, err
+	}
+
+	root, err := openHaul(ctx, arg, dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open haul [%s]: %w", arg, err)
+	}
+	s, err := store.NewLayout(root)
+	if err != nil {
+		return nil, err
+	}
+	side, err := readDiffLayout(ctx, s)
+	if err != nil {
+		return nil, err
+	}
+	side.name = arg
+	return side, nil
+}
+
+func isManifestPath(p string) bool {
+	ext := strings.ToLower(filepath.Ext(p))
+	return ext == ".yaml" || ext == ".yml"
+}
+
+func readDiffStore(ctx context.Context, o *flags.DiffOpts, dir string, ro *flags.CliRootOpts) (*diffSide, error) {
+	// only overriding StoreDir, everything else still comes from o
+	altOpts := *o.StoreRootOpts
+	altOpts.StoreDir = dir
+	s, err := altOpts.Store(ctx, ro)
+	if err != nil {
+		return nil, err
+	}
+	if err := altOpts.LockShared(ctx, s, "hauler store diff"); err != nil {
+		return nil, err
+	}
+	defer s.Unlock()
+
+	side, err := readDiffLayout(ctx, s)
+	if err != nil {
+		return nil, err
+	}
+	side.name = s.Root
+	return side, nil
+}
+
+func readDiffLayout(ctx context.Context, s *store.Layout) (*diffSide, error) {
+	side := &diffSide{artifacts: map[string]diffArtifact{}, blobs: map[digest.Digest]int64{}}
+	err := s.Walk(func(_ string, desc ocispec.Descriptor) error {
+		refName := desc.Annotations[ocispec.AnnotationRefName]
+		if refName == "" {
+			return nil
+		}
+		err := s.WalkBlobs(ctx, desc, func(d ocispec.Descriptor) error {
+			side.blobs[d.Digest] = d.Size
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("cannot read manifest [%s] of [%s] in [%s]: %w", desc.Digest, refName, s.Root, err)
+		}
+		side.add(diffArtifact{Reference: refName, Type: artifactType(ctx, s, desc), Digest: desc.Digest.String()}, desc.Annotations[consts.KindAnnotationName])
+		return nil
+	})
+	return side, err
+}
+
+// readDiffManifest lists the references a sync of the manifest at p would
+// store, without contacting any registry: only images pinned by digest carry
+// one, and a chart with no version matches any stored version.
+func readDiffManifest(ctx context.Context, p string) (*diffSide, error) {
+	l := log.FromContext(ctx)
+
+	fi, err := os.Open(p)
+	if err != nil {
+		return nil, err
+	}
+	defer fi.Close()
+
+	side := &diffSide{name: p, artifacts: map[string]diffArtifact{}}
+	reader := yaml.NewYAMLReader(bufio.NewReader(fi))
+	for {
+		doc, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		obj, err := content.Load(doc)
+		if err != nil {
+			l.Warnf("skipping document in [%s] due to %v", p, err)
+			continue
+		}
+		gvk := obj.GroupVersionKind()
+		if gvk.Version != "v1" {
+			return nil, fmt.Errorf("unsupported version [%s] for kind [%s]... valid versions are [v1]", gvk.Version, gvk.Kind)
+		}
+
+		switch gvk.Kind {
+		case consts.ImagesContentKind:
+			var cfg v1.Images
+			if err := yaml.Unmarshal(doc, &cfg); err != nil {
+				return nil, err
+			}
+			for _, i := range cfg.Spec.Images {
+				art, err := manifestImageArtifact(i)
+				if err != nil {
+					return nil, err
+				}
+				side.add(art, "")
+			}
+
+		case consts.ChartsContentKind:
+			var cfg v1.Charts
+			if err := yaml.Unmarshal(doc, &cfg); err != nil {
+				return nil, err
+			}
+			for _, c := range cfg.Spec.Charts {
+				art, err := manifestChartArtifact(c)
+				if err != nil {
+					return nil, err
+				}
+				side.add(art, "")
+			}
+
+		case consts.FilesContentKind:
+			var cfg v1.Files
+			if err := yaml.Unmarshal(doc, &cfg); err != nil {
+				return nil, err
+			}
+			for _, f := range cfg.Spec.Files {
+				n := getter.NewClient(getter.ClientOptions{NameOverride: f.Name}).Name(f.Path)
+				if n == f.Path {
+					// no getter recognized the path (a local file missing
+					// here), so name it the way the file getter would
+					n = filepath.Base(f.Path)
+				}
+				ref, err := reference.NewTagged(n, consts.DefaultTag)
+				if err != nil {
+					return nil, fmt.Errorf("unable to derive a store reference for file [%s]: %w", f.Path, err)
+				}
+				side.add(diffArtifact{Reference: ref.Name(), Type: "file"}, "")
+			}
+		}
+	}
+	return side, nil
+}
+
+// manifestImageArtifact names an image the way storeImage would index it.
+func manifestImageArtifact(i v1.Image) (diffArtifact, error) {
+	r, err := name.ParseReference(i.Name)
+	if err != nil {
+		return diffArtifact{}, fmt.Errorf("unable to parse image [%s]: %w", i.Name, err)
+	}
+	art := diffArtifact{Reference: strings.TrimPrefix(r.Name(), r.Context().RegistryStr()+"/"), Type: "image"}
+	if d, ok := r.(name.Digest); ok && i.Platform == "" {
+		// under a platform filter the store holds the selected child instead
+		art.Digest = d.DigestStr()
+	}
+
+	if i.Rewrite != "" {
+		newRef, err := imageRewriteRef(r, i.Rewrite)
+		if err != nil {
+			return diffArtifact{}, err
+		}
+		_, _, art.Reference, _ = rewriteNames(r, newRef, i.Rewrite)
+	}
+	return art, nil
+}
+
+// manifestChartArtifact names a chart the way storeChart would index it,
+// assuming the chart's own name matches the last element of its spec name.
+func manifestChartArtifact(c v1.Chart) (diffArtifact, error) {
+	chartName := path.Base(strings.TrimSuffix(c.Name, "/"))
+	version := c.Version
+	if version == "" {
+		version = consts.DefaultTag
+	}
+	ref, err := reference.NewTagged(chartName, version)
+	if err != nil {
+		return diffArtifact{}, fmt.Errorf("unable to derive a store reference for chart [%s]: %w", c.Name, err)
+	}
+	art := diffArtifact{Reference: ref.Name(), Type: "chart"}
+	if c.Version == "" {
+		art.Reference = ref.Context().RepositoryStr()
+		art.anyTag = true
+	}
+
+	if c.Rewrite != "" {
+		_, newTotal, err := chartRewriteNames(ref, c.Rewrite)
+		if err != nil {
+			return diffArtifact{}, err
+		}
+		art.Reference = newTotal
+	}
+	return art, nil
+}
+
+func isRelatedType(typ string) bool {
+	switch typ {
+	case "sigs", "atts", "sbom", "referrer":
+		return true
+	}
+	return false
+}
+
+func buildDiffTable(out *diffOutput) error {
+	table := tablewriter.NewTable(os.Stdout)
+	table.Configure(func(cfg *tablewriter.Config) {
+		cfg.Header.Alignment.Global = tw.AlignLeft
+		cfg.Footer.Alignment.PerColumn = []tw.Align{tw.AlignLeft}
+		cfg.Row.Merging.Mode = tw.MergeVertical
+		cfg.Row.Merging.ByColumnIndex = tw.NewBoolMapper(0)
+	})
+	table.Header("Change", "Type", "Reference", "Digest")
+
+	orDash := func(s string) string {
+		if s == "" {
+			return "-"
+		}
+		return s
+	}
+	var rows [][]string
+	for _, a := range out.Added {
+		rows = append(rows, []string{"added", a.Type, truncateReference(a.Reference), orDash(a.Digest)})
+	}
+	for _, r := range out.Removed {
+		rows = append(rows, []string{"removed", r.Type, truncateReference(r.Reference), orDash(r.Digest)})
+	}
+	for _, r := range out.Retagged {
+		rows = append(rows, []string{"retagged", r.Type, truncateReference(r.From) + " -> " + truncateReference(r.To), r.Digest})
+	}
+	for _, c := range out.Changed {
+		rows = append(rows, []string{"changed", c.Type, truncateReference(c.Reference), c.From + " -> " + c.To})
+	}
+	for _, row := range rows {
+		if err := table.Append(row); err != nil {
+			return err
+		}
+	}
+
+	newBlobs := "-"
+	if out.NewBlobs != nil {
+		newBlobs = fmt.Sprintf("%d (%s)", out.NewBlobs.Count, byteCountSI(out.NewBlobs.Size))
+	}
+	table.Footer("from: "+out.From+"\nto: "+out.To, "", "New Unique Blobs", newBlobs)
+	return table.Render()
+}
@@ -0,0 +1,132 @@
+package store
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"hauler.dev/go/hauler/v2/internal/flags"
+	v1 "hauler.dev/go/hauler/v2/pkg/apis/hauler.cattle.io/v1"
+	"hauler.dev/go/hauler/v2/pkg/store"
+)
+
+func diffTestSides(t *testing.T, o *flags.DiffOpts, a, b string, ro *flags.CliRootOpts) *diffOutput {
+	t.Helper()
+	ctx := newTestContext(t)
+	from, err := openDiffSide(ctx, o, a, filepath.Join(t.TempDir(), "a"), ro)
+	if err != nil {
+		t.Fatalf("openDiffSide(%s): %v", a, err)
+	}
+	to, err := openDiffSide(ctx, o, b, filepath.Join(t.TempDir(), "b"), ro)
+	if err != nil {
+		t.Fatalf("openDiffSide(%s): %v", b, err)
+	}
+	return diffSides(from, to)
+}
+
+func TestDiffCmd_Stores(t *testing.T) {
+	ctx := newTestContext(t)
+	host, _ := newLocalhostRegistry(t)
+	for _, repo := range []string{"diff/same", "diff/gone", "diff/moved", "diff/bumped"} {
+		seedImage(t, host, repo, "v1")
+	}
+
+	ro := defaultCliOpts()
+	ro.HaulerDir = t.TempDir()
+	add := func(s *store.Layout, ref, rewrite string) {
+		t.Helper()
+		if err := storeImage(ctx, s, v1.Image{Name: host + "/" + ref}, "", true, defaultRootOpts(s.Root), ro, rewrite, "", false); err != nil {
+			t.Fatalf("storeImage(%s): %v", ref, err)
+		}
+	}
+
+	a := newTestStore(t)
+	add(a, "diff/same:v1", "")
+	add(a, "diff/gone:v1", "")
+	add(a, "diff/moved:v1", "")
+	add(a, "diff/bumped:v1", "")
+
+	seedImage(t, host, "diff/bumped", "v1")
+	seedImage(t, host, "diff/new", "v1")
+	b := newTestStore(t)
+	add(b, "diff/same:v1", "")
+	add(b, "diff/moved:v1", "diff/renamed:v1")
+	add(b, "diff/bumped:v1", "")
+	add(b, "diff/new:v1", "")
+
+	out := diffTestSides(t, &flags.DiffOpts{StoreRootOpts: defaultRootOpts(a.Root)}, a.Root, b.Root, ro)
+
+	if len(out.Added) != 1 || out.Added[0].Reference != "diff/new:v1" {
+		t.Errorf("added = %+v, want diff/new:v1", out.Added)
+	}
+	if len(out.Removed) != 1 || out.Removed[0].Reference != "diff/gone:v1" {
+		t.Errorf("removed = %+v, want diff/gone:v1", out.Removed)
+	}
+	if len(out.Retagged) != 1 || out.Retagged[0].From != "diff/moved:v1" || out.Retagged[0].To != "diff/renamed:v1" {
+		t.Errorf("retagged = %+v, want diff/moved:v1 -> diff/renamed:v1", out.Retagged)
+	}
+	if len(out.Changed) != 1 || out.Changed[0].Reference != "diff/bumped:v1" {
+		t.Errorf("changed = %+v, want diff/bumped:v1", out.Changed)
+	}
+	// the new tag of diff/bumped and diff/new each bring a manifest, a
+	// config, and two layers
+	if out.NewBlobs == nil || out.NewBlobs.Count != 8 || out.NewBlobs.Size <= 0 {
+		t.Errorf("new blobs = %+v, want the 8 blobs of diff/bumped and diff/new", out.NewBlobs)
+	}
+}
+
+// A manifest side is read offline: tags are compared by name, a digest pin
+// is compared by digest, and a rewrite names the reference sync would store.
+func TestDiffCmd_Manifest(t *testing.T) {
+	ctx := newTestContext(t)
+	host, _ := newLocalhostRegistry(t)
+	seedImage(t, host, "diff/tagged", "v1")
+	seedImage(t, host, "diff/pinned", "v1")
+	seedImage(t, host, "diff/source", "v1")
+
+	ro := defaultCliOpts()
+	ro.HaulerDir = t.TempDir()
+	s := newTestStore(t)
+	for _, i := range []v1.Image{
+		{Name: host + "/diff/tagged:v1"},
+		{Name: host + "/diff/pinned:v1"},
+		{Name: host + "/diff/source:v1", Rewrite: "diff/rewritten:v1"},
+	} {
+		if err := storeImage(ctx, s, i, "", true, defaultRootOpts(s.Root), ro, i.Rewrite, "", false); err != nil {
+			t.Fatalf("storeImage(%s): %v", i.Name, err)
+		}
+	}
+
+	stale := seedImage(t, host, "diff/pinned", "v2")
+	staleDigest, err := stale.Digest()
+	if err != nil {
+		t.Fatalf("Digest: %v", err)
+	}
+	manifest := writeTempFile(t, "hauler-manifest.yaml", fmt.Sprintf(`apiVersion: content.hauler.cattle.io/v1
+kind: Images
+metadata:
+  name: diff
+spec:
+  images:
+    - name: %[1]s/diff/tagged:v1
+    - name: %[1]s/diff/pinned@%[2]s
+    - name: %[1]s/diff/source:v1
+      rewrite: diff/rewritten:v1
+    - name: %[1]s/diff/missing:v1
+`, host, staleDigest))
+
+	out := diffTestSides(t, &flags.DiffOpts{StoreRootOpts: defaultRootOpts(s.Root)}, s.Root, manifest, ro)
+
+	if len(out.Added) != 2 {
+		t.Errorf("added = %+v, want diff/missing:v1 and the pinned digest", out.Added)
+	}
+	if len(out.Removed) != 1 || out.Removed[0].Reference != "diff/pinned:v1" {
+		t.Errorf("removed = %+v, want diff/pinned:v1", out.Removed)
+	}
+	if len(out.Changed) != 0 || len(out.Retagged) != 0 {
+		t.Errorf("changed = %+v, retagged = %+v, want neither", out.Changed, out.Retagged)
+	}
+	if out.NewBlobs != nil {
+		t.Errorf("new blobs = %+v, want none reported against a manifest", out.NewBlobs)
+	}
+}
@@ -3,13 +3,20 @@ package store
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
 	"strings"
 
+	libv1 "github.com/google/go-containerregistry/pkg/v1"
 	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
 
 	"hauler.dev/go/hauler/v2/internal/flags"
 	"hauler.dev/go/hauler/v2/internal/mapper"
+	"hauler.dev/go/hauler/v2/pkg/archives"
 	"hauler.dev/go/hauler/v2/pkg/consts"
 	"hauler.dev/go/hauler/v2/pkg/log"
 	"hauler.dev/go/hauler/v2/pkg/reference"
@@ -86,94 +93,376 @@ func isContainerImageManifest(m ocispec.Manifest) bool {
 func ExtractCmd(ctx context.Context, o *flags.ExtractOpts, s *store.Layout, ref string) error {
 	l := log.FromContext(ctx)
 
-	r, err := reference.Parse(ref)
-	if err != nil {
-		return err
+	if (ref == "") == (o.Select == "") {
+		return fmt.Errorf("specify either an artifact reference or --select")
 	}
 
-	// use the repository from the context and the identifier from the reference
-	repo := r.Context().RepositoryStr() + ":" + r.Identifier()
+	if o.RootFS {
+		if o.Select != "" {
+			return fmt.Errorf("--rootfs unpacks a single image... it can't be combined with --select")
+		}
+		return extractRootFS(ctx, o, s, ref)
+	}
 
-	found := false
-	if err := s.Walk(func(reference string, desc ocispec.Descriptor) error {
-		if !strings.Contains(reference, repo) {
+	var candidates []store.Selected
+	if o.Select != "" {
+		selected, err := selectArtifacts(ctx, s, o.Select, false)
+		if err != nil {
+			return err
+		}
+		candidates = selected
+	} else {
+		r, err := reference.Parse(ref)
+		if err != nil {
+			return err
+		}
+
+		// use the repository from the context and the identifier from the reference
+		repo := r.Context().RepositoryStr() + ":" + r.Identifier()
+
+		if err := s.Walk(func(reference string, desc ocispec.Descriptor) error {
+			if strings.Contains(reference, repo) {
+				candidates = append(candidates, store.Selected{Key: reference, Desc: desc})
+			}
 			return nil
+		}); err != nil {
+			return err
 		}
+	}
 
+	var extractable []store.Selected
+	for _, c := range candidates {
 		// Cosign sig/att/sbom/referrer descriptors are registry-only metadata —
 		// they are never extractable to disk. Skip them silently at debug level,
 		// mirroring the same guard in copy.go (directory-target path).
-		kind := desc.Annotations[consts.KindAnnotationName]
+		kind := c.Desc.Annotations[consts.KindAnnotationName]
 		switch kind {
 		case consts.KindAnnotationSigs, consts.KindAnnotationAtts, consts.KindAnnotationSboms:
-			l.Debugf("skipping cosign artifact [%s] for extract", reference)
-			return nil
+			l.Debugf("skipping cosign artifact [%s] for extract", c.Key)
+			continue
 		}
 		if strings.HasPrefix(kind, consts.KindAnnotationReferrers) {
-			l.Debugf("skipping OCI referrer [%s] for extract", reference)
-			return nil
+			l.Debugf("skipping OCI referrer [%s] for extract", c.Key)
+			continue
 		}
+		extractable = append(extractable, c)
+	}
 
-		found = true
+	if len(extractable) == 0 {
+		if o.Select != "" {
+			return fmt.Errorf("no extractable artifacts in store match selector [%s]", o.Select)
+		}
+		return fmt.Errorf("reference [%s] not found in store (hint: use `hauler store info` to list store contents)", ref)
+	}
 
-		rc, err := s.Fetch(ctx, desc)
+	// a dry run lists exactly what a real run would extract, so both skip
+	// the same artifacts
+	var selected []store.Selected
+	var manifests []ocispec.Manifest
+	for _, c := range extractable {
+		m, ok, err := extractableManifest(ctx, s, c.Key, c.Desc)
 		if err != nil {
 			return err
 		}
-		defer rc.Close()
+		if ok {
+			selected = append(selected, c)
+			manifests = append(manifests, m)
+		}
+	}
 
-		// For image indexes, decoding the index JSON as ocispec.Manifest produces
-		// an empty Config.MediaType and nil Layers — causing FromManifest to fall
-		// back to Default() mapper, which writes config blobs as sha256:<digest>.bin.
-		// Instead, peek at the first child manifest to get real config/layer info.
-		var m ocispec.Manifest
-		if desc.MediaType == ocispec.MediaTypeImageIndex || desc.MediaType == consts.DockerManifestListSchema2 {
-			var idx ocispec.Index
-			if err := json.NewDecoder(rc).Decode(&idx); err != nil {
-				return err
-			}
-			if len(idx.Manifests) == 0 {
-				l.Warnf("skipping [%s]: image index has no child manifests", reference)
-				return nil
-			}
-			var err error
-			m, err = firstLeafManifest(ctx, s, idx)
-			if err != nil {
-				return err
-			}
-		} else {
-			if err := json.NewDecoder(rc).Decode(&m); err != nil {
-				return err
+	if o.DryRun {
+		for i := range selected {
+			if selected[i].Type == "" {
+				selected[i].Type = artifactType(ctx, s, selected[i].Desc)
 			}
 		}
+		logSelection(ctx, "extract", "extracted", selected)
+		return nil
+	}
 
-		// Container images (no AnnotationTitle on any layer) are not extractable
-		// to disk in a meaningful way — use `hauler store copy` to push to a registry.
-		if isContainerImageManifest(m) {
-			l.Warnf("skipping [%s]: container images cannot be extracted (use `hauler store copy` to push to a registry)", reference)
-			return nil
+	for i, c := range selected {
+		if err := extractArtifact(ctx, o, s, c.Key, manifests[i]); err != nil {
+			return err
 		}
+	}
 
-		mapperStore, err := mapper.FromManifest(m, o.DestinationDir)
+	return nil
+}
+
+// extractableManifest returns the manifest that decides how the artifact
+// stored under reference is extracted, and false for one that can't be:
+// a container image, or an image index with no children.
+func extractableManifest(ctx context.Context, s *store.Layout, reference string, desc ocispec.Descriptor) (ocispec.Manifest, bool, error) {
+	l := log.FromContext(ctx)
+
+	rc, err := s.Fetch(ctx, desc)
+	if err != nil {
+		return ocispec.Manifest{}, false, err
+	}
+	defer rc.Close()
+
+	// For image indexes, decoding the index JSON as ocispec.Manifest produces
+	// an empty Config.MediaType and nil Layers — causing FromManifest to fall
+	// back to Default() mapper, which writes config blobs as sha256:<digest>.bin.
+	// Instead, peek at the first child manifest to get real config/layer info.
+	var m ocispec.Manifest
+	if desc.MediaType == ocispec.MediaTypeImageIndex || desc.MediaType == consts.DockerManifestListSchema2 {
+		var idx ocispec.Index
+		if err := json.NewDecoder(rc).Decode(&idx); err != nil {
+			return m, false, err
+		}
+		if len(idx.Manifests) == 0 {
+			l.Warnf("skipping [%s]: image index has no child manifests", reference)
+			return m, false, nil
+		}
+		var err error
+		m, err = firstLeafManifest(ctx, s, idx)
 		if err != nil {
-			return err
+			return m, false, err
+		}
+	} else {
+		if err := json.NewDecoder(rc).Decode(&m); err != nil {
+			return m, false, err
 		}
+	}
+
+	// Container images (no AnnotationTitle on any layer) are not extractable
+	// to disk in a meaningful way — use `hauler store copy` to push to a registry.
+	if isContainerImageManifest(m) {
+		l.Warnf("skipping [%s]: container images cannot be extracted (use `--rootfs` to unpack its filesystem or `hauler store copy` to push to a registry)", reference)
+		return m, false, nil
+	}
+	return m, true, nil
+}
+
+// extractArtifact writes the content of the file or chart artifact stored
+// under reference, laid out by m, to o.DestinationDir.
+func extractArtifact(ctx context.Context, o *flags.ExtractOpts, s *store.Layout, reference string, m ocispec.Manifest) error {
+	l := log.FromContext(ctx)
+
+	mapperStore, err := mapper.FromManifest(m, o.DestinationDir)
+	if err != nil {
+		return err
+	}
+
+	pushedDesc, err := s.Copy(ctx, reference, mapperStore, "")
+	if err != nil {
+		return err
+	}
+
+	l.Infof("extracted [%s] from store with digest [%s]", pushedDesc.MediaType, pushedDesc.Digest.String())
+
+	return nil
+}
+
+// extractRootFS unpacks the root filesystem of a single container image into
+// o.DestinationDir by applying its layers in order. Multi-platform images
+// need --platform unless they only carry one platform.
+func extractRootFS(ctx context.Context, o *flags.ExtractOpts, s *store.Layout, ref string) error {
+	l := log.FromContext(ctx)
+
+	if o.DestinationDir == "" {
+		return fmt.Errorf("--rootfs requires an output directory (-o)")
+	}
+	if err := checkRootFSDestination(o.DestinationDir); err != nil {
+		return err
+	}
+
+	desc, name, err := resolveRootFSImage(s, ref)
+	if err != nil {
+		return err
+	}
 
-		pushedDesc, err := s.Copy(ctx, reference, mapperStore, "")
+	if isIndexMediaType(desc.MediaType) {
+		desc, err = selectPlatformManifest(ctx, s, desc, o.Platform)
 		if err != nil {
-			return err
+			return fmt.Errorf("image [%s]: %w", name, err)
 		}
+	} else if o.Platform != "" {
+		l.Debugf("image [%s] is a single-platform image... ignoring platform [%s]", name, o.Platform)
+	}
 
-		l.Infof("extracted [%s] from store with digest [%s]", pushedDesc.MediaType, pushedDesc.Digest.String())
+	rc, err := s.Fetch(ctx, desc)
+	if err != nil {
+		return err
+	}
+	var m ocispec.Manifest
+	err = json.NewDecoder(rc).Decode(&m)
+	rc.Close()
+	if err != nil {
+		return fmt.Errorf("decoding manifest [%s]: %w", desc.Digest, err)
+	}
+	if !isContainerImageManifest(m) {
+		return fmt.Errorf("[%s] is not a container image... extract it without --rootfs", name)
+	}
 
-		return nil
-	}); err != nil {
+	if err := os.MkdirAll(o.DestinationDir, 0o755); err != nil {
 		return err
 	}
 
-	if !found {
-		return fmt.Errorf("reference [%s] not found in store (hint: use `hauler store info` to list store contents)", ref)
+	for i, layer := range m.Layers {
+		l.Debugf("applying layer [%d/%d] [%s]", i+1, len(m.Layers), layer.Digest)
+		rc, err := s.Fetch(ctx, layer)
+		if err != nil {
+			return fmt.Errorf("fetching layer [%s]: %w", layer.Digest, err)
+		}
+		err = archives.ApplyLayer(ctx, rc, layer.MediaType, o.DestinationDir, o.Path)
+		rc.Close()
+		if err != nil {
+			return fmt.Errorf("applying layer [%s]: %w", layer.Digest, err)
+		}
 	}
 
+	if o.Path != "" {
+		if _, err := os.Lstat(filepath.Join(o.DestinationDir, filepath.FromSlash(path.Clean("/"+o.Path)))); err != nil {
+			return fmt.Errorf("path [%s] not found in image [%s]", o.Path, name)
+		}
+	}
+
+	l.Infof("extracted root filesystem of [%s] with digest [%s] to [%s]", name, desc.Digest.String(), o.DestinationDir)
 	return nil
 }
+
+// refuses to unpack over an existing file or a non-empty directory... layers
+// remove paths while they're applied, so anything already there is at risk
+func checkRootFSDestination(dir string) error {
+	fi, err := os.Stat(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !fi.IsDir() {
+		return fmt.Errorf("output [%s] is not a directory", dir)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	if len(entries) > 0 {
+		return fmt.Errorf("output directory [%s] is not empty", dir)
+	}
+	return nil
+}
+
+// resolveRootFSImage finds the one image or image index in the store that
+// matches ref, preferring an exact name match over a partial one.
+func resolveRootFSImage(s *store.Layout, ref string) (ocispec.Descriptor, string, error) {
+	r, err := reference.Parse(ref)
+	if err != nil {
+		return ocispec.Descriptor{}, "", err
+	}
+	repo := r.Context().RepositoryStr() + ":" + r.Identifier()
+
+	var exact, partial []ocispec.Descriptor
+	if err := s.Walk(func(reference string, desc ocispec.Descriptor) error {
+		switch desc.Annotations[consts.KindAnnotationName] {
+		case consts.KindAnnotationImage, consts.KindAnnotationIndex:
+		default:
+			return nil
+		}
+		if !strings.Contains(reference, repo) {
+			return nil
+		}
+		name := desc.Annotations[ocispec.AnnotationRefName]
+		if name == r.Name() || name == repo || strings.HasSuffix(name, "/"+repo) {
+			exact = append(exact, desc)
+		} else {
+			partial = append(partial, desc)
+		}
+		return nil
+	}); err != nil {
+		return ocispec.Descriptor{}, "", err
+	}
+
+	candidates := exact
+	if len(candidates) == 0 {
+		candidates = partial
+	}
+	if len(candidates) == 0 {
+		return ocispec.Descriptor{}, "", fmt.Errorf("image [%s] not found in store (hint: use `hauler store info` to list store contents)", ref)
+	}
+
+	var names []string
+	for _, d := range candidates {
+		if d.Digest != candidates[0].Digest {
+			for _, c := range candidates {
+				names = append(names, c.Annotations[ocispec.AnnotationRefName])
+			}
+			sort.Strings(names)
+			return ocispec.Descriptor{}, "", fmt.Errorf("reference [%s] matches more than one image: %s", ref, strings.Join(names, ", "))
+		}
+	}
+	return candidates[0], candidates[0].Annotations[ocispec.AnnotationRefName], nil
+}
+
+// selectPlatformManifest picks the child manifest of an image index matching
+// platform ("os/arch[/variant]"). With no platform, an index that carries a
+// single platform resolves to it and anything else is an error listing what's
+// available. Children without a usable platform (i.e. attestation manifests)
+// are never picked.
+func selectPlatformManifest(ctx context.Context, s *store.Layout, desc ocispec.Descriptor, platform string) (ocispec.Descriptor, error) {
+	var want *libv1.Platform
+	if platform != "" {
+		p, err := libv1.ParsePlatform(platform)
+		if err != nil {
+			return ocispec.Descriptor{}, err
+		}
+		want = p
+	}
+
+	rc, err := s.Fetch(ctx, desc)
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	var idx ocispec.Index
+	err = json.NewDecoder(rc).Decode(&idx)
+	rc.Close()
+	if err != nil {
+		return ocispec.Descriptor{}, fmt.Errorf("decoding index: %w", err)
+	}
+
+	var candidates []ocispec.Descriptor
+	var available []string
+	for _, child := range idx.Manifests {
+		if isIndexMediaType(child.MediaType) || child.Platform == nil {
+			continue
+		}
+		p := child.Platform
+		if p.OS == "" || p.OS == "unknown" || p.Architecture == "" || p.Architecture == "unknown" {
+			continue
+		}
+		available = append(available, platformString(p))
+		if want != nil {
+			if p.OS != want.OS || p.Architecture != want.Architecture {
+				continue
+			}
+			if want.Variant != "" && p.Variant != want.Variant {
+				continue
+			}
+		}
+		candidates = append(candidates, child)
+	}
+
+	switch {
+	case len(candidates) == 1:
+		return candidates[0], nil
+	case len(available) == 0:
+		return ocispec.Descriptor{}, fmt.Errorf("image index has no platform manifests")
+	case want == nil:
+		return ocispec.Descriptor{}, fmt.Errorf("multiple platforms available, specify one with --platform: %s", strings.Join(available, ", "))
+	case len(candidates) == 0:
+		return ocispec.Descriptor{}, fmt.Errorf("platform [%s] not found, available: %s", platform, strings.Join(available, ", "))
+	default:
+		return ocispec.Descriptor{}, fmt.Errorf("platform [%s] is ambiguous, include the variant: %s", platform, strings.Join(available, ", "))
+	}
+}
+
+func platformString(p *ocispec.Platform) string {
+	s := p.OS + "/" + p.Architecture
+	if p.Variant != "" {
+		s += "/" + p.Variant
+	}
+	return s
+}
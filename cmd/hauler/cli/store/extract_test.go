@@ -1,6 +1,7 @@
 package store
 
 import (
+	"archive/tar"
 	"bytes"
 	"context"
 	"os"
@@ -21,6 +22,7 @@ import (
 	"hauler.dev/go/hauler/v2/internal/flags"
 	v1 "hauler.dev/go/hauler/v2/pkg/apis/hauler.cattle.io/v1"
 	"hauler.dev/go/hauler/v2/pkg/consts"
+	"hauler.dev/go/hauler/v2/pkg/store"
 )
 
 // chartTestdataDir is defined in add_test.go as "../../../../testdata".
@@ -435,7 +437,19 @@ func TestExtractCmd_ContainerImage_Skipped(t *testing.T) {
 	eo := &flags.ExtractOpts{
 		StoreRootOpts:  defaultRootOpts(s.Root),
 		DestinationDir: destDir,
+		DryRun:         true,
 	}
+
+	// a dry run skips the same images a real run does
+	var logBuf bytes.Buffer
+	if err := ExtractCmd(newLogCaptureContext(&logBuf), eo, s, "myapp/myimage:v1"); err != nil {
+		t.Fatalf("ExtractCmd(--dry-run): %v", err)
+	}
+	if strings.Contains(logBuf.String(), "would extract") || !strings.Contains(logBuf.String(), "[0] artifact(s) would be extracted") {
+		t.Errorf("dry run lists a container image it would skip:\n%s", logBuf.String())
+	}
+
+	eo.DryRun = false
 	if err := ExtractCmd(ctx, eo, s, "myapp/myimage:v1"); err != nil {
 		t.Fatalf("ExtractCmd: %v", err)
 	}
@@ -628,3 +642,180 @@ func TestExtractCmd_CosignArtifactsProduceNoContainerImageWarning(t *testing.T)
 		t.Errorf("content mismatch: got %q, want %q", string(data), fileContent)
 	}
 }
+
+// rootfsLayer builds an uncompressed tar layer from name → content pairs. An
+// empty content with a trailing "/" name is a directory.
+func rootfsLayer(t *testing.T, files ...[2]string) gcrv1.Layer {
+	t.Helper()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, f := range files {
+		hdr := &tar.Header{Name: f[0], Mode: 0o644, Typeflag: tar.TypeReg, Size: int64(len(f[1]))}
+		if strings.HasSuffix(f[0], "/") {
+			hdr = &tar.Header{Name: f[0], Mode: 0o755, Typeflag: tar.TypeDir}
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatalf("WriteHeader: %v", err)
+		}
+		if _, err := tw.Write([]byte(f[1])); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("tar close: %v", err)
+	}
+	return static.NewLayer(buf.Bytes(), gvtypes.OCIUncompressedLayer)
+}
+
+func seedRootFSIndex(t *testing.T, ctx context.Context) (*store.Layout, string) {
+	t.Helper()
+	host, rOpts := newLocalhostRegistry(t)
+
+	buildPlatformImg := func(arch string) gcrv1.Image {
+		img, err := mutate.Append(empty.Image,
+			mutate.Addendum{Layer: rootfsLayer(t,
+				[2]string{"etc/", ""},
+				[2]string{"etc/arch", "base"},
+				[2]string{"etc/removed", "gone"},
+				[2]string{"usr/", ""},
+				[2]string{"usr/bin/", ""},
+				[2]string{"usr/bin/tool", "tool-" + arch},
+			)},
+			mutate.Addendum{Layer: rootfsLayer(t,
+				[2]string{"etc/arch", arch},
+				[2]string{"etc/.wh.removed", ""},
+			)},
+		)
+		if err != nil {
+			t.Fatalf("mutate.Append: %v", err)
+		}
+		img = mutate.MediaType(img, gvtypes.OCIManifestSchema1)
+		img = mutate.ConfigMediaType(img, gvtypes.MediaType(ocispec.MediaTypeImageConfig))
+		return img
+	}
+
+	idx := mutate.AppendManifests(empty.Index,
+		mutate.IndexAddendum{
+			Add: buildPlatformImg("amd64"),
+			Descriptor: gcrv1.Descriptor{
+				MediaType: gvtypes.OCIManifestSchema1,
+				Platform:  &gcrv1.Platform{OS: "linux", Architecture: "amd64"},
+			},
+		},
+		mutate.IndexAddendum{
+			Add: buildPlatformImg("arm64"),
+			Descriptor: gcrv1.Descriptor{
+				MediaType: gvtypes.OCIManifestSchema1,
+				Platform:  &gcrv1.Platform{OS: "linux", Architecture: "arm64"},
+			},
+		},
+	)
+
+	ref := host + "/myapp/rootfs:v1"
+	tag, err := name.NewTag(ref, name.Insecure)
+	if err != nil {
+		t.Fatalf("name.NewTag: %v", err)
+	}
+	if err := remote.WriteIndex(tag, idx, rOpts...); err != nil {
+		t.Fatalf("remote.WriteIndex: %v", err)
+	}
+
+	s := newTestStore(t)
+	if _, err := s.AddImage(ctx, ref, "", false, "", false, "", rOpts...); err != nil {
+		t.Fatalf("AddImage: %v", err)
+	}
+	return s, "myapp/rootfs:v1"
+}
+
+func TestExtractCmd_RootFS(t *testing.T) {
+	ctx := newTestContext(t)
+	s, ref := seedRootFSIndex(t, ctx)
+
+	destDir := filepath.Join(t.TempDir(), "rootfs")
+	eo := &flags.ExtractOpts{
+		StoreRootOpts:  defaultRootOpts(s.Root),
+		DestinationDir: destDir,
+		RootFS:         true,
+		Platform:       "linux/arm64",
+	}
+	if err := ExtractCmd(ctx, eo, s, ref); err != nil {
+		t.Fatalf("ExtractCmd --rootfs: %v", err)
+	}
+
+	for p, want := range map[string]string{"etc/arch": "arm64", "usr/bin/tool": "tool-arm64"} {
+		got, err := os.ReadFile(filepath.Join(destDir, p))
+		if err != nil {
+			t.Fatalf("ReadFile(%s): %v", p, err)
+		}
+		if string(got) != want {
+			t.Errorf("%s = %q, want %q", p, got, want)
+		}
+	}
+	if _, err := os.Lstat(filepath.Join(destDir, "etc", "removed")); !os.IsNotExist(err) {
+		t.Errorf("expected whited-out etc/removed to be absent, got err=%v", err)
+	}
+
+	// a second extract into the now non-empty output is refused
+	if err := ExtractCmd(ctx, eo, s, ref); err == nil || !strings.Contains(err.Error(), "not empty") {
+		t.Errorf("expected non-empty output error, got %v", err)
+	}
+}
+
+func TestExtractCmd_RootFS_Path(t *testing.T) {
+	ctx := newTestContext(t)
+	s, ref := seedRootFSIndex(t, ctx)
+
+	destDir := t.TempDir()
+	eo := &flags.ExtractOpts{
+		StoreRootOpts:  defaultRootOpts(s.Root),
+		DestinationDir: destDir,
+		RootFS:         true,
+		Platform:       "linux/amd64",
+		Path:           "/usr/bin/tool",
+	}
+	if err := ExtractCmd(ctx, eo, s, ref); err != nil {
+		t.Fatalf("ExtractCmd --rootfs --path: %v", err)
+	}
+
+	got, err := os.ReadFile(filepath.Join(destDir, "usr", "bin", "tool"))
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	if string(got) != "tool-amd64" {
+		t.Errorf("usr/bin/tool = %q, want %q", got, "tool-amd64")
+	}
+	if _, err := os.Lstat(filepath.Join(destDir, "etc")); !os.IsNotExist(err) {
+		t.Errorf("expected etc to be filtered out, got err=%v", err)
+	}
+
+	eo.DestinationDir = t.TempDir()
+	eo.Path = "/does/not/exist"
+	if err := ExtractCmd(ctx, eo, s, ref); err == nil || !strings.Contains(err.Error(), "not found in image") {
+		t.Errorf("expected missing path error, got %v", err)
+	}
+}
+
+func TestExtractCmd_RootFS_RequiresPlatform(t *testing.T) {
+	ctx := newTestContext(t)
+	s, ref := seedRootFSIndex(t, ctx)
+
+	eo := &flags.ExtractOpts{
+		StoreRootOpts:  defaultRootOpts(s.Root),
+		DestinationDir: t.TempDir(),
+		RootFS:         true,
+	}
+	err := ExtractCmd(ctx, eo, s, ref)
+	if err == nil {
+		t.Fatal("expected an error for a multi-platform image without --platform")
+	}
+	for _, want := range []string{"--platform", "linux/amd64", "linux/arm64"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %q", err, want)
+		}
+	}
+
+	eo.Platform = "linux/s390x"
+	if err := ExtractCmd(ctx, eo, s, ref); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("expected unknown platform error, got %v", err)
+	}
+}
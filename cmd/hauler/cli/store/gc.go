@@ -0,0 +1,147 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/olekukonko/tablewriter/tw"
+
+	"hauler.dev/go/hauler/v2/internal/flags"
+	"hauler.dev/go/hauler/v2/pkg/audit"
+	"hauler.dev/go/hauler/v2/pkg/log"
+	"hauler.dev/go/hauler/v2/pkg/store"
+)
+
+// GCCmd collects the store's garbage: signature, attestation, sbom, and
+// referrer entries whose subject image is gone, blobs nothing references, and
+// temp files left by interrupted writes. --dry-run lists it all without
+// deleting anything.
+func GCCmd(ctx context.Context, o *flags.GCOpts, s *store.Layout, ro *flags.CliRootOpts) error {
+	l := log.FromContext(ctx)
+
+	res, err := s.GC(ctx, store.GCOptions{DryRun: o.DryRun, TempGrace: o.TempGrace})
+	if err != nil {
+		return err
+	}
+
+	if !o.DryRun {
+		auditGC(ctx, o, s, res, ro)
+	}
+
+	if o.OutputFormat == "json" {
+		data, err := json.MarshalIndent(struct {
+			StorePath string `json:"store-path"`
+			StoreID   string `json:"store-id"`
+			DryRun    bool   `json:"dry-run"`
+			Freed     int64  `json:"freed"`
+			*store.GCResult
+		}{s.Root, s.StoreID, o.DryRun, res.Freed(), res}, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	if len(res.Orphans)+len(res.Blobs)+len(res.Temps) == 0 {
+		l.Infof("nothing to collect in store [%s]", s.Root)
+		logHeldBlobs(ctx, res)
+		return nil
+	}
+	if err := buildGCTable(s.Root, s.StoreID, res); err != nil {
+		return err
+	}
+
+	verb := "deleted"
+	if o.DryRun {
+		verb = "would delete"
+	}
+	l.Infof("%s [%d] orphaned entries, [%d] blobs, and [%d] temp files [%s]", verb, len(res.Orphans), len(res.Blobs), len(res.Temps), byteCountSI(res.Freed()))
+	logHeldBlobs(ctx, res)
+	return nil
+}
+
+// logHeldBlobs tells the user why a collection freed less than they might
+// expect: blobs nothing in the index references, still kept for the
+// generations of the store's history that reference them.
+func logHeldBlobs(ctx context.Context, res *store.GCResult) {
+	if len(res.Held) == 0 {
+		return
+	}
+	log.FromContext(ctx).Infof("[%d] unreferenced blobs [%s] are still held by the store history for 'hauler store rollback'... run 'hauler store history --trim' to drop the history, then 'hauler store gc' to free them",
+		len(res.Held), byteCountSI(res.HeldSize()))
+}
+
+// auditGC records each orphan and blob the pass deleted. Temp files are
+// never content, so they go unrecorded.
+func auditGC(ctx context.Context, o *flags.GCOpts, s *store.Layout, res *store.GCResult, ro *flags.CliRootOpts) {
+	l := log.FromContext(ctx)
+	if auditLevel(ro) == "none" {
+		l.Debugf("generated audit id of [none]")
+		return
+	}
+
+	var entries []audit.Entry
+	for _, orphan := range res.Orphans {
+		entries = append(entries, audit.Entry{Type: orphan.Type, Reference: orphan.Reference, Digest: orphan.Digest})
+	}
+	for _, b := range res.Blobs {
+		entries = append(entries, audit.Entry{Type: "blob", Digest: b.Digest})
+	}
+
+	for _, e := range entries {
+		e.StoreID = s.StoreID
+		e.Store = s.Root
+		e.Command = "store gc"
+		if auditLevel(ro) == "verbose" {
+			sys := audit.BuildSystem()
+			g := audit.BuildGlobal(ro, o.StoreRootOpts)
+			e.System = &sys
+			e.Global = &g
+			e.Flags = map[string]any{
+				"temp-grace": o.TempGrace.String(),
+			}
+		}
+		if err := audit.Append(ro.HaulerDir, e); err != nil {
+			l.Warnf("failed to write audit entry: %v", err)
+			return
+		}
+	}
+	if len(entries) > 0 {
+		l.Debugf("generated audit id of [%s]", audit.ID())
+	}
+}
+
+func buildGCTable(storePath, storeID string, res *store.GCResult) error {
+	table := tablewriter.NewTable(os.Stdout)
+	table.Configure(func(cfg *tablewriter.Config) {
+		cfg.Header.Alignment.Global = tw.AlignLeft
+		cfg.Footer.Alignment.PerColumn = []tw.Align{tw.AlignLeft}
+		cfg.Row.Merging.Mode = tw.MergeVertical
+		cfg.Row.Merging.ByColumnIndex = tw.NewBoolMapper(0)
+	})
+	table.Header("Garbage", "Item", "Size")
+
+	for _, o := range res.Orphans {
+		item := fmt.Sprintf("%s [%s] %s", truncateReference(o.Reference), o.Type, o.Digest)
+		if err := table.Append([]string{"orphaned entry", item, "-"}); err != nil {
+			return err
+		}
+	}
+	for _, b := range res.Blobs {
+		if err := table.Append([]string{"blob", b.Digest, byteCountSI(b.Size)}); err != nil {
+			return err
+		}
+	}
+	for _, t := range res.Temps {
+		if err := table.Append([]string{"temp file", t.Path, byteCountSI(t.Size)}); err != nil {
+			return err
+		}
+	}
+
+	table.Footer("store-path: "+storePath+"\nstore-id: "+storeID, "Total", byteCountSI(res.Freed()))
+	return table.Render()
+}
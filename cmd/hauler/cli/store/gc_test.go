@@ -0,0 +1,53 @@
+package store
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/opencontainers/go-digest"
+
+	"hauler.dev/go/hauler/v2/internal/flags"
+	"hauler.dev/go/hauler/v2/pkg/consts"
+)
+
+func TestGCCmd(t *testing.T) {
+	ctx := newTestContext(t)
+	s := newTestStore(t)
+	ro := defaultCliOpts()
+	ro.HaulerDir = t.TempDir()
+	ro.AuditLevel = "standard"
+
+	stray := digest.FromString("stray")
+	strayPath := filepath.Join(s.Root, "blobs", "sha256", stray.Encoded())
+	if err := os.MkdirAll(filepath.Dir(strayPath), 0o755); err != nil {
+		t.Fatalf("MkdirAll: %v", err)
+	}
+	if err := os.WriteFile(strayPath, []byte("stray"), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	o := &flags.GCOpts{StoreRootOpts: defaultRootOpts(s.Root), DryRun: true, TempGrace: consts.DefaultGCTempGrace, OutputFormat: "table"}
+	if err := GCCmd(ctx, o, s, ro); err != nil {
+		t.Fatalf("GCCmd(dry run): %v", err)
+	}
+	if _, err := os.Stat(strayPath); err != nil {
+		t.Fatalf("dry run deleted the stray blob: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(ro.HaulerDir, "audit.log")); !os.IsNotExist(err) {
+		t.Errorf("dry run wrote an audit entry")
+	}
+
+	o.DryRun = false
+	if err := GCCmd(ctx, o, s, ro); err != nil {
+		t.Fatalf("GCCmd: %v", err)
+	}
+	if _, err := os.Stat(strayPath); !os.IsNotExist(err) {
+		t.Errorf("stray blob survived GC")
+	}
+	data, err := os.ReadFile(filepath.Join(ro.HaulerDir, "audit.log"))
+	if err != nil || !strings.Contains(string(data), `"store gc"`) || !strings.Contains(string(data), stray.String()) {
+		t.Errorf("audit.log = %q (%v), want a store gc entry for the stray blob", data, err)
+	}
+}
@@ -0,0 +1,240 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/olekukonko/tablewriter/tw"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+
+	"hauler.dev/go/hauler/v2/internal/flags"
+	"hauler.dev/go/hauler/v2/pkg/audit"
+	"hauler.dev/go/hauler/v2/pkg/consts"
+	"hauler.dev/go/hauler/v2/pkg/content"
+	"hauler.dev/go/hauler/v2/pkg/log"
+	"hauler.dev/go/hauler/v2/pkg/store"
+)
+
+// historyItem is one generation as `store history` reports it.
+type historyItem struct {
+	Generation int    `json:"generation"`
+	Timestamp  string `json:"timestamp"`
+	Command    string `json:"command"`
+	AuditID    string `json:"audit-id,omitempty"`
+	Artifacts  int    `json:"artifacts"`
+}
+
+// HistoryCmd lists the generations of index.json the store keeps: the index
+// as it stood before each command that changed it, newest first. --trim
+// first drops all but the newest --keep generations.
+func HistoryCmd(ctx context.Context, o *flags.HistoryOpts, s *store.Layout) error {
+	l := log.FromContext(ctx)
+
+	if o.Trim {
+		dropped, err := s.TrimHistory(o.Keep)
+		if err != nil {
+			return fmt.Errorf("failed to trim store history: %w", err)
+		}
+		l.Infof("dropped [%d] generation(s) from the history of store [%s]", dropped, s.Root)
+	}
+
+	entries, err := s.History()
+	if err != nil {
+		return err
+	}
+
+	items := make([]historyItem, 0, len(entries))
+	for i := len(entries) - 1; i >= 0; i-- {
+		e := entries[i]
+		items = append(items, historyItem{
+			Generation: e.Generation,
+			Timestamp:  e.Timestamp.Format(time.RFC3339),
+			Command:    e.Command,
+			AuditID:    e.AuditID,
+			Artifacts:  len(e.Manifests),
+		})
+	}
+
+	if o.OutputFormat == "json" {
+		data, err := json.MarshalIndent(struct {
+			StorePath   string        `json:"store-path"`
+			StoreID     string        `json:"store-id"`
+			Generations []historyItem `json:"generations"`
+		}{s.Root, s.StoreID, items}, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	if len(items) == 0 {
+		l.Infof("no history recorded for store [%s]", s.Root)
+		return nil
+	}
+	return buildHistoryTable(s.Root, s.StoreID, items)
+}
+
+func buildHistoryTable(storePath, storeID string, items []historyItem) error {
+	table := tablewriter.NewTable(os.Stdout)
+	table.Configure(func(cfg *tablewriter.Config) {
+		cfg.Header.Alignment.Global = tw.AlignLeft
+		cfg.Footer.Alignment.PerColumn = []tw.Align{tw.AlignLeft}
+	})
+	table.Header("Generation", "Recorded", "Before Command", "Audit ID", "Artifacts")
+
+	for _, i := range items {
+		auditID := i.AuditID
+		if auditID == "" {
+			auditID = "-"
+		}
+		if err := table.Append([]string{strconv.Itoa(i.Generation), i.Timestamp, i.Command, auditID, strconv.Itoa(i.Artifacts)}); err != nil {
+			return err
+		}
+	}
+
+	table.Footer("store-path: "+storePath+"\nstore-id: "+storeID, "", "", "", "")
+	return table.Render()
+}
+
+// rollbackChange is an artifact a rollback restores or drops.
+type rollbackChange struct {
+	verb string // restore | drop
+	desc ocispec.Descriptor
+}
+
+func (c rollbackChange) done() string {
+	if c.verb == "drop" {
+		return "dropped"
+	}
+	return "restored"
+}
+
+// RollbackCmd replaces the store's index with generation gen of its history.
+// Artifacts added since are dropped from the index (their blobs stay until
+// `hauler store gc`), and artifacts removed since are restored. The rollback
+// is recorded as a generation itself, so it can be rolled back in turn.
+func RollbackCmd(ctx context.Context, o *flags.RollbackOpts, s *store.Layout, gen int, ro *flags.CliRootOpts) error {
+	l := log.FromContext(ctx)
+
+	e, err := s.HistoryEntry(gen)
+	if err != nil {
+		return err
+	}
+	changes, err := rollbackChanges(s, e)
+	if err != nil {
+		return err
+	}
+
+	if o.DryRun {
+		for _, c := range changes {
+			l.Infof("would %s [%s] with digest [%s]", c.verb, c.desc.Annotations[ocispec.AnnotationRefName], c.desc.Digest)
+		}
+		l.Infof("dry run... rolling back to generation [%d] would change [%d] artifact(s)", gen, len(changes))
+		return nil
+	}
+
+	if len(changes) == 0 {
+		l.Infof("store already matches generation [%d]... nothing to roll back", gen)
+		return nil
+	}
+
+	if _, err := s.Rollback(ctx, gen); err != nil {
+		return err
+	}
+
+	for _, c := range changes {
+		auditRollback(ctx, o, s, gen, c, ro)
+		l.Infof("%s [%s] of type [%s] with digest [%s]", c.done(), c.desc.Annotations[ocispec.AnnotationRefName], s.ArtifactType(ctx, c.desc), c.desc.Digest)
+	}
+	l.Infof("rolled back store [%s] to generation [%d] from before [%s]", s.Root, gen, e.Command)
+	return nil
+}
+
+// rollbackChanges lists what rolling back to e would restore and drop,
+// compared by index key and descriptor.
+func rollbackChanges(s *store.Layout, e *store.HistoryEntry) ([]rollbackChange, error) {
+	target := map[string]ocispec.Descriptor{}
+	for _, desc := range e.Manifests {
+		key, err := content.IndexKey(desc)
+		if err != nil {
+			return nil, fmt.Errorf("generation [%d] holds an invalid entry: %w", e.Generation, err)
+		}
+		target[key] = desc
+	}
+	current := map[string]ocispec.Descriptor{}
+	if err := s.Walk(func(key string, desc ocispec.Descriptor) error {
+		current[key] = desc
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	var keys []string
+	for key := range target {
+		keys = append(keys, key)
+	}
+	for key := range current {
+		if _, ok := target[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	var changes []rollbackChange
+	for _, key := range keys {
+		want, inTarget := target[key]
+		have, inCurrent := current[key]
+		switch {
+		case !inCurrent:
+			changes = append(changes, rollbackChange{verb: "restore", desc: want})
+		case !inTarget:
+			changes = append(changes, rollbackChange{verb: "drop", desc: have})
+		case want.Digest != have.Digest || !maps.Equal(want.Annotations, have.Annotations):
+			changes = append(changes, rollbackChange{verb: "restore", desc: want})
+		}
+	}
+	return changes, nil
+}
+
+func auditRollback(ctx context.Context, o *flags.RollbackOpts, s *store.Layout, gen int, c rollbackChange, ro *flags.CliRootOpts) {
+	l := log.FromContext(ctx)
+	if auditLevel(ro) == "none" {
+		l.Debugf("generated audit id of [none]")
+		return
+	}
+
+	ref := c.desc.Annotations[consts.ContainerdImageNameKey]
+	if ref == "" {
+		ref = c.desc.Annotations[ocispec.AnnotationRefName]
+	}
+	ae := audit.Entry{
+		StoreID:   s.StoreID,
+		Store:     s.Root,
+		Type:      s.ArtifactType(ctx, c.desc),
+		Command:   "store rollback",
+		Args:      []string{strconv.Itoa(gen)},
+		Reference: ref,
+		Digest:    c.desc.Digest.String(),
+	}
+	if auditLevel(ro) == "verbose" {
+		sys := audit.BuildSystem()
+		g := audit.BuildGlobal(ro, o.StoreRootOpts)
+		ae.System = &sys
+		ae.Global = &g
+		ae.Flags = map[string]any{
+			"change": c.verb,
+		}
+	}
+	if err := audit.Append(ro.HaulerDir, ae); err != nil {
+		l.Warnf("failed to write audit entry: %v", err)
+	}
+	l.Debugf("generated audit id of [%s]", audit.ID())
+}
@@ -0,0 +1,79 @@
+package store
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"hauler.dev/go/hauler/v2/internal/flags"
+	v1 "hauler.dev/go/hauler/v2/pkg/apis/hauler.cattle.io/v1"
+	"hauler.dev/go/hauler/v2/pkg/store"
+)
+
+func TestRollbackCmd(t *testing.T) {
+	ctx := newTestContext(t)
+	host, _ := newLocalhostRegistry(t)
+	seedImage(t, host, "rollback/app", "v1")
+
+	s := newTestStore(t)
+	rso := defaultRootOpts(s.Root)
+	ro := defaultCliOpts()
+
+	lock := func(command string) {
+		t.Helper()
+		if err := s.Lock(ctx, store.LockExclusive, 0, command); err != nil {
+			t.Fatalf("Lock: %v", err)
+		}
+	}
+
+	lock("hauler store add image")
+	if err := storeImage(ctx, s, v1.Image{Name: host + "/rollback/app:v1"}, "", false, rso, ro, "", "", false); err != nil {
+		t.Fatalf("storeImage: %v", err)
+	}
+	s.Unlock()
+
+	// the removed image's blobs are kept for the history, and remove says so
+	var buf bytes.Buffer
+	lock("hauler store remove")
+	if err := RemoveCmd(newLogCaptureContext(&buf), &flags.RemoveOpts{Force: true}, s, "rollback/app", ro, rso); err != nil {
+		t.Fatalf("RemoveCmd: %v", err)
+	}
+	s.Unlock()
+	if !strings.Contains(buf.String(), "held by the store history") {
+		t.Errorf("remove did not report the blobs the history holds; log:\n%s", buf.String())
+	}
+	if kinds := storedKinds(t, s); len(kinds) != 0 {
+		t.Fatalf("after remove the index holds %v, want nothing", kinds)
+	}
+
+	if err := HistoryCmd(ctx, &flags.HistoryOpts{StoreRootOpts: rso, OutputFormat: "json"}, s); err != nil {
+		t.Fatalf("HistoryCmd: %v", err)
+	}
+
+	o := &flags.RollbackOpts{StoreRootOpts: rso}
+	if err := RollbackCmd(ctx, &flags.RollbackOpts{StoreRootOpts: rso, DryRun: true}, s, 2, ro); err != nil {
+		t.Fatalf("RollbackCmd(dry-run): %v", err)
+	}
+	if kinds := storedKinds(t, s); len(kinds) != 0 {
+		t.Fatalf("dry run changed the index to %v", kinds)
+	}
+
+	lock("hauler store rollback")
+	if err := RollbackCmd(ctx, o, s, 2, ro); err != nil {
+		t.Fatalf("RollbackCmd: %v", err)
+	}
+	s.Unlock()
+	if kinds := storedKinds(t, s); len(kinds["rollback/app:v1"]) != 1 {
+		t.Errorf("after rollback the index holds %v, want rollback/app:v1 restored", kinds)
+	}
+
+	// undoing the rollback drops the image again
+	lock("hauler store rollback")
+	if err := RollbackCmd(ctx, o, s, 3, ro); err != nil {
+		t.Fatalf("RollbackCmd(undo): %v", err)
+	}
+	s.Unlock()
+	if kinds := storedKinds(t, s); len(kinds) != 0 {
+		t.Errorf("after undoing the rollback the index holds %v, want nothing", kinds)
+	}
+}
@@ -10,6 +10,7 @@ import (
 
 	"github.com/olekukonko/tablewriter"
 	"github.com/olekukonko/tablewriter/tw"
+	"github.com/opencontainers/go-digest"
 	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
 
 	"hauler.dev/go/hauler/v2/internal/flags"
@@ -20,19 +21,33 @@ import (
 )
 
 type infoOutput struct {
-	StorePath string `json:"store-path"`
-	StoreID   string `json:"store-id"`
-	Artifacts []item `json:"artifacts"`
+	StorePath string      `json:"store-path"`
+	StoreID   string      `json:"store-id"`
+	Sizes     *storeSizes `json:"sizes,omitempty"`
+	Artifacts []item      `json:"artifacts"`
+}
+
+// storeSizes is the store-wide half of --sizes: what the store holds with
+// shared blobs counted once, and about what a haul of it would weigh.
+type storeSizes struct {
+	Footprint     int64 `json:"footprint"`
+	EstimatedHaul int64 `json:"estimated-haul"`
 }
 
 func InfoCmd(ctx context.Context, o *flags.InfoOpts, s *store.Layout) error {
+	// nil unless --select narrows the listing
+	selected, err := selectedKeys(ctx, s, o.Select, false)
+	if err != nil {
+		return err
+	}
+
 	var checker *store.Checker
 	if o.Check {
 		checker = s.NewChecker()
 
 		total := 0
-		_ = s.OCI.Walk(func(_ string, desc ocispec.Descriptor) error {
-			if _, ok := desc.Annotations[ocispec.AnnotationRefName]; ok {
+		_ = s.OCI.Walk(func(key string, desc ocispec.Descriptor) error {
+			if _, ok := desc.Annotations[ocispec.AnnotationRefName]; ok && (selected == nil || selected[key]) {
 				total++
 			}
 			return nil
@@ -43,10 +58,13 @@ func InfoCmd(ctx context.Context, o *flags.InfoOpts, s *store.Layout) error {
 	var items []item
 	var totalChecked, totalCorrupt int
 
-	if err := s.Walk(func(_ string, desc ocispec.Descriptor) error {
+	if err := s.Walk(func(key string, desc ocispec.Descriptor) error {
 		if _, ok := desc.Annotations[ocispec.AnnotationRefName]; !ok {
 			return nil
 		}
+		if selected != nil && !selected[key] {
+			return nil
+		}
 		rc, err := s.Fetch(ctx, desc)
 		if err != nil {
 			return err
@@ -265,6 +283,22 @@ func InfoCmd(ctx context.Context, o *flags.InfoOpts, s *store.Layout) error {
 		return nil
 	}
 
+	// sizes are accounted over the whole store, so a filtered listing still
+	// shows what each artifact shares with the ones left out
+	var sizes *storeSizes
+	if o.Sizes {
+		report, err := s.Sizes(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to account store sizes: %w", err)
+		}
+		for n := range items {
+			if size, ok := report.Artifacts[digest.Digest(items[n].Digest)]; ok {
+				items[n].Sizes = &size
+			}
+		}
+		sizes = &storeSizes{Footprint: report.Footprint, EstimatedHaul: report.EstimatedHaul}
+	}
+
 	// sort items by ref and arch
 	sort.Sort(byReferenceAndArch(items))
 
@@ -277,6 +311,7 @@ func InfoCmd(ctx context.Context, o *flags.InfoOpts, s *store.Layout) error {
 		out := infoOutput{
 			StorePath: s.Root,
 			StoreID:   s.StoreID,
+			Sizes:     sizes,
 			Artifacts: items,
 		}
 		data, err := json.MarshalIndent(out, "", "  ")
@@ -292,7 +327,7 @@ func InfoCmd(ctx context.Context, o *flags.InfoOpts, s *store.Layout) error {
 				}
 			}
 		} else {
-			if err := buildTable(s.Root, s.StoreID, o.ShowDigests, items...); err != nil {
+			if err := buildTable(s.Root, s.StoreID, o.ShowDigests, sizes, items...); err != nil {
 				return err
 			}
 		}
@@ -335,8 +370,11 @@ func buildListRepos(items ...item) {
 }
 
 // buildTable renders the standard (non-check) inventory table: one row per item,
-// with the shape unchanged from before the --check redesign.
-func buildTable(storePath, storeID string, showDigests bool, items ...item) error {
+// with the shape unchanged from before the --check redesign. With sizes, the
+// Size column splits into each artifact's full, unique, and shared bytes, and
+// the footer reports the deduplicated footprint and estimated haul size in
+// place of the plain total, which counts shared layers once per artifact.
+func buildTable(storePath, storeID string, showDigests bool, sizes *storeSizes, items ...item) error {
 	table := tablewriter.NewTable(os.Stdout)
 	table.Configure(func(cfg *tablewriter.Config) {
 		cfg.Header.Alignment.Global = tw.AlignLeft
@@ -345,11 +383,29 @@ func buildTable(storePath, storeID string, showDigests bool, items ...item) erro
 		cfg.Row.Merging.ByColumnIndex = tw.NewBoolMapper(0)
 	})
 
+	// the Labels column only shows when something in the table is labeled
+	showLabels := false
+	for _, i := range items {
+		if len(i.Labels) > 0 {
+			showLabels = true
+			break
+		}
+	}
+
+	header := []string{"Reference", "Type", "Platform"}
 	if showDigests {
-		table.Header("Reference", "Type", "Platform", "Digest", "# Layers", "Size")
+		header = append(header, "Digest")
+	}
+	if showLabels {
+		header = append(header, "Labels")
+	}
+	header = append(header, "# Layers")
+	if sizes != nil {
+		header = append(header, "Size", "Unique", "Shared")
 	} else {
-		table.Header("Reference", "Type", "Platform", "# Layers", "Size")
+		header = append(header, "Size")
 	}
+	table.Header(header)
 
 	totalSize := int64(0)
 
@@ -358,30 +414,25 @@ func buildTable(storePath, storeID string, showDigests bool, items ...item) erro
 			continue
 		}
 
-		ref := truncateReference(i.Reference)
-		var row []string
-
+		row := []string{truncateReference(i.Reference), i.Type, i.Platform}
 		if showDigests {
 			digest := i.Digest
 			if digest == "" {
 				digest = "-"
 			}
-			row = []string{
-				ref,
-				i.Type,
-				i.Platform,
-				digest,
-				fmt.Sprintf("%d", i.Layers),
-				byteCountSI(i.Size),
-			}
-		} else {
-			row = []string{
-				ref,
-				i.Type,
-				i.Platform,
-				fmt.Sprintf("%d", i.Layers),
-				byteCountSI(i.Size),
-			}
+			row = append(row, digest)
+		}
+		if showLabels {
+			row = append(row, strings.Join(store.FormatLabels(i.Labels), "\n"))
+		}
+		row = append(row, fmt.Sprintf("%d", i.Layers))
+		switch {
+		case sizes == nil:
+			row = append(row, byteCountSI(i.Size))
+		case i.Sizes == nil:
+			row = append(row, "-", "-", "-")
+		default:
+			row = append(row, byteCountSI(i.Sizes.Full), byteCountSI(i.Sizes.Unique), byteCountSI(i.Sizes.Shared))
 		}
 
 		totalSize += i.Size
@@ -390,12 +441,18 @@ func buildTable(storePath, storeID string, showDigests bool, items ...item) erro
 		}
 	}
 
-	footerLabel := "store-path: " + storePath + "\nstore-id: " + storeID
-	if showDigests {
-		table.Footer(footerLabel, "", "", "", "Total", byteCountSI(totalSize))
+	footer := make([]string, len(header))
+	footer[0] = "store-path: " + storePath + "\nstore-id: " + storeID
+	if sizes != nil {
+		footer[len(footer)-4] = "Footprint"
+		footer[len(footer)-3] = byteCountSI(sizes.Footprint)
+		footer[len(footer)-2] = "Est. Haul"
+		footer[len(footer)-1] = byteCountSI(sizes.EstimatedHaul)
 	} else {
-		table.Footer(footerLabel, "", "", "Total", byteCountSI(totalSize))
+		footer[len(footer)-2] = "Total"
+		footer[len(footer)-1] = byteCountSI(totalSize)
 	}
+	table.Footer(footer)
 
 	return table.Render()
 }
@@ -513,13 +570,15 @@ func truncateReference(ref string) string {
 }
 
 type item struct {
-	Reference string   `json:"reference"`
-	Type      string   `json:"type"`
-	Platform  string   `json:"platform"`
-	Digest    string   `json:"digest,omitempty"`
-	Layers    int      `json:"layers"`
-	Size      int64    `json:"size"`
-	Problems  []string `json:"problems,omitempty"` // populated only for corrupt items
+	Reference string              `json:"reference"`
+	Type      string              `json:"type"`
+	Platform  string              `json:"platform"`
+	Digest    string              `json:"digest,omitempty"`
+	Layers    int                 `json:"layers"`
+	Size      int64               `json:"size"`
+	Labels    map[string]string   `json:"labels,omitempty"`
+	Sizes     *store.ArtifactSize `json:"sizes,omitempty"`    // populated only with --sizes
+	Problems  []string            `json:"problems,omitempty"` // populated only for corrupt items
 
 	// blobProblems holds the same information as Problems but as structured
 	// store.BlobResult values, used by buildFailureTable to render one row per
@@ -606,7 +665,18 @@ func newItem(s *store.Layout, desc ocispec.Descriptor, m ocispec.Manifest, plat
 		Digest:    desc.Digest.String(),
 		Layers:    len(m.Layers),
 		Size:      size,
+		Labels:    itemLabels(desc),
+	}
+}
+
+// itemLabels returns desc's user labels, or nil when it has none so they're
+// left out of --output json.
+func itemLabels(desc ocispec.Descriptor) map[string]string {
+	labels := store.Labels(desc)
+	if len(labels) == 0 {
+		return nil
 	}
+	return labels
 }
 
 // resolveCtype computes the human-readable content type ("image", "chart", "file",
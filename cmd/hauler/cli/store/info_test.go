@@ -339,6 +339,66 @@ func TestInfoCmd(t *testing.T) {
 	})
 }
 
+func TestInfoCmd_Sizes(t *testing.T) {
+	ctx := newTestContext(t)
+	s := newTestStore(t)
+
+	// the same content under two names is one blob shared by two artifacts
+	dir := t.TempDir()
+	content := []byte(strings.Repeat("hauler ", 1000))
+	for _, name := range []string{"a.txt", "b.txt"} {
+		if err := os.WriteFile(dir+"/"+name, content, 0o644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+		if err := storeFile(ctx, s, v1.File{Path: dir + "/" + name}, defaultCliOpts(), defaultRootOpts(s.Root)); err != nil {
+			t.Fatalf("storeFile(%s): %v", name, err)
+		}
+	}
+
+	o := &flags.InfoOpts{StoreRootOpts: defaultRootOpts(s.Root), OutputFormat: "json", TypeFilter: "all", Sizes: true}
+	out, err := captureStdout(t, func() error { return InfoCmd(ctx, o, s) })
+	if err != nil {
+		t.Fatalf("InfoCmd(--sizes): %v", err)
+	}
+	var got infoOutput
+	if err := json.Unmarshal([]byte(out), &got); err != nil {
+		t.Fatalf("unmarshal output: %v\n%s", err, out)
+	}
+
+	if got.Sizes == nil {
+		t.Fatalf("output has no store sizes:\n%s", out)
+	}
+	var naive int64
+	for _, i := range got.Artifacts {
+		if i.Sizes == nil {
+			t.Fatalf("%s has no sizes", i.Reference)
+		}
+		if i.Sizes.Shared < int64(len(content)) {
+			t.Errorf("%s shares %d bytes, want at least the %d-byte file", i.Reference, i.Sizes.Shared, len(content))
+		}
+		naive += i.Sizes.Full
+	}
+	if len(got.Artifacts) != 2 || got.Sizes.Footprint > naive-int64(len(content)) {
+		t.Errorf("footprint %d over %d artifacts doesn't count the shared file once (naive total %d)", got.Sizes.Footprint, len(got.Artifacts), naive)
+	}
+	// repetitive text compresses well below its stored size
+	if got.Sizes.EstimatedHaul <= 0 || got.Sizes.EstimatedHaul >= got.Sizes.Footprint {
+		t.Errorf("estimated haul %d, want a positive estimate under the %d-byte footprint", got.Sizes.EstimatedHaul, got.Sizes.Footprint)
+	}
+
+	// the table renders with the sizes columns and footer
+	o.OutputFormat = "table"
+	out, err = captureStdout(t, func() error { return InfoCmd(ctx, o, s) })
+	if err != nil {
+		t.Fatalf("InfoCmd(--sizes, table): %v", err)
+	}
+	for _, want := range []string{"UNIQUE", "SHARED", "FOOTPRINT", "EST. HAUL"} {
+		if !strings.Contains(strings.ToUpper(out), want) {
+			t.Errorf("table is missing %q:\n%s", want, out)
+		}
+	}
+}
+
 // captureStdout redirects os.Stdout for the duration of fn and returns everything
 // written to it, along with fn's return value.
 func captureStdout(t *testing.T, fn func() error) (string, error) {
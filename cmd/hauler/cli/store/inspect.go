@@ -0,0 +1,105 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/olekukonko/tablewriter/tw"
+	"gopkg.in/yaml.v3"
+
+	"hauler.dev/go/hauler/v2/internal/flags"
+	"hauler.dev/go/hauler/v2/pkg/archives"
+	"hauler.dev/go/hauler/v2/pkg/consts"
+	"hauler.dev/go/hauler/v2/pkg/log"
+)
+
+// HaulInspectCmd lists what the haul at haulPath (local, remote, or any chunk
+// of a chunked haul) holds, reading only its contents header as it streams
+// in. A haul saved before the header existed is scanned for its index.json.
+func HaulInspectCmd(ctx context.Context, o *flags.HaulInspectOpts, haulPath string) error {
+	l := log.FromContext(ctx)
+
+	switch o.OutputFormat {
+	case "table", "json", "yaml":
+	default:
+		return fmt.Errorf("invalid --output %q: must be one of table, json, yaml", o.OutputFormat)
+	}
+
+	haulPath = resolveHaulPath(haulPath)
+	rc, name, err := openHaulStream(ctx, haulPath)
+	if err != nil {
+		return fmt.Errorf("failed to open haul [%s]: %w", haulPath, err)
+	}
+	defer rc.Close()
+
+	c, err := archives.ReadContents(ctx, name, rc, os.Getenv(consts.HaulerTempDir))
+	if err != nil {
+		return fmt.Errorf("failed to inspect haul [%s]: %w", haulPath, err)
+	}
+	if c.Legacy {
+		l.Warnf("haul [%s] has no contents header... listing its index instead, without platforms or full sizes", haulPath)
+	}
+
+	switch o.OutputFormat {
+	case "json":
+		data, err := json.MarshalIndent(c, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	case "yaml":
+		data, err := yaml.Marshal(c)
+		if err != nil {
+			return err
+		}
+		fmt.Print(string(data))
+		return nil
+	}
+	return buildContentsTable(haulPath, c)
+}
+
+func buildContentsTable(haulPath string, c *archives.HaulContents) error {
+	table := tablewriter.NewTable(os.Stdout)
+	table.Configure(func(cfg *tablewriter.Config) {
+		cfg.Header.Alignment.Global = tw.AlignLeft
+		cfg.Footer.Alignment.PerColumn = []tw.Align{tw.AlignLeft}
+		cfg.Row.Merging.Mode = tw.MergeVertical
+		cfg.Row.Merging.ByColumnIndex = tw.NewBoolMapper(0)
+	})
+	table.Header([]string{"Reference", "Type", "Platforms", "Digest", "Size"})
+
+	var total int64
+	for _, a := range c.Artifacts {
+		platforms := strings.Join(a.Platforms, "\n")
+		if platforms == "" {
+			platforms = "-"
+		}
+		row := []string{truncateReference(a.Reference), a.Type, platforms, truncateDigest(a.Digest.String()), byteCountSI(a.Size)}
+		if err := table.Append(row); err != nil {
+			return err
+		}
+		total += a.Size
+	}
+
+	footer := []string{"haul: " + haulPath, "", "", "Total", byteCountSI(total)}
+	if c.StoreID != "" {
+		footer[0] += "\nstore-id: " + c.StoreID
+	}
+	if c.HaulerVersion != "" {
+		footer[0] += "\nhauler-version: " + c.HaulerVersion
+	}
+	if c.Created != "" {
+		footer[0] += "\ncreated: " + c.Created
+	}
+	if c.Delta {
+		footer[0] += "\ndelta: true"
+	}
+	table.Footer(footer)
+
+	return table.Render()
+}
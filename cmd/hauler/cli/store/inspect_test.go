@@ -0,0 +1,89 @@
+package store
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"hauler.dev/go/hauler/v2/internal/flags"
+	v1 "hauler.dev/go/hauler/v2/pkg/apis/hauler.cattle.io/v1"
+	"hauler.dev/go/hauler/v2/pkg/archives"
+	"hauler.dev/go/hauler/v2/pkg/consts"
+)
+
+func TestHaulInspectCmd(t *testing.T) {
+	ctx := newTestContext(t)
+	host, _ := newLocalhostRegistry(t)
+	seedImage(t, host, "test/inspect", "v1")
+
+	s := newTestStore(t)
+	if _, err := s.AddImage(ctx, host+"/test/inspect:v1", "", false, "", false, ""); err != nil {
+		t.Fatalf("AddImage: %v", err)
+	}
+	file := filepath.Join(t.TempDir(), "notes.txt")
+	if err := os.WriteFile(file, []byte("hello"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := storeFile(ctx, s, v1.File{Path: file}, defaultCliOpts(), defaultRootOpts(s.Root)); err != nil {
+		t.Fatalf("storeFile: %v", err)
+	}
+
+	inspect := func(t *testing.T, haul string) *archives.HaulContents {
+		t.Helper()
+		out, err := captureStdout(t, func() error {
+			return HaulInspectCmd(ctx, &flags.HaulInspectOpts{OutputFormat: "json"}, haul)
+		})
+		if err != nil {
+			t.Fatalf("HaulInspectCmd: %v", err)
+		}
+		var c archives.HaulContents
+		if err := json.Unmarshal([]byte(out), &c); err != nil {
+			t.Fatalf("parse output: %v\n%s", err, out)
+		}
+		return &c
+	}
+
+	for _, tc := range []struct {
+		name      string
+		chunkSize string
+		arg       string
+	}{
+		{name: "archive", arg: "haul.tar.zst"},
+		{name: "chunk", chunkSize: "1K", arg: "haul.tar.zst.002"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			o := newSaveOpts(s.Root, filepath.Join(dir, "haul.tar.zst"))
+			o.ChunkSize = tc.chunkSize
+			if err := SaveCmd(ctx, o, s, defaultRootOpts(s.Root), defaultCliOpts()); err != nil {
+				t.Fatalf("SaveCmd: %v", err)
+			}
+			if _, err := os.Stat(filepath.Join(s.Root, consts.DefaultHaulContentsName)); !os.IsNotExist(err) {
+				t.Error("save left its contents header in the store")
+			}
+
+			c := inspect(t, filepath.Join(dir, tc.arg))
+			if c.Legacy || c.StoreID != s.StoreID || c.Created == "" {
+				t.Errorf("contents = %+v, want the header save wrote", c)
+			}
+			types := map[string]archives.HaulArtifact{}
+			for _, a := range c.Artifacts {
+				types[a.Type] = a
+			}
+			img, ok := types["image"]
+			if !ok || img.Size <= 0 {
+				t.Errorf("image entry = %+v, want its full size", img)
+			}
+			if _, ok := types["file"]; !ok {
+				t.Errorf("contents have no file entry: %+v", c.Artifacts)
+			}
+		})
+	}
+
+	t.Run("invalid output", func(t *testing.T) {
+		if err := HaulInspectCmd(ctx, &flags.HaulInspectOpts{OutputFormat: "xml"}, "haul.tar.zst"); err == nil {
+			t.Error("HaulInspectCmd with -o xml succeeded")
+		}
+	})
+}
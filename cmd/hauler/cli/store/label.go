@@ -0,0 +1,189 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+
+	"hauler.dev/go/hauler/v2/internal/flags"
+	"hauler.dev/go/hauler/v2/pkg/audit"
+	"hauler.dev/go/hauler/v2/pkg/consts"
+	"hauler.dev/go/hauler/v2/pkg/content"
+	"hauler.dev/go/hauler/v2/pkg/log"
+	"hauler.dev/go/hauler/v2/pkg/store"
+)
+
+// LabelCmd sets and unsets labels on artifacts already in the store, named by
+// a reference or by --select. Each edit is "key=value" to set a label or
+// "key-" to unset one. Labels live on the artifact itself; its signatures,
+// attestations, sboms, and referrers are matched by a selector through it.
+func LabelCmd(ctx context.Context, o *flags.LabelOpts, s *store.Layout, args []string, ro *flags.CliRootOpts) error {
+	l := log.FromContext(ctx)
+
+	edits := args
+	if o.Select == "" {
+		if len(args) == 0 {
+			return fmt.Errorf("an artifact reference or --select is required")
+		}
+		edits = args[1:]
+	}
+	set, remove, err := parseLabelEdits(edits)
+	if err != nil {
+		return err
+	}
+
+	targets, err := labelTargets(ctx, o, s, args)
+	if err != nil {
+		return err
+	}
+
+	if o.DryRun {
+		logSelection(ctx, "label", "labeled", targets)
+		return nil
+	}
+
+	for _, t := range targets {
+		if _, err := s.SetLabels(t.Desc.Annotations[ocispec.AnnotationRefName], set, remove); err != nil {
+			return err
+		}
+	}
+	if err := s.OCI.SaveIndex(); err != nil {
+		return fmt.Errorf("failed to save index: %w", err)
+	}
+
+	for _, t := range targets {
+		auditLabel(ctx, o, s, t, edits, ro)
+		l.Infof("labeled [%s] of type [%s] with digest [%s]", formatReference(t.Key), t.Type, t.Desc.Digest)
+	}
+	if len(targets) == 0 {
+		l.Infof("no artifacts matched [%s]... nothing to label", o.Select)
+	}
+	return nil
+}
+
+// parseLabelEdits splits "key=value" edits from "key-" removals.
+func parseLabelEdits(edits []string) (map[string]string, []string, error) {
+	if len(edits) == 0 {
+		return nil, nil, fmt.Errorf("at least one label edit is required... key=value to set a label, key- to unset one")
+	}
+	var pairs, remove []string
+	for _, e := range edits {
+		if key, ok := strings.CutSuffix(e, "-"); ok && !strings.Contains(e, "=") {
+			if err := store.ValidateLabelKey(key); err != nil {
+				return nil, nil, err
+			}
+			remove = append(remove, key)
+			continue
+		}
+		pairs = append(pairs, e)
+	}
+	set, err := store.ParseLabels(pairs)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, k := range remove {
+		if _, ok := set[k]; ok {
+			return nil, nil, fmt.Errorf("label [%s] is both set and unset", k)
+		}
+	}
+	return set, remove, nil
+}
+
+// labelTargets returns the artifacts a label command edits, leaving out
+// signatures, attestations, sboms, and referrers.
+func labelTargets(ctx context.Context, o *flags.LabelOpts, s *store.Layout, args []string) ([]store.Selected, error) {
+	var candidates []store.Selected
+	if o.Select != "" {
+		selected, err := selectArtifacts(ctx, s, o.Select, false)
+		if err != nil {
+			return nil, err
+		}
+		candidates = selected
+	} else {
+		indexed := map[string]ocispec.Descriptor{}
+		if err := s.Walk(func(key string, desc ocispec.Descriptor) error {
+			indexed[key] = desc
+			return nil
+		}); err != nil {
+			return nil, err
+		}
+		group, err := matchTagSource(indexed, args[0])
+		if err != nil {
+			return nil, err
+		}
+		for _, desc := range group {
+			key, err := content.IndexKey(desc)
+			if err != nil {
+				return nil, err
+			}
+			candidates = append(candidates, store.Selected{Key: key, Desc: desc, Type: s.ArtifactType(ctx, desc)})
+		}
+	}
+
+	var targets []store.Selected
+	for _, c := range candidates {
+		if isRelatedType(c.Type) {
+			continue
+		}
+		targets = append(targets, c)
+	}
+	return targets, nil
+}
+
+// validateLabels checks the keys of the labels a manifest sets on content,
+// before any of that content is fetched.
+func validateLabels(labels map[string]string) error {
+	for k := range labels {
+		if err := store.ValidateLabelKey(k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// labelImage sets labels on the image just stored as r.
+func labelImage(s *store.Layout, r name.Reference, labels map[string]string) error {
+	if len(labels) == 0 {
+		return nil
+	}
+	_, err := s.SetLabels(strings.TrimPrefix(r.Name(), r.Context().RegistryStr()+"/"), labels, nil)
+	return err
+}
+
+func auditLabel(ctx context.Context, o *flags.LabelOpts, s *store.Layout, t store.Selected, edits []string, ro *flags.CliRootOpts) {
+	l := log.FromContext(ctx)
+	if auditLevel(ro) == "none" {
+		l.Debugf("generated audit id of [none]")
+		return
+	}
+
+	ref := t.Desc.Annotations[consts.ContainerdImageNameKey]
+	if ref == "" {
+		ref = t.Desc.Annotations[ocispec.AnnotationRefName]
+	}
+	ae := audit.Entry{
+		StoreID:   s.StoreID,
+		Store:     s.Root,
+		Type:      t.Type,
+		Command:   "store label",
+		Args:      append([]string{ref}, edits...),
+		Reference: ref,
+		Digest:    t.Desc.Digest.String(),
+	}
+	if auditLevel(ro) == "verbose" {
+		sys := audit.BuildSystem()
+		g := audit.BuildGlobal(ro, o.StoreRootOpts)
+		ae.System = &sys
+		ae.Global = &g
+		ae.Flags = map[string]any{
+			"select": o.Select,
+		}
+	}
+	if err := audit.Append(ro.HaulerDir, ae); err != nil {
+		l.Warnf("failed to write audit entry: %v", err)
+	}
+	l.Debugf("generated audit id of [%s]", audit.ID())
+}
@@ -0,0 +1,178 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+
+	"hauler.dev/go/hauler/v2/internal/flags"
+	v1 "hauler.dev/go/hauler/v2/pkg/apis/hauler.cattle.io/v1"
+	"hauler.dev/go/hauler/v2/pkg/store"
+)
+
+// storedLabels maps each reference in s's index to the labels of the
+// artifact stored under it, leaving out signatures and the like.
+func storedLabels(t *testing.T, s *store.Layout) map[string]string {
+	t.Helper()
+	labels := map[string]string{}
+	if err := s.Walk(func(_ string, desc ocispec.Descriptor) error {
+		if isRelatedType(s.ArtifactType(context.Background(), desc)) {
+			if l := store.Labels(desc); len(l) > 0 {
+				t.Errorf("related artifact of [%s] labeled %v", desc.Annotations[ocispec.AnnotationRefName], l)
+			}
+			return nil
+		}
+		labels[desc.Annotations[ocispec.AnnotationRefName]] = fmt.Sprint(store.FormatLabels(store.Labels(desc)))
+		return nil
+	}); err != nil {
+		t.Fatalf("Walk: %v", err)
+	}
+	return labels
+}
+
+func TestLabelCmd(t *testing.T) {
+	ctx := newTestContext(t)
+	host, _ := newLocalhostRegistry(t)
+	seedSignedImage(t, host, "label/app", "v1")
+	seedImage(t, host, "label/other", "v1")
+
+	s := newTestStore(t)
+	rso := defaultRootOpts(s.Root)
+	ro := defaultCliOpts()
+
+	// labels set at add time follow the image through a rewrite
+	app := v1.Image{Name: host + "/label/app:v1", Labels: map[string]string{"team": "platform"}}
+	if err := storeImage(ctx, s, app, "", false, rso, ro, "label/renamed:v1", "", false); err != nil {
+		t.Fatalf("storeImage: %v", err)
+	}
+	if err := storeImage(ctx, s, v1.Image{Name: host + "/label/other:v1"}, "", false, rso, ro, "", "", false); err != nil {
+		t.Fatalf("storeImage: %v", err)
+	}
+	if got := storedLabels(t, s); got["label/renamed:v1"] != "[team=platform]" || got["label/other:v1"] != "[]" {
+		t.Fatalf("after add labels are %v", got)
+	}
+
+	o := &flags.LabelOpts{StoreRootOpts: rso}
+	if err := LabelCmd(ctx, o, s, []string{host + "/label/renamed:v1", "ticket=OPS-1", "team-"}, ro); err != nil {
+		t.Fatalf("LabelCmd: %v", err)
+	}
+	if got := storedLabels(t, s); got["label/renamed:v1"] != "[ticket=OPS-1]" {
+		t.Errorf("after label the image has %v, want [ticket=OPS-1]", got["label/renamed:v1"])
+	}
+
+	// --select labels everything it matches; --dry-run changes nothing
+	if err := LabelCmd(ctx, &flags.LabelOpts{StoreRootOpts: rso, Select: "type=image", DryRun: true}, s, []string{"env=prod"}, ro); err != nil {
+		t.Fatalf("LabelCmd(dry-run): %v", err)
+	}
+	if got := storedLabels(t, s); got["label/other:v1"] != "[]" {
+		t.Errorf("dry run labeled label/other:v1 with %v", got["label/other:v1"])
+	}
+	if err := LabelCmd(ctx, &flags.LabelOpts{StoreRootOpts: rso, Select: "type=image"}, s, []string{"env=prod"}, ro); err != nil {
+		t.Fatalf("LabelCmd(select): %v", err)
+	}
+	got := storedLabels(t, s)
+	if got["label/renamed:v1"] != "[env=prod ticket=OPS-1]" || got["label/other:v1"] != "[env=prod]" {
+		t.Errorf("after select labels are %v", got)
+	}
+
+	selected, err := selectArtifacts(ctx, s, "label.ticket=OPS-*", true)
+	if err != nil {
+		t.Fatalf("selectArtifacts: %v", err)
+	}
+	if len(selected) < 2 {
+		t.Errorf("label.ticket selected %d artifacts, want the image and its signature", len(selected))
+	}
+	for _, sa := range selected {
+		if ref := sa.Desc.Annotations[ocispec.AnnotationRefName]; ref != "label/renamed:v1" {
+			t.Errorf("label.ticket selected [%s], want only label/renamed:v1 and what is saved with it", ref)
+		}
+	}
+
+	for _, args := range [][]string{
+		{host + "/label/renamed:v1"},
+		{host + "/label/renamed:v1", "team"},
+		{host + "/label/renamed:v1", "bad key=x"},
+		{host + "/label/renamed:v1", "team=a", "team-"},
+		{"label/missing:v1", "team=a"},
+	} {
+		if err := LabelCmd(ctx, o, s, args, ro); err == nil {
+			t.Errorf("LabelCmd(%q) = nil error, want one", args)
+		}
+	}
+}
+
+func TestCreateManifestCmd_Labels(t *testing.T) {
+	ctx := newTestContext(t)
+	s := newTestStore(t)
+	rso := defaultRootOpts(s.Root)
+	ro := defaultCliOpts()
+
+	tmp, err := os.CreateTemp(t.TempDir(), "testfile-*.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmp.WriteString("hello hauler") //nolint:errcheck
+	tmp.Close()
+
+	if err := storeFile(ctx, s, v1.File{Path: tmp.Name(), Labels: map[string]string{"team": "platform"}}, ro, rso); err != nil {
+		t.Fatalf("storeFile: %v", err)
+	}
+
+	o := newCreateManifestOpts(t, rso)
+	if err := CreateManifestCmd(ctx, o, s); err != nil {
+		t.Fatalf("CreateManifestCmd: %v", err)
+	}
+
+	content := readManifest(t, o.Output)
+	if !strings.Contains(content, "labels:") || !strings.Contains(content, "team: platform") {
+		t.Errorf("expected the file's labels in the manifest, got:\n%s", content)
+	}
+}
+
+// A manifest's bad label key fails the sync before any registry is contacted:
+// nothing listens on the manifest's registry, so only an early check names
+// the key.
+func TestSyncCmd_InvalidLabelKey(t *testing.T) {
+	for _, doc := range []string{
+		`kind: Images
+metadata:
+  name: bad-labels
+spec:
+  images:
+    - name: 127.0.0.1:1/label/app:v1
+      labels:
+        "-team": infra
+`,
+		`kind: Files
+metadata:
+  name: bad-labels
+spec:
+  files:
+    - path: http://127.0.0.1:1/file.txt
+      labels:
+        "-team": infra
+`,
+		`kind: Charts
+metadata:
+  name: bad-labels
+spec:
+  charts:
+    - name: app
+      repoURL: http://127.0.0.1:1/charts
+      labels:
+        "-team": infra
+`,
+	} {
+		s := newTestStore(t)
+		o := newSyncOpts(s.Root)
+		o.FileName = []string{writeTempFile(t, "hauler-manifest.yaml", "apiVersion: content.hauler.cattle.io/v1\n"+doc)}
+		err := SyncCmd(newTestContext(t), o, s, o.StoreRootOpts, defaultCliOpts())
+		if err == nil || !strings.Contains(err.Error(), "invalid label key [-team]") {
+			t.Errorf("SyncCmd = %v, want an invalid label key error for\n%s", err, doc)
+		}
+	}
+}
@@ -7,16 +7,21 @@ import (
 	"io"
 	"net/url"
 	"os"
+	"path"
 	"path/filepath"
 	"regexp"
 	"strconv"
 	"strings"
 
+	mholt "github.com/mholt/archives"
+	"github.com/opencontainers/go-digest"
+
 	"hauler.dev/go/hauler/v2/internal/flags"
 	"hauler.dev/go/hauler/v2/pkg/archives"
 	"hauler.dev/go/hauler/v2/pkg/audit"
 	"hauler.dev/go/hauler/v2/pkg/consts"
 	"hauler.dev/go/hauler/v2/pkg/content"
+	"hauler.dev/go/hauler/v2/pkg/cosign"
 	"hauler.dev/go/hauler/v2/pkg/getter"
 	"hauler.dev/go/hauler/v2/pkg/log"
 	"hauler.dev/go/hauler/v2/pkg/store"
@@ -49,16 +54,56 @@ func LoadCmd(ctx context.Context, o *flags.LoadOpts, s *store.Layout, rso *flags
 	}
 	defer os.RemoveAll(stageDir)
 
-	fileNames, remoteOrigin, err := stageRemoteChunks(ctx, o.FileName, stageDir)
+	verify := o.VerifyKey != "" || o.VerifyChecksums
+	// `-f -` reads a haul from stdin, which only streams by once and has no
+	// checksums beside it
+	var stdin int
+	for _, fn := range o.FileName {
+		if fn == "-" {
+			stdin++
+		}
+	}
+	if stdin > 1 {
+		return fmt.Errorf("stdin (-f -) can only be loaded once")
+	}
+	if stdin > 0 && verify {
+		return fmt.Errorf("--verify-key and --verify-checksums can't be combined with loading from stdin (-f -)... there are no checksums to verify against")
+	}
+	fileNames, remoteOrigin, err := stageRemoteChunks(ctx, o.FileName, stageDir, verify, o.VerifyKey != "")
 	if err != nil {
 		return err
 	}
 
 	l.Debugf("using temporary directory at [%s]", tempDir)
 
+	// --policy (or the hauler dir's policy.yaml) is checked against each haul before it's copied in
+	adm, err := newAdmission(ctx, o.Policy, "store load", rso, ro)
+	if err != nil {
+		return err
+	}
+	defer adm.Close()
+	ctx = withAdmission(ctx, adm)
+
 	for _, fileName := range fileNames {
 		resolved := resolveHaulPath(fileName)
 		wasRemote := strings.HasPrefix(fileName, "http://") || strings.HasPrefix(fileName, "https://") || remoteOrigin[fileName]
+		if verify {
+			// a remote haul is verified from a local copy before anything reads it
+			if strings.HasPrefix(resolved, "http://") || strings.HasPrefix(resolved, "https://") {
+				local, err := downloadHaul(ctx, resolved, stageDir)
+				if err != nil {
+					return err
+				}
+				if err := downloadChecksums(ctx, resolved, local, o.VerifyKey != ""); err != nil {
+					return err
+				}
+				resolved = local
+			}
+			if err := verifyHaul(ctx, o, resolved); err != nil {
+				return err
+			}
+		}
+
 		l.Infof("loading haul [%s] to [%s]", resolved, o.StoreDir)
 		err := unarchiveLayoutTo(ctx, resolved, o.StoreDir, tempDir, ro, wasRemote)
 		if err != nil {
@@ -77,6 +122,10 @@ func LoadCmd(ctx context.Context, o *flags.LoadOpts, s *store.Layout, rso *flags
 				g := audit.BuildGlobal(ro, rso)
 				e.System = &sys
 				e.Global = &g
+				e.Flags = map[string]any{
+					"verify-key":       o.VerifyKey,
+					"verify-checksums": o.VerifyChecksums,
+				}
 			}
 			if err := audit.Append(ro.HaulerDir, e); err != nil {
 				l.Warnf("failed to write audit entry: %v", err)
@@ -93,14 +142,16 @@ func LoadCmd(ctx context.Context, o *flags.LoadOpts, s *store.Layout, rso *flags
 // into stageDir before the main load loop starts. That's what makes
 // `store load -f url1 -f url2 ...` work for a remote chunk set — they all
 // land on disk together, so JoinChunks can just find them once it runs.
-// Non-chunk URLs are left alone and go through unarchiveLayoutTo's normal
-// single-file download instead.
+// Non-chunk URLs are left alone and unarchiveLayoutTo streams them straight
+// from the server instead.
 //
 // Only one path per chunk set gets added to the returned list, so the main
 // loop doesn't process the same haul twice. remoteOrigin tracks which of
 // those returned paths actually came from a download, since the caller
-// needs that later to pick the right wording for a failure hint.
-func stageRemoteChunks(ctx context.Context, fileNames []string, stageDir string) ([]string, map[string]bool, error) {
+// needs that later to pick the right wording for a failure hint. With
+// checksums, each set's sidecar is staged alongside it, and with signed its
+// signature too.
+func stageRemoteChunks(ctx context.Context, fileNames []string, stageDir string, checksums, signed bool) ([]string, map[string]bool, error) {
 	added := map[string]bool{}
 	remoteOrigin := map[string]bool{}
 	var result []string
@@ -128,6 +179,11 @@ func stageRemoteChunks(ctx context.Context, fileNames []string, stageDir string)
 
 		key, _ := archives.ChunkGroupKey(filepath.Base(local))
 		if !added[key] {
+			if checksums {
+				if err := downloadChecksums(ctx, fn, local, signed); err != nil {
+					return nil, nil, err
+				}
+			}
 			result = append(result, local)
 			added[key] = true
 		}
@@ -168,32 +224,119 @@ func downloadHaul(ctx context.Context, urlStr, destDir string) (string, error) {
 	return localPath, nil
 }
 
-// accepts an archived OCI layout, extracts the contents to an existing OCI layout, and preserves the index
-func unarchiveLayoutTo(ctx context.Context, haulPath string, dest string, tempDir string, ro *flags.CliRootOpts, wasRemote bool) error {
+// verifyHaul checks the haul at haulPath, a local file or any chunk of a
+// local set, against the checksums saved next to it... and with
+// --verify-key, that those checksums were signed by the key's owner. Every
+// chunk is read through before the haul is joined or extracted.
+func verifyHaul(ctx context.Context, o *flags.LoadOpts, haulPath string) error {
 	l := log.FromContext(ctx)
 
-	if strings.HasPrefix(haulPath, "http://") || strings.HasPrefix(haulPath, "https://") {
-		l.Debugf("detected remote archive... starting download... [%s]", haulPath)
-		local, err := downloadHaul(ctx, haulPath, tempDir)
+	path := archives.ChecksumsPath(haulPath)
+	c, data, err := archives.ReadChecksums(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("haul [%s] has no checksums [%s] to verify against... it must be saved by a hauler that writes them", haulPath, filepath.Base(path))
+		}
+		return err
+	}
+
+	if o.VerifyKey != "" {
+		sig, err := os.ReadFile(path + consts.DefaultHaulSignatureExt)
 		if err != nil {
+			if os.IsNotExist(err) {
+				return fmt.Errorf("haul [%s] is not signed... no signature [%s] found", haulPath, filepath.Base(path)+consts.DefaultHaulSignatureExt)
+			}
 			return err
 		}
-		haulPath = local
+		if err := cosign.VerifyBlob(ctx, o.VerifyKey, data, sig); err != nil {
+			return fmt.Errorf("signature of haul [%s] does not verify with key [%s]: %w", haulPath, o.VerifyKey, err)
+		}
+		l.Infof("verified signature of haul [%s] with key [%s]", c.Archive.Name, o.VerifyKey)
+	}
+
+	if err := c.Verify(ctx, haulPath); err != nil {
+		return err
+	}
+	l.Infof("verified haul [%s] with digest [%s]", c.Archive.Name, c.Archive.Digest)
+	return nil
+}
+
+// downloadChecksums fetches the checksums sidecar of the remote haul (or
+// chunk) at urlStr, and its signature when signed, to where verifyHaul looks
+// for them next to local, the haul's downloaded copy.
+func downloadChecksums(ctx context.Context, urlStr, local string, signed bool) error {
+	u, err := url.Parse(urlStr)
+	if err != nil {
+		return err
+	}
+	name := path.Base(u.Path)
+	if key, ok := archives.ChunkGroupKey(name); ok {
+		name = key
+	}
+	u.Path = path.Join(path.Dir(u.Path), name+consts.DefaultHaulChecksumsExt)
+
+	dst := archives.ChecksumsPath(local)
+	if err := downloadTo(ctx, u, dst); err != nil {
+		return fmt.Errorf("failed to fetch checksums of haul [%s]: %w", audit.SanitizeURL(urlStr), err)
+	}
+	if !signed {
+		return nil
 	}
+	u.Path += consts.DefaultHaulSignatureExt
+	if err := downloadTo(ctx, u, dst+consts.DefaultHaulSignatureExt); err != nil {
+		return fmt.Errorf("failed to fetch signature of haul [%s]: %w", audit.SanitizeURL(urlStr), err)
+	}
+	return nil
+}
+
+// downloadTo fetches u to the file at dst.
+func downloadTo(ctx context.Context, u *url.URL, dst string) error {
+	rc, err := getter.NewHttp(false, "").Open(ctx, u)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, rc); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
 
-	// reassemble chunk files if haulPath matches the chunk naming pattern.
-	// hang onto the pre-join name for the hint below — once joined, even a
-	// lone unjoinable fragment looks like a normal file (it just gets
-	// copied to itself), so the hint needs the name we were actually asked
-	// to load, not whatever JoinChunks renamed it to.
+// streams an archived OCI layout into an existing OCI layout, and preserves the index
+//
+// the haul is read in a single pass: every blob goes straight into dest as it
+// arrives, verified against its digest, and only the haul's small metadata
+// files (index.json, delta.json, audit.log, ...) are written to tempDir. the
+// haul's index is merged into dest's last, once everything it references is
+// in place. a load that fails partway leaves dest's index as it was... any
+// blobs written that nothing ends up referencing (a failed load's, or those
+// of an artifact the admission policy rejects) are left for `hauler store gc`.
+func unarchiveLayoutTo(ctx context.Context, haulPath string, dest string, tempDir string, ro *flags.CliRootOpts, wasRemote bool) error {
+	l := log.FromContext(ctx)
+
+	// hang onto the name we were actually asked to load for the hint below...
+	// once a chunk set is opened, even a lone unreadable fragment looks like a
+	// normal haul
 	preChunkPath := haulPath
-	joined, err := archives.JoinChunks(ctx, haulPath, tempDir)
+
+	rc, name, err := openHaulStream(ctx, haulPath)
 	if err != nil {
 		return err
 	}
-	haulPath = joined
+	defer rc.Close()
 
-	if err := archives.Unarchive(ctx, haulPath, tempDir); err != nil {
+	ts, err := content.NewOCI(dest)
+	if err != nil {
+		return err
+	}
+
+	if err := streamHaul(ctx, rc, name, ts, tempDir); err != nil {
 		if line1, line2, ok := chunkHint(preChunkPath, wasRemote); ok {
 			ignoreErrors := ro.IgnoreErrors
 			if !ignoreErrors && os.Getenv(consts.HaulerIgnoreErrors) == "true" {
@@ -249,22 +392,35 @@ func unarchiveLayoutTo(ctx context.Context, haulPath string, dest string, tempDi
 		return err
 	}
 
-	s, err := store.NewLayout(tempDir)
-	if err != nil {
+	// the haul's blobs are already in dest... linking them into tempDir gives
+	// the delta and admission checks the haul's layout to read, without a copy
+	if err := linkHaulBlobs(tempDir, dest); err != nil {
 		return err
 	}
 
-	ts, err := content.NewOCI(dest)
+	// a delta only carries what its baseline doesn't, so the rest has to
+	// already be in the target store before anything gets merged
+	if err := prepareDelta(ctx, tempDir, dest); err != nil {
+		return err
+	}
+
+	// admitted after the delta's baseline blobs are in place, so every image
+	// in the haul can be inspected in full
+	if err := admissionFromContext(ctx).admitHaul(ctx, tempDir, dest); err != nil {
+		return err
+	}
+
+	s, err := store.NewLayout(tempDir)
 	if err != nil {
 		return err
 	}
 
-	if _, err := s.CopyAll(ctx, ts, nil); err != nil {
+	if err := mergeHaulIndex(ctx, s, ts, dest); err != nil {
 		return err
 	}
 
-	// CopyAll only copies OCI content; the haul's audit.log sits alongside
-	// it, not in it, so it has to be merged in separately.
+	// the haul's audit.log sits alongside its OCI content, not in it, so it
+	// has to be merged in separately.
 	if err := audit.MergeStoreLog(tempDir, dest); err != nil {
 		l.Warnf("failed to merge audit log from haul: %v", err)
 	}
@@ -272,6 +428,159 @@ func unarchiveLayoutTo(ctx context.Context, haulPath string, dest string, tempDi
 	return nil
 }
 
+// openHaulStream opens haulPath for a single pass: a remote haul straight
+// from its download, a chunk set as its chunks back to back, and - as stdin.
+// name is what the archive goes by, for identifying its format.
+func openHaulStream(ctx context.Context, haulPath string) (io.ReadCloser, string, error) {
+	if haulPath == "-" {
+		// identified by its content, as it has no name to go by
+		log.FromContext(ctx).Debugf("streaming haul from stdin")
+		return io.NopCloser(os.Stdin), haulPath, nil
+	}
+	if !strings.HasPrefix(haulPath, "http://") && !strings.HasPrefix(haulPath, "https://") {
+		return archives.OpenChunks(ctx, haulPath)
+	}
+
+	log.FromContext(ctx).Debugf("detected remote archive... streaming download... [%s]", haulPath)
+	h := getter.NewHttp(false, "")
+	parsedURL, err := url.Parse(haulPath)
+	if err != nil {
+		return nil, "", err
+	}
+	rc, err := h.Open(ctx, parsedURL)
+	if err != nil {
+		return nil, "", err
+	}
+	name := h.Name(parsedURL)
+	if name == "" {
+		name = filepath.Base(parsedURL.Path)
+	}
+	return rc, name, nil
+}
+
+// streamHaul reads the haul from r in one pass, writing each blob into ts as
+// it arrives and every other file into tempDir. A zip haul is spooled beside
+// tempDir, in the same --tempdir, rather than in the layout staged inside it.
+func streamHaul(ctx context.Context, r io.Reader, name string, ts *content.OCI, tempDir string) error {
+	l := log.FromContext(ctx)
+	l.Debugf("streaming haul [%s]", name)
+
+	blobPrefix := ocispec.ImageBlobsDir + "/"
+	var blobs int
+	var size int64
+
+	err := archives.WalkReader(ctx, name, r, filepath.Dir(tempDir), func(entry string, f mholt.FileInfo) error {
+		if rest, ok := strings.CutPrefix(entry, blobPrefix); ok {
+			d := digest.Digest(strings.Replace(rest, "/", ":", 1))
+			if d.Validate() != nil {
+				l.Debugf("skipping [%s]... not a blob", entry)
+				return nil
+			}
+			open := func() (io.ReadCloser, error) { return f.Open() }
+			if err := ts.WriteBlob(ctx, d, f.Size(), open); err != nil {
+				return fmt.Errorf("failed to load blob [%s]: %w", d, err)
+			}
+			blobs++
+			size += f.Size()
+			return nil
+		}
+
+		dst := filepath.Join(tempDir, filepath.FromSlash(entry))
+		if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+			return err
+		}
+		if err := copyArchiveEntry(f, dst); err != nil {
+			return fmt.Errorf("failed to read [%s] from haul: %w", entry, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(filepath.Join(tempDir, ocispec.ImageIndexFile)); err != nil {
+		return fmt.Errorf("haul [%s] has no %s... is it a hauler haul?", name, ocispec.ImageIndexFile)
+	}
+	l.Debugf("streamed [%d] blobs [%s] from haul [%s]", blobs, byteCountSI(size), name)
+	return nil
+}
+
+// copyArchiveEntry writes the archive entry f to the file at dst as it
+// streams by.
+func copyArchiveEntry(f mholt.FileInfo, dst string) error {
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, rc); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// linkHaulBlobs points tempDir's blobs directory at dest's, where the
+// haul's blobs were streamed.
+func linkHaulBlobs(tempDir, dest string) error {
+	blobsDir, err := filepath.Abs(filepath.Join(dest, ocispec.ImageBlobsDir))
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(blobsDir, 0o755); err != nil {
+		return err
+	}
+	if err := os.Symlink(blobsDir, filepath.Join(tempDir, ocispec.ImageBlobsDir)); err != nil {
+		return fmt.Errorf("failed to stage haul layout: %w", err)
+	}
+	return nil
+}
+
+// mergeHaulIndex adds every artifact in the haul's index to ts's, once all of
+// them are known to be complete in dest. hs is the haul's layout as staged
+// by linkHaulBlobs.
+func mergeHaulIndex(ctx context.Context, hs *store.Layout, ts *content.OCI, dest string) error {
+	var descs []ocispec.Descriptor
+	var missing []string
+	if err := hs.Walk(func(_ string, desc ocispec.Descriptor) error {
+		err := hs.WalkBlobs(ctx, desc, func(b ocispec.Descriptor) error {
+			if _, err := os.Stat(filepath.Join(dest, ocispec.ImageBlobsDir, b.Digest.Algorithm().String(), b.Digest.Encoded())); err != nil {
+				return fmt.Errorf("blob [%s] is missing", b.Digest)
+			}
+			return nil
+		})
+		if err != nil {
+			missing = append(missing, fmt.Sprintf("%s (%v)", desc.Annotations[ocispec.AnnotationRefName], err))
+			return nil
+		}
+		descs = append(descs, desc)
+		return nil
+	}); err != nil {
+		return err
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("haul is incomplete... [%d] artifact(s) are missing content: %s", len(missing), strings.Join(missing, ", "))
+	}
+
+	if err := ts.LoadIndex(); err != nil {
+		return err
+	}
+	for _, desc := range descs {
+		if err := ts.AddIndex(desc); err != nil {
+			return err
+		}
+	}
+	if err := ts.SaveIndex(); err != nil {
+		return fmt.Errorf("failed to save index: %w", err)
+	}
+	return nil
+}
+
 // matches the <path>.NNN chunk suffix that is used to filter resolveHaulPath
 // glob matches down to real chunks, so an unrelated sibling file (.sig, .bak,
 // etc...) is never picked up instead of the actual first chunk
@@ -305,10 +614,10 @@ func chunkHint(haulPath string, wasRemote bool) (line1, line2 string, ok bool) {
 		true
 }
 
-// resolveHaulPath returns path as-is if it exists or is a URL, otherwise
+// resolveHaulPath returns path as-is if it exists or is a URL or stdin, otherwise
 // globs for chunk files matching <path>.NNN so JoinChunks can reassemble them.
 func resolveHaulPath(path string) string {
-	if strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://") {
+	if path == "-" || strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://") {
 		return path
 	}
 	if _, err := os.Stat(path); err == nil {
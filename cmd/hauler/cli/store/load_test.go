@@ -9,7 +9,9 @@ package store
 
 import (
 	"context"
+	"crypto/rand"
 	"encoding/json"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"os"
@@ -18,11 +20,15 @@ import (
 	"testing"
 
 	mholtarchives "github.com/mholt/archives"
+	"github.com/opencontainers/go-digest"
 	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	cosignpkg "github.com/sigstore/cosign/v3/pkg/cosign"
 
 	"hauler.dev/go/hauler/v2/internal/flags"
+	v1 "hauler.dev/go/hauler/v2/pkg/apis/hauler.cattle.io/v1"
 	"hauler.dev/go/hauler/v2/pkg/archives"
 	"hauler.dev/go/hauler/v2/pkg/consts"
+	"hauler.dev/go/hauler/v2/pkg/cosign"
 	"hauler.dev/go/hauler/v2/pkg/store"
 )
 
@@ -144,6 +150,60 @@ func TestUnarchiveLayoutTo_MergesAuditLog(t *testing.T) {
 	}
 }
 
+// TestUnarchiveLayoutTo_CorruptBlobLeavesIndex checks a haul that fails
+// partway through streaming leaves the target store's index as it was.
+func TestUnarchiveLayoutTo_CorruptBlobLeavesIndex(t *testing.T) {
+	ctx := newTestContext(t)
+
+	// the haul: one file artifact, with its content blob corrupted
+	src := newTestStore(t)
+	tmpFile := filepath.Join(t.TempDir(), "payload.txt")
+	if err := os.WriteFile(tmpFile, []byte("streamed payload"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := storeFile(ctx, src, v1.File{Path: tmpFile}, defaultCliOpts(), defaultRootOpts(src.Root)); err != nil {
+		t.Fatalf("storeFile: %v", err)
+	}
+	payload := digest.FromString("streamed payload")
+	if err := os.WriteFile(filepath.Join(src.Root, ocispec.ImageBlobsDir, "sha256", payload.Encoded()), []byte("tampered payload"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	archivePath := filepath.Join(t.TempDir(), "haul.tar.zst")
+	if err := createRootLevelArchive(src.Root, archivePath); err != nil {
+		t.Fatalf("createRootLevelArchive: %v", err)
+	}
+
+	// the target already holds something of its own
+	dest := newTestStore(t)
+	keep := filepath.Join(t.TempDir(), "keep.txt")
+	if err := os.WriteFile(keep, []byte("already here"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := storeFile(ctx, dest, v1.File{Path: keep}, defaultCliOpts(), defaultRootOpts(dest.Root)); err != nil {
+		t.Fatalf("storeFile: %v", err)
+	}
+	before, err := os.ReadFile(filepath.Join(dest.Root, ocispec.ImageIndexFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = unarchiveLayoutTo(ctx, archivePath, dest.Root, t.TempDir(), defaultCliOpts(), false)
+	if err == nil || !strings.Contains(err.Error(), "digest mismatch") {
+		t.Fatalf("unarchiveLayoutTo = %v, want a digest mismatch", err)
+	}
+
+	after, err := os.ReadFile(filepath.Join(dest.Root, ocispec.ImageIndexFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(before) != string(after) {
+		t.Errorf("failed load changed the target index:\nbefore: %s\nafter:  %s", before, after)
+	}
+	if _, err := os.Stat(filepath.Join(dest.Root, ocispec.ImageBlobsDir, "sha256", payload.Encoded())); !os.IsNotExist(err) {
+		t.Errorf("corrupt blob landed in the target store (stat err %v)", err)
+	}
+}
+
 // --------------------------------------------------------------------------
 // TestLoadCmd_LocalFile
 // --------------------------------------------------------------------------
@@ -225,6 +285,156 @@ func TestLoadCmd_LocalFile(t *testing.T) {
 }
 
 // --------------------------------------------------------------------------
+// TestLoadCmd_VerifyKey saves a signed, chunked haul and checks load accepts
+// it with the matching key, names a tampered chunk, and rejects another key.
+func TestLoadCmd_VerifyKey(t *testing.T) {
+	ctx := newTestContext(t)
+
+	keys, err := cosignpkg.GenerateKeyPair(func(bool) ([]byte, error) { return []byte("hunter2"), nil })
+	if err != nil {
+		t.Fatalf("GenerateKeyPair: %v", err)
+	}
+	keyDir := t.TempDir()
+	priv, pub := filepath.Join(keyDir, "cosign.key"), filepath.Join(keyDir, "cosign.pub")
+	if err := os.WriteFile(priv, keys.PrivateBytes, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(pub, keys.PublicBytes, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(cosign.PasswordEnv, "hunter2")
+
+	// random content, so the compressed haul still spans several chunks
+	payload := make([]byte, 8<<10)
+	if _, err := rand.Read(payload); err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(t.TempDir(), "payload.bin")
+	if err := os.WriteFile(file, payload, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	s := newTestStore(t)
+	if err := storeFile(ctx, s, v1.File{Path: file}, defaultCliOpts(), defaultRootOpts(s.Root)); err != nil {
+		t.Fatalf("storeFile: %v", err)
+	}
+
+	archivePath := filepath.Join(t.TempDir(), "haul.tar.zst")
+	so := newSaveOpts(s.Root, archivePath)
+	so.ChunkSize = "2K"
+	so.SignKey = priv
+	if err := SaveCmd(ctx, so, s, defaultRootOpts(s.Root), defaultCliOpts()); err != nil {
+		t.Fatalf("SaveCmd: %v", err)
+	}
+	sidecar := archives.ChecksumsPath(archivePath)
+	for _, p := range []string{sidecar, sidecar + consts.DefaultHaulSignatureExt} {
+		if _, err := os.Stat(p); err != nil {
+			t.Fatalf("expected %s next to the haul: %v", filepath.Base(p), err)
+		}
+	}
+	chunks, err := filepath.Glob(archivePath + ".[0-9][0-9][0-9]")
+	if err != nil || len(chunks) < 2 {
+		t.Fatalf("expected several chunks, got %v (%v)", chunks, err)
+	}
+
+	load := func(key string) error {
+		destDir := t.TempDir()
+		dest, err := store.NewLayout(destDir)
+		if err != nil {
+			t.Fatalf("store.NewLayout: %v", err)
+		}
+		o := &flags.LoadOpts{
+			StoreRootOpts: defaultRootOpts(destDir),
+			FileName:      []string{chunks[0]},
+			VerifyKey:     key,
+		}
+		return LoadCmd(ctx, o, dest, defaultRootOpts(destDir), defaultCliOpts())
+	}
+
+	if err := load(pub); err != nil {
+		t.Fatalf("LoadCmd with the signing key: %v", err)
+	}
+
+	other, err := cosignpkg.GenerateKeyPair(func(bool) ([]byte, error) { return []byte("hunter2"), nil })
+	if err != nil {
+		t.Fatalf("GenerateKeyPair: %v", err)
+	}
+	otherPub := filepath.Join(keyDir, "other.pub")
+	if err := os.WriteFile(otherPub, other.PublicBytes, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := load(otherPub); err == nil {
+		t.Error("LoadCmd with another key succeeded")
+	}
+
+	data, err := os.ReadFile(chunks[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	data[0] ^= 0xff
+	if err := os.WriteFile(chunks[1], data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	err = load(pub)
+	if err == nil || !strings.Contains(err.Error(), filepath.Base(chunks[1])) {
+		t.Errorf("LoadCmd of a tampered chunk: error = %v, want one naming %s", err, filepath.Base(chunks[1]))
+	}
+}
+
+// TestLoadCmd_RemoteChunksChecksums serves an unsigned, chunked haul and
+// checks --verify-checksums loads it without asking for a signature.
+func TestLoadCmd_RemoteChunksChecksums(t *testing.T) {
+	ctx := newTestContext(t)
+
+	payload := make([]byte, 8<<10)
+	if _, err := rand.Read(payload); err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(t.TempDir(), "payload.bin")
+	if err := os.WriteFile(file, payload, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	s := newTestStore(t)
+	if err := storeFile(ctx, s, v1.File{Path: file}, defaultCliOpts(), defaultRootOpts(s.Root)); err != nil {
+		t.Fatalf("storeFile: %v", err)
+	}
+
+	haulDir := t.TempDir()
+	archivePath := filepath.Join(haulDir, "haul.tar.zst")
+	so := newSaveOpts(s.Root, archivePath)
+	so.ChunkSize = "2K"
+	if err := SaveCmd(ctx, so, s, defaultRootOpts(s.Root), defaultCliOpts()); err != nil {
+		t.Fatalf("SaveCmd: %v", err)
+	}
+	chunks, err := filepath.Glob(archivePath + ".[0-9][0-9][0-9]")
+	if err != nil || len(chunks) < 2 {
+		t.Fatalf("expected several chunks, got %v (%v)", chunks, err)
+	}
+
+	srv := httptest.NewServer(http.FileServer(http.Dir(haulDir)))
+	t.Cleanup(srv.Close)
+	var urls []string
+	for _, c := range chunks {
+		urls = append(urls, srv.URL+"/"+filepath.Base(c))
+	}
+
+	destDir := t.TempDir()
+	dest, err := store.NewLayout(destDir)
+	if err != nil {
+		t.Fatalf("store.NewLayout: %v", err)
+	}
+	o := &flags.LoadOpts{
+		StoreRootOpts:   defaultRootOpts(destDir),
+		FileName:        urls,
+		VerifyChecksums: true,
+	}
+	if err := LoadCmd(ctx, o, dest, defaultRootOpts(destDir), defaultCliOpts()); err != nil {
+		t.Fatalf("LoadCmd: %v", err)
+	}
+	if countArtifactsInStore(t, dest) != 1 {
+		t.Errorf("expected the file in the store after LoadCmd")
+	}
+}
+
 // TestLoadCmd_RemoteArchive
 // --------------------------------------------------------------------------
 
@@ -473,3 +683,65 @@ func TestClearDir(t *testing.T) {
 		t.Errorf("clearDir: expected empty dir, found: %s", strings.Join(names, ", "))
 	}
 }
+
+func TestLoadCmd_Stdin(t *testing.T) {
+	ctx := newTestContext(t)
+
+	file := filepath.Join(t.TempDir(), "payload.txt")
+	if err := os.WriteFile(file, []byte("streamed"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	s := newTestStore(t)
+	if err := storeFile(ctx, s, v1.File{Path: file}, defaultCliOpts(), defaultRootOpts(s.Root)); err != nil {
+		t.Fatalf("storeFile: %v", err)
+	}
+
+	// stdout and stdin stand in for the two ends of a pipe
+	stream, err := os.Create(filepath.Join(t.TempDir(), "stream"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Close()
+	stdout, stdin := os.Stdout, os.Stdin
+	t.Cleanup(func() { os.Stdout, os.Stdin = stdout, stdin })
+
+	os.Stdout = stream
+	so := newSaveOpts(s.Root, "-")
+	err = SaveCmd(ctx, so, s, defaultRootOpts(s.Root), defaultCliOpts())
+	os.Stdout = stdout
+	if err != nil {
+		t.Fatalf("SaveCmd: %v", err)
+	}
+	if _, err := os.Stat(archives.ChecksumsPath("-")); !os.IsNotExist(err) {
+		t.Error("SaveCmd to stdout wrote a checksums file")
+	}
+
+	load := func(o *flags.LoadOpts) (*store.Layout, error) {
+		destDir := t.TempDir()
+		dest, err := store.NewLayout(destDir)
+		if err != nil {
+			t.Fatalf("store.NewLayout: %v", err)
+		}
+		o.StoreRootOpts = defaultRootOpts(destDir)
+		return dest, LoadCmd(ctx, o, dest, defaultRootOpts(destDir), defaultCliOpts())
+	}
+
+	if _, err := stream.Seek(0, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	os.Stdin = stream
+	dest, err := load(&flags.LoadOpts{FileName: []string{"-"}})
+	if err != nil {
+		t.Fatalf("LoadCmd from stdin: %v", err)
+	}
+	if got, want := countArtifactsInStore(t, dest), countArtifactsInStore(t, s); got != want {
+		t.Errorf("loaded %d artifacts from stdin, want %d", got, want)
+	}
+
+	if _, err := load(&flags.LoadOpts{FileName: []string{"-", "-"}}); err == nil {
+		t.Error("LoadCmd reading stdin twice succeeded")
+	}
+	if _, err := load(&flags.LoadOpts{FileName: []string{"-"}, VerifyChecksums: true}); err == nil {
+		t.Error("LoadCmd verifying stdin succeeded")
+	}
+}
@@ -0,0 +1,295 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+
+	gname "github.com/google/go-containerregistry/pkg/name"
+	"gopkg.in/yaml.v3"
+
+	"hauler.dev/go/hauler/v2/internal/flags"
+	v1 "hauler.dev/go/hauler/v2/pkg/apis/hauler.cattle.io/v1"
+	"hauler.dev/go/hauler/v2/pkg/artifacts"
+)
+
+// lockfileVersion is bumped whenever a field changes meaning, so an older
+// hauler refuses a lockfile it would otherwise misread.
+const lockfileVersion = 1
+
+// lockfile pins everything a `store sync` fetched: images to the digest their
+// tag resolved to, charts to the version a constraint resolved to plus the
+// chart archive's digest, and files to the digest of their content. Entries
+// are keyed by what the manifests ask for (name, platform, repo, constraint,
+// path), so a --locked run can look each job up before it fetches anything.
+type lockfile struct {
+	Version int           `yaml:"version"`
+	Images  []lockedImage `yaml:"images,omitempty"`
+	Charts  []lockedChart `yaml:"charts,omitempty"`
+	Files   []lockedFile  `yaml:"files,omitempty"`
+}
+
+type lockedImage struct {
+	Name     string `yaml:"name"`
+	Platform string `yaml:"platform,omitempty"`
+	Digest   string `yaml:"digest"`
+}
+
+type lockedChart struct {
+	Name       string `yaml:"name"`
+	RepoURL    string `yaml:"repoURL,omitempty"`
+	Constraint string `yaml:"constraint,omitempty"` // the version as written in the manifest, if any
+	Version    string `yaml:"version"`
+	Digest     string `yaml:"digest"`
+}
+
+type lockedFile struct {
+	Path   string `yaml:"path"`
+	Name   string `yaml:"name,omitempty"`
+	Digest string `yaml:"digest"`
+}
+
+func (e lockedImage) key() string { return e.Name + "|" + e.Platform }
+func (e lockedChart) key() string { return e.Name + "|" + e.RepoURL + "|" + e.Constraint }
+func (e lockedFile) key() string  { return e.Path + "|" + e.Name }
+
+// syncLock carries --lock/--locked state through a sync. It rides on the
+// context (see withSyncLock) so storeImage, storeFile, and fetchChart can
+// consult it without every runner growing another parameter. All methods are
+// safe on a nil *syncLock, which is what every non-sync caller sees.
+type syncLock struct {
+	path string
+
+	// pinned is the lockfile being enforced under --locked, or nil
+	pinned *lockfile
+
+	// recording is true under --lock; fetched entries collect below
+	recording bool
+	mu        sync.Mutex
+	images    map[string]lockedImage
+	charts    map[string]lockedChart
+	files     map[string]lockedFile
+}
+
+// newSyncLock returns the lock state for o, reading the lockfile under
+// --locked. It returns nil when neither flag is set.
+func newSyncLock(o *flags.SyncOpts) (*syncLock, error) {
+	if !o.Lock && !o.Locked {
+		return nil, nil
+	}
+
+	lk := &syncLock{
+		path:      o.Lockfile,
+		recording: o.Lock,
+		images:    map[string]lockedImage{},
+		charts:    map[string]lockedChart{},
+		files:     map[string]lockedFile{},
+	}
+	if !o.Locked {
+		return lk, nil
+	}
+
+	data, err := os.ReadFile(o.Lockfile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read lockfile: %w", err)
+	}
+	var lf lockfile
+	if err := yaml.Unmarshal(data, &lf); err != nil {
+		return nil, fmt.Errorf("failed to parse lockfile [%s]: %w", o.Lockfile, err)
+	}
+	if lf.Version != lockfileVersion {
+		return nil, fmt.Errorf("unsupported lockfile version [%d] in [%s]... expected [%d]", lf.Version, o.Lockfile, lockfileVersion)
+	}
+	lk.pinned = &lf
+	return lk, nil
+}
+
+type syncLockKey struct{}
+
+func withSyncLock(ctx context.Context, lk *syncLock) context.Context {
+	return context.WithValue(ctx, syncLockKey{}, lk)
+}
+
+func syncLockFromContext(ctx context.Context) *syncLock {
+	lk, _ := ctx.Value(syncLockKey{}).(*syncLock)
+	return lk
+}
+
+// pinImage returns the digest j should be fetched at. Under --locked that's
+// the lockfile's digest, after checking the tag still resolves to it; under
+// --lock the tag is resolved here (unless verification already pinned it),
+// so the digest recorded is exactly the one fetched. Otherwise pinned passes
+// through untouched.
+func (lk *syncLock) pinImage(ctx context.Context, j imageJob, pinned string, rso *flags.StoreRootOpts, ro *flags.CliRootOpts) (string, error) {
+	if lk == nil || (lk.pinned == nil && !lk.recording) {
+		return pinned, nil
+	}
+
+	current := pinned
+	if current == "" {
+		ref, err := gname.ParseReference(j.img.Name)
+		if err != nil {
+			return "", err
+		}
+		current, err = pinDigest(ctx, ref, rso, ro)
+		if err != nil {
+			return "", fmt.Errorf("unable to resolve image digest: %w", err)
+		}
+	}
+	if lk.pinned == nil {
+		return current, nil
+	}
+
+	want := lockedImage{Name: j.img.Name, Platform: j.platform}
+	for _, e := range lk.pinned.Images {
+		if e.key() != want.key() {
+			continue
+		}
+		if e.Digest != current {
+			return "", fmt.Errorf("image [%s] has drifted from the lockfile: locked [%s], upstream now [%s]", j.img.Name, e.Digest, current)
+		}
+		return e.Digest, nil
+	}
+	return "", fmt.Errorf("image [%s] (platform %q) is not in lockfile [%s]... re-run with --lock to update it", j.img.Name, j.platform, lk.path)
+}
+
+// lockedChartVersion returns the version a --locked run must fetch for j, or
+// j's own version when nothing is locked.
+func (lk *syncLock) lockedChartVersion(j chartJob) (string, error) {
+	if lk == nil || lk.pinned == nil {
+		return j.opts.ChartOpts.Version, nil
+	}
+	e, err := lk.lockedChart(j)
+	if err != nil {
+		return "", err
+	}
+	return e.Version, nil
+}
+
+// checkChart compares a fetched chart's archive digest against the lockfile.
+func (lk *syncLock) checkChart(j chartJob, digest string) error {
+	if lk == nil || lk.pinned == nil {
+		return nil
+	}
+	e, err := lk.lockedChart(j)
+	if err != nil {
+		return err
+	}
+	if e.Digest != digest {
+		return fmt.Errorf("chart [%s] version [%s] has drifted from the lockfile: locked [%s], upstream now [%s]", j.cfg.Name, e.Version, e.Digest, digest)
+	}
+	return nil
+}
+
+func (lk *syncLock) lockedChart(j chartJob) (lockedChart, error) {
+	want := lockedChart{Name: j.cfg.Name, RepoURL: j.cfg.RepoURL, Constraint: j.cfg.Version}
+	for _, e := range lk.pinned.Charts {
+		if e.key() == want.key() {
+			return e, nil
+		}
+	}
+	return lockedChart{}, fmt.Errorf("chart [%s] (repo %q, version %q) is not in lockfile [%s]... re-run with --lock to update it", j.cfg.Name, j.cfg.RepoURL, j.cfg.Version, lk.path)
+}
+
+// checkFile compares a fetched file's content digest against the lockfile.
+func (lk *syncLock) checkFile(fi v1.File, digest string) error {
+	if lk == nil || lk.pinned == nil {
+		return nil
+	}
+	want := lockedFile{Path: fi.Path, Name: fi.Name}
+	for _, e := range lk.pinned.Files {
+		if e.key() != want.key() {
+			continue
+		}
+		if e.Digest != digest {
+			return fmt.Errorf("file [%s] has drifted from the lockfile: locked [%s], upstream now [%s]", fi.Path, e.Digest, digest)
+		}
+		return nil
+	}
+	return fmt.Errorf("file [%s] is not in lockfile [%s]... re-run with --lock to update it", fi.Path, lk.path)
+}
+
+func (lk *syncLock) recordImage(name, platform, digest string) {
+	if lk == nil || !lk.recording {
+		return
+	}
+	e := lockedImage{Name: name, Platform: platform, Digest: digest}
+	lk.mu.Lock()
+	lk.images[e.key()] = e
+	lk.mu.Unlock()
+}
+
+func (lk *syncLock) recordChart(j chartJob, version, digest string) {
+	if lk == nil || !lk.recording {
+		return
+	}
+	e := lockedChart{Name: j.cfg.Name, RepoURL: j.cfg.RepoURL, Constraint: j.cfg.Version, Version: version, Digest: digest}
+	lk.mu.Lock()
+	lk.charts[e.key()] = e
+	lk.mu.Unlock()
+}
+
+func (lk *syncLock) recordFile(fi v1.File, digest string) {
+	if lk == nil || !lk.recording {
+		return
+	}
+	e := lockedFile{Path: fi.Path, Name: fi.Name, Digest: digest}
+	lk.mu.Lock()
+	lk.files[e.key()] = e
+	lk.mu.Unlock()
+}
+
+// write saves everything recorded under --lock, sorted so that two runs that
+// fetched the same content produce byte-identical lockfiles.
+func (lk *syncLock) write() error {
+	if lk == nil || !lk.recording {
+		return nil
+	}
+
+	lk.mu.Lock()
+	lf := lockfile{Version: lockfileVersion}
+	for _, k := range sortedKeys(lk.images) {
+		lf.Images = append(lf.Images, lk.images[k])
+	}
+	for _, k := range sortedKeys(lk.charts) {
+		lf.Charts = append(lf.Charts, lk.charts[k])
+	}
+	for _, k := range sortedKeys(lk.files) {
+		lf.Files = append(lf.Files, lk.files[k])
+	}
+	lk.mu.Unlock()
+
+	data, err := yaml.Marshal(lf)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(lk.path, data, 0o644)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// artifactDigest returns the digest of an artifact's first (content) layer:
+// the chart archive for charts, the raw content for files.
+func artifactDigest(a artifacts.OCI) (string, error) {
+	layers, err := a.Layers()
+	if err != nil {
+		return "", err
+	}
+	if len(layers) == 0 {
+		return "", fmt.Errorf("artifact has no layers")
+	}
+	d, err := layers[0].Digest()
+	if err != nil {
+		return "", err
+	}
+	return d.String(), nil
+}
@@ -0,0 +1,240 @@
+package store
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// mutableFileServer serves whatever *content currently holds at /filename,
+// so a test can change upstream content between syncs.
+func mutableFileServer(t *testing.T, filename string, content *atomic.Value) string {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/"+filename, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/octet-stream")
+		io.WriteString(w, content.Load().(string)) //nolint:errcheck
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv.URL + "/" + filename
+}
+
+func readLockfile(t *testing.T, path string) lockfile {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile(%s): %v", path, err)
+	}
+	var lf lockfile
+	if err := yaml.Unmarshal(data, &lf); err != nil {
+		t.Fatalf("Unmarshal lockfile: %v", err)
+	}
+	return lf
+}
+
+func lockTestManifest(host, fileURL string) string {
+	return fmt.Sprintf(`apiVersion: content.hauler.cattle.io/v1
+kind: Images
+metadata:
+  name: lock-images
+spec:
+  images:
+    - name: %[1]s/lock/single:v1
+    - name: %[1]s/lock/multi:v1
+      platform: linux/arm64
+---
+apiVersion: content.hauler.cattle.io/v1
+kind: Files
+metadata:
+  name: lock-files
+spec:
+  files:
+    - path: %[2]s
+---
+apiVersion: content.hauler.cattle.io/v1
+kind: Charts
+metadata:
+  name: lock-charts
+spec:
+  charts:
+    - name: rancher-cluster-templates-0.5.2.tgz
+      repoURL: %[3]s
+`, host, fileURL, chartTestdataDir)
+}
+
+func TestSyncCmd_Lock_ThenLocked(t *testing.T) {
+	ctx := newTestContext(t)
+	host, _ := newLocalhostRegistry(t)
+	single := seedImage(t, host, "lock/single", "v1")
+	multi := seedIndex(t, host, "lock/multi", "v1")
+
+	var fileContent atomic.Value
+	fileContent.Store("#!/bin/sh\necho v1\n")
+	fileURL := mutableFileServer(t, "lock-me.sh", &fileContent)
+
+	manifest := writeTempFile(t, "hauler-manifest.yaml", lockTestManifest(host, fileURL))
+	lockPath := filepath.Join(t.TempDir(), "hauler-lock.yaml")
+
+	// --lock writes what was fetched
+	s := newTestStore(t)
+	o := newSyncOpts(s.Root)
+	o.FileName = []string{manifest}
+	o.Lock = true
+	o.Lockfile = lockPath
+	if err := SyncCmd(ctx, o, s, o.StoreRootOpts, defaultCliOpts()); err != nil {
+		t.Fatalf("SyncCmd --lock: %v", err)
+	}
+
+	lf := readLockfile(t, lockPath)
+	if lf.Version != lockfileVersion {
+		t.Errorf("lockfile version = %d, want %d", lf.Version, lockfileVersion)
+	}
+	singleDigest, _ := single.Digest()
+	multiDigest, _ := multi.Digest()
+	want := map[string]string{
+		host + "/lock/single:v1|":           singleDigest.String(),
+		host + "/lock/multi:v1|linux/arm64": multiDigest.String(),
+	}
+	if len(lf.Images) != len(want) {
+		t.Fatalf("lockfile images = %+v, want %d entries", lf.Images, len(want))
+	}
+	for _, e := range lf.Images {
+		if want[e.key()] != e.Digest {
+			t.Errorf("locked image %s = %s, want %s", e.key(), e.Digest, want[e.key()])
+		}
+	}
+	if len(lf.Files) != 1 || lf.Files[0].Path != fileURL || !strings.HasPrefix(lf.Files[0].Digest, "sha256:") {
+		t.Errorf("lockfile files = %+v", lf.Files)
+	}
+	if len(lf.Charts) != 1 || lf.Charts[0].Version != "0.5.2" || !strings.HasPrefix(lf.Charts[0].Digest, "sha256:") {
+		t.Errorf("lockfile charts = %+v", lf.Charts)
+	}
+
+	// --locked against unchanged upstream reproduces the store
+	s2 := newTestStore(t)
+	o2 := newSyncOpts(s2.Root)
+	o2.FileName = []string{manifest}
+	o2.Locked = true
+	o2.Lockfile = lockPath
+	if err := SyncCmd(ctx, o2, s2, o2.StoreRootOpts, defaultCliOpts()); err != nil {
+		t.Fatalf("SyncCmd --locked: %v", err)
+	}
+	if got, want := countArtifactsInStore(t, s2), countArtifactsInStore(t, s); got != want {
+		t.Errorf("--locked store has %d artifacts, --lock store has %d", got, want)
+	}
+
+	// a moved tag is drift
+	seedImage(t, host, "lock/single", "v1")
+	s3 := newTestStore(t)
+	o3 := newSyncOpts(s3.Root)
+	o3.FileName = []string{manifest}
+	o3.Locked = true
+	o3.Lockfile = lockPath
+	err := SyncCmd(ctx, o3, s3, o3.StoreRootOpts, defaultCliOpts())
+	if err == nil || !strings.Contains(err.Error(), "drifted") {
+		t.Fatalf("expected image drift error, got %v", err)
+	}
+}
+
+func TestSyncCmd_Locked_FileDrift(t *testing.T) {
+	ctx := newTestContext(t)
+
+	var fileContent atomic.Value
+	fileContent.Store("v1")
+	fileURL := mutableFileServer(t, "drift.txt", &fileContent)
+
+	manifest := writeTempFile(t, "hauler-manifest.yaml", fmt.Sprintf(`apiVersion: content.hauler.cattle.io/v1
+kind: Files
+metadata:
+  name: drift
+spec:
+  files:
+    - path: %s
+`, fileURL))
+	lockPath := filepath.Join(t.TempDir(), "hauler-lock.yaml")
+
+	s := newTestStore(t)
+	o := newSyncOpts(s.Root)
+	o.FileName = []string{manifest}
+	o.Lock = true
+	o.Lockfile = lockPath
+	if err := SyncCmd(ctx, o, s, o.StoreRootOpts, defaultCliOpts()); err != nil {
+		t.Fatalf("SyncCmd --lock: %v", err)
+	}
+
+	fileContent.Store("v2")
+
+	s2 := newTestStore(t)
+	o2 := newSyncOpts(s2.Root)
+	o2.FileName = []string{manifest}
+	o2.Locked = true
+	o2.Lockfile = lockPath
+	err := SyncCmd(ctx, o2, s2, o2.StoreRootOpts, defaultCliOpts())
+	if err == nil || !strings.Contains(err.Error(), "drifted") {
+		t.Fatalf("expected file drift error, got %v", err)
+	}
+	if n := countArtifactsInStore(t, s2); n != 0 {
+		t.Errorf("drifted file was stored anyway: %d artifacts", n)
+	}
+}
+
+func TestSyncCmd_Locked_MissingEntry(t *testing.T) {
+	ctx := newTestContext(t)
+	host, _ := newLocalhostRegistry(t)
+	seedImage(t, host, "lock/known", "v1")
+	seedImage(t, host, "lock/unknown", "v1")
+
+	lockPath := filepath.Join(t.TempDir(), "hauler-lock.yaml")
+	s := newTestStore(t)
+	o := newSyncOpts(s.Root)
+	o.FileName = []string{writeTempFile(t, "hauler-manifest.yaml", fmt.Sprintf(`apiVersion: content.hauler.cattle.io/v1
+kind: Images
+metadata:
+  name: known
+spec:
+  images:
+    - name: %s/lock/known:v1
+`, host))}
+	o.Lock = true
+	o.Lockfile = lockPath
+	if err := SyncCmd(ctx, o, s, o.StoreRootOpts, defaultCliOpts()); err != nil {
+		t.Fatalf("SyncCmd --lock: %v", err)
+	}
+
+	s2 := newTestStore(t)
+	o2 := newSyncOpts(s2.Root)
+	o2.FileName = []string{writeTempFile(t, "hauler-manifest.yaml", fmt.Sprintf(`apiVersion: content.hauler.cattle.io/v1
+kind: Images
+metadata:
+  name: unknown
+spec:
+  images:
+    - name: %s/lock/unknown:v1
+`, host))}
+	o2.Locked = true
+	o2.Lockfile = lockPath
+	err := SyncCmd(ctx, o2, s2, o2.StoreRootOpts, defaultCliOpts())
+	if err == nil || !strings.Contains(err.Error(), "not in lockfile") {
+		t.Fatalf("expected not-in-lockfile error, got %v", err)
+	}
+}
+
+func TestSyncCmd_Locked_MissingLockfile(t *testing.T) {
+	ctx := newTestContext(t)
+	s := newTestStore(t)
+	o := newSyncOpts(s.Root)
+	o.Locked = true
+	o.Lockfile = filepath.Join(t.TempDir(), "missing.yaml")
+	if err := SyncCmd(ctx, o, s, o.StoreRootOpts, defaultCliOpts()); err == nil {
+		t.Fatal("expected an error for a missing lockfile")
+	}
+}
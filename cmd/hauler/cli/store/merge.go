@@ -0,0 +1,214 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/olekukonko/tablewriter/tw"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+
+	"hauler.dev/go/hauler/v2/internal/flags"
+	"hauler.dev/go/hauler/v2/pkg/audit"
+	"hauler.dev/go/hauler/v2/pkg/consts"
+	"hauler.dev/go/hauler/v2/pkg/log"
+	"hauler.dev/go/hauler/v2/pkg/store"
+)
+
+// MergeCmd copies the index entries and blobs of every --from store straight
+// into s, without staging a haul, and appends each source's store audit log
+// onto s's.
+func MergeCmd(ctx context.Context, o *flags.MergeOpts, s *store.Layout, ro *flags.CliRootOpts) error {
+	l := log.FromContext(ctx)
+
+	if !slices.Contains(store.MergeConflictPolicies, store.MergeConflictPolicy(o.Conflict)) {
+		return fmt.Errorf("conflict must be one of %v", store.MergeConflictPolicies)
+	}
+
+	var sources []*store.Layout
+	for _, from := range o.From {
+		abs, err := flags.ResolveStoreDir(ctx, ro, from)
+		if err != nil {
+			return err
+		}
+		if _, err := os.Stat(abs); err != nil {
+			return fmt.Errorf("no store found at [%s]", from)
+		}
+
+		// only overriding StoreDir, everything else still comes from o
+		altOpts := *o.StoreRootOpts
+		altOpts.StoreDir = abs
+		src, err := altOpts.Store(ctx, ro)
+		if err != nil {
+			return err
+		}
+		if src.Root == s.Root {
+			return fmt.Errorf("cannot merge store [%s] into itself", from)
+		}
+		if err := altOpts.LockShared(ctx, src, "hauler store merge"); err != nil {
+			return err
+		}
+		defer src.Unlock()
+		sources = append(sources, src)
+	}
+
+	results, err := s.Merge(ctx, sources, store.MergeOptions{Conflict: store.MergeConflictPolicy(o.Conflict)})
+	if err != nil {
+		var conflictErr *store.MergeConflictError
+		if errors.As(err, &conflictErr) {
+			l.Errorf("rerun with --conflict keep-existing, overwrite, or rename to merge anyway")
+		}
+		return err
+	}
+
+	for i, src := range sources {
+		if !mergedAny(results[i]) {
+			continue
+		}
+		if err := audit.MergeNewStoreLog(src.Root, s.Root); err != nil {
+			l.Warnf("failed to merge audit log from [%s]: %v", src.Root, err)
+		}
+	}
+	types := mergeTypes(ctx, s)
+	auditMerge(ctx, o, s, results, types, ro)
+
+	if o.OutputFormat == "json" {
+		data, err := json.MarshalIndent(struct {
+			StorePath string               `json:"store-path"`
+			StoreID   string               `json:"store-id"`
+			Conflict  string               `json:"conflict"`
+			Sources   []*store.MergeResult `json:"sources"`
+		}{s.Root, s.StoreID, o.Conflict, results}, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	if err := buildMergeTable(s.Root, s.StoreID, results, types); err != nil {
+		return err
+	}
+
+	var blobs int
+	var bytes int64
+	for _, res := range results {
+		blobs += res.Blobs
+		bytes += res.Bytes
+	}
+	l.Infof("merged [%d] store(s) into [%s]... copied [%d] blobs [%s]", len(results), s.Root, blobs, byteCountSI(bytes))
+	return nil
+}
+
+// mergedAny reports whether the merge wrote any of res's entries into the
+// target; a source that added nothing has no history to bring along.
+func mergedAny(res *store.MergeResult) bool {
+	for _, e := range res.Entries {
+		switch e.Action {
+		case store.MergeAdded, store.MergeOverwritten, store.MergeRenamed:
+			return true
+		}
+	}
+	return false
+}
+
+// mergeTypes types every entry of the merged index the way info does, keyed
+// by reference and kind.
+func mergeTypes(ctx context.Context, s *store.Layout) map[string]string {
+	types := map[string]string{}
+	_ = s.Walk(func(_ string, desc ocispec.Descriptor) error {
+		types[desc.Annotations[ocispec.AnnotationRefName]+"|"+desc.Annotations[consts.KindAnnotationName]] = artifactType(ctx, s, desc)
+		return nil
+	})
+	return types
+}
+
+// mergedRef is the reference e ended up under in the target.
+func mergedRef(e store.MergeEntry) string {
+	if e.RenamedTo != "" {
+		return e.RenamedTo
+	}
+	return e.Reference
+}
+
+// auditMerge records each entry the merge wrote into s.
+func auditMerge(ctx context.Context, o *flags.MergeOpts, s *store.Layout, results []*store.MergeResult, types map[string]string, ro *flags.CliRootOpts) {
+	l := log.FromContext(ctx)
+	if auditLevel(ro) == "none" {
+		l.Debugf("generated audit id of [none]")
+		return
+	}
+
+	written := 0
+	for _, res := range results {
+		for _, me := range res.Entries {
+			if me.Action == store.MergeUnchanged || me.Action == store.MergeKept {
+				continue
+			}
+			ref := mergedRef(me)
+			e := audit.Entry{
+				StoreID:   s.StoreID,
+				Store:     s.Root,
+				Type:      types[ref+"|"+me.Kind],
+				Command:   "store merge",
+				Args:      []string{res.Source},
+				Reference: ref,
+				Digest:    me.Digest,
+			}
+			if auditLevel(ro) == "verbose" {
+				sys := audit.BuildSystem()
+				g := audit.BuildGlobal(ro, o.StoreRootOpts)
+				e.System = &sys
+				e.Global = &g
+				e.Flags = map[string]any{
+					"from":     o.From,
+					"conflict": o.Conflict,
+					"action":   me.Action,
+				}
+			}
+			if err := audit.Append(ro.HaulerDir, e); err != nil {
+				l.Warnf("failed to write audit entry: %v", err)
+				return
+			}
+			written++
+		}
+	}
+	if written > 0 {
+		l.Debugf("generated audit id of [%s]", audit.ID())
+	}
+}
+
+func buildMergeTable(storePath, storeID string, results []*store.MergeResult, types map[string]string) error {
+	table := tablewriter.NewTable(os.Stdout)
+	table.Configure(func(cfg *tablewriter.Config) {
+		cfg.Header.Alignment.Global = tw.AlignLeft
+		cfg.Footer.Alignment.PerColumn = []tw.Align{tw.AlignLeft}
+		cfg.Row.Merging.Mode = tw.MergeVertical
+		cfg.Row.Merging.ByColumnIndex = tw.NewBoolMapper(0)
+	})
+	table.Header("Source", "Reference", "Type", "Action")
+
+	var blobs int
+	var bytes int64
+	for _, res := range results {
+		source := res.Source + "\n" + res.SourceID
+		for _, e := range res.Entries {
+			action := e.Action
+			if e.RenamedTo != "" {
+				action += " to " + truncateReference(e.RenamedTo)
+			}
+			if err := table.Append([]string{source, truncateReference(e.Reference), types[mergedRef(e)+"|"+e.Kind], action}); err != nil {
+				return err
+			}
+		}
+		blobs += res.Blobs
+		bytes += res.Bytes
+	}
+
+	table.Footer("store-path: "+storePath+"\nstore-id: "+storeID, "", "Blobs Copied", fmt.Sprintf("%d (%s)", blobs, byteCountSI(bytes)))
+	return table.Render()
+}
@@ -0,0 +1,63 @@
+package store
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"hauler.dev/go/hauler/v2/internal/flags"
+	v1 "hauler.dev/go/hauler/v2/pkg/apis/hauler.cattle.io/v1"
+	"hauler.dev/go/hauler/v2/pkg/audit"
+)
+
+func TestMergeCmd(t *testing.T) {
+	ctx := newTestContext(t)
+	host, _ := newLocalhostRegistry(t)
+	seedImage(t, host, "merge/app", "v1")
+
+	ro := defaultCliOpts()
+	ro.HaulerDir = t.TempDir()
+	ro.AuditLevel = "standard"
+
+	src := newTestStore(t)
+	if err := storeImage(ctx, src, v1.Image{Name: host + "/merge/app:v1"}, "", true, defaultRootOpts(src.Root), ro, "", "", false); err != nil {
+		t.Fatalf("storeImage: %v", err)
+	}
+
+	s := newTestStore(t)
+	o := &flags.MergeOpts{StoreRootOpts: defaultRootOpts(s.Root), From: []string{src.Root}, Conflict: "fail", OutputFormat: "table"}
+	if err := MergeCmd(ctx, o, s, ro); err != nil {
+		t.Fatalf("MergeCmd: %v", err)
+	}
+	if n := countArtifactsInStore(t, s); n != 1 {
+		t.Errorf("merged store holds %d artifacts, want 1", n)
+	}
+
+	// the source's own history comes along, followed by the merge itself
+	data, err := os.ReadFile(filepath.Join(s.Root, audit.LogFileName))
+	if err != nil {
+		t.Fatalf("ReadFile(store audit.log): %v", err)
+	}
+	add, merge := strings.Index(string(data), `"store add image"`), strings.Index(string(data), `"store merge"`)
+	if add == -1 || merge == -1 || add > merge {
+		t.Errorf("store audit.log = %q, want the source's add followed by the merge", data)
+	}
+
+	// merging the same store again adds nothing, so brings no history along
+	if err := MergeCmd(ctx, o, s, ro); err != nil {
+		t.Fatalf("MergeCmd (again): %v", err)
+	}
+	data, err = os.ReadFile(filepath.Join(s.Root, audit.LogFileName))
+	if err != nil {
+		t.Fatalf("ReadFile(store audit.log): %v", err)
+	}
+	if n := strings.Count(string(data), `"store add image"`); n != 1 {
+		t.Errorf("store audit.log holds the source's add %d times after a second merge, want 1:\n%s", n, data)
+	}
+
+	o.From = []string{s.Root}
+	if err := MergeCmd(ctx, o, s, ro); err == nil || !strings.Contains(err.Error(), "into itself") {
+		t.Errorf("MergeCmd(self) = %v, want a refusal", err)
+	}
+}
@@ -0,0 +1,280 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+
+	"hauler.dev/go/hauler/v2/internal/flags"
+	"hauler.dev/go/hauler/v2/pkg/audit"
+	"hauler.dev/go/hauler/v2/pkg/consts"
+	"hauler.dev/go/hauler/v2/pkg/log"
+	"hauler.dev/go/hauler/v2/pkg/store"
+)
+
+// syncPrune collects, per store, the reference names a `store sync --prune`
+// run produced. Names are what the index records in AnnotationRefName, which
+// cosign signatures, attestations, SBOMs, and referrers share with the
+// artifact they belong to -- so keeping a name keeps all of them, and pruning
+// one prunes all of them.
+//
+// Names are recorded when a job starts rather than when it succeeds: under
+// --ignore-errors a failed fetch must not turn the copy already in the store
+// into a prune candidate. A rewritten name is only recorded once the rewrite
+// is applied, so an item that fails before then keeps its original name but
+// not a copy stored under the rewrite. Like syncLock it rides on the context, and every
+// method is a no-op on a nil *syncPrune.
+type syncPrune struct {
+	mu     sync.Mutex
+	stores map[string]*store.Layout
+	keep   map[string]map[string]bool // store root -> AnnotationRefName
+	// repos keeps every tag of a repository, for a chart whose version a
+	// dry run can't resolve without fetching its index
+	repos map[string]map[string]bool // store root -> repository
+	// unsure holds the artifact types a dry run can't name because a chart
+	// adds them once it's fetched: images for --add-images, charts for
+	// --add-dependencies
+	unsure map[string]map[string]string // store root -> type -> chart adding it
+}
+
+func newSyncPrune(o *flags.SyncOpts) *syncPrune {
+	if !o.Prune {
+		return nil
+	}
+	return &syncPrune{
+		stores: map[string]*store.Layout{},
+		keep:   map[string]map[string]bool{},
+		repos:  map[string]map[string]bool{},
+		unsure: map[string]map[string]string{},
+	}
+}
+
+type syncPruneKey struct{}
+
+func withSyncPrune(ctx context.Context, p *syncPrune) context.Context {
+	return context.WithValue(ctx, syncPruneKey{}, p)
+}
+
+func syncPruneFromContext(ctx context.Context) *syncPrune {
+	p, _ := ctx.Value(syncPruneKey{}).(*syncPrune)
+	return p
+}
+
+// target marks s as a store some manifest, product, or image.txt synced to.
+// Only targeted stores are pruned, so a store every document redirected away
+// from (via hauler.dev/store) isn't emptied. A nil s is a store a dry run
+// found missing, with nothing in it to prune.
+func (p *syncPrune) target(s *store.Layout) {
+	if p == nil || s == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, ok := p.stores[s.Root]; !ok {
+		p.stores[s.Root] = s
+		p.keep[s.Root] = map[string]bool{}
+		p.repos[s.Root] = map[string]bool{}
+	}
+}
+
+// keepName records refName (as stored in AnnotationRefName) as wanted in s.
+func (p *syncPrune) keepName(s *store.Layout, refName string) {
+	if p == nil || s == nil {
+		return
+	}
+	p.target(s)
+	p.mu.Lock()
+	p.keep[s.Root][refName] = true
+	p.mu.Unlock()
+}
+
+// keepRepo records every tag of repo (as in AnnotationRefName, without its
+// tag) as wanted in s.
+func (p *syncPrune) keepRepo(s *store.Layout, repo string) {
+	if p == nil || s == nil {
+		return
+	}
+	p.target(s)
+	p.mu.Lock()
+	p.repos[s.Root][repo] = true
+	p.mu.Unlock()
+}
+
+// chartAdds records, for a dry run, that j's chart adds images or dependent
+// charts to s that only fetching it would name.
+func (p *syncPrune) chartAdds(s *store.Layout, j chartJob) {
+	if p == nil || s == nil || !(j.opts.AddImages || j.opts.AddDependencies) {
+		return
+	}
+	p.target(s)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.unsure[s.Root] == nil {
+		p.unsure[s.Root] = map[string]string{}
+	}
+	if j.opts.AddImages {
+		p.unsure[s.Root]["image"] = chartDisplayName(j.cfg.Name)
+	}
+	if j.opts.AddDependencies {
+		p.unsure[s.Root]["chart"] = chartDisplayName(j.cfg.Name)
+	}
+}
+
+// kept reports whether refName was recorded as wanted in the store at root.
+func (p *syncPrune) kept(root, refName string) bool {
+	if p.keep[root][refName] {
+		return true
+	}
+	if i := strings.LastIndex(refName, ":"); i != -1 && p.repos[root][refName[:i]] {
+		return true
+	}
+	return false
+}
+
+// keepImage records the name an image is indexed under: its reference
+// without the registry, matching writeImage/writeIndex.
+func (p *syncPrune) keepImage(s *store.Layout, r name.Reference) {
+	p.keepName(s, strings.TrimPrefix(r.Name(), r.Context().RegistryStr()+"/"))
+}
+
+// prune removes everything not kept from every targeted store, then cleans
+// up the blobs that leaves unreferenced. Under dryRun it only reports what
+// would go.
+func (p *syncPrune) prune(ctx context.Context, o *flags.SyncOpts, rso *flags.StoreRootOpts, ro *flags.CliRootOpts) error {
+	if p == nil {
+		return nil
+	}
+
+	roots := make([]string, 0, len(p.stores))
+	for root := range p.stores {
+		roots = append(roots, root)
+	}
+	sort.Strings(roots)
+
+	for _, root := range roots {
+		kept := func(refName string) bool { return p.kept(root, refName) }
+		if err := pruneStore(ctx, p.stores[root], kept, p.unsure[root], o, rso, ro); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// pruneStore removes the artifacts in s that kept doesn't want. A dry run
+// leaves out those of a type in unsure, which a chart may add.
+func pruneStore(ctx context.Context, s *store.Layout, kept func(refName string) bool, unsure map[string]string, o *flags.SyncOpts, rso *flags.StoreRootOpts, ro *flags.CliRootOpts) error {
+	l := log.FromContext(ctx)
+
+	type candidate struct {
+		reference string
+		desc      ocispec.Descriptor
+	}
+	var candidates []candidate
+	undetermined := map[string]int{}
+	if err := s.Walk(func(reference string, desc ocispec.Descriptor) error {
+		refName, ok := desc.Annotations[ocispec.AnnotationRefName]
+		if !ok || kept(refName) {
+			return nil
+		}
+		if typ := pruneType(ctx, s, desc); unsure[typ] != "" {
+			undetermined[typ]++
+			return nil
+		}
+		candidates = append(candidates, candidate{reference: reference, desc: desc})
+		return nil
+	}); err != nil {
+		return err
+	}
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].reference < candidates[j].reference })
+
+	for _, typ := range []string{"image", "chart"} {
+		if n := undetermined[typ]; n > 0 {
+			l.Infof("left [%d] %s entries of store [%s] out of the dry run... chart [%s] adds %ss that are only known once it's fetched, and a sync keeps whichever it adds",
+				n, typ, s.Root, unsure[typ], typ)
+		}
+	}
+	if len(candidates) == 0 {
+		l.Infof("nothing to prune from store [%s]", s.Root)
+		return nil
+	}
+
+	for _, c := range candidates {
+		kind := artifactType(ctx, s, c.desc)
+		if o.DryRun {
+			l.Infof("would prune [%s] of type [%s] with digest [%s]", formatReference(c.reference), kind, c.desc.Digest.String())
+			continue
+		}
+
+		if err := s.RemoveArtifact(ctx, c.reference, c.desc); err != nil {
+			return fmt.Errorf("failed to prune artifact [%s]: %w", formatReference(c.reference), err)
+		}
+
+		if auditLevel(ro) != "none" {
+			cleanRef := c.desc.Annotations[consts.ContainerdImageNameKey]
+			if cleanRef == "" {
+				cleanRef = c.desc.Annotations[ocispec.AnnotationRefName]
+			}
+			e := audit.Entry{
+				StoreID:   s.StoreID,
+				Store:     s.Root,
+				Type:      kind,
+				Command:   "store sync",
+				Args:      o.FileName,
+				Reference: cleanRef,
+				Digest:    c.desc.Digest.String(),
+			}
+			if auditLevel(ro) == "verbose" {
+				sys := audit.BuildSystem()
+				g := audit.BuildGlobal(ro, rso)
+				e.System = &sys
+				e.Global = &g
+				e.Flags = map[string]any{
+					"prune":     o.Prune,
+					"image-txt": o.ImageTxt,
+					"products":  o.Products,
+				}
+			}
+			if err := audit.Append(ro.HaulerDir, e); err != nil {
+				l.Warnf("failed to write audit entry: %v", err)
+			}
+			l.Debugf("generated audit id of [%s]", audit.ID())
+		} else {
+			l.Debugf("generated audit id of [none]")
+		}
+
+		l.Infof("pruned [%s] of type [%s] with digest [%s]", formatReference(c.reference), kind, c.desc.Digest.String())
+	}
+
+	if o.DryRun {
+		l.Infof("dry run... [%d] artifact(s) would be pruned from store [%s]", len(candidates), s.Root)
+		return nil
+	}
+
+	l.Infof("cleaning up all unreferenced blobs...")
+	res, err := s.CleanUp(ctx)
+	if err != nil {
+		l.Warnf("garbage collection failed: [%v]", err)
+		return nil
+	}
+	if len(res.Blobs) > 0 {
+		l.Infof("successfully removed [%d] unreferenced blobs [freed %d bytes]", len(res.Blobs), res.Freed())
+	}
+	logHeldBlobs(ctx, res)
+	return nil
+}
+
+// pruneType is desc's artifact type as the dry run weighs it: a signature,
+// attestation, sbom, or referrer goes with the image it was saved for.
+func pruneType(ctx context.Context, s *store.Layout, desc ocispec.Descriptor) string {
+	switch typ := artifactType(ctx, s, desc); typ {
+	case "sigs", "atts", "sbom", "referrer":
+		return "image"
+	default:
+		return typ
+	}
+}
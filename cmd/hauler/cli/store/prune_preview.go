@@ -0,0 +1,331 @@
+package store
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	gname "github.com/google/go-containerregistry/pkg/name"
+	"golang.org/x/mod/semver"
+	"helm.sh/helm/v4/pkg/chart/v2/loader"
+	"k8s.io/apimachinery/pkg/util/yaml"
+
+	"hauler.dev/go/hauler/v2/internal/flags"
+	v1 "hauler.dev/go/hauler/v2/pkg/apis/hauler.cattle.io/v1"
+	"hauler.dev/go/hauler/v2/pkg/artifacts/file"
+	"hauler.dev/go/hauler/v2/pkg/consts"
+	"hauler.dev/go/hauler/v2/pkg/content"
+	"hauler.dev/go/hauler/v2/pkg/getter"
+	"hauler.dev/go/hauler/v2/pkg/log"
+	"hauler.dev/go/hauler/v2/pkg/reference"
+	"hauler.dev/go/hauler/v2/pkg/store"
+)
+
+// previewSyncPrune is `store sync --prune --dry-run`: it reads the names a
+// sync would store from its products, manifests, and image.txt files,
+// without fetching any of their content, and reports what --prune would
+// remove. Nothing is written... no store content, temp dir, lockfile, or
+// report.
+//
+// A chart's name and version come from the manifest, or from the chart
+// itself when it's local; an unpinned remote chart keeps every version
+// stored under its name. The images and dependencies a chart adds are only
+// known once it's fetched, so while one does, stored images (or charts) are
+// left out of the report, and counted instead, rather than listed as pruned
+// when a real sync might keep them.
+func previewSyncPrune(ctx context.Context, o *flags.SyncOpts, s *store.Layout, rso *flags.StoreRootOpts, ro *flags.CliRootOpts) error {
+	l := log.FromContext(ctx)
+
+	// --locked settles each chart's version; the lockfile is only read
+	lk, err := newSyncLock(&flags.SyncOpts{Locked: o.Locked, Lockfile: o.Lockfile})
+	if err != nil {
+		return err
+	}
+
+	pr := newSyncPrune(o)
+	targetStores := map[string]*store.Layout{}
+	defer func() {
+		for _, ts := range targetStores {
+			ts.Unlock()
+		}
+	}()
+
+	for _, productName := range o.Products {
+		l.Infof("reading product manifest for [%s]", productName)
+		_, storeRef, _ := productManifestRef(o, productName)
+		pr.keepName(s, storeRef)
+		data, err := fetchProductManifest(ctx, o, productName)
+		if err != nil {
+			return err
+		}
+		if err := previewContent(ctx, data, "", o, s, rso, ro, pr, lk, targetStores); err != nil {
+			return err
+		}
+	}
+
+	for _, fileName := range o.FileName {
+		l.Infof("reading manifest [%s]", fileName)
+		data, err := readSyncInput(ctx, o, fileName)
+		if err != nil {
+			return err
+		}
+		if err := previewContent(ctx, data, filepath.Dir(fileName), o, s, rso, ro, pr, lk, targetStores); err != nil {
+			return err
+		}
+	}
+
+	for _, imageTxt := range o.ImageTxt {
+		l.Infof("reading image.txt [%s]", imageTxt)
+		data, err := readSyncInput(ctx, o, imageTxt)
+		if err != nil {
+			return err
+		}
+		pr.target(s)
+		scanner := bufio.NewScanner(bytes.NewReader(data))
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			if err := previewImage(pr, s, line, ""); err != nil {
+				return err
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return err
+		}
+	}
+
+	return pr.prune(ctx, o, rso, ro)
+}
+
+// readSyncInput reads a local or remote manifest or image.txt into memory.
+func readSyncInput(ctx context.Context, o *flags.SyncOpts, path string) ([]byte, error) {
+	if !strings.HasPrefix(path, "http://") && !strings.HasPrefix(path, "https://") {
+		return os.ReadFile(path)
+	}
+
+	h := getter.NewHttp(o.InsecureSkipTLSVerify, o.CaFile)
+	parsedURL, err := url.Parse(path)
+	if err != nil {
+		return nil, err
+	}
+	rc, err := h.Open(ctx, parsedURL)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(rc)
+}
+
+// previewContent records the names every document of a manifest would be
+// stored under, mirroring processContent.
+func previewContent(ctx context.Context, data []byte, manifestDir string, o *flags.SyncOpts, s *store.Layout, rso *flags.StoreRootOpts, ro *flags.CliRootOpts, pr *syncPrune, lk *syncLock, targetStores map[string]*store.Layout) error {
+	l := log.FromContext(ctx)
+
+	reader := yaml.NewYAMLReader(bufio.NewReader(bytes.NewReader(data)))
+	for {
+		doc, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		obj, err := content.Load(doc)
+		if err != nil {
+			l.Warnf("skipping syncing due to %v", err)
+			continue
+		}
+		gvk := obj.GroupVersionKind()
+		if gvk.Version != "v1" {
+			return fmt.Errorf("unsupported version [%s] for kind [%s]... valid versions are [v1]", gvk.Version, gvk.Kind)
+		}
+
+		switch gvk.Kind {
+		case consts.FilesContentKind:
+			var cfg v1.Files
+			if err := yaml.Unmarshal(doc, &cfg); err != nil {
+				return err
+			}
+			docStore, err := previewTargetStore(ctx, cfg.GetAnnotations(), s, o, rso, ro, targetStores)
+			if err != nil {
+				return err
+			}
+			if docStore == nil {
+				continue
+			}
+			pr.target(docStore)
+			for _, j := range resolveFileJobs(o, cfg.GetAnnotations(), cfg.Spec.Files) {
+				f := file.NewFile(j.file.Path, file.WithClient(getter.NewClient(getter.ClientOptions{NameOverride: j.file.Name})))
+				ref, err := reference.NewTagged(f.Name(j.file.Path), consts.DefaultTag)
+				if err != nil {
+					return fmt.Errorf("unable to derive a store reference for file [%s]: %w", j.file.Path, err)
+				}
+				pr.keepName(docStore, ref.Name())
+			}
+
+		case consts.ImagesContentKind:
+			var cfg v1.Images
+			if err := yaml.Unmarshal(doc, &cfg); err != nil {
+				return err
+			}
+			docStore, err := previewTargetStore(ctx, cfg.GetAnnotations(), s, o, rso, ro, targetStores)
+			if err != nil {
+				return err
+			}
+			if docStore == nil {
+				continue
+			}
+			pr.target(docStore)
+			jobs, err := resolveImageJobs(o, cfg.GetAnnotations(), cfg.Spec.Images)
+			if err != nil {
+				return err
+			}
+			for _, j := range jobs {
+				if err := previewImage(pr, docStore, j.img.Name, j.rewrite); err != nil {
+					return err
+				}
+			}
+
+		case consts.ChartsContentKind:
+			var cfg v1.Charts
+			if err := yaml.Unmarshal(doc, &cfg); err != nil {
+				return err
+			}
+			docStore, err := previewTargetStore(ctx, cfg.GetAnnotations(), s, o, rso, ro, targetStores)
+			if err != nil {
+				return err
+			}
+			if docStore == nil {
+				continue
+			}
+			pr.target(docStore)
+			jobs, err := resolveChartJobs(o, cfg.GetAnnotations(), manifestDir, cfg.Spec.Charts)
+			if err != nil {
+				return err
+			}
+			for _, j := range jobs {
+				pr.chartAdds(docStore, j)
+				if err := previewChart(pr, docStore, lk, j); err != nil {
+					return err
+				}
+			}
+
+		default:
+			return fmt.Errorf("unsupported kind [%s]... valid kinds are [Files, Images, Charts]", gvk.Kind)
+		}
+	}
+}
+
+// previewTargetStore is resolveTargetStore for a dry run: a target store
+// that doesn't exist has nothing to prune, so it's returned as nil rather
+// than created, and an existing one is only locked shared. def is nil when
+// the default store doesn't exist either.
+func previewTargetStore(ctx context.Context, a map[string]string, def *store.Layout, o *flags.SyncOpts, rso *flags.StoreRootOpts, ro *flags.CliRootOpts, targetStores map[string]*store.Layout) (*store.Layout, error) {
+	target := a[consts.AnnotationTargetStore]
+	if o.StoreChanged || target == "" {
+		return def, nil
+	}
+
+	abs, err := flags.ResolveStoreDir(ctx, ro, target)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve target store [%s]: %w", target, err)
+	}
+	if def != nil && abs == def.Root {
+		return def, nil
+	}
+	if ts, ok := targetStores[abs]; ok {
+		return ts, nil
+	}
+	if _, err := os.Stat(abs); os.IsNotExist(err) {
+		log.FromContext(ctx).Infof("nothing to prune from store [%s]... it doesn't exist yet", abs)
+		return nil, nil
+	}
+
+	altOpts := *rso
+	altOpts.StoreDir = abs
+	ts, err := altOpts.Store(ctx, ro)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open target store [%s]: %w", target, err)
+	}
+	if err := altOpts.LockShared(ctx, ts, "hauler store sync"); err != nil {
+		return nil, err
+	}
+	targetStores[abs] = ts
+	return ts, nil
+}
+
+// previewImage records the name the image called imageName is stored under,
+// and the one a rewrite gives it.
+func previewImage(pr *syncPrune, s *store.Layout, imageName, rewrite string) error {
+	r, err := gname.ParseReference(imageName)
+	if err != nil {
+		return fmt.Errorf("unable to parse image [%s]: %w", imageName, err)
+	}
+	pr.keepImage(s, r)
+	if rewrite == "" {
+		return nil
+	}
+	newRef, err := imageRewriteRef(r, rewrite)
+	if err != nil {
+		return err
+	}
+	_, _, newTotal, _ := rewriteNames(r, newRef, rewrite)
+	pr.keepName(s, newTotal)
+	return nil
+}
+
+// previewChart records the name j's chart is stored under: exactly when its
+// version is pinned, or else every version stored under its name.
+func previewChart(pr *syncPrune, s *store.Layout, lk *syncLock, j chartJob) error {
+	version, err := lk.lockedChartVersion(j)
+	if err != nil {
+		return err
+	}
+
+	chartName := path.Base(strings.TrimSuffix(j.cfg.Name, "/"))
+	if j.opts.ChartOpts.RepoURL == "" {
+		// a local chart names and versions itself
+		if c, err := loader.Load(j.cfg.Name); err == nil {
+			chartName, version = c.Name(), c.Metadata.Version
+		}
+	}
+
+	exact := semver.IsValid("v"+strings.TrimPrefix(version, "v")) && strings.Count(strings.SplitN(version, "-", 2)[0], ".") == 2
+	tag := version
+	if !exact {
+		tag = consts.DefaultTag
+	}
+	ref, err := reference.NewTagged(chartName, tag)
+	if err != nil {
+		return fmt.Errorf("unable to derive a store reference for chart [%s]: %w", j.cfg.Name, err)
+	}
+	if exact {
+		pr.keepName(s, ref.Name())
+	} else {
+		pr.keepRepo(s, ref.Context().Name())
+	}
+
+	if j.rewrite == "" {
+		return nil
+	}
+	_, newTotal, err := chartRewriteNames(ref, j.rewrite)
+	if err != nil {
+		return err
+	}
+	if exact || strings.Contains(strings.TrimPrefix(j.rewrite, "/"), ":") {
+		pr.keepName(s, newTotal)
+	} else {
+		pr.keepRepo(s, newTotal[:strings.LastIndex(newTotal, ":")])
+	}
+	return nil
+}
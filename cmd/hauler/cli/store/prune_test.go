@@ -0,0 +1,194 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/rs/zerolog"
+
+	"hauler.dev/go/hauler/v2/pkg/store"
+)
+
+func imagesManifest(host string, repos ...string) string {
+	var b strings.Builder
+	b.WriteString("apiVersion: content.hauler.cattle.io/v1\nkind: Images\nmetadata:\n  name: prune\nspec:\n  images:\n")
+	for _, r := range repos {
+		fmt.Fprintf(&b, "    - name: %s/%s:v1\n", host, r)
+	}
+	return b.String()
+}
+
+// refNamesInStore returns how many index entries carry each AnnotationRefName.
+func refNamesInStore(t *testing.T, s *store.Layout) map[string]int {
+	t.Helper()
+	names := map[string]int{}
+	if err := s.OCI.Walk(func(_ string, desc ocispec.Descriptor) error {
+		names[desc.Annotations[ocispec.AnnotationRefName]]++
+		return nil
+	}); err != nil {
+		t.Fatalf("walk: %v", err)
+	}
+	return names
+}
+
+func TestSyncCmd_Prune(t *testing.T) {
+	ctx := newTestContext(t)
+	host, rOpts := newLocalhostRegistry(t)
+	seedImage(t, host, "prune/keep", "v1")
+	gone := seedImage(t, host, "prune/gone", "v1")
+	seedCosignV2Artifacts(t, host, "prune/gone", gone, rOpts...)
+
+	s := newTestStore(t)
+	o := newSyncOpts(s.Root)
+	o.FileName = []string{writeTempFile(t, "hauler-manifest.yaml", imagesManifest(host, "prune/keep", "prune/gone"))}
+	if err := SyncCmd(ctx, o, s, o.StoreRootOpts, defaultCliOpts()); err != nil {
+		t.Fatalf("SyncCmd: %v", err)
+	}
+	if n := refNamesInStore(t, s)["prune/gone:v1"]; n < 2 {
+		t.Fatalf("expected prune/gone:v1 with its signatures, got %d entries", n)
+	}
+
+	o.FileName = []string{writeTempFile(t, "hauler-manifest.yaml", imagesManifest(host, "prune/keep"))}
+	o.Prune = true
+
+	// --dry-run leaves the store as it is
+	o.DryRun = true
+	before := countArtifactsInStore(t, s)
+	if err := SyncCmd(ctx, o, s, o.StoreRootOpts, defaultCliOpts()); err != nil {
+		t.Fatalf("SyncCmd --prune --dry-run: %v", err)
+	}
+	if after := countArtifactsInStore(t, s); after != before {
+		t.Fatalf("--dry-run changed the store: %d artifacts, want %d", after, before)
+	}
+
+	o.DryRun = false
+	if err := SyncCmd(ctx, o, s, o.StoreRootOpts, defaultCliOpts()); err != nil {
+		t.Fatalf("SyncCmd --prune: %v", err)
+	}
+	names := refNamesInStore(t, s)
+	if n := names["prune/gone:v1"]; n != 0 {
+		t.Errorf("prune/gone:v1 still has %d entries after --prune", n)
+	}
+	if n := names["prune/keep:v1"]; n == 0 {
+		t.Errorf("prune/keep:v1 was pruned")
+	}
+}
+
+func TestSyncCmd_Prune_KeepsFailedItems(t *testing.T) {
+	ctx := newTestContext(t)
+	host, _ := newLocalhostRegistry(t)
+	seedImage(t, host, "prune/keep", "v1")
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/flaky.txt", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("content")) //nolint:errcheck
+	})
+	srv := httptest.NewServer(mux)
+	manifest := writeTempFile(t, "hauler-manifest.yaml", imagesManifest(host, "prune/keep")+fmt.Sprintf(`---
+apiVersion: content.hauler.cattle.io/v1
+kind: Files
+metadata:
+  name: prune-files
+spec:
+  files:
+    - path: %s/flaky.txt
+`, srv.URL))
+
+	s := newTestStore(t)
+	o := newSyncOpts(s.Root)
+	o.FileName = []string{manifest}
+	if err := SyncCmd(ctx, o, s, o.StoreRootOpts, defaultCliOpts()); err != nil {
+		t.Fatalf("SyncCmd: %v", err)
+	}
+	srv.Close()
+
+	// the file can no longer be fetched... under --ignore-errors the copy
+	// already in the store must survive the prune
+	ro := defaultCliOpts()
+	ro.IgnoreErrors = true
+	o.Prune = true
+	if err := SyncCmd(ctx, o, s, o.StoreRootOpts, ro); err != nil {
+		t.Fatalf("SyncCmd --prune: %v", err)
+	}
+	assertArtifactInStore(t, s, "flaky.txt")
+	assertArtifactInStore(t, s, "prune/keep:v1")
+}
+
+func TestSyncCmd_PruneDryRun(t *testing.T) {
+	host, _ := newLocalhostRegistry(t)
+	seedImage(t, host, "prune/keep", "v1")
+	seedImage(t, host, "prune/gone", "v1")
+	seedImage(t, host, "prune/new", "v1")
+
+	s := newTestStore(t)
+	o := newSyncOpts(s.Root)
+	o.FileName = []string{writeTempFile(t, "hauler-manifest.yaml", imagesManifest(host, "prune/keep", "prune/gone"))}
+	if err := SyncCmd(newTestContext(t), o, s, o.StoreRootOpts, defaultCliOpts()); err != nil {
+		t.Fatalf("SyncCmd: %v", err)
+	}
+	before := refNamesInStore(t, s)
+
+	var logs bytes.Buffer
+	ctx := zerolog.New(&logs).WithContext(context.Background())
+	o.FileName = []string{writeTempFile(t, "hauler-manifest.yaml", imagesManifest(host, "prune/keep", "prune/new"))}
+	o.Prune = true
+	o.DryRun = true
+	if err := SyncCmd(ctx, o, s, o.StoreRootOpts, defaultCliOpts()); err != nil {
+		t.Fatalf("SyncCmd --prune --dry-run: %v", err)
+	}
+
+	// nothing is fetched or removed... prune/new stays out of the store
+	if after := refNamesInStore(t, s); !reflect.DeepEqual(after, before) {
+		t.Errorf("--dry-run changed the store: %v, want %v", after, before)
+	}
+	var pruned []string
+	for _, line := range strings.Split(logs.String(), "\n") {
+		if strings.Contains(line, "would prune") {
+			pruned = append(pruned, line)
+		}
+	}
+	if len(pruned) == 0 || !strings.Contains(strings.Join(pruned, "\n"), "prune/gone") {
+		t.Errorf("--dry-run didn't report prune/gone as pruned: %v", pruned)
+	}
+	for _, line := range pruned {
+		if strings.Contains(line, "prune/keep") {
+			t.Errorf("--dry-run reported prune/keep as pruned: %s", line)
+		}
+	}
+
+	// a chart adding the images it references may keep prune/gone, so it's
+	// left out of the report rather than listed as pruned
+	logs.Reset()
+	o.FileName = []string{writeTempFile(t, "hauler-manifest.yaml", imagesManifest(host, "prune/keep")+`---
+apiVersion: content.hauler.cattle.io/v1
+kind: Charts
+metadata:
+  name: prune-charts
+spec:
+  charts:
+    - name: `+t.TempDir()+`/app
+      version: 1.0.0
+      add-images: true
+`)}
+	if err := SyncCmd(ctx, o, s, o.StoreRootOpts, defaultCliOpts()); err != nil {
+		t.Fatalf("SyncCmd --prune --dry-run with a chart: %v", err)
+	}
+	if strings.Contains(logs.String(), "would prune") {
+		t.Errorf("--dry-run listed an image a chart may add as pruned:\n%s", logs.String())
+	}
+	if !strings.Contains(logs.String(), "left [1] image entries") {
+		t.Errorf("--dry-run didn't say it left the image out:\n%s", logs.String())
+	}
+
+	// a store that doesn't exist has nothing to prune, and isn't created
+	if err := SyncCmd(ctx, o, nil, o.StoreRootOpts, defaultCliOpts()); err != nil {
+		t.Errorf("SyncCmd --prune --dry-run without a store: %v", err)
+	}
+}
@@ -3,7 +3,6 @@ package store
 import (
 	"bufio"
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
@@ -23,38 +22,7 @@ import (
 // does: from the manifest's config media type, since AddArtifact stores every non-image-command
 // artifact (files, charts) under the same "kind" annotation and can't distinguish them
 func artifactType(ctx context.Context, s *store.Layout, desc ocispec.Descriptor) string {
-	switch {
-	case desc.Annotations[consts.KindAnnotationName] == consts.KindAnnotationSigs:
-		return "sigs"
-	case desc.Annotations[consts.KindAnnotationName] == consts.KindAnnotationAtts:
-		return "atts"
-	case desc.Annotations[consts.KindAnnotationName] == consts.KindAnnotationSboms:
-		return "sbom"
-	case strings.HasPrefix(desc.Annotations[consts.KindAnnotationName], consts.KindAnnotationReferrers):
-		return "referrer"
-	case desc.MediaType == consts.OCIImageIndexSchema, desc.MediaType == consts.DockerManifestListSchema2:
-		return "image"
-	}
-
-	rc, err := s.Fetch(ctx, desc)
-	if err != nil {
-		return "image"
-	}
-	defer rc.Close()
-
-	var m ocispec.Manifest
-	if err := json.NewDecoder(rc).Decode(&m); err != nil {
-		return "image"
-	}
-
-	switch m.Config.MediaType {
-	case consts.ChartConfigMediaType:
-		return "chart"
-	case consts.FileLocalConfigMediaType, consts.FileHttpConfigMediaType, consts.FileDirectoryConfigMediaType:
-		return "file"
-	default:
-		return "image"
-	}
+	return s.ArtifactType(ctx, desc)
 }
 
 func formatReference(ref string) string {
@@ -83,35 +51,63 @@ func formatReference(ref string) string {
 func RemoveCmd(ctx context.Context, o *flags.RemoveOpts, s *store.Layout, ref string, ro *flags.CliRootOpts, rso *flags.StoreRootOpts) error {
 	l := log.FromContext(ctx)
 
+	if (ref == "") == (o.Select == "") {
+		return fmt.Errorf("specify either an artifact reference or --select")
+	}
+
 	// collect matching artifacts
 	type match struct {
 		reference string
 		desc      ocispec.Descriptor
+		typ       string
 	}
 	var matches []match
 
-	if err := s.Walk(func(reference string, desc ocispec.Descriptor) error {
-		registryRef := desc.Annotations[consts.ContainerdImageNameKey]
-		if registryRef == "" {
-			registryRef = desc.Annotations[ocispec.AnnotationRefName]
+	if o.Select != "" {
+		selected, err := selectArtifacts(ctx, s, o.Select, true)
+		if err != nil {
+			return err
 		}
-
-		if !strings.Contains(reference, ref) && !strings.Contains(registryRef, ref) {
-			return nil
+		for _, sa := range selected {
+			matches = append(matches, match{reference: sa.Key, desc: sa.Desc, typ: sa.Type})
 		}
+		if len(matches) == 0 {
+			return fmt.Errorf("no artifacts in store match selector [%s]", o.Select)
+		}
+	} else {
+		if err := s.Walk(func(reference string, desc ocispec.Descriptor) error {
+			registryRef := desc.Annotations[consts.ContainerdImageNameKey]
+			if registryRef == "" {
+				registryRef = desc.Annotations[ocispec.AnnotationRefName]
+			}
+
+			if !strings.Contains(reference, ref) && !strings.Contains(registryRef, ref) {
+				return nil
+			}
+
+			matches = append(matches, match{
+				reference: reference,
+				desc:      desc,
+				typ:       artifactType(ctx, s, desc),
+			})
 
-		matches = append(matches, match{
-			reference: reference,
-			desc:      desc,
-		})
+			return nil // continue walking
+		}); err != nil {
+			return err
+		}
 
-		return nil // continue walking
-	}); err != nil {
-		return err
+		if len(matches) == 0 {
+			return fmt.Errorf("reference [%s] not found in store (use `hauler store info` to list store contents)", ref)
+		}
 	}
 
-	if len(matches) == 0 {
-		return fmt.Errorf("reference [%s] not found in store (use `hauler store info` to list store contents)", ref)
+	if o.DryRun {
+		selected := make([]store.Selected, 0, len(matches))
+		for _, m := range matches {
+			selected = append(selected, store.Selected{Key: m.reference, Desc: m.desc, Type: m.typ})
+		}
+		logSelection(ctx, "remove", "removed", selected)
+		return nil
 	}
 
 	if len(matches) >= 1 {
@@ -146,6 +142,11 @@ func RemoveCmd(ctx context.Context, o *flags.RemoveOpts, s *store.Layout, ref st
 		}
 	}
 
+	args := []string{ref}
+	if o.Select != "" {
+		args = []string{o.Select}
+	}
+
 	// remove artifact(s)
 	for _, m := range matches {
 		if err := s.RemoveArtifact(ctx, m.reference, m.desc); err != nil {
@@ -160,9 +161,9 @@ func RemoveCmd(ctx context.Context, o *flags.RemoveOpts, s *store.Layout, ref st
 			e := audit.Entry{
 				StoreID:   s.StoreID,
 				Store:     s.Root,
-				Type:      artifactType(ctx, s, m.desc),
+				Type:      m.typ,
 				Command:   "store remove",
-				Args:      []string{ref},
+				Args:      args,
 				Reference: cleanRef,
 				Digest:    m.desc.Digest.String(),
 			}
@@ -172,7 +173,8 @@ func RemoveCmd(ctx context.Context, o *flags.RemoveOpts, s *store.Layout, ref st
 				e.System = &sys
 				e.Global = &g
 				e.Flags = map[string]any{
-					"force": o.Force,
+					"force":  o.Force,
+					"select": o.Select,
 				}
 			}
 			if err := audit.Append(ro.HaulerDir, e); err != nil {
@@ -188,12 +190,15 @@ func RemoveCmd(ctx context.Context, o *flags.RemoveOpts, s *store.Layout, ref st
 
 	// clean up unreferenced blobs
 	l.Infof("cleaning up all unreferenced blobs...")
-	removedCount, removedSize, err := s.CleanUp(ctx)
+	res, err := s.CleanUp(ctx)
 	if err != nil {
 		l.Warnf("garbage collection failed: [%v]", err)
-	} else if removedCount > 0 {
-		l.Infof("successfully removed [%d] unreferenced blobs [freed %d bytes]", removedCount, removedSize)
+		return nil
+	}
+	if len(res.Blobs) > 0 {
+		l.Infof("successfully removed [%d] unreferenced blobs [freed %d bytes]", len(res.Blobs), res.Freed())
 	}
+	logHeldBlobs(ctx, res)
 
 	return nil
 }
@@ -187,3 +187,48 @@ func TestRemoveCmd_Force_MultipleMatches(t *testing.T) {
 		t.Errorf("expected 0 artifacts after removal of both, got %d", n)
 	}
 }
+
+func TestRemoveCmd_Select(t *testing.T) {
+	ctx := newTestContext(t)
+	s := newTestStore(t)
+	ro, rso := defaultCliOpts(), defaultRootOpts(s.Root)
+	// added terms read the store's audit log
+	ro.AuditLevel, ro.HaulerDir = "standard", t.TempDir()
+
+	for _, name := range []string{"keep.txt", "drop.txt"} {
+		url := seedFileInHTTPServer(t, name, "content of "+name)
+		if err := storeFile(ctx, s, v1.File{Path: url}, ro, rso); err != nil {
+			t.Fatalf("storeFile(%s): %v", name, err)
+		}
+	}
+
+	if err := RemoveCmd(ctx, &flags.RemoveOpts{Force: true, Select: "type=file"}, s, "drop", ro, rso); err == nil {
+		t.Error("RemoveCmd with both a reference and --select = nil error, want one")
+	}
+
+	// both were just added, so neither was added over an hour ago
+	if err := RemoveCmd(ctx, &flags.RemoveOpts{Force: true, Select: "type=file,added<1h"}, s, "", ro, rso); err == nil {
+		t.Error("RemoveCmd(added<1h) = nil error, want no matches")
+	}
+
+	if err := RemoveCmd(ctx, &flags.RemoveOpts{Force: true, Select: "type=file,ref=*drop*", DryRun: true}, s, "", ro, rso); err != nil {
+		t.Fatalf("RemoveCmd(--dry-run): %v", err)
+	}
+	if n := countArtifactsInStore(t, s); n != 2 {
+		t.Fatalf("--dry-run left %d artifacts, want 2", n)
+	}
+
+	if err := RemoveCmd(ctx, &flags.RemoveOpts{Force: true, Select: "type=file,!ref=*keep*,added>1h"}, s, "", ro, rso); err != nil {
+		t.Fatalf("RemoveCmd: %v", err)
+	}
+	var left []string
+	if err := s.Walk(func(reference string, _ ocispec.Descriptor) error {
+		left = append(left, reference)
+		return nil
+	}); err != nil {
+		t.Fatalf("Walk: %v", err)
+	}
+	if len(left) != 1 || !strings.Contains(left[0], "keep") {
+		t.Errorf("store holds %v after removing the selection, want only keep.txt", left)
+	}
+}
@@ -0,0 +1,408 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+
+	"hauler.dev/go/hauler/v2/internal/flags"
+	"hauler.dev/go/hauler/v2/pkg/archives"
+	"hauler.dev/go/hauler/v2/pkg/audit"
+	"hauler.dev/go/hauler/v2/pkg/consts"
+	"hauler.dev/go/hauler/v2/pkg/content"
+	"hauler.dev/go/hauler/v2/pkg/log"
+	"hauler.dev/go/hauler/v2/pkg/store"
+)
+
+type repairOutput struct {
+	StorePath  string           `json:"store-path"`
+	StoreID    string           `json:"store-id"`
+	Checked    int              `json:"checked"`
+	Repaired   int              `json:"repaired"`
+	Unrepaired int              `json:"unrepaired"`
+	Artifacts  []repairArtifact `json:"artifacts"`
+}
+
+// repairArtifact is one damaged artifact and what became of each bad blob.
+type repairArtifact struct {
+	Reference string       `json:"reference"`
+	Type      string       `json:"type"`
+	Digest    string       `json:"digest"`
+	Blobs     []repairBlob `json:"blobs"`
+}
+
+type repairBlob struct {
+	Digest   string `json:"digest"`
+	Problem  string `json:"problem"`
+	Detail   string `json:"detail,omitempty"`
+	Repaired bool   `json:"repaired"`
+	Source   string `json:"source,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// repairSource is somewhere one blob might be re-fetched from.
+type repairSource struct {
+	name string
+	open func() (io.ReadCloser, error)
+}
+
+type repairer struct {
+	s          *store.Layout
+	hauls      []string // layout roots to copy blobs from, in --haul order
+	mirror     string
+	nameOpts   []name.Option
+	remoteOpts []remote.Option
+	client     *http.Client
+}
+
+// RepairCmd finds damaged blobs with the same checker `info --check` uses and
+// rewrites each from the first source that serves its exact digest: the hauls
+// given with --haul, in order, then the registry the artifact came from (its
+// hauler.dev/original-ref repository, or the same repository on --mirror).
+// Only the bad blobs are rewritten; index.json is left alone. A file fetched
+// from a URL or local path can have its content re-read from there, but the
+// manifests hauler builds itself for files and charts only come back from a
+// haul. Anything left unrepaired fails the command.
+func RepairCmd(ctx context.Context, o *flags.RepairOpts, s *store.Layout, ro *flags.CliRootOpts) error {
+	l := log.FromContext(ctx)
+
+	tr, err := content.BuildTransport(o.InsecureSkipTLSVerify, o.CaFile)
+	if err != nil {
+		return err
+	}
+	r := &repairer{
+		s:      s,
+		mirror: o.Mirror,
+		remoteOpts: []remote.Option{
+			remote.WithAuthFromKeychain(authn.DefaultKeychain),
+			remote.WithContext(ctx),
+			remote.WithTransport(tr),
+		},
+		client: &http.Client{Transport: tr},
+	}
+	if o.InsecureSkipTLSVerify {
+		r.nameOpts = append(r.nameOpts, name.Insecure)
+	}
+
+	if len(o.Hauls) > 0 {
+		tempDir, err := os.MkdirTemp(o.TempOverride, consts.DefaultHaulerTempDirName)
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(tempDir)
+
+		for i, h := range o.Hauls {
+			root, err := openHaul(ctx, h, filepath.Join(tempDir, fmt.Sprint(i)))
+			if err != nil {
+				return fmt.Errorf("failed to open haul [%s]: %w", h, err)
+			}
+			r.hauls = append(r.hauls, root)
+		}
+	}
+
+	var entries []storedImage
+	if err := s.Walk(func(_ string, desc ocispec.Descriptor) error {
+		if refName := desc.Annotations[ocispec.AnnotationRefName]; refName != "" {
+			entries = append(entries, storedImage{ref: refName, desc: desc})
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].ref < entries[j].ref })
+	l.Infof("checking integrity of %d artifacts... this reads every blob in the store", len(entries))
+
+	out := repairOutput{StorePath: s.Root, StoreID: s.StoreID, Checked: len(entries), Artifacts: []repairArtifact{}}
+	for _, e := range entries {
+		a, err := r.repairArtifact(ctx, e)
+		if err != nil {
+			return err
+		}
+		if a == nil {
+			continue
+		}
+		repaired := 0
+		for _, b := range a.Blobs {
+			if b.Repaired {
+				repaired++
+			} else {
+				out.Unrepaired++
+			}
+		}
+		out.Repaired += repaired
+		out.Artifacts = append(out.Artifacts, *a)
+
+		if repaired > 0 && auditLevel(ro) != "none" {
+			ae := audit.Entry{
+				StoreID:   s.StoreID,
+				Store:     s.Root,
+				Type:      a.Type,
+				Command:   "store repair",
+				Reference: e.desc.Annotations[consts.OriginalRefAnnotation],
+				Digest:    a.Digest,
+			}
+			if ae.Reference == "" {
+				ae.Reference = a.Reference
+			}
+			if auditLevel(ro) == "verbose" {
+				sys := audit.BuildSystem()
+				g := audit.BuildGlobal(ro, o.StoreRootOpts)
+				ae.System = &sys
+				ae.Global = &g
+				ae.Flags = map[string]any{
+					"mirror": o.Mirror,
+					"haul":   o.Hauls,
+				}
+			}
+			if err := audit.Append(ro.HaulerDir, ae); err != nil {
+				l.Warnf("failed to write audit entry: %v", err)
+			}
+			l.Debugf("generated audit id of [%s]", audit.ID())
+		}
+	}
+
+	if o.OutputFormat == "json" {
+		data, err := json.MarshalIndent(out, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+	}
+
+	switch {
+	case out.Unrepaired > 0:
+		return fmt.Errorf("[%d] of [%d] damaged blob(s) could not be repaired", out.Unrepaired, out.Repaired+out.Unrepaired)
+	case out.Repaired > 0:
+		l.Infof("repaired [%d] blob(s) across [%d] artifact(s)", out.Repaired, len(out.Artifacts))
+	default:
+		l.Infof("no damaged blobs found in [%d] artifact(s)", len(entries))
+	}
+	return nil
+}
+
+// repairArtifact checks e and repairs what it can, returning nil when e was
+// intact. Repairing a manifest can reveal damage beneath it that the checker
+// could not see past, so e is rechecked until a pass repairs nothing new.
+func (r *repairer) repairArtifact(ctx context.Context, e storedImage) (*repairArtifact, error) {
+	l := log.FromContext(ctx)
+
+	var blobs []repairBlob
+	seen := map[string]bool{}
+	for {
+		res := r.s.NewChecker().Check(ctx, e.desc)
+		if res.OK {
+			break
+		}
+		progressed := false
+		for _, p := range res.Problems {
+			if seen[p.Digest] {
+				continue
+			}
+			seen[p.Digest] = true
+
+			b := repairBlob{Digest: p.Digest, Problem: string(p.Status), Detail: p.Detail}
+			src, err := r.repairBlob(ctx, e, p.Digest)
+			if errors.Is(err, context.Canceled) {
+				return nil, err
+			}
+			if err != nil {
+				b.Error = err.Error()
+				l.Errorf("unable to repair blob [%s] of [%s] (%s): %v", p.Digest, e.ref, p.Status, err)
+			} else {
+				b.Repaired, b.Source = true, src
+				progressed = true
+				l.Infof("repaired blob [%s] of [%s] (%s) from [%s]", p.Digest, e.ref, p.Status, src)
+			}
+			blobs = append(blobs, b)
+		}
+		if !progressed {
+			break
+		}
+	}
+	if len(blobs) == 0 {
+		return nil, nil
+	}
+	return &repairArtifact{
+		Reference: e.ref,
+		Type:      artifactType(ctx, r.s, e.desc),
+		Digest:    e.desc.Digest.String(),
+		Blobs:     blobs,
+	}, nil
+}
+
+// repairBlob rewrites dgst from the first source that serves it, returning
+// that source's name.
+func (r *repairer) repairBlob(ctx context.Context, e storedImage, dgst string) (string, error) {
+	d, err := digest.Parse(dgst)
+	if err != nil {
+		return "", err
+	}
+
+	sources := r.sources(ctx, e, d)
+	if len(sources) == 0 {
+		return "", fmt.Errorf("no source can serve this blob (use --haul to supply a haul that holds it)")
+	}
+
+	var errs []string
+	for _, src := range sources {
+		err := r.s.RepairBlob(ctx, d, src.open)
+		if err == nil {
+			return src.name, nil
+		}
+		if errors.Is(err, context.Canceled) {
+			return "", err
+		}
+		errs = append(errs, fmt.Sprintf("%s: %v", src.name, err))
+	}
+	return "", errors.New(strings.Join(errs, "; "))
+}
+
+// sources lists where d, a blob of e, might be re-fetched from.
+func (r *repairer) sources(ctx context.Context, e storedImage, d digest.Digest) []repairSource {
+	var sources []repairSource
+	for _, root := range r.hauls {
+		p := filepath.Join(root, ocispec.ImageBlobsDir, d.Algorithm().String(), d.Encoded())
+		sources = append(sources, repairSource{name: "haul " + root, open: func() (io.ReadCloser, error) { return os.Open(p) }})
+	}
+
+	orig := e.desc.Annotations[consts.OriginalRefAnnotation]
+	if orig == "" {
+		orig = e.desc.Annotations[consts.ContainerdImageNameKey]
+	}
+
+	// every kind hauler pulls from a registry (images and the signatures,
+	// attestations, sboms and referrers saved with them) shares its
+	// subject's repository, which serves any of its blobs by digest
+	if strings.HasPrefix(e.desc.Annotations[consts.KindAnnotationName], "dev.hauler/") {
+		if src, ok := r.registrySource(orig, e.desc.Digest == d, d); ok {
+			sources = append(sources, src)
+		}
+		return sources
+	}
+
+	if src, ok := r.fileSource(ctx, e, orig, d); ok {
+		sources = append(sources, src)
+	}
+	return sources
+}
+
+func (r *repairer) registrySource(orig string, isManifest bool, d digest.Digest) (repairSource, bool) {
+	ref, err := name.ParseReference(orig, r.nameOpts...)
+	if err != nil {
+		return repairSource{}, false
+	}
+	repo := ref.Context()
+	if r.mirror != "" {
+		if repo, err = name.NewRepository(strings.TrimSuffix(r.mirror, "/")+"/"+repo.RepositoryStr(), r.nameOpts...); err != nil {
+			return repairSource{}, false
+		}
+	}
+	dref := repo.Digest(d.String())
+
+	fetchManifest := func() (io.ReadCloser, error) {
+		desc, err := remote.Get(dref, r.remoteOpts...)
+		if err != nil {
+			return nil, err
+		}
+		return io.NopCloser(bytes.NewReader(desc.Manifest)), nil
+	}
+	return repairSource{name: repo.Name(), open: func() (io.ReadCloser, error) {
+		if isManifest {
+			return fetchManifest()
+		}
+		layer, err := remote.Layer(dref, r.remoteOpts...)
+		if err == nil {
+			var rc io.ReadCloser
+			if rc, err = layer.Compressed(); err == nil {
+				return rc, nil
+			}
+		}
+		// a child manifest of an index is only served from /manifests/
+		if rc, merr := fetchManifest(); merr == nil {
+			return rc, nil
+		}
+		return nil, err
+	}}, true
+}
+
+// fileSource re-reads a file's content from where it was added from. Only the
+// content layer can come back this way: the file's manifest and config were
+// built by hauler, and a directory was archived into its layer.
+func (r *repairer) fileSource(ctx context.Context, e storedImage, orig string, d digest.Digest) (repairSource, bool) {
+	if orig == "" {
+		return repairSource{}, false
+	}
+
+	rc, err := r.s.Fetch(ctx, e.desc)
+	if err != nil {
+		return repairSource{}, false
+	}
+	var m ocispec.Manifest
+	err = json.NewDecoder(rc).Decode(&m)
+	rc.Close()
+	if err != nil || len(m.Layers) != 1 || m.Layers[0].Digest != d {
+		return repairSource{}, false
+	}
+	switch m.Config.MediaType {
+	case consts.FileHttpConfigMediaType:
+		return repairSource{name: orig, open: func() (io.ReadCloser, error) {
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, orig, nil)
+			if err != nil {
+				return nil, err
+			}
+			resp, err := r.client.Do(req)
+			if err != nil {
+				return nil, err
+			}
+			if resp.StatusCode != http.StatusOK {
+				resp.Body.Close()
+				return nil, fmt.Errorf("unexpected status [%s]", resp.Status)
+			}
+			return resp.Body, nil
+		}}, true
+	case consts.FileLocalConfigMediaType:
+		return repairSource{name: orig, open: func() (io.ReadCloser, error) { return os.Open(orig) }}, true
+	}
+	return repairSource{}, false
+}
+
+// openHaul returns the layout root of a haul: a store directory as is,
+// or an archive (local, remote, or chunked) unpacked into dir.
+func openHaul(ctx context.Context, haul, dir string) (string, error) {
+	if fi, err := os.Stat(haul); err == nil && fi.IsDir() {
+		return haul, nil
+	}
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return "", err
+	}
+
+	if strings.HasPrefix(haul, "http://") || strings.HasPrefix(haul, "https://") {
+		local, err := downloadHaul(ctx, haul, dir)
+		if err != nil {
+			return "", err
+		}
+		haul = local
+	}
+	joined, err := archives.JoinChunks(ctx, haul, dir)
+	if err != nil {
+		return "", err
+	}
+	if err := archives.Unarchive(ctx, joined, dir); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
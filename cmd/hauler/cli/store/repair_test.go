@@ -0,0 +1,122 @@
+package store
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+
+	"hauler.dev/go/hauler/v2/internal/flags"
+	v1 "hauler.dev/go/hauler/v2/pkg/apis/hauler.cattle.io/v1"
+	"hauler.dev/go/hauler/v2/pkg/store"
+)
+
+// damageStoredImage stores ref in s, then truncates its first layer and
+// deletes its config, returning the image's index descriptor.
+func damageStoredImage(t *testing.T, s *store.Layout, ref string, rso *flags.StoreRootOpts, ro *flags.CliRootOpts) ocispec.Descriptor {
+	t.Helper()
+	ctx := newTestContext(t)
+	if err := storeImage(ctx, s, v1.Image{Name: ref}, "", true, rso, ro, "", "", false); err != nil {
+		t.Fatalf("storeImage(%s): %v", ref, err)
+	}
+
+	var desc ocispec.Descriptor
+	if err := s.Walk(func(_ string, d ocispec.Descriptor) error {
+		desc = d
+		return nil
+	}); err != nil {
+		t.Fatalf("Walk: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(s.Root, "blobs", "sha256", desc.Digest.Encoded()))
+	if err != nil {
+		t.Fatalf("ReadFile(manifest): %v", err)
+	}
+	var m ocispec.Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		t.Fatalf("Unmarshal(manifest): %v", err)
+	}
+
+	if err := os.Truncate(filepath.Join(s.Root, "blobs", "sha256", m.Layers[0].Digest.Encoded()), 1); err != nil {
+		t.Fatalf("Truncate(layer): %v", err)
+	}
+	if err := os.Remove(filepath.Join(s.Root, "blobs", "sha256", m.Config.Digest.Encoded())); err != nil {
+		t.Fatalf("Remove(config): %v", err)
+	}
+	if res := s.NewChecker().Check(ctx, desc); len(res.Problems) != 2 {
+		t.Fatalf("damaged image has problems %+v, want the layer and config", res.Problems)
+	}
+	return desc
+}
+
+func TestRepairCmd_FromRegistry(t *testing.T) {
+	ctx := newTestContext(t)
+	host, _ := newLocalhostRegistry(t)
+	seedImage(t, host, "repair/app", "v1")
+
+	s := newTestStore(t)
+	rso := defaultRootOpts(s.Root)
+	ro := defaultCliOpts()
+	ro.HaulerDir = t.TempDir()
+	ro.AuditLevel = "standard"
+	desc := damageStoredImage(t, s, host+"/repair/app:v1", rso, ro)
+
+	o := &flags.RepairOpts{StoreRootOpts: rso, OutputFormat: "table"}
+	if err := RepairCmd(ctx, o, s, ro); err != nil {
+		t.Fatalf("RepairCmd: %v", err)
+	}
+	if res := s.NewChecker().Check(ctx, desc); !res.OK {
+		t.Errorf("image still damaged after repair: %+v", res.Problems)
+	}
+
+	data, err := os.ReadFile(filepath.Join(ro.HaulerDir, "audit.log"))
+	if err != nil || !strings.Contains(string(data), `"store repair"`) {
+		t.Errorf("audit.log = %q (%v), want a store repair entry", data, err)
+	}
+}
+
+// A haul is tried before any registry, so a store whose registry is gone can
+// still be repaired from a copy of itself.
+func TestRepairCmd_FromHaul(t *testing.T) {
+	ctx := newTestContext(t)
+	host, _ := newLocalhostRegistry(t)
+	seedImage(t, host, "repair/haul", "v1")
+
+	haul := newTestStore(t)
+	if err := storeImage(ctx, haul, v1.Image{Name: host + "/repair/haul:v1"}, "", true, defaultRootOpts(haul.Root), defaultCliOpts(), "", "", false); err != nil {
+		t.Fatalf("storeImage(haul): %v", err)
+	}
+
+	s := newTestStore(t)
+	rso := defaultRootOpts(s.Root)
+	ro := defaultCliOpts()
+	desc := damageStoredImage(t, s, host+"/repair/haul:v1", rso, ro)
+
+	// nothing listens on port 1, so only the haul can serve the blobs
+	o := &flags.RepairOpts{StoreRootOpts: rso, OutputFormat: "table", Mirror: "localhost:1", Hauls: []string{haul.Root}}
+	if err := RepairCmd(ctx, o, s, ro); err != nil {
+		t.Fatalf("RepairCmd: %v", err)
+	}
+	if res := s.NewChecker().Check(ctx, desc); !res.OK {
+		t.Errorf("image still damaged after repair: %+v", res.Problems)
+	}
+}
+
+func TestRepairCmd_Unrepairable(t *testing.T) {
+	ctx := newTestContext(t)
+	host, _ := newLocalhostRegistry(t)
+	seedImage(t, host, "repair/gone", "v1")
+
+	s := newTestStore(t)
+	rso := defaultRootOpts(s.Root)
+	ro := defaultCliOpts()
+	damageStoredImage(t, s, host+"/repair/gone:v1", rso, ro)
+
+	o := &flags.RepairOpts{StoreRootOpts: rso, OutputFormat: "table", Mirror: "localhost:1"}
+	err := RepairCmd(ctx, o, s, ro)
+	if err == nil || !strings.Contains(err.Error(), "[2] of [2] damaged blob(s) could not be repaired") {
+		t.Errorf("RepairCmd = %v, want both blobs reported unrepaired", err)
+	}
+}
@@ -0,0 +1,330 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+
+	"hauler.dev/go/hauler/v2/internal/flags"
+	"hauler.dev/go/hauler/v2/pkg/consts"
+	"hauler.dev/go/hauler/v2/pkg/store"
+)
+
+// statuses a sync report entry can end in
+const (
+	reportStored       = "stored"
+	reportSkipped      = "skipped-already-present"
+	reportVerifyFailed = "verify-failed"
+	reportFetchFailed  = "fetch-failed"
+	reportRejected     = "rejected-by-policy"
+)
+
+// syncReport collects one entry per image, chart, and file job of a
+// `store sync --report` run. Like syncLock it rides on the context, and every
+// method is a no-op on a nil *syncReport (or *reportEntry), which is what
+// every caller outside a reporting sync sees.
+type syncReport struct {
+	path   string
+	format string
+
+	mu      sync.Mutex
+	entries []*reportEntry
+}
+
+// reportEntry is one job's outcome. A job's entry is only ever touched by the
+// goroutine running it; the report reads it once every job has returned.
+type reportEntry struct {
+	Kind            string  `json:"kind"`
+	Name            string  `json:"name"`
+	Reference       string  `json:"reference,omitempty"`
+	Digest          string  `json:"digest,omitempty"`
+	Store           string  `json:"store"`
+	Status          string  `json:"status"`
+	Attempts        int     `json:"attempts"`
+	Bytes           int64   `json:"bytes,omitempty"`
+	DurationSeconds float64 `json:"durationSeconds"`
+	Error           string  `json:"error,omitempty"`
+
+	start time.Time
+}
+
+type reportSummary struct {
+	Total        int `json:"total"`
+	Stored       int `json:"stored"`
+	Skipped      int `json:"skipped"`
+	VerifyFailed int `json:"verifyFailed"`
+	FetchFailed  int `json:"fetchFailed"`
+	Rejected     int `json:"rejected"`
+}
+
+func newSyncReport(o *flags.SyncOpts) (*syncReport, error) {
+	if o.Report == "" {
+		return nil, nil
+	}
+	switch o.ReportFormat {
+	case "json", "junit":
+	default:
+		return nil, fmt.Errorf("unsupported report format [%s]... valid formats are [json, junit]", o.ReportFormat)
+	}
+	return &syncReport{path: o.Report, format: o.ReportFormat}, nil
+}
+
+type syncReportKey struct{}
+type reportEntryKey struct{}
+
+func withSyncReport(ctx context.Context, r *syncReport) context.Context {
+	return context.WithValue(ctx, syncReportKey{}, r)
+}
+
+func syncReportFromContext(ctx context.Context) *syncReport {
+	r, _ := ctx.Value(syncReportKey{}).(*syncReport)
+	return r
+}
+
+// withReportEntry hands a job's entry down to the store* call that finishes
+// it, so a job that starts before storing (verification) and the store call
+// itself share one entry.
+func withReportEntry(ctx context.Context, e *reportEntry) context.Context {
+	return context.WithValue(ctx, reportEntryKey{}, e)
+}
+
+// reportEntryFor returns the entry a calling job already opened on ctx, or
+// opens a new one. owned reports whether the caller owns (and so must finish)
+// the entry.
+func reportEntryFor(ctx context.Context, kind, name string, s *store.Layout) (e *reportEntry, owned bool) {
+	if e, _ := ctx.Value(reportEntryKey{}).(*reportEntry); e != nil {
+		return e, false
+	}
+	return syncReportFromContext(ctx).begin(kind, name, s), true
+}
+
+// begin opens an entry for a job named as the manifest asks for it.
+func (r *syncReport) begin(kind, name string, s *store.Layout) *reportEntry {
+	if r == nil {
+		return nil
+	}
+	e := &reportEntry{Kind: kind, Name: name, Store: s.Root, start: time.Now()}
+	r.mu.Lock()
+	r.entries = append(r.entries, e)
+	r.mu.Unlock()
+	return e
+}
+
+func (e *reportEntry) attempt() {
+	if e != nil {
+		e.Attempts++
+	}
+}
+
+// fail records a failure. A later failure replaces an earlier one, so an item
+// that failed verification and then couldn't be fetched reads fetch-failed.
+func (e *reportEntry) fail(status string, err error) {
+	if e == nil {
+		return
+	}
+	e.Status = status
+	if err != nil {
+		e.Error = err.Error()
+	}
+}
+
+// stored records a successful store. previous is the digest the store held
+// under the same name beforehand; matching it means nothing changed. An item
+// stored unverified after a verification failure keeps verify-failed.
+func (e *reportEntry) stored(reference, digest, previous string, bytes int64) {
+	if e == nil {
+		return
+	}
+	e.Reference = reference
+	e.Digest = digest
+	e.Bytes = bytes
+	if e.Status != "" {
+		return
+	}
+	if previous != "" && previous == digest {
+		e.Status = reportSkipped
+	} else {
+		e.Status = reportStored
+	}
+}
+
+// done closes the entry. A job that returned without reaching stored or fail
+// (a cancelled context, a bad reference, a failed rewrite) counts as a fetch
+// failure.
+func (e *reportEntry) done() {
+	if e == nil {
+		return
+	}
+	e.DurationSeconds = time.Since(e.start).Seconds()
+	if e.Status == "" {
+		e.Status = reportFetchFailed
+	}
+}
+
+// indexedDigest returns the digest s currently holds for refName, ignoring
+// the signatures, attestations, SBOMs, and referrers that share its name.
+// Only a reporting run pays for the walk.
+func (e *reportEntry) indexedDigest(s *store.Layout, refName string) string {
+	if e == nil {
+		return ""
+	}
+	var d string
+	_ = s.Walk(func(_ string, desc ocispec.Descriptor) error {
+		if desc.Annotations[ocispec.AnnotationRefName] != refName {
+			return nil
+		}
+		switch kind := desc.Annotations[consts.KindAnnotationName]; {
+		case kind == consts.KindAnnotationSigs, kind == consts.KindAnnotationAtts, kind == consts.KindAnnotationSboms,
+			strings.HasPrefix(kind, consts.KindAnnotationReferrers):
+			return nil
+		}
+		d = desc.Digest.String()
+		return nil
+	})
+	return d
+}
+
+// write saves the report, sorted by kind, name, then store, so two runs over
+// the same manifests list their items in the same order.
+func (r *syncReport) write() error {
+	if r == nil {
+		return nil
+	}
+
+	r.mu.Lock()
+	entries := append([]*reportEntry(nil), r.entries...)
+	r.mu.Unlock()
+	sort.SliceStable(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return a.Store < b.Store
+	})
+
+	var sum reportSummary
+	for _, e := range entries {
+		sum.Total++
+		switch e.Status {
+		case reportStored:
+			sum.Stored++
+		case reportSkipped:
+			sum.Skipped++
+		case reportVerifyFailed:
+			sum.VerifyFailed++
+		case reportFetchFailed:
+			sum.FetchFailed++
+		case reportRejected:
+			sum.Rejected++
+		}
+	}
+
+	var data []byte
+	var err error
+	switch r.format {
+	case "junit":
+		data, err = junitReport(entries, sum)
+	default:
+		data, err = json.MarshalIndent(struct {
+			Summary reportSummary  `json:"summary"`
+			Items   []*reportEntry `json:"items"`
+		}{sum, entries}, "", "  ")
+	}
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(r.path, append(data, '\n'), 0o644)
+}
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Skipped  int             `xml:"skipped,attr"`
+	Time     string          `xml:"time,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Skipped   *junitMessage `xml:"skipped,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr,omitempty"`
+	Text    string `xml:",chardata"`
+}
+
+// junitReport renders one testsuite per kind and one testcase per item, so CI
+// systems show each failed image, chart, or file as its own failing test.
+func junitReport(entries []*reportEntry, sum reportSummary) ([]byte, error) {
+	out := junitTestSuites{
+		Tests:    sum.Total,
+		Failures: sum.VerifyFailed + sum.FetchFailed + sum.Rejected,
+		Skipped:  sum.Skipped,
+	}
+
+	suites := map[string]*junitTestSuite{}
+	var kinds []string
+	totals := map[string]float64{}
+	for _, e := range entries {
+		ts, ok := suites[e.Kind]
+		if !ok {
+			ts = &junitTestSuite{Name: "hauler store sync " + e.Kind}
+			suites[e.Kind] = ts
+			kinds = append(kinds, e.Kind)
+		}
+		tc := junitTestCase{
+			Name:      e.Name,
+			ClassName: e.Kind,
+			Time:      fmt.Sprintf("%.3f", e.DurationSeconds),
+			SystemOut: fmt.Sprintf("reference=%s digest=%s store=%s attempts=%d bytes=%d", e.Reference, e.Digest, e.Store, e.Attempts, e.Bytes),
+		}
+		switch e.Status {
+		case reportVerifyFailed, reportFetchFailed, reportRejected:
+			tc.Failure = &junitMessage{Message: e.Status, Type: e.Status, Text: e.Error}
+			ts.Failures++
+		case reportSkipped:
+			tc.Skipped = &junitMessage{Message: e.Status}
+			ts.Skipped++
+		}
+		ts.Tests++
+		totals[e.Kind] += e.DurationSeconds
+		ts.Cases = append(ts.Cases, tc)
+	}
+	for _, k := range kinds {
+		ts := suites[k]
+		ts.Time = fmt.Sprintf("%.3f", totals[k])
+		out.Suites = append(out.Suites, *ts)
+	}
+
+	data, err := xml.MarshalIndent(out, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), data...), nil
+}
@@ -0,0 +1,184 @@
+package store
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type testReport struct {
+	Summary reportSummary `json:"summary"`
+	Items   []reportEntry `json:"items"`
+}
+
+func readReport(t *testing.T, path string) testReport {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile(%s): %v", path, err)
+	}
+	var r testReport
+	if err := json.Unmarshal(data, &r); err != nil {
+		t.Fatalf("Unmarshal report: %v", err)
+	}
+	return r
+}
+
+func reportTestManifest(host, fileURL string) string {
+	return fmt.Sprintf(`apiVersion: content.hauler.cattle.io/v1
+kind: Images
+metadata:
+  name: report-images
+spec:
+  images:
+    - name: %[1]s/report/present:v1
+    - name: %[1]s/report/missing:v1
+---
+apiVersion: content.hauler.cattle.io/v1
+kind: Files
+metadata:
+  name: report-files
+spec:
+  files:
+    - path: %[2]s
+`, host, fileURL)
+}
+
+func TestSyncCmd_Report(t *testing.T) {
+	ctx := newTestContext(t)
+	host, _ := newLocalhostRegistry(t)
+	img := seedImage(t, host, "report/present", "v1")
+	fileURL := seedFileInHTTPServer(t, "report.txt", "content")
+	manifest := writeTempFile(t, "hauler-manifest.yaml", reportTestManifest(host, fileURL))
+
+	ro := defaultCliOpts()
+	ro.IgnoreErrors = true
+	s := newTestStore(t)
+	o := newSyncOpts(s.Root)
+	o.FileName = []string{manifest}
+	o.Report = filepath.Join(t.TempDir(), "report.json")
+	o.ReportFormat = "json"
+	if err := SyncCmd(ctx, o, s, o.StoreRootOpts, ro); err != nil {
+		t.Fatalf("SyncCmd: %v", err)
+	}
+
+	r := readReport(t, o.Report)
+	if r.Summary.Total != 3 || r.Summary.Stored != 2 || r.Summary.FetchFailed != 1 {
+		t.Errorf("summary = %+v, want 3 total, 2 stored, 1 fetch-failed", r.Summary)
+	}
+	digest, _ := img.Digest()
+	byName := map[string]reportEntry{}
+	for _, e := range r.Items {
+		byName[e.Name] = e
+		if e.Store != s.Root {
+			t.Errorf("%s: store = %q, want %q", e.Name, e.Store, s.Root)
+		}
+		if e.Attempts < 1 {
+			t.Errorf("%s: attempts = %d, want at least 1", e.Name, e.Attempts)
+		}
+	}
+	present := byName[host+"/report/present:v1"]
+	if present.Status != reportStored || present.Digest != digest.String() || present.Bytes == 0 {
+		t.Errorf("present image entry = %+v", present)
+	}
+	missing := byName[host+"/report/missing:v1"]
+	if missing.Status != reportFetchFailed || missing.Error == "" {
+		t.Errorf("missing image entry = %+v", missing)
+	}
+	if f := byName[fileURL]; f.Kind != "file" || f.Status != reportStored || !strings.HasPrefix(f.Digest, "sha256:") {
+		t.Errorf("file entry = %+v", f)
+	}
+
+	// a second run finds everything already in place
+	if err := SyncCmd(ctx, o, s, o.StoreRootOpts, ro); err != nil {
+		t.Fatalf("SyncCmd (second run): %v", err)
+	}
+	r = readReport(t, o.Report)
+	if r.Summary.Skipped != 2 || r.Summary.FetchFailed != 1 {
+		t.Errorf("second run summary = %+v, want 2 skipped, 1 fetch-failed", r.Summary)
+	}
+}
+
+func TestSyncCmd_Report_JUnit(t *testing.T) {
+	ctx := newTestContext(t)
+	host, _ := newLocalhostRegistry(t)
+	seedImage(t, host, "report/present", "v1")
+	fileURL := seedFileInHTTPServer(t, "report.txt", "content")
+
+	ro := defaultCliOpts()
+	ro.IgnoreErrors = true
+	s := newTestStore(t)
+	o := newSyncOpts(s.Root)
+	o.FileName = []string{writeTempFile(t, "hauler-manifest.yaml", reportTestManifest(host, fileURL))}
+	o.Report = filepath.Join(t.TempDir(), "report.xml")
+	o.ReportFormat = "junit"
+	if err := SyncCmd(ctx, o, s, o.StoreRootOpts, ro); err != nil {
+		t.Fatalf("SyncCmd: %v", err)
+	}
+
+	data, err := os.ReadFile(o.Report)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	var suites junitTestSuites
+	if err := xml.Unmarshal(data, &suites); err != nil {
+		t.Fatalf("Unmarshal junit: %v", err)
+	}
+	if suites.Tests != 3 || suites.Failures != 1 {
+		t.Errorf("testsuites tests=%d failures=%d, want 3 and 1", suites.Tests, suites.Failures)
+	}
+	var failed []string
+	for _, ts := range suites.Suites {
+		for _, tc := range ts.Cases {
+			if tc.Failure != nil {
+				failed = append(failed, tc.Name)
+			}
+		}
+	}
+	if len(failed) != 1 || failed[0] != host+"/report/missing:v1" {
+		t.Errorf("failed testcases = %v", failed)
+	}
+}
+
+func TestSyncCmd_Report_BadFormat(t *testing.T) {
+	s := newTestStore(t)
+	o := newSyncOpts(s.Root)
+	o.Report = filepath.Join(t.TempDir(), "report")
+	o.ReportFormat = "yaml"
+	if err := SyncCmd(newTestContext(t), o, s, o.StoreRootOpts, defaultCliOpts()); err == nil {
+		t.Fatal("expected an error for an unsupported report format")
+	}
+}
+
+func TestSyncCmd_Report_ChartFailure(t *testing.T) {
+	manifest := writeTempFile(t, "hauler-manifest.yaml", fmt.Sprintf(`apiVersion: content.hauler.cattle.io/v1
+kind: Charts
+metadata:
+  name: report-charts
+spec:
+  charts:
+    - name: does-not-exist-0.0.1.tgz
+      repoURL: %s
+`, chartTestdataDir))
+
+	s := newTestStore(t)
+	o := newSyncOpts(s.Root)
+	o.FileName = []string{manifest}
+	o.Report = filepath.Join(t.TempDir(), "report.json")
+	o.ReportFormat = "json"
+	if err := SyncCmd(newTestContext(t), o, s, o.StoreRootOpts, defaultCliOpts()); err == nil {
+		t.Fatal("expected an error for a missing chart")
+	}
+
+	r := readReport(t, o.Report)
+	if len(r.Items) != 1 {
+		t.Fatalf("report items = %+v, want 1", r.Items)
+	}
+	if e := r.Items[0]; e.Kind != "chart" || e.Status != reportFetchFailed || e.Error == "" {
+		t.Errorf("chart entry = %+v, want fetch-failed with an error", e)
+	}
+}
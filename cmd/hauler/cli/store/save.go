@@ -9,8 +9,10 @@ import (
 	"path"
 	"path/filepath"
 	"slices"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	referencev3 "github.com/distribution/reference"
 	"github.com/google/go-containerregistry/pkg/name"
@@ -18,12 +20,16 @@ import (
 	"github.com/google/go-containerregistry/pkg/v1/layout"
 	"github.com/google/go-containerregistry/pkg/v1/tarball"
 	"github.com/google/go-containerregistry/pkg/v1/types"
+	mholt "github.com/mholt/archives"
+	"github.com/opencontainers/go-digest"
 	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
 
 	"hauler.dev/go/hauler/v2/internal/flags"
+	"hauler.dev/go/hauler/v2/internal/version"
 	"hauler.dev/go/hauler/v2/pkg/archives"
 	"hauler.dev/go/hauler/v2/pkg/audit"
 	"hauler.dev/go/hauler/v2/pkg/consts"
+	"hauler.dev/go/hauler/v2/pkg/cosign"
 	"hauler.dev/go/hauler/v2/pkg/log"
 	"hauler.dev/go/hauler/v2/pkg/store"
 )
@@ -32,31 +38,133 @@ import (
 func SaveCmd(ctx context.Context, o *flags.SaveOpts, s *store.Layout, rso *flags.StoreRootOpts, ro *flags.CliRootOpts) error {
 	l := log.FromContext(ctx)
 
-	// maps to handle compression and archival types
-	compressionMap := archives.CompressionMap
-	archivalMap := archives.ArchivalMap
+	// the haul's format comes from the flags, or else the filename's extension
+	format, err := archives.NewFormat(o.FileName, archives.FormatOptions{
+		Compression: o.Compression,
+		Archival:    o.Format,
+		Level:       o.CompressionLevel,
+		Threads:     o.Threads,
+	})
+	if err != nil {
+		return err
+	}
+	if o.ContainerdCompatibility {
+		if _, isTar := format.Archival.(mholt.Tar); !isTar {
+			return fmt.Errorf("--containerd needs a tar haul... containerd can't import a %s", strings.TrimPrefix(format.Archival.Extension(), "."))
+		}
+		switch format.Compression.(type) {
+		case nil, mholt.Gz, mholt.Zstd:
+		default:
+			l.Warnf("compatibility warning... containerd... only imports hauls that are uncompressed, gz, or zst compressed")
+		}
+	}
+
+	// `-f -` writes the haul to stdout, leaving no file to split or sign
+	toStdout := o.FileName == "-"
+	if toStdout {
+		if o.ChunkSize != "" {
+			return fmt.Errorf("--chunk-size can't be combined with saving to stdout (-f -)")
+		}
+		if o.SignKey != "" {
+			return fmt.Errorf("--sign-key can't be combined with saving to stdout (-f -)... there's no checksums file to sign")
+		}
+	}
 
-	// select the compression and archival type based parsed filename extension
-	compression := compressionMap["zst"]
-	archival := archivalMap["tar"]
+	var maxBytes int64
+	if o.ChunkSize != "" {
+		if maxBytes, err = parseChunkSize(o.ChunkSize); err != nil {
+			return err
+		}
+	}
 
 	absOutputfile, err := filepath.Abs(o.FileName)
 	if err != nil {
 		return err
 	}
 
+	absContentList, err := filepath.Abs(o.ContentList)
+	if err != nil {
+		return err
+	}
+
+	// nil unless --select narrows the haul... an artifact selected brings the
+	// signatures, attestations, sboms, and referrers saved for it along
+	selected, err := selectedKeys(ctx, s, o.Select, true)
+	if err != nil {
+		return err
+	}
+	if o.DryRun {
+		return previewSave(ctx, o, s, rso, selected)
+	}
+
+	// loaded up front so a bad key or password fails before the haul is written
+	var signer *cosign.Signer
+	if o.SignKey != "" {
+		if signer, err = cosign.NewSigner(o.SignKey); err != nil {
+			return err
+		}
+	}
+
+	// a delta or a selection is staged on its own instead of archiving the
+	// store as-is
+	archiveDir := o.StoreDir
+	var list *contentList
+	if o.Since != "" {
+		if o.ContainerdCompatibility {
+			return fmt.Errorf("--containerd can't be combined with --since... delta hauls can only be imported with `hauler store load`")
+		}
+
+		stageDir, err := os.MkdirTemp(rso.TempOverride, consts.DefaultHaulerTempDirName)
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(stageDir)
+
+		joinDir, err := os.MkdirTemp(rso.TempOverride, consts.DefaultHaulerTempDirName)
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(joinDir)
+
+		l.Infof("reading baseline [%s]", o.Since)
+		base, err := readContentList(ctx, o.Since, joinDir)
+		if err != nil {
+			return err
+		}
+
+		list, err = writeDelta(ctx, s, base, selected, stageDir)
+		if err != nil {
+			return err
+		}
+		archiveDir = stageDir
+	} else if selected != nil {
+		stageDir, err := os.MkdirTemp(rso.TempOverride, consts.DefaultHaulerTempDirName)
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(stageDir)
+
+		if err := writeSelection(ctx, s, selected, stageDir); err != nil {
+			return err
+		}
+		archiveDir = stageDir
+	}
+
 	cwd, err := os.Getwd()
 	if err != nil {
 		return err
 	}
 	defer os.Chdir(cwd)
-	if err := os.Chdir(o.StoreDir); err != nil {
+	if err := os.Chdir(archiveDir); err != nil {
 		return err
 	}
 
-	// create the manifest.json file
-	if err := writeExportsManifest(ctx, ".", o.Platform); err != nil {
-		return err
+	// create the manifest.json file... a delta only carries part of each
+	// image, so there's nothing a runtime could import from it directly
+	if o.Since == "" {
+		if err := writeExportsManifest(ctx, ".", o.Platform); err != nil {
+			return err
+		}
 	}
 
 	// strip out the oci-layout file from the haul
@@ -71,29 +179,63 @@ func SaveCmd(ctx context.Context, o *flags.SaveOpts, s *store.Layout, rso *flags
 		}
 	}
 
-	// create the archive
-	err = archives.Archive(ctx, ".", absOutputfile, compression, archival)
+	// the bill of contents goes first in the haul, so `hauler haul inspect`
+	// reads it without extracting anything
+	contents, err := haulContents(ctx, s, ".", o.Since != "")
 	if err != nil {
 		return err
 	}
 
-	if o.ChunkSize != "" {
-		if o.ContainerdCompatibility == true {
+	// the haul is chunked and digested as it's written, so it's never read
+	// back... which is also what lets it stream to stdout
+	var w *archives.HaulWriter
+	if toStdout {
+		w = archives.NewHaulStreamWriter(os.Stdout, o.FileName)
+	} else if w, err = archives.NewHaulWriter(absOutputfile, maxBytes); err != nil {
+		return err
+	}
+	if err := archives.ArchiveTo(ctx, ".", w, format.Compression, format.Archival, contents); err != nil {
+		if derr := w.Discard(); derr != nil {
+			l.Warnf("failed to remove partial haul [%s]: %v", o.FileName, derr)
+		}
+		return fmt.Errorf("error during archive creation for output file [%s]: %w", o.FileName, err)
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	switch {
+	case toStdout:
+		c := w.Checksums()
+		l.Infof("saving store [%s] to stdout with digest [%s] and size [%s]", o.StoreDir, c.Archive.Digest, byteCountSI(c.Archive.Size))
+	case maxBytes > 0:
+		if o.ContainerdCompatibility {
 			l.Warnf("compatibility warning... stores split by chunk size must be imported using `hauler store load` to rejoin before import to containerd")
 		}
-		maxBytes, err := parseChunkSize(o.ChunkSize)
-		if err != nil {
-			return err
+		for _, c := range w.Files() {
+			l.Infof("saving store [%s] to chunk [%s]", o.StoreDir, filepath.Base(c))
 		}
-		chunks, err := archives.SplitArchive(ctx, absOutputfile, maxBytes)
-		if err != nil {
+	default:
+		l.Infof("saving store [%s] to archive [%s]", o.StoreDir, o.FileName)
+	}
+
+	// a stream has nowhere for a sidecar to go... its digest is logged instead
+	if !toStdout {
+		if err := writeHaulChecksums(ctx, absOutputfile, w.Checksums(), signer); err != nil {
 			return err
 		}
-		for _, c := range chunks {
-			l.Infof("saving store [%s] to chunk [%s]", o.StoreDir, filepath.Base(c))
+	}
+
+	if o.ContentList != "" {
+		if list == nil {
+			if list, err = storeContentList(".", s.StoreID); err != nil {
+				return err
+			}
 		}
-	} else {
-		l.Infof("saving store [%s] to archive [%s]", o.StoreDir, o.FileName)
+		if err := writeContentList(absContentList, list); err != nil {
+			return err
+		}
+		l.Infof("saving content list to [%s]", o.ContentList)
 	}
 
 	if auditLevel(ro) != "none" {
@@ -109,9 +251,17 @@ func SaveCmd(ctx context.Context, o *flags.SaveOpts, s *store.Layout, rso *flags
 			e.System = &sys
 			e.Global = &g
 			e.Flags = map[string]any{
-				"platform":   o.Platform,
-				"containerd": o.ContainerdCompatibility,
-				"chunk-size": o.ChunkSize,
+				"platform":          o.Platform,
+				"containerd":        o.ContainerdCompatibility,
+				"chunk-size":        o.ChunkSize,
+				"since":             o.Since,
+				"content-list":      o.ContentList,
+				"select":            o.Select,
+				"sign-key":          o.SignKey,
+				"compression":       o.Compression,
+				"compression-level": o.CompressionLevel,
+				"threads":           o.Threads,
+				"format":            o.Format,
 			}
 		}
 		if err := audit.Append(ro.HaulerDir, e); err != nil {
@@ -122,6 +272,155 @@ func SaveCmd(ctx context.Context, o *flags.SaveOpts, s *store.Layout, rso *flags
 	return nil
 }
 
+// writeHaulChecksums writes c, the sidecar digest manifest of the haul at
+// archivePath or of the chunks it was split into, and signs it with signer
+// when there is one.
+func writeHaulChecksums(ctx context.Context, archivePath string, c *archives.Checksums, signer *cosign.Signer) error {
+	l := log.FromContext(ctx)
+
+	path := archives.ChecksumsPath(archivePath)
+	data, err := c.Write(path)
+	if err != nil {
+		return fmt.Errorf("failed to write haul checksums: %w", err)
+	}
+	l.Infof("saving haul checksums to [%s] with digest [%s]", filepath.Base(path), c.Archive.Digest)
+
+	sigPath := path + consts.DefaultHaulSignatureExt
+	if signer == nil {
+		// a stale signature from an earlier save would no longer match
+		if err := os.Remove(sigPath); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	sig, err := signer.SignBlob(ctx, data)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(sigPath, sig, 0o644); err != nil {
+		return fmt.Errorf("failed to write haul signature: %w", err)
+	}
+	l.Infof("signed haul checksums to [%s]", filepath.Base(sigPath))
+	return nil
+}
+
+// haulContents returns the bill of contents of the layout in dir, an export
+// of s: every index entry with its type, platforms, digest, and size, along
+// with where and when the haul was saved.
+func haulContents(ctx context.Context, s *store.Layout, dir string, delta bool) ([]byte, error) {
+	data, err := os.ReadFile(filepath.Join(dir, ocispec.ImageIndexFile))
+	if err != nil {
+		return nil, err
+	}
+	var idx ocispec.Index
+	if err := json.Unmarshal(data, &idx); err != nil {
+		return nil, err
+	}
+
+	c := archives.HaulContents{
+		StoreID:       s.StoreID,
+		HaulerVersion: version.GetVersionInfo().GitVersion,
+		Created:       time.Now().UTC().Format(time.RFC3339),
+		Delta:         delta,
+		Artifacts:     make([]archives.HaulArtifact, 0, len(idx.Manifests)),
+	}
+	for _, desc := range idx.Manifests {
+		ref, err := resolveDisplayReference(desc)
+		if err != nil {
+			ref = desc.Annotations[ocispec.AnnotationRefName]
+		}
+		blobs, err := s.Blobs(ctx, desc)
+		if err != nil {
+			return nil, fmt.Errorf("failed to size [%s]: %w", ref, err)
+		}
+		var size int64
+		for _, n := range blobs {
+			size += n
+		}
+		c.Artifacts = append(c.Artifacts, archives.HaulArtifact{
+			Reference: ref,
+			Type:      s.ArtifactType(ctx, desc),
+			Platforms: s.Platforms(ctx, desc),
+			Digest:    desc.Digest,
+			Size:      size,
+		})
+	}
+	sort.SliceStable(c.Artifacts, func(i, j int) bool {
+		if c.Artifacts[i].Reference != c.Artifacts[j].Reference {
+			return c.Artifacts[i].Reference < c.Artifacts[j].Reference
+		}
+		return c.Artifacts[i].Type < c.Artifacts[j].Type
+	})
+
+	out, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(out, '\n'), nil
+}
+
+// writeSelection stages a layout of the selected index entries of s, and
+// every blob they reach, in stageDir.
+func writeSelection(ctx context.Context, s *store.Layout, selected map[string]bool, stageDir string) error {
+	l := log.FromContext(ctx)
+
+	var entries []ocispec.Descriptor
+	blobs := map[digest.Digest]bool{}
+	err := s.Walk(func(reference string, desc ocispec.Descriptor) error {
+		if !selected[reference] {
+			return nil
+		}
+		entries = append(entries, desc)
+		return s.WalkBlobs(ctx, desc, func(d ocispec.Descriptor) error {
+			blobs[d.Digest] = true
+			return nil
+		})
+	})
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		return fmt.Errorf("no artifacts in store match the selector")
+	}
+
+	l.Infof("saving [%d] selected entries with [%d] blobs", len(entries), len(blobs))
+	return stageLayout(s, entries, blobs, stageDir)
+}
+
+// previewSave lists what save would put in the haul, without writing one.
+func previewSave(ctx context.Context, o *flags.SaveOpts, s *store.Layout, rso *flags.StoreRootOpts, selected map[string]bool) error {
+	var baseEntries map[string]bool
+	if o.Since != "" {
+		joinDir, err := os.MkdirTemp(rso.TempOverride, consts.DefaultHaulerTempDirName)
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(joinDir)
+
+		base, err := readContentList(ctx, o.Since, joinDir)
+		if err != nil {
+			return err
+		}
+		baseEntries = make(map[string]bool, len(base.Manifests))
+		for _, desc := range base.Manifests {
+			baseEntries[entryKey(desc)] = true
+		}
+	}
+
+	all, err := s.Select(ctx, nil, store.SelectOptions{})
+	if err != nil {
+		return err
+	}
+	var saved []store.Selected
+	for _, sa := range all {
+		if (selected == nil || selected[sa.Key]) && !baseEntries[entryKey(sa.Desc)] {
+			saved = append(saved, sa)
+		}
+	}
+	logSelection(ctx, "save", "saved", saved)
+	return nil
+}
+
 // parseChunkSize parses a human-readable byte size string (e.g. "1G", "500M", "2GB")
 // into a byte count. Suffixes are treated as binary units (1K = 1024).
 func parseChunkSize(s string) (int64, error) {
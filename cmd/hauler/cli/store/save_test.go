@@ -12,10 +12,13 @@ import (
 	"path/filepath"
 	"testing"
 
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+
 	"hauler.dev/go/hauler/v2/internal/flags"
 	v1 "hauler.dev/go/hauler/v2/pkg/apis/hauler.cattle.io/v1"
 	"hauler.dev/go/hauler/v2/pkg/archives"
 	"hauler.dev/go/hauler/v2/pkg/consts"
+	"hauler.dev/go/hauler/v2/pkg/store"
 )
 
 // manifestEntry mirrors tarball.Descriptor for asserting manifest.json contents.
@@ -231,6 +234,58 @@ func TestSaveCmd_ContainerdCompatibility(t *testing.T) {
 	}
 }
 
+func TestSaveCmd_Select(t *testing.T) {
+	ctx := newTestContext(t)
+	host, _ := newLocalhostRegistry(t)
+	seedImage(t, host, "test/selected", "v1")
+	seedImage(t, host, "test/skipped", "v1")
+
+	s := newTestStore(t)
+	for _, repo := range []string{"test/selected", "test/skipped"} {
+		if _, err := s.AddImage(ctx, host+"/"+repo+":v1", "", false, "", false, ""); err != nil {
+			t.Fatalf("AddImage(%s): %v", repo, err)
+		}
+	}
+
+	archivePath := filepath.Join(t.TempDir(), "haul-select.tar.zst")
+	o := newSaveOpts(s.Root, archivePath)
+	o.Select = "ref=test/sel*"
+
+	if err := SaveCmd(ctx, o, s, defaultRootOpts(s.Root), defaultCliOpts()); err != nil {
+		t.Fatalf("SaveCmd --select: %v", err)
+	}
+
+	destDir := t.TempDir()
+	if err := archives.Unarchive(ctx, archivePath, destDir); err != nil {
+		t.Fatalf("Unarchive: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(destDir, ocispec.ImageIndexFile))
+	if err != nil {
+		t.Fatalf("read index: %v", err)
+	}
+	var idx ocispec.Index
+	if err := json.Unmarshal(data, &idx); err != nil {
+		t.Fatalf("unmarshal index: %v", err)
+	}
+	if len(idx.Manifests) != 1 || idx.Manifests[0].Annotations[ocispec.AnnotationRefName] != "test/selected:v1" {
+		t.Errorf("haul index holds %v, want only test/selected:v1", idx.Manifests)
+	}
+	if entries := readManifestJSON(t, destDir); len(entries) != 1 {
+		t.Errorf("manifest.json holds %d images, want 1", len(entries))
+	}
+
+	// a dry run writes nothing
+	dryPath := filepath.Join(t.TempDir(), "haul-dry.tar.zst")
+	o = newSaveOpts(s.Root, dryPath)
+	o.Select, o.DryRun = "type=image", true
+	if err := SaveCmd(ctx, o, s, defaultRootOpts(s.Root), defaultCliOpts()); err != nil {
+		t.Fatalf("SaveCmd --dry-run: %v", err)
+	}
+	if _, err := os.Stat(dryPath); !os.IsNotExist(err) {
+		t.Errorf("--dry-run wrote a haul: %v", err)
+	}
+}
+
 func TestSaveCmd_EmptyStore(t *testing.T) {
 	ctx := newTestContext(t)
 	s := newTestStore(t)
@@ -334,6 +389,66 @@ func TestSaveCmd_ChunkSize(t *testing.T) {
 	}
 }
 
+// TestSaveCmd_Formats saves the same store in several formats and checks each
+// loads back.
+func TestSaveCmd_Formats(t *testing.T) {
+	ctx := newTestContext(t)
+	host, _ := newLocalhostRegistry(t)
+	seedImage(t, host, "test/formats", "v1")
+
+	s := newTestStore(t)
+	if _, err := s.AddImage(ctx, host+"/test/formats:v1", "", false, "", false, ""); err != nil {
+		t.Fatalf("AddImage: %v", err)
+	}
+
+	tests := []struct {
+		filename  string
+		configure func(o *flags.SaveOpts)
+	}{
+		{filename: "haul.tar.gz"},
+		{filename: "haul.tar"},
+		{filename: "haul.zip"},
+		{filename: "haul.tar.zst", configure: func(o *flags.SaveOpts) { o.CompressionLevel = 19; o.Threads = 2 }},
+		{filename: "haul.bin", configure: func(o *flags.SaveOpts) { o.Compression = "lz4" }},
+		{filename: "chunked.zip", configure: func(o *flags.SaveOpts) { o.ChunkSize = "1K" }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.filename, func(t *testing.T) {
+			archivePath := filepath.Join(t.TempDir(), tt.filename)
+			o := newSaveOpts(s.Root, archivePath)
+			if tt.configure != nil {
+				tt.configure(o)
+			}
+			if err := SaveCmd(ctx, o, s, defaultRootOpts(s.Root), defaultCliOpts()); err != nil {
+				t.Fatalf("SaveCmd: %v", err)
+			}
+			if o.ChunkSize != "" {
+				archivePath += ".001"
+			}
+
+			destDir := t.TempDir()
+			if err := unarchiveLayoutTo(ctx, archivePath, destDir, t.TempDir(), defaultCliOpts(), false); err != nil {
+				t.Fatalf("unarchiveLayoutTo: %v", err)
+			}
+			dest, err := store.NewLayout(destDir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, want := countArtifactsInStore(t, dest), countArtifactsInStore(t, s); got != want {
+				t.Errorf("loaded %d artifacts, want %d", got, want)
+			}
+		})
+	}
+
+	t.Run("containerd needs tar", func(t *testing.T) {
+		o := newSaveOpts(s.Root, filepath.Join(t.TempDir(), "haul.zip"))
+		o.ContainerdCompatibility = true
+		if err := SaveCmd(ctx, o, s, defaultRootOpts(s.Root), defaultCliOpts()); err == nil {
+			t.Error("SaveCmd of a zip haul with --containerd succeeded")
+		}
+	})
+}
+
 func TestSaveCmd_ChunkSize_Invalid(t *testing.T) {
 	ctx := newTestContext(t)
 	s := newTestStore(t)
@@ -348,3 +463,24 @@ func TestSaveCmd_ChunkSize_Invalid(t *testing.T) {
 		t.Fatal("SaveCmd: expected error for chunk-size=0, got nil")
 	}
 }
+
+func TestSaveCmd_Stdout_Invalid(t *testing.T) {
+	ctx := newTestContext(t)
+	s := newTestStore(t)
+	if err := s.SaveIndex(); err != nil {
+		t.Fatalf("SaveIndex: %v", err)
+	}
+
+	for name, configure := range map[string]func(o *flags.SaveOpts){
+		"chunk-size": func(o *flags.SaveOpts) { o.ChunkSize = "1K" },
+		"sign-key":   func(o *flags.SaveOpts) { o.SignKey = "cosign.key" },
+	} {
+		t.Run(name, func(t *testing.T) {
+			o := newSaveOpts(s.Root, "-")
+			configure(o)
+			if err := SaveCmd(ctx, o, s, defaultRootOpts(s.Root), defaultCliOpts()); err == nil {
+				t.Errorf("SaveCmd to stdout with --%s succeeded", name)
+			}
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package store
+
+import (
+	"context"
+
+	"hauler.dev/go/hauler/v2/pkg/log"
+	"hauler.dev/go/hauler/v2/pkg/store"
+)
+
+// selectArtifacts parses a --select expression and returns the index entries
+// of s it matches, with the signatures, attestations, sboms, and referrers of
+// each matched artifact when related is set.
+func selectArtifacts(ctx context.Context, s *store.Layout, expr string, related bool) ([]store.Selected, error) {
+	sel, err := store.ParseSelector(expr)
+	if err != nil {
+		return nil, err
+	}
+	return s.Select(ctx, sel, store.SelectOptions{Related: related})
+}
+
+// selectedKeys is selectArtifacts as a set of index keys, or nil when expr is
+// empty so callers that filter on it select everything.
+func selectedKeys(ctx context.Context, s *store.Layout, expr string, related bool) (map[string]bool, error) {
+	if expr == "" {
+		return nil, nil
+	}
+	selected, err := selectArtifacts(ctx, s, expr, related)
+	if err != nil {
+		return nil, err
+	}
+	keys := make(map[string]bool, len(selected))
+	for _, sa := range selected {
+		keys[sa.Key] = true
+	}
+	return keys, nil
+}
+
+// logSelection lists what a --dry-run would do to each selected artifact.
+func logSelection(ctx context.Context, verb, done string, selected []store.Selected) {
+	l := log.FromContext(ctx)
+	for _, sa := range selected {
+		l.Infof("would %s [%s] of type [%s] with digest [%s]", verb, formatReference(sa.Key), sa.Type, sa.Desc.Digest)
+	}
+	l.Infof("dry run... [%d] artifact(s) would be %s", len(selected), done)
+}
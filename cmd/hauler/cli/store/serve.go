@@ -89,6 +89,12 @@ func ServeRegistryCmd(ctx context.Context, o *flags.ServeRegistryOpts, s *store.
 		return err
 	}
 
+	// a read-only registry with no custom config is answered straight from the
+	// store's layout; anything else still needs distribution's own storage root
+	if o.ConfigFile == "" && o.ReadOnly {
+		return serveStoreRegistry(ctx, o, s)
+	}
+
 	tr := server.NewTempRegistry(ctx, o.RootDir)
 	if err := tr.Start(); err != nil {
 		return err
@@ -145,6 +151,25 @@ func ServeRegistryCmd(ctx context.Context, o *flags.ServeRegistryOpts, s *store.
 	return nil
 }
 
+// serveStoreRegistry serves s through server.NewStoreRegistry, which starts
+// immediately and needs no disk beyond the store itself.
+func serveStoreRegistry(ctx context.Context, o *flags.ServeRegistryOpts, s *store.Layout) error {
+	l := log.FromContext(ctx)
+
+	r, err := server.NewStoreRegistry(ctx, s, *o)
+	if err != nil {
+		return err
+	}
+
+	if o.TLSCert != "" && o.TLSKey != "" {
+		l.Infof("starting registry with tls on port [%d] from store [%s]", o.Port, s.Root)
+		return r.ListenAndServeTLS(o.TLSCert, o.TLSKey)
+	}
+
+	l.Infof("starting registry on port [%d] from store [%s]", o.Port, s.Root)
+	return r.ListenAndServe()
+}
+
 func ServeFilesCmd(ctx context.Context, o *flags.ServeFilesOpts, s *store.Layout, ro *flags.CliRootOpts) error {
 	l := log.FromContext(ctx)
 
@@ -0,0 +1,183 @@
+package store
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	gcrv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+
+	"hauler.dev/go/hauler/v2/internal/flags"
+	"hauler.dev/go/hauler/v2/pkg/audit"
+	"hauler.dev/go/hauler/v2/pkg/consts"
+	"hauler.dev/go/hauler/v2/pkg/cosign"
+	"hauler.dev/go/hauler/v2/pkg/log"
+	"hauler.dev/go/hauler/v2/pkg/store"
+)
+
+// SignCmd signs images already in the store with a local private key, for
+// images whose upstream signatures no longer apply (moved by --rewrite) or
+// never existed (built in-house and added from the docker daemon). Each
+// signature is appended to the image's dev.hauler/sigs entry, so it travels
+// with the store through save/load and is pushed to the sha256-<hex>.sig tag
+// by `store copy registry://` exactly like a signature pulled from upstream.
+// With --referrer, a sigstore bundle is also stored as an OCI 1.1 referrer.
+//
+// Every image whose reference contains one of refs is signed.
+func SignCmd(ctx context.Context, o *flags.SignOpts, s *store.Layout, refs []string, ro *flags.CliRootOpts) error {
+	l := log.FromContext(ctx)
+
+	signer, err := cosign.NewSigner(o.Key)
+	if err != nil {
+		return err
+	}
+
+	targets, err := storedImages(ctx, s, refs)
+	if err != nil {
+		return err
+	}
+
+	for _, t := range targets {
+		display := t.desc.Annotations[consts.ContainerdImageNameKey]
+		if display == "" {
+			display = t.ref
+		}
+
+		sigDesc, err := signStoredImage(ctx, s, signer, t)
+		if err != nil {
+			return fmt.Errorf("failed to sign [%s]: %w", display, err)
+		}
+		l.Infof("signed [%s] with digest [%s]", display, t.desc.Digest)
+		auditSign(ctx, s, o, refs, ro, "sigs", display, sigDesc)
+
+		if o.Referrer {
+			refDesc, err := addSignatureBundle(ctx, s, signer, t)
+			if err != nil {
+				return fmt.Errorf("failed to attach signature bundle to [%s]: %w", display, err)
+			}
+			l.Infof("attached signature bundle to [%s] as referrer [%s]", display, refDesc.Digest)
+			auditSign(ctx, s, o, refs, ro, "referrer", display, refDesc)
+		}
+	}
+
+	l.Infof("signed [%d] image(s)", len(targets))
+	return nil
+}
+
+// signStoredImage signs t and stores the signature alongside any it already
+// has. The payload names the image by the repository it was added (or
+// rewritten) under, pinned to its digest, as `cosign sign` would.
+func signStoredImage(ctx context.Context, s *store.Layout, signer *cosign.Signer, t storedImage) (ocispec.Descriptor, error) {
+	repo := t.desc.Annotations[consts.ContainerdImageNameKey]
+	if repo == "" {
+		repo = t.ref
+	}
+	r, err := name.ParseReference(repo)
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+
+	sig, err := signer.Signature(ctx, r.Context().Digest(t.desc.Digest.String()))
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+
+	existing, err := storedSignatures(s, t.ref)
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	sigs, err := cosign.AppendSignature(existing, sig)
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	return s.AddRelated(ctx, t.desc, sigs, consts.KindAnnotationSigs)
+}
+
+// storedSignatures returns the signature manifest the store holds for refName,
+// or nil if it has none.
+func storedSignatures(s *store.Layout, refName string) (gcrv1.Image, error) {
+	var sigDigest string
+	if err := s.Walk(func(_ string, desc ocispec.Descriptor) error {
+		if desc.Annotations[ocispec.AnnotationRefName] == refName &&
+			desc.Annotations[consts.KindAnnotationName] == consts.KindAnnotationSigs {
+			sigDigest = desc.Digest.String()
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	if sigDigest == "" {
+		return nil, nil
+	}
+
+	p, err := layout.FromPath(s.Root)
+	if err != nil {
+		return nil, err
+	}
+	h, err := gcrv1.NewHash(sigDigest)
+	if err != nil {
+		return nil, err
+	}
+	img, err := p.Image(h)
+	if err != nil {
+		return nil, fmt.Errorf("reading existing signatures: %w", err)
+	}
+	return img, nil
+}
+
+// addSignatureBundle stores a sigstore bundle for t as an OCI 1.1 referrer.
+// Each bundle is its own referrer, so earlier ones are kept.
+func addSignatureBundle(ctx context.Context, s *store.Layout, signer *cosign.Signer, t storedImage) (ocispec.Descriptor, error) {
+	h, err := gcrv1.NewHash(t.desc.Digest.String())
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	img, err := signer.Bundle(ctx, gcrv1.Descriptor{
+		MediaType: types.MediaType(t.desc.MediaType),
+		Digest:    h,
+		Size:      t.desc.Size,
+	})
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	d, err := img.Digest()
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	return s.AddRelated(ctx, t.desc, img, consts.KindAnnotationReferrers+"/"+d.Hex)
+}
+
+// auditSign records one signature or bundle SignCmd added to the store.
+func auditSign(ctx context.Context, s *store.Layout, o *flags.SignOpts, refs []string, ro *flags.CliRootOpts, typ, reference string, desc ocispec.Descriptor) {
+	l := log.FromContext(ctx)
+
+	if auditLevel(ro) == "none" {
+		l.Debugf("generated audit id of [none]")
+		return
+	}
+	e := audit.Entry{
+		StoreID:   s.StoreID,
+		Store:     s.Root,
+		Type:      typ,
+		Command:   "store sign",
+		Args:      refs,
+		Reference: reference,
+		Digest:    desc.Digest.String(),
+	}
+	if auditLevel(ro) == "verbose" {
+		sys := audit.BuildSystem()
+		g := audit.BuildGlobal(ro, o.StoreRootOpts)
+		e.System = &sys
+		e.Global = &g
+		e.Flags = map[string]any{
+			"referrer": o.Referrer,
+		}
+	}
+	if err := audit.Append(ro.HaulerDir, e); err != nil {
+		l.Warnf("failed to write audit entry: %v", err)
+	}
+	l.Debugf("generated audit id of [%s]", audit.ID())
+}
@@ -0,0 +1,146 @@
+package store
+
+import (
+	"crypto/ecdsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+
+	"hauler.dev/go/hauler/v2/internal/flags"
+	v1 "hauler.dev/go/hauler/v2/pkg/apis/hauler.cattle.io/v1"
+	"hauler.dev/go/hauler/v2/pkg/consts"
+	"hauler.dev/go/hauler/v2/pkg/store"
+)
+
+// writeSigningKey writes priv as an unencrypted PKCS#8 PEM file.
+func writeSigningKey(t *testing.T, priv *ecdsa.PrivateKey) string {
+	t.Helper()
+	der, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		t.Fatalf("MarshalPKCS8PrivateKey: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "cosign.key")
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	return path
+}
+
+// relatedEntries returns the index entries of the given kind prefix stored
+// for refSubstr.
+func relatedEntries(t *testing.T, s *store.Layout, refSubstr, kindPrefix string) []ocispec.Descriptor {
+	t.Helper()
+	var out []ocispec.Descriptor
+	if err := s.Walk(func(_ string, desc ocispec.Descriptor) error {
+		if strings.Contains(desc.Annotations[ocispec.AnnotationRefName], refSubstr) &&
+			strings.HasPrefix(desc.Annotations[consts.KindAnnotationName], kindPrefix) {
+			out = append(out, desc)
+		}
+		return nil
+	}); err != nil {
+		t.Fatalf("Walk: %v", err)
+	}
+	return out
+}
+
+func TestSignCmd(t *testing.T) {
+	ctx := newTestContext(t)
+	host, _ := newLocalhostRegistry(t)
+	seedImage(t, host, "sign/app", "v1")
+	priv, pub := newVerifyKey(t)
+	_, otherPub := newVerifyKey(t)
+
+	s := newTestStore(t)
+	rso := defaultRootOpts(s.Root)
+	ro := defaultCliOpts()
+	if err := storeImage(ctx, s, v1.Image{Name: host + "/sign/app:v1"}, "", false, rso, ro, "", "", false); err != nil {
+		t.Fatalf("storeImage: %v", err)
+	}
+
+	vo := &flags.VerifyOpts{StoreRootOpts: rso, Key: pub}
+	if err := VerifyCmd(ctx, vo, s, []string{"sign/app"}, ro); err == nil {
+		t.Fatal("VerifyCmd succeeded before signing")
+	}
+
+	so := &flags.SignOpts{StoreRootOpts: rso, Key: writeSigningKey(t, priv)}
+	if err := SignCmd(ctx, so, s, []string{"sign/app"}, ro); err != nil {
+		t.Fatalf("SignCmd: %v", err)
+	}
+	if err := VerifyCmd(ctx, vo, s, []string{"sign/app"}, ro); err != nil {
+		t.Errorf("VerifyCmd after signing: %v", err)
+	}
+	vo.Key = otherPub
+	if err := VerifyCmd(ctx, vo, s, []string{"sign/app"}, ro); err == nil {
+		t.Error("VerifyCmd with the wrong key succeeded")
+	}
+
+	// signing again adds a second signature to the same entry
+	if err := SignCmd(ctx, so, s, []string{"sign/app"}, ro); err != nil {
+		t.Fatalf("SignCmd (second): %v", err)
+	}
+	sigs := relatedEntries(t, s, "sign/app", consts.KindAnnotationSigs)
+	if len(sigs) != 1 {
+		t.Fatalf("sig entries = %d, want 1", len(sigs))
+	}
+	img, err := storedSignatures(s, sigs[0].Annotations[ocispec.AnnotationRefName])
+	if err != nil {
+		t.Fatalf("storedSignatures: %v", err)
+	}
+	if layers, _ := img.Layers(); len(layers) != 2 {
+		t.Errorf("signature layers = %d, want 2", len(layers))
+	}
+
+	if err := SignCmd(ctx, so, s, []string{"nothing-matches"}, ro); err == nil {
+		t.Error("SignCmd with no matching images succeeded")
+	}
+}
+
+func TestSignCmd_Referrer(t *testing.T) {
+	ctx := newTestContext(t)
+	host, _ := newLocalhostRegistry(t)
+	seedImage(t, host, "sign/bundled", "v1")
+	priv, pub := newVerifyKey(t)
+
+	s := newTestStore(t)
+	rso := defaultRootOpts(s.Root)
+	ro := defaultCliOpts()
+	if err := storeImage(ctx, s, v1.Image{Name: host + "/sign/bundled:v1"}, "", false, rso, ro, "", "", false); err != nil {
+		t.Fatalf("storeImage: %v", err)
+	}
+
+	so := &flags.SignOpts{StoreRootOpts: rso, Key: writeSigningKey(t, priv), Referrer: true}
+	if err := SignCmd(ctx, so, s, []string{"sign/bundled"}, ro); err != nil {
+		t.Fatalf("SignCmd: %v", err)
+	}
+	if n := len(relatedEntries(t, s, "sign/bundled", consts.KindAnnotationReferrers)); n != 1 {
+		t.Fatalf("referrer entries = %d, want 1", n)
+	}
+
+	// with the classic signature gone, verification must fall back to the bundle
+	sigRefs := map[string]ocispec.Descriptor{}
+	if err := s.Walk(func(reference string, desc ocispec.Descriptor) error {
+		if desc.Annotations[consts.KindAnnotationName] == consts.KindAnnotationSigs {
+			sigRefs[reference] = desc
+		}
+		return nil
+	}); err != nil {
+		t.Fatalf("Walk: %v", err)
+	}
+	for reference, desc := range sigRefs {
+		if err := s.RemoveArtifact(ctx, reference, desc); err != nil {
+			t.Fatalf("RemoveArtifact: %v", err)
+		}
+	}
+	if n := len(relatedEntries(t, s, "sign/bundled", consts.KindAnnotationSigs)); n != 0 {
+		t.Fatalf("sig entries after removal = %d, want 0", n)
+	}
+	vo := &flags.VerifyOpts{StoreRootOpts: rso, Key: pub}
+	if err := VerifyCmd(ctx, vo, s, []string{"sign/bundled"}, ro); err != nil {
+		t.Errorf("VerifyCmd against the bundle: %v", err)
+	}
+}
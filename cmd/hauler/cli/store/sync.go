@@ -40,56 +40,10 @@ func SyncCmd(ctx context.Context, o *flags.SyncOpts, s *store.Layout, rso *flags
 	l := log.FromContext(ctx)
 
 	// Handle dry-run before any local side effects (temp dirs, store writes).
-	if o.DryRun {
+	// Under --prune, --dry-run instead previews the prune from the manifests.
+	if o.DryRun && !o.Prune {
 		for _, productName := range o.Products {
-			parts := strings.Split(productName, "=")
-			tag := strings.ReplaceAll(parts[1], "+", "-")
-
-			ProductRegistry := o.ProductRegistry
-			if o.ProductRegistry == "" {
-				ProductRegistry = consts.CarbideRegistry
-			}
-
-			manifestLoc := fmt.Sprintf("%s/hauler/%s-manifest.yaml:%s", ProductRegistry, parts[0], tag)
-			fileName := fmt.Sprintf("%s-manifest.yaml", parts[0])
-
-			parsedRef, err := gname.ParseReference(manifestLoc)
-			if err != nil {
-				return fmt.Errorf("failed to fetch product manifest for [%s]: %w", productName, err)
-			}
-			remoteImg, err := remote.Image(parsedRef,
-				remote.WithAuthFromKeychain(authn.DefaultKeychain),
-				remote.WithContext(ctx),
-			)
-			if err != nil {
-				return fmt.Errorf("failed to fetch product manifest for [%s]: %w", productName, err)
-			}
-			mf, err := remoteImg.Manifest()
-			if err != nil {
-				return err
-			}
-			// Select the layer whose AnnotationTitle matches the expected
-			// manifest filename, rather than assuming layer order.
-			var layerDigest *gv1.Hash
-			for _, desc := range mf.Layers {
-				if desc.Annotations[ocispec.AnnotationTitle] == fileName {
-					layerDigest = &desc.Digest
-					break
-				}
-			}
-			if layerDigest == nil {
-				return fmt.Errorf("product manifest for [%s] has no layer with title %q", productName, fileName)
-			}
-			layer, err := remoteImg.LayerByDigest(*layerDigest)
-			if err != nil {
-				return err
-			}
-			rc, err := layer.Compressed()
-			if err != nil {
-				return err
-			}
-			content, err := io.ReadAll(rc)
-			rc.Close()
+			content, err := fetchProductManifest(ctx, o, productName)
 			if err != nil {
 				return err
 			}
@@ -104,6 +58,44 @@ func SyncCmd(ctx context.Context, o *flags.SyncOpts, s *store.Layout, rso *flags
 		}
 		return nil
 	}
+	if o.DryRun {
+		return previewSyncPrune(ctx, o, s, rso, ro)
+	}
+
+	// --lock records what every job fetches; --locked pins every job to the
+	// lockfile. Either way it rides on ctx to storeImage/storeFile/fetchChart.
+	lk, err := newSyncLock(o)
+	if err != nil {
+		return err
+	}
+	ctx = withSyncLock(ctx, lk)
+
+	// --report collects one entry per job; written even when the sync fails,
+	// since that's when it's wanted most
+	rep, err := newSyncReport(o)
+	if err != nil {
+		return err
+	}
+	ctx = withSyncReport(ctx, rep)
+	defer func() {
+		if err := rep.write(); err != nil {
+			l.Warnf("failed to write sync report [%s]: %v", o.Report, err)
+		} else if rep != nil {
+			l.Infof("wrote sync report [%s]", o.Report)
+		}
+	}()
+
+	// --prune records every name the run produces, per store, and removes the rest at the end
+	pr := newSyncPrune(o)
+	ctx = withSyncPrune(ctx, pr)
+
+	// --policy (or the hauler dir's policy.yaml) admits or rejects every image and file before it's fetched
+	adm, err := newAdmission(ctx, o.Policy, "store sync", rso, ro)
+	if err != nil {
+		return err
+	}
+	defer adm.Close()
+	ctx = withAdmission(ctx, adm)
 
 	// caches stores opened via hauler.dev/store, keyed by abs path, so docs sharing a target reuse one Layout
 	targetStores := map[string]*store.Layout{}
@@ -127,6 +119,7 @@ func SyncCmd(ctx context.Context, o *flags.SyncOpts, s *store.Layout, rso *flags
 				l.Warnf("failed to save index for target store [%s]: %v", ts.Root, err)
 			}
 			l.Debugf("%s", formatIOStats(ts.OCI.Stats().Snapshot(), ts.OCI.BlobConcurrency()))
+			ts.Unlock()
 		}
 	}()
 
@@ -142,16 +135,7 @@ func SyncCmd(ctx context.Context, o *flags.SyncOpts, s *store.Layout, rso *flags
 	// if passed products, check for a remote manifest to retrieve and use
 	for _, productName := range o.Products {
 		l.Infof("processing product manifest for [%s] to store [%s]", productName, o.StoreDir)
-		parts := strings.Split(productName, "=")
-		tag := strings.ReplaceAll(parts[1], "+", "-")
-
-		ProductRegistry := o.ProductRegistry // cli flag
-		// if no cli flag use CarbideRegistry.
-		if o.ProductRegistry == "" {
-			ProductRegistry = consts.CarbideRegistry
-		}
-
-		manifestLoc := fmt.Sprintf("%s/hauler/%s-manifest.yaml:%s", ProductRegistry, parts[0], tag)
+		manifestLoc, storeRef, fileName := productManifestRef(o, productName)
 		l.Infof("fetching product manifest from [%s]", manifestLoc)
 
 		img := v1.Image{
@@ -159,17 +143,21 @@ func SyncCmd(ctx context.Context, o *flags.SyncOpts, s *store.Layout, rso *flags
 			InsecureSkipTLSVerify: o.InsecureSkipTLSVerify,
 			CaFile:                o.CaFile,
 		}
-		err := storeImage(ctx, s, img, o.Platform, o.ExcludeExtras, rso, ro, "", "", false)
+		pr.target(s)
+		pinned, err := lk.pinImage(ctx, imageJob{img: img, platform: o.Platform}, "", rso, ro)
+		if err != nil {
+			return fmt.Errorf("failed to fetch product manifest for [%s]: %w", productName, err)
+		}
+		err = storeImage(ctx, s, img, o.Platform, o.ExcludeExtras, rso, ro, "", pinned, false)
 		if err != nil {
 			return fmt.Errorf("failed to fetch product manifest for [%s]: %w", productName, err)
 		}
 		// The manifest is output for the user, so it goes to workDir, not tempDir (removed when sync returns).
 		workDir := flags.ResolveWorkDir(ro)
-		err = ExtractCmd(ctx, &flags.ExtractOpts{StoreRootOpts: o.StoreRootOpts, DestinationDir: workDir}, s, fmt.Sprintf("hauler/%s-manifest.yaml:%s", parts[0], tag))
+		err = ExtractCmd(ctx, &flags.ExtractOpts{StoreRootOpts: o.StoreRootOpts, DestinationDir: workDir}, s, storeRef)
 		if err != nil {
 			return err
 		}
-		fileName := fmt.Sprintf("%s-manifest.yaml", parts[0])
 		fi, err := os.Open(filepath.Join(workDir, fileName))
 		if err != nil {
 			return err
@@ -277,6 +265,7 @@ func SyncCmd(ctx context.Context, o *flags.SyncOpts, s *store.Layout, rso *flags
 			}
 			defer fi.Close()
 
+			pr.target(s)
 			err = processImageTxt(ctx, fi, o, s, rso, ro)
 			if err != nil {
 				return err
@@ -286,9 +275,83 @@ func SyncCmd(ctx context.Context, o *flags.SyncOpts, s *store.Layout, rso *flags
 		}
 	}
 
+	if o.Lock {
+		if err := lk.write(); err != nil {
+			return fmt.Errorf("failed to write lockfile: %w", err)
+		}
+		l.Infof("wrote lockfile [%s]", o.Lockfile)
+	}
+
+	if err := pr.prune(ctx, o, rso, ro); err != nil {
+		return err
+	}
+
 	return nil
 }
 
+// productManifestRef returns where a --products entry's manifest is
+// published, the name it's stored under, and the name of the manifest file
+// in it.
+func productManifestRef(o *flags.SyncOpts, productName string) (manifestLoc, storeRef, fileName string) {
+	parts := strings.Split(productName, "=")
+	tag := strings.ReplaceAll(parts[1], "+", "-")
+
+	ProductRegistry := o.ProductRegistry // cli flag
+	// if no cli flag use CarbideRegistry.
+	if o.ProductRegistry == "" {
+		ProductRegistry = consts.CarbideRegistry
+	}
+
+	storeRef = fmt.Sprintf("hauler/%s-manifest.yaml:%s", parts[0], tag)
+	manifestLoc = ProductRegistry + "/" + storeRef
+	fileName = fmt.Sprintf("%s-manifest.yaml", parts[0])
+	return manifestLoc, storeRef, fileName
+}
+
+// fetchProductManifest reads a --products entry's manifest straight from its
+// registry, without adding it to a store.
+func fetchProductManifest(ctx context.Context, o *flags.SyncOpts, productName string) ([]byte, error) {
+	manifestLoc, _, fileName := productManifestRef(o, productName)
+
+	parsedRef, err := gname.ParseReference(manifestLoc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch product manifest for [%s]: %w", productName, err)
+	}
+	remoteImg, err := remote.Image(parsedRef,
+		remote.WithAuthFromKeychain(authn.DefaultKeychain),
+		remote.WithContext(ctx),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch product manifest for [%s]: %w", productName, err)
+	}
+	mf, err := remoteImg.Manifest()
+	if err != nil {
+		return nil, err
+	}
+	// Select the layer whose AnnotationTitle matches the expected
+	// manifest filename, rather than assuming layer order.
+	var layerDigest *gv1.Hash
+	for _, desc := range mf.Layers {
+		if desc.Annotations[ocispec.AnnotationTitle] == fileName {
+			layerDigest = &desc.Digest
+			break
+		}
+	}
+	if layerDigest == nil {
+		return nil, fmt.Errorf("product manifest for [%s] has no layer with title %q", productName, fileName)
+	}
+	layer, err := remoteImg.LayerByDigest(*layerDigest)
+	if err != nil {
+		return nil, err
+	}
+	rc, err := layer.Compressed()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(rc)
+}
+
 // resolveBoolFlag applies CLI-first precedence for a plain-bool flag: an
 // explicitly-set CLI flag wins outright (even when false); otherwise the flag
 // is on if the resolved CLI value (e.g. from an env var), the per-item field,
@@ -341,6 +404,7 @@ func processContent(ctx context.Context, fi *os.File, o *flags.SyncOpts, s *stor
 				if err != nil {
 					return err
 				}
+				syncPruneFromContext(ctx).target(docStore)
 				docRso, err := resolveDocRetries(a, rso, o.RetriesChanged)
 				if err != nil {
 					return err
@@ -368,6 +432,7 @@ func processContent(ctx context.Context, fi *os.File, o *flags.SyncOpts, s *stor
 				if err != nil {
 					return err
 				}
+				syncPruneFromContext(ctx).target(docStore)
 				docRso, err := resolveDocRetries(a, rso, o.RetriesChanged)
 				if err != nil {
 					return err
@@ -397,6 +462,7 @@ func processContent(ctx context.Context, fi *os.File, o *flags.SyncOpts, s *stor
 				if err != nil {
 					return err
 				}
+				syncPruneFromContext(ctx).target(docStore)
 				docRso, err := resolveDocRetries(a, rso, o.RetriesChanged)
 				if err != nil {
 					return err
@@ -454,6 +520,10 @@ func resolveTargetStore(ctx context.Context, a map[string]string, def *store.Lay
 	if err != nil {
 		return nil, fmt.Errorf("failed to open target store [%s]: %w", target, err)
 	}
+	// released by SyncCmd's end-of-run index checkpoint
+	if err := altOpts.LockExclusive(ctx, ts, "hauler store sync"); err != nil {
+		return nil, err
+	}
 
 	targetStores[abs] = ts
 	return ts, nil
@@ -559,14 +629,15 @@ func newProgressRenderer(noProgress bool, logLevel string) *log.Renderer {
 	return log.NewRenderer(os.Stdout)
 }
 
-// formatIOStats renders one line summarizing a sync's disk contention.
-// ceiling is the store's configured blob-write limit, so peak-inflight
-// reads as a fraction of what was permitted rather than a bare number.
+// formatIOStats renders one line summarizing a sync's (or a registry
+// copy's) disk contention. ceiling is the store's configured blob-write
+// limit, so peak-inflight reads as a fraction of what was permitted rather
+// than a bare number.
 //
-// blobs/written/cached/bytes cover only the WriteBlob path (store.AddImage
-// and friends); a registry push shares the same blob semaphore without
-// calling WriteBlob, so those counters can read zero on a store-to-store
-// copy. blobsem-wait sums wait time across every goroutine that touched the
+// blobs/written/cached/bytes cover the WriteBlob path (store.AddImage and
+// friends) and pushes made through content.OCI.LimitTarget (`store copy
+// registry://`), where "cached" means the registry already had the blob.
+// blobsem-wait sums wait time across every goroutine that touched the
 // semaphore, not wall-clock, so it can exceed the run's total duration.
 func formatIOStats(st content.IOStatsSnapshot, ceiling int) string {
 	return fmt.Sprintf(
@@ -1117,8 +1188,19 @@ func runRemoteImageJobsWith(ctx context.Context, s *store.Layout, jobs []imageJo
 			// log.FromContext(ctx) and keep the field for attribution.
 			jctx = log.WithBaseLogger(jctx, baseLogger)
 
+			// one report entry spans verification and the store itself
+			rep := syncReportFromContext(jctx).begin("image", j.img.Name, s)
+			defer rep.done()
+			jctx = withReportEntry(jctx, rep)
+
+			if err := validateLabels(j.img.Labels); err != nil {
+				rep.fail(reportFetchFailed, err)
+				return err
+			}
+
 			pinned, err := resolveAndVerify(jctx, cache, j, rso, ro)
 			if err != nil {
+				rep.fail(reportVerifyFailed, err)
 				// A verification failure either fails the run or is stored
 				// unverified, depending on --ignore-errors -- see
 				// logVerifyFailure's doc for the exact rule, including the
@@ -1134,6 +1216,25 @@ func runRemoteImageJobsWith(ctx context.Context, s *store.Layout, jobs []imageJo
 			// and succeeded: err is nil on success and stays non-nil on a
 			// failure that fell through to here under --ignore-errors.
 			verified := err == nil && !j.verifyConfig().Empty()
+
+			// Under --lock/--locked the digest is settled before the fetch,
+			// so what's recorded (or enforced) is exactly what's stored.
+			pinned, lockErr := syncLockFromContext(jctx).pinImage(jctx, j, pinned, rso, ro)
+			if lockErr != nil {
+				rep.fail(reportFetchFailed, lockErr)
+				if ignoreErrors {
+					baseLogger.Warnf("unable to pin image [%s]: %v... skipping...", j.img.Name, lockErr)
+					if r, err := gname.ParseReference(j.img.Name); err == nil {
+						syncPruneFromContext(jctx).keepImage(s, r)
+					}
+					return nil
+				} else if errors.Is(lockErr, context.Canceled) {
+					baseLogger.Debugf("unable to pin image [%s]: %v", j.img.Name, lockErr)
+					return lockErr
+				}
+				baseLogger.Errorf("unable to pin image [%s]: %v", j.img.Name, lockErr)
+				return lockErr
+			}
 			return storeImage(jctx, s, j.img, j.platform, j.excludeExtras, rso, ro, j.rewrite, pinned, verified)
 		})
 	}
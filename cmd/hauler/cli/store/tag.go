@@ -0,0 +1,261 @@
+package store
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+
+	"hauler.dev/go/hauler/v2/internal/flags"
+	"hauler.dev/go/hauler/v2/pkg/audit"
+	"hauler.dev/go/hauler/v2/pkg/consts"
+	"hauler.dev/go/hauler/v2/pkg/content"
+	"hauler.dev/go/hauler/v2/pkg/log"
+	"hauler.dev/go/hauler/v2/pkg/reference"
+	"hauler.dev/go/hauler/v2/pkg/store"
+)
+
+// tagMapping is one src -> dst pair, from the command line or a mapping file.
+type tagMapping struct {
+	src string
+	dst string
+}
+
+// tagEntry is one index entry to store under a new reference.
+type tagEntry struct {
+	mapping tagMapping
+	oldKey  string
+	desc    ocispec.Descriptor // as it will be indexed under the new reference
+}
+
+// TagCmd stores already-stored artifacts under new references, the same
+// rename --rewrite applies at add time. Everything saved under an artifact's
+// reference -- signatures, attestations, sboms, and referrers -- is tagged
+// along with it, and hauler.dev/original-ref is left alone so `store create
+// manifest` can still recover where the artifact came from. With --move the
+// old reference is dropped.
+//
+// Every mapping is resolved before the index is touched, so a bad line in a
+// mapping file leaves the store as it was.
+func TagCmd(ctx context.Context, o *flags.TagOpts, s *store.Layout, args []string, ro *flags.CliRootOpts) error {
+	l := log.FromContext(ctx)
+
+	var mappings []tagMapping
+	if o.File != "" {
+		var err error
+		if mappings, err = readTagMappings(o.File); err != nil {
+			return err
+		}
+	} else {
+		mappings = []tagMapping{{src: args[0], dst: args[1]}}
+	}
+
+	indexed := map[string]ocispec.Descriptor{}
+	if err := s.Walk(func(key string, desc ocispec.Descriptor) error {
+		indexed[key] = desc
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	var planned []tagEntry
+	for _, m := range mappings {
+		group, err := matchTagSource(indexed, m.src)
+		if err != nil {
+			return err
+		}
+		refName, fullName, err := tagNames(group[0], m.dst)
+		if err != nil {
+			return err
+		}
+
+		for _, desc := range group {
+			oldKey, _ := content.IndexKey(desc)
+			tagged := store.RenameDescriptor(desc, refName, fullName)
+			newKey, err := content.IndexKey(tagged)
+			if err != nil {
+				return fmt.Errorf("invalid destination reference [%s]: %w", m.dst, err)
+			}
+			if existing, ok := indexed[newKey]; ok {
+				if existing.Digest != desc.Digest {
+					return fmt.Errorf("reference [%s] is already stored with digest [%s]... remove it first", refName, existing.Digest)
+				}
+				if newKey == oldKey || !o.Move {
+					// already tagged
+					continue
+				}
+			}
+			indexed[newKey] = tagged
+			planned = append(planned, tagEntry{mapping: m, oldKey: oldKey, desc: tagged})
+		}
+	}
+
+	for _, e := range planned {
+		if err := s.OCI.AddIndex(e.desc); err != nil {
+			return err
+		}
+	}
+	if o.Move {
+		for _, e := range planned {
+			if newKey, _ := content.IndexKey(e.desc); newKey != e.oldKey {
+				s.OCI.RemoveFromIndex(e.oldKey)
+			}
+		}
+	}
+	if err := s.OCI.SaveIndex(); err != nil {
+		return fmt.Errorf("failed to save index: %w", err)
+	}
+
+	verb := "tagged"
+	if o.Move {
+		verb = "moved"
+	}
+	for _, e := range planned {
+		auditTag(ctx, o, s, e, ro)
+		l.Infof("%s [%s] of type [%s] as [%s] with digest [%s]", verb, e.mapping.src, artifactType(ctx, s, e.desc), e.desc.Annotations[ocispec.AnnotationRefName], e.desc.Digest)
+	}
+	if len(planned) == 0 {
+		l.Infof("nothing to tag... every destination reference is already in place")
+	}
+	return nil
+}
+
+// readTagMappings reads a mapping file: one "<src-ref> <dst-ref>" pair per
+// line, with blank lines and # comments skipped.
+func readTagMappings(path string) ([]tagMapping, error) {
+	fi, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer fi.Close()
+
+	var mappings []tagMapping
+	scanner := bufio.NewScanner(fi)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if i := strings.Index(line, "#"); i != -1 {
+			line = strings.TrimSpace(line[:i])
+		}
+		if line == "" {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("%s:%d: want '<src-ref> <dst-ref>', got [%s]", path, n, line)
+		}
+		mappings = append(mappings, tagMapping{src: fields[0], dst: fields[1]})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(mappings) == 0 {
+		return nil, fmt.Errorf("no mappings found in [%s]", path)
+	}
+	return mappings, nil
+}
+
+// matchTagSource returns every index entry stored under the one reference src
+// names, the artifact itself first. src may be the stored reference
+// ("repo:tag"), the image's full name, or a short name the way add takes it.
+func matchTagSource(indexed map[string]ocispec.Descriptor, src string) ([]ocispec.Descriptor, error) {
+	candidates := map[string]bool{src: true}
+	if r, err := name.ParseReference(src); err == nil {
+		candidates[strings.TrimPrefix(r.Name(), r.Context().RegistryStr()+"/")] = true
+	}
+	if r, err := reference.Parse(src); err == nil {
+		candidates[r.Name()] = true
+	}
+
+	refNames := map[string]bool{}
+	for _, desc := range indexed {
+		if candidates[desc.Annotations[ocispec.AnnotationRefName]] || desc.Annotations[consts.ContainerdImageNameKey] == src {
+			refNames[desc.Annotations[ocispec.AnnotationRefName]] = true
+		}
+	}
+	switch len(refNames) {
+	case 0:
+		return nil, fmt.Errorf("reference [%s] not found in store (use `hauler store info` to list store contents)", src)
+	case 1:
+	default:
+		var names []string
+		for n := range refNames {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("reference [%s] is ambiguous... matches %v", src, names)
+	}
+
+	var group []ocispec.Descriptor
+	for _, desc := range indexed {
+		if refNames[desc.Annotations[ocispec.AnnotationRefName]] {
+			group = append(group, desc)
+		}
+	}
+	store.SortRelatedLast(group)
+	return group, nil
+}
+
+// tagNames computes the stored reference, and for an image the full name,
+// that desc gets under dst: the names --rewrite would give it at add time.
+func tagNames(desc ocispec.Descriptor, dst string) (refName, fullName string, err error) {
+	if full, ok := desc.Annotations[consts.ContainerdImageNameKey]; ok {
+		oldRef, err := name.ParseReference(full)
+		if err != nil {
+			return "", "", fmt.Errorf("unable to parse image [%s]: %w", full, err)
+		}
+		newRef, err := imageRewriteRef(oldRef, dst)
+		if err != nil {
+			return "", "", err
+		}
+		_, _, refName, fullName = rewriteNames(oldRef, newRef, dst)
+		return refName, fullName, nil
+	}
+
+	ref, err := reference.Parse(desc.Annotations[ocispec.AnnotationRefName])
+	if err != nil {
+		return "", "", fmt.Errorf("parsing reference [%s]: %w", desc.Annotations[ocispec.AnnotationRefName], err)
+	}
+	_, refName, err = chartRewriteNames(ref, dst)
+	return refName, "", err
+}
+
+func auditTag(ctx context.Context, o *flags.TagOpts, s *store.Layout, e tagEntry, ro *flags.CliRootOpts) {
+	l := log.FromContext(ctx)
+	if auditLevel(ro) == "none" {
+		l.Debugf("generated audit id of [none]")
+		return
+	}
+
+	ref := e.desc.Annotations[consts.ContainerdImageNameKey]
+	if ref == "" {
+		ref = e.desc.Annotations[ocispec.AnnotationRefName]
+	}
+	ae := audit.Entry{
+		StoreID:   s.StoreID,
+		Store:     s.Root,
+		Type:      artifactType(ctx, s, e.desc),
+		Command:   "store tag",
+		Args:      []string{e.mapping.src, e.mapping.dst},
+		Reference: ref,
+		Digest:    e.desc.Digest.String(),
+	}
+	if auditLevel(ro) == "verbose" {
+		sys := audit.BuildSystem()
+		g := audit.BuildGlobal(ro, o.StoreRootOpts)
+		ae.System = &sys
+		ae.Global = &g
+		ae.Flags = map[string]any{
+			"move": o.Move,
+			"file": o.File,
+		}
+	}
+	if err := audit.Append(ro.HaulerDir, ae); err != nil {
+		l.Warnf("failed to write audit entry: %v", err)
+	}
+	l.Debugf("generated audit id of [%s]", audit.ID())
+}
@@ -0,0 +1,140 @@
+package store
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+
+	"hauler.dev/go/hauler/v2/internal/flags"
+	v1 "hauler.dev/go/hauler/v2/pkg/apis/hauler.cattle.io/v1"
+	"hauler.dev/go/hauler/v2/pkg/consts"
+	"hauler.dev/go/hauler/v2/pkg/store"
+)
+
+// storedKinds maps each reference in s's index to the kinds stored under it.
+func storedKinds(t *testing.T, s *store.Layout) map[string][]string {
+	t.Helper()
+	refs := map[string][]string{}
+	if err := s.Walk(func(_ string, desc ocispec.Descriptor) error {
+		ref := desc.Annotations[ocispec.AnnotationRefName]
+		refs[ref] = append(refs[ref], desc.Annotations[consts.KindAnnotationName])
+		return nil
+	}); err != nil {
+		t.Fatalf("Walk: %v", err)
+	}
+	return refs
+}
+
+func TestTagCmd(t *testing.T) {
+	ctx := newTestContext(t)
+	host, _ := newLocalhostRegistry(t)
+	seedSignedImage(t, host, "tag/app", "v1")
+	seedImage(t, host, "tag/other", "v1")
+
+	s := newTestStore(t)
+	rso := defaultRootOpts(s.Root)
+	ro := defaultCliOpts()
+	ro.HaulerDir = t.TempDir()
+	ro.AuditLevel = "standard"
+	for _, ref := range []string{"tag/app:v1", "tag/other:v1"} {
+		if err := storeImage(ctx, s, v1.Image{Name: host + "/" + ref}, "", false, rso, ro, "", "", false); err != nil {
+			t.Fatalf("storeImage(%s): %v", ref, err)
+		}
+	}
+	if kinds := storedKinds(t, s)["tag/app:v1"]; len(kinds) != 2 {
+		t.Fatalf("tag/app:v1 stored as %v, want the image and its signature", kinds)
+	}
+
+	o := &flags.TagOpts{StoreRootOpts: rso}
+	if err := TagCmd(ctx, o, s, []string{host + "/tag/app:v1", "tag/alias:v2"}, ro); err != nil {
+		t.Fatalf("TagCmd: %v", err)
+	}
+	kinds := storedKinds(t, s)
+	if len(kinds["tag/alias:v2"]) != 2 || len(kinds["tag/app:v1"]) != 2 {
+		t.Errorf("after tag the index holds %v, want the image and signature under both references", kinds)
+	}
+
+	if err := TagCmd(ctx, &flags.TagOpts{StoreRootOpts: rso, Move: true}, s, []string{"tag/alias:v2", "tag/moved:v3"}, ro); err != nil {
+		t.Fatalf("TagCmd(move): %v", err)
+	}
+	kinds = storedKinds(t, s)
+	if _, ok := kinds["tag/alias:v2"]; ok || len(kinds["tag/moved:v3"]) != 2 {
+		t.Errorf("after move the index holds %v, want tag/alias:v2 gone and tag/moved:v3 with its signature", kinds)
+	}
+
+	// the original pull reference survives both renames
+	if err := s.Walk(func(_ string, desc ocispec.Descriptor) error {
+		if desc.Annotations[ocispec.AnnotationRefName] == "tag/moved:v3" && desc.Annotations[consts.KindAnnotationName] == consts.KindAnnotationImage {
+			if got := desc.Annotations[consts.OriginalRefAnnotation]; got != host+"/tag/app:v1" {
+				t.Errorf("original ref = %q, want %q", got, host+"/tag/app:v1")
+			}
+			if got := desc.Annotations[consts.ContainerdImageNameKey]; got != host+"/tag/moved:v3" {
+				t.Errorf("image name = %q, want %q", got, host+"/tag/moved:v3")
+			}
+		}
+		return nil
+	}); err != nil {
+		t.Fatalf("Walk: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(ro.HaulerDir, "audit.log"))
+	if err != nil || !strings.Contains(string(data), `"store tag"`) {
+		t.Errorf("audit.log = %q (%v), want a store tag entry", data, err)
+	}
+
+	err = TagCmd(ctx, o, s, []string{"tag/other:v1", "tag/app:v1"}, ro)
+	if err == nil || !strings.Contains(err.Error(), "already stored") {
+		t.Errorf("TagCmd onto another image = %v, want a refusal", err)
+	}
+}
+
+// A mapping file is checked in full before any of it is applied.
+func TestTagCmd_MappingFile(t *testing.T) {
+	ctx := newTestContext(t)
+	host, _ := newLocalhostRegistry(t)
+	seedImage(t, host, "tag/app", "v1")
+	fileURL := seedFileInHTTPServer(t, "notes.txt", "notes")
+
+	s := newTestStore(t)
+	rso := defaultRootOpts(s.Root)
+	ro := defaultCliOpts()
+	ro.HaulerDir = t.TempDir()
+	if err := storeImage(ctx, s, v1.Image{Name: host + "/tag/app:v1"}, "", true, rso, ro, "", "", false); err != nil {
+		t.Fatalf("storeImage: %v", err)
+	}
+	if err := storeFile(ctx, s, v1.File{Path: fileURL}, ro, rso); err != nil {
+		t.Fatalf("storeFile: %v", err)
+	}
+
+	mapping := filepath.Join(t.TempDir(), "retag.txt")
+	write := func(content string) {
+		if err := os.WriteFile(mapping, []byte(content), 0o644); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+	}
+
+	write("tag/app:v1 tag/app:stable\nmissing:v1 anything:v1\n")
+	if err := TagCmd(ctx, &flags.TagOpts{StoreRootOpts: rso, File: mapping}, s, nil, ro); err == nil {
+		t.Fatal("TagCmd succeeded with a mapping for a missing reference")
+	}
+	if _, ok := storedKinds(t, s)["tag/app:stable"]; ok {
+		t.Error("a failed mapping file was partly applied")
+	}
+
+	write("# release aliases\ntag/app:v1 tag/app:stable\nnotes.txt docs/notes.txt:v1\n")
+	if err := TagCmd(ctx, &flags.TagOpts{StoreRootOpts: rso, File: mapping, Move: true}, s, nil, ro); err != nil {
+		t.Fatalf("TagCmd: %v", err)
+	}
+	kinds := storedKinds(t, s)
+	for _, want := range []string{"tag/app:stable", "docs/notes.txt:v1"} {
+		if _, ok := kinds[want]; !ok {
+			t.Errorf("index holds %v, want %s", kinds, want)
+		}
+	}
+	if len(kinds) != 2 {
+		t.Errorf("index holds %v, want only the moved references", kinds)
+	}
+}
@@ -73,6 +73,17 @@ func newTestKeyPair(t *testing.T) (*ecdsa.PrivateKey, string) {
 	return priv, path
 }
 
+// writeTempFile writes content to a file called name in a fresh temp dir and
+// returns its path.
+func writeTempFile(t *testing.T, name, content string) string {
+	t.Helper()
+	p := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	return p
+}
+
 // writeTestPubKey returns the path to a public key with no signed image behind
 // it -- for tests that only need verification to fail closed.
 func writeTestPubKey(t *testing.T) string {
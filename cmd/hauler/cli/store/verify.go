@@ -0,0 +1,167 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+
+	"hauler.dev/go/hauler/v2/internal/flags"
+	"hauler.dev/go/hauler/v2/internal/server"
+	"hauler.dev/go/hauler/v2/pkg/consts"
+	"hauler.dev/go/hauler/v2/pkg/cosign"
+	"hauler.dev/go/hauler/v2/pkg/log"
+	"hauler.dev/go/hauler/v2/pkg/store"
+)
+
+// VerifyCmd re-verifies the cosign signatures of images already in the store,
+// without network access. The signature manifests, attestations, and referrer
+// bundles saved alongside each image are served to cosign over a loopback
+// registry backed directly by the store layout, so cosign reads exactly what
+// `store add`/`sync` (or `store load`) put there. Trust comes from --key or
+// from a sigstore trusted root file... never from TUF or Rekor.
+//
+// When refs are given, only images whose reference contains one of them are
+// verified. Every image's result is logged; the command fails if any failed.
+func VerifyCmd(ctx context.Context, o *flags.VerifyOpts, s *store.Layout, refs []string, ro *flags.CliRootOpts) error {
+	l := log.FromContext(ctx)
+
+	cfg := cosign.Config{
+		Key:                          o.Key,
+		Tlog:                         o.Tlog,
+		CertIdentity:                 o.CertIdentity,
+		CertIdentityRegexp:           o.CertIdentityRegexp,
+		CertOidcIssuer:               o.CertOidcIssuer,
+		CertOidcIssuerRegexp:         o.CertOidcIssuerRegexp,
+		CertGithubWorkflowRepository: o.CertGithubWorkflowRepository,
+		TrustedRoot:                  o.TrustedRoot,
+		Offline:                      true,
+	}
+
+	targets, err := storedImages(ctx, s, refs)
+	if err != nil {
+		return err
+	}
+	if len(targets) == 0 {
+		l.Infof("no images in the store to verify")
+		return nil
+	}
+
+	host, stop, err := serveStoreLoopback(ctx, s)
+	if err != nil {
+		return err
+	}
+	defer stop()
+
+	// nothing an offline check reads can change between attempts, so a
+	// failure is final... never sit out RetriesInterval on one
+	vrso := *o.StoreRootOpts
+	vrso.Retries = 1
+	v, err := cosign.NewVerifier(ctx, cfg, &vrso, ro)
+	if err != nil {
+		return fmt.Errorf("failed to set up verification: %w", err)
+	}
+	defer v.Close()
+
+	var failed int
+	for _, t := range targets {
+		display := t.desc.Annotations[consts.ContainerdImageNameKey]
+		if display == "" {
+			display = t.ref
+		}
+
+		r, err := name.ParseReference(host+"/"+t.ref, name.WithDefaultRegistry(""))
+		if err == nil {
+			err = v.Verify(ctx, r.Context().Digest(t.desc.Digest.String()).Name())
+		}
+		if err != nil {
+			if errors.Is(err, context.Canceled) {
+				return err
+			}
+			failed++
+			l.Errorf("✗ %s [%s]: %s", display, t.desc.Digest, flattenVerifyError(err))
+			continue
+		}
+		l.Infof("✓ %s [%s]: verified", display, t.desc.Digest)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("signature verification failed for [%d] of [%d] image(s)", failed, len(targets))
+	}
+	l.Infof("verified [%d] image(s)", len(targets))
+	return nil
+}
+
+// storedImage is an image (or image index) entry in the store's index.
+type storedImage struct {
+	ref  string
+	desc ocispec.Descriptor
+}
+
+// storedImages lists the store's images sorted by reference, narrowed to
+// those matching refs when any are given. Matching nothing at all is an error
+// when refs were given, since the caller named images the store doesn't hold.
+func storedImages(ctx context.Context, s *store.Layout, refs []string) ([]storedImage, error) {
+	var images []storedImage
+	if err := s.Walk(func(reference string, desc ocispec.Descriptor) error {
+		refName := desc.Annotations[ocispec.AnnotationRefName]
+		if refName == "" || artifactType(ctx, s, desc) != "image" {
+			return nil
+		}
+		if len(refs) > 0 && !matchesAny(desc, refs) {
+			return nil
+		}
+		images = append(images, storedImage{ref: refName, desc: desc})
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	sort.Slice(images, func(i, j int) bool { return images[i].ref < images[j].ref })
+
+	if len(images) == 0 && len(refs) > 0 {
+		return nil, fmt.Errorf("no images in the store match %v", refs)
+	}
+	return images, nil
+}
+
+// matchesAny reports whether desc's reference contains any of refs, the same
+// partial matching `store remove` and `store extract` accept.
+func matchesAny(desc ocispec.Descriptor, refs []string) bool {
+	for _, ref := range refs {
+		if strings.Contains(desc.Annotations[ocispec.AnnotationRefName], ref) ||
+			strings.Contains(desc.Annotations[consts.ContainerdImageNameKey], ref) {
+			return true
+		}
+	}
+	return false
+}
+
+// serveStoreLoopback serves s as a read-only registry on a random loopback
+// port, returning its host:port and a func that shuts it down. Loopback
+// registries are spoken to over plain HTTP, so no TLS setup is needed.
+func serveStoreLoopback(ctx context.Context, s *store.Layout) (string, func(), error) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to start loopback registry: %w", err)
+	}
+
+	srv := &http.Server{
+		Handler:           server.NewStoreRegistryHandler(s),
+		ReadHeaderTimeout: time.Duration(consts.DefaultFileserverTimeout) * time.Second,
+	}
+	go srv.Serve(lis) //nolint:errcheck // always ErrServerClosed after stop
+
+	stop := func() {
+		sctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(sctx) //nolint:errcheck
+	}
+	return lis.Addr().String(), stop, nil
+}
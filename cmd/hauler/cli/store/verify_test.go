@@ -0,0 +1,147 @@
+package store
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	gcrv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/sigstore/cosign/v3/pkg/oci/empty"
+	"github.com/sigstore/cosign/v3/pkg/oci/mutate"
+	"github.com/sigstore/cosign/v3/pkg/oci/static"
+	"github.com/sigstore/sigstore/pkg/signature"
+	"github.com/sigstore/sigstore/pkg/signature/payload"
+
+	"hauler.dev/go/hauler/v2/internal/flags"
+	v1 "hauler.dev/go/hauler/v2/pkg/apis/hauler.cattle.io/v1"
+)
+
+// newVerifyKey generates an ECDSA key pair and writes the public half as a
+// cosign-style PEM file.
+func newVerifyKey(t *testing.T) (*ecdsa.PrivateKey, string) {
+	t.Helper()
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&priv.PublicKey)
+	if err != nil {
+		t.Fatalf("MarshalPKIXPublicKey: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "cosign.pub")
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	return priv, path
+}
+
+// signSeededImage pushes a valid cosign v2 signature for img, signed by priv,
+// at its sha256-<hex>.sig tag.
+func signSeededImage(t *testing.T, host, repo string, img gcrv1.Image, priv *ecdsa.PrivateKey, opts ...remote.Option) {
+	t.Helper()
+	h, err := img.Digest()
+	if err != nil {
+		t.Fatalf("Digest: %v", err)
+	}
+	d, err := name.NewDigest(host+"/"+repo+"@"+h.String(), name.Insecure)
+	if err != nil {
+		t.Fatalf("NewDigest: %v", err)
+	}
+	pl, err := payload.Cosign{Image: d}.MarshalJSON()
+	if err != nil {
+		t.Fatalf("payload: %v", err)
+	}
+	sv, err := signature.LoadECDSASignerVerifier(priv, crypto.SHA256)
+	if err != nil {
+		t.Fatalf("LoadECDSASignerVerifier: %v", err)
+	}
+	raw, err := sv.SignMessage(bytes.NewReader(pl))
+	if err != nil {
+		t.Fatalf("SignMessage: %v", err)
+	}
+	sig, err := static.NewSignature(pl, base64.StdEncoding.EncodeToString(raw))
+	if err != nil {
+		t.Fatalf("NewSignature: %v", err)
+	}
+	sigs, err := mutate.AppendSignatures(empty.Signatures(), false, sig)
+	if err != nil {
+		t.Fatalf("AppendSignatures: %v", err)
+	}
+	tag, err := name.NewTag(host+"/"+repo+":"+strings.ReplaceAll(h.String(), ":", "-")+".sig", name.Insecure)
+	if err != nil {
+		t.Fatalf("NewTag: %v", err)
+	}
+	if err := remote.Write(tag, sigs, opts...); err != nil {
+		t.Fatalf("remote.Write: %v", err)
+	}
+}
+
+func TestVerifyCmd_Key(t *testing.T) {
+	ctx := newTestContext(t)
+	host, rOpts := newLocalhostRegistry(t)
+	priv, pub := newVerifyKey(t)
+	_, otherPub := newVerifyKey(t)
+
+	signed := seedImage(t, host, "verify/signed", "v1")
+	signSeededImage(t, host, "verify/signed", signed, priv, rOpts...)
+	seedImage(t, host, "verify/unsigned", "v1")
+
+	s := newTestStore(t)
+	rso := defaultRootOpts(s.Root)
+	ro := defaultCliOpts()
+	for _, ref := range []string{host + "/verify/signed:v1", host + "/verify/unsigned:v1"} {
+		if err := storeImage(ctx, s, v1.Image{Name: ref}, "", false, rso, ro, "", "", false); err != nil {
+			t.Fatalf("storeImage(%s): %v", ref, err)
+		}
+	}
+
+	o := &flags.VerifyOpts{StoreRootOpts: rso, Key: pub}
+	if err := VerifyCmd(ctx, o, s, []string{"verify/signed"}, ro); err != nil {
+		t.Errorf("VerifyCmd(signed): %v", err)
+	}
+
+	o.Key = otherPub
+	if err := VerifyCmd(ctx, o, s, []string{"verify/signed"}, ro); err == nil {
+		t.Error("VerifyCmd with the wrong key succeeded")
+	}
+
+	o.Key = pub
+	err := VerifyCmd(ctx, o, s, nil, ro)
+	if err == nil || !strings.Contains(err.Error(), "[1] of [2]") {
+		t.Errorf("VerifyCmd(all) = %v, want the unsigned image to fail", err)
+	}
+
+	if err := VerifyCmd(ctx, o, s, []string{"nothing-matches"}, ro); err == nil {
+		t.Error("VerifyCmd with no matching images succeeded")
+	}
+}
+
+func TestVerifyCmd_KeylessRequiresTrustedRoot(t *testing.T) {
+	ctx := newTestContext(t)
+	host, _ := newLocalhostRegistry(t)
+	seedImage(t, host, "verify/keyless", "v1")
+
+	s := newTestStore(t)
+	rso := defaultRootOpts(s.Root)
+	ro := defaultCliOpts()
+	if err := storeImage(ctx, s, v1.Image{Name: host + "/verify/keyless:v1"}, "", false, rso, ro, "", "", false); err != nil {
+		t.Fatalf("storeImage: %v", err)
+	}
+
+	o := &flags.VerifyOpts{StoreRootOpts: rso, CertIdentity: "me@example.com", CertOidcIssuer: "https://issuer.example.com"}
+	err := VerifyCmd(ctx, o, s, nil, ro)
+	if err == nil || !strings.Contains(err.Error(), "trusted root") {
+		t.Errorf("VerifyCmd keyless without --trusted-root = %v, want a trusted root error", err)
+	}
+}
@@ -16,8 +16,14 @@ func main() {
 	logger := log.NewLogger(os.Stdout)
 	ctx = logger.WithContext(ctx)
 
-	if err := cli.New(ctx, &flags.CliRootOpts{}).ExecuteContext(ctx); err != nil {
-		logger.Errorf("%v", err)
+	if cmd, err := cli.New(ctx, &flags.CliRootOpts{}).ExecuteContextC(ctx); err != nil {
+		// logged wherever the command sent its logs... stderr, when stdout
+		// carries a haul
+		if cmd != nil {
+			log.FromContext(cmd.Context()).Errorf("%v", err)
+		} else {
+			logger.Errorf("%v", err)
+		}
 		cancel()
 		os.Exit(1)
 	}
@@ -23,6 +23,8 @@ type AddImageOpts struct {
 	Local                        bool
 	CaFile                       string
 	InsecureSkipTLSVerify        bool
+	Policy                       string
+	Labels                       []string
 }
 
 func (o *AddImageOpts) AddFlags(cmd *cobra.Command) {
@@ -40,6 +42,8 @@ func (o *AddImageOpts) AddFlags(cmd *cobra.Command) {
 	f.BoolVar(&o.Local, "local", false, "(Optional) Add image from the local Docker daemon instead of a remote registry")
 	f.StringVar(&o.CaFile, "ca-file", "", "(Optional) Location of CA Bundle to enable certification verification")
 	f.BoolVar(&o.InsecureSkipTLSVerify, "insecure-skip-tls-verify", false, "(Optional) Skip TLS certificate verification")
+	f.StringVar(&o.Policy, "policy", "", "(Optional) Location of an admission policy file to enforce (defaults to policy.yaml in the hauler directory, when present)")
+	f.StringArrayVar(&o.Labels, "label", []string{}, labelUsage)
 }
 
 type AddFileOpts struct {
@@ -47,6 +51,7 @@ type AddFileOpts struct {
 	Name                  string
 	CaFile                string
 	InsecureSkipTLSVerify bool
+	Labels                []string
 }
 
 func (o *AddFileOpts) AddFlags(cmd *cobra.Command) {
@@ -54,6 +59,7 @@ func (o *AddFileOpts) AddFlags(cmd *cobra.Command) {
 	f.StringVarP(&o.Name, "name", "n", "", "(Optional) Rewrite the name of the file")
 	f.StringVar(&o.CaFile, "ca-file", "", "(Optional) Location of CA Bundle to enable certification verification for remote files")
 	f.BoolVar(&o.InsecureSkipTLSVerify, "insecure-skip-tls-verify", false, "(Optional) Skip TLS certificate verification for remote files")
+	f.StringArrayVar(&o.Labels, "label", []string{}, labelUsage)
 }
 
 type AddChartOpts struct {
@@ -70,6 +76,7 @@ type AddChartOpts struct {
 	KubeVersion     string
 	Concurrency     int
 	NoProgress      bool
+	Labels          []string
 }
 
 func (o *AddChartOpts) AddFlags(cmd *cobra.Command) {
@@ -99,4 +106,5 @@ func (o *AddChartOpts) AddFlags(cmd *cobra.Command) {
 	f.StringVar(&o.KubeVersion, "kube-version", "v1.34.1", "(Optional) Override the kubernetes version for helm template rendering")
 	f.IntVarP(&o.Concurrency, "concurrency", "j", consts.DefaultConcurrency, "(Optional) Maximum number of charts and their discovered images to fetch and store concurrently (1 = serial; also via HAULER_CONCURRENCY, explicit flag wins)")
 	f.BoolVar(&o.NoProgress, "no-progress", false, "(Optional) Disable the live progress display")
+	f.StringArrayVar(&o.Labels, "label", []string{}, labelUsage+" (also set on dependencies and images added with the chart)")
 }
@@ -1,6 +1,10 @@
 package flags
 
-import "github.com/spf13/cobra"
+import (
+	"github.com/spf13/cobra"
+
+	"hauler.dev/go/hauler/v2/pkg/consts"
+)
 
 type CopyOpts struct {
 	*StoreRootOpts
@@ -10,6 +14,11 @@ type CopyOpts struct {
 	Insecure  bool
 	PlainHTTP bool
 	Only      string
+	Select    string
+	DryRun    bool
+
+	Concurrency int
+	NoProgress  bool
 }
 
 func (o *CopyOpts) AddFlags(cmd *cobra.Command) {
@@ -20,6 +29,10 @@ func (o *CopyOpts) AddFlags(cmd *cobra.Command) {
 	f.BoolVar(&o.Insecure, "insecure", false, "(Optional) Allow insecure connections")
 	f.BoolVar(&o.PlainHTTP, "plain-http", false, "(Optional) Allow plain HTTP connections")
 	f.StringVarP(&o.Only, "only", "o", "", "(Optional) Custom string array to only copy specific 'image' items")
+	f.StringVar(&o.Select, "select", "", selectUsage)
+	f.BoolVar(&o.DryRun, "dry-run", false, "(Optional) List the artifact(s) that would be copied without copying anything")
+	f.IntVarP(&o.Concurrency, "concurrency", "j", consts.DefaultConcurrency, "(Optional) Maximum number of artifacts to push concurrently to a registry (1 = serial; also via HAULER_CONCURRENCY, explicit flag wins)")
+	f.BoolVar(&o.NoProgress, "no-progress", false, "(Optional) Disable the live progress display")
 
 	cmd.MarkFlagsRequiredTogether("username", "password")
 
@@ -0,0 +1,15 @@
+package flags
+
+import "github.com/spf13/cobra"
+
+type DiffOpts struct {
+	*StoreRootOpts
+
+	OutputFormat string
+}
+
+func (o *DiffOpts) AddFlags(cmd *cobra.Command) {
+	f := cmd.Flags()
+
+	f.StringVarP(&o.OutputFormat, "output", "o", "table", "(Optional) Specify the output format (table | json)")
+}
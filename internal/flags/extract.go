@@ -5,10 +5,20 @@ import "github.com/spf13/cobra"
 type ExtractOpts struct {
 	*StoreRootOpts
 	DestinationDir string
+	RootFS         bool
+	Platform       string
+	Path           string
+	Select         string
+	DryRun         bool
 }
 
 func (o *ExtractOpts) AddFlags(cmd *cobra.Command) {
 	f := cmd.Flags()
 
 	f.StringVarP(&o.DestinationDir, "output", "o", "", "(Optional) Set the directory to output (defaults to current directory)")
+	f.BoolVar(&o.RootFS, "rootfs", false, "(Optional) Unpack a container image's root filesystem into the output directory")
+	f.StringVarP(&o.Platform, "platform", "p", "", "(Optional) Specify the platform to unpack with --rootfs for multi-platform images... i.e. linux/amd64")
+	f.StringVar(&o.Path, "path", "", "(Optional) Only unpack this file or directory from the image with --rootfs... i.e. /bin")
+	f.StringVar(&o.Select, "select", "", selectUsage)
+	f.BoolVar(&o.DryRun, "dry-run", false, "(Optional) List the artifact(s) that would be extracted without extracting anything")
 }
@@ -0,0 +1,25 @@
+package flags
+
+import (
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"hauler.dev/go/hauler/v2/pkg/consts"
+)
+
+type GCOpts struct {
+	*StoreRootOpts
+
+	DryRun       bool
+	TempGrace    time.Duration
+	OutputFormat string
+}
+
+func (o *GCOpts) AddFlags(cmd *cobra.Command) {
+	f := cmd.Flags()
+
+	f.BoolVar(&o.DryRun, "dry-run", false, "(Optional) List what would be deleted without deleting anything")
+	f.DurationVar(&o.TempGrace, "temp-grace", consts.DefaultGCTempGrace, "(Optional) Only sweep temp files left by interrupted writes once they are at least this old")
+	f.StringVarP(&o.OutputFormat, "output", "o", "table", "(Optional) Specify the output format (table | json)")
+}
@@ -0,0 +1,13 @@
+package flags
+
+import "github.com/spf13/cobra"
+
+type HaulInspectOpts struct {
+	OutputFormat string
+}
+
+func (o *HaulInspectOpts) AddFlags(cmd *cobra.Command) {
+	f := cmd.Flags()
+
+	f.StringVarP(&o.OutputFormat, "output", "o", "table", "(Optional) Specify the output format (table | json | yaml)")
+}
@@ -0,0 +1,31 @@
+package flags
+
+import "github.com/spf13/cobra"
+
+type HistoryOpts struct {
+	*StoreRootOpts
+
+	OutputFormat string
+	Trim         bool
+	Keep         int
+}
+
+func (o *HistoryOpts) AddFlags(cmd *cobra.Command) {
+	f := cmd.Flags()
+
+	f.StringVarP(&o.OutputFormat, "output", "o", "table", "(Optional) Specify the output format (table | json)")
+	f.BoolVar(&o.Trim, "trim", false, "(Optional) Drop all but the newest --keep generations before listing")
+	f.IntVar(&o.Keep, "keep", 0, "(Optional) Set how many of the newest generations --trim keeps")
+}
+
+type RollbackOpts struct {
+	*StoreRootOpts
+
+	DryRun bool
+}
+
+func (o *RollbackOpts) AddFlags(cmd *cobra.Command) {
+	f := cmd.Flags()
+
+	f.BoolVar(&o.DryRun, "dry-run", false, "(Optional) List the artifacts the rollback would restore and drop without changing the store")
+}
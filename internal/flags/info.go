@@ -7,10 +7,12 @@ type InfoOpts struct {
 
 	OutputFormat string
 	TypeFilter   string
+	Select       string
 	SizeUnit     string
 	ListRepos    bool
 	ShowDigests  bool
 	Check        bool
+	Sizes        bool
 }
 
 func (o *InfoOpts) AddFlags(cmd *cobra.Command) {
@@ -18,8 +20,11 @@ func (o *InfoOpts) AddFlags(cmd *cobra.Command) {
 
 	f.StringVarP(&o.OutputFormat, "output", "o", "table", "(Optional) Specify the output format (table | json)")
 	f.StringVar(&o.TypeFilter, "type", "all", "(Optional) Filter on content type (image | chart | file | sigs | atts | sbom | referrer)")
+	f.StringVar(&o.Select, "select", "", selectUsage)
 	f.BoolVar(&o.ListRepos, "list-repos", false, "(Optional) List all repository names")
 	f.BoolVar(&o.ShowDigests, "digests", false, "(Optional) Show digests of each artifact in the output table")
 	f.BoolVar(&o.Check, "check", false,
 		"(Optional) Check the integrity of each artifact by hashing every blob (slow on large stores)")
+	f.BoolVar(&o.Sizes, "sizes", false,
+		"(Optional) Show the bytes unique to and shared by each artifact, the store's deduplicated footprint, and the estimated haul size")
 }
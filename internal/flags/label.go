@@ -0,0 +1,20 @@
+package flags
+
+import "github.com/spf13/cobra"
+
+// labelUsage is the --label help shared by every command that adds content.
+const labelUsage = "(Optional) Label the artifact with key=value for filtering with --select label.<key>... can be repeated"
+
+type LabelOpts struct {
+	*StoreRootOpts
+
+	Select string
+	DryRun bool
+}
+
+func (o *LabelOpts) AddFlags(cmd *cobra.Command) {
+	f := cmd.Flags()
+
+	f.StringVar(&o.Select, "select", "", selectUsage)
+	f.BoolVar(&o.DryRun, "dry-run", false, "(Optional) List the artifacts that would be labeled without changing them")
+}
@@ -7,11 +7,17 @@ import (
 
 type LoadOpts struct {
 	*StoreRootOpts
-	FileName []string
+	FileName        []string
+	Policy          string
+	VerifyKey       string
+	VerifyChecksums bool
 }
 
 func (o *LoadOpts) AddFlags(cmd *cobra.Command) {
 	f := cmd.Flags()
 
-	f.StringSliceVarP(&o.FileName, "filename", "f", []string{consts.DefaultHaulerArchiveName}, "(Optional) Specify the name of inputted haul(s)")
+	f.StringSliceVarP(&o.FileName, "filename", "f", []string{consts.DefaultHaulerArchiveName}, "(Optional) Specify the name of inputted haul(s)... - reads one from stdin")
+	f.StringVar(&o.VerifyKey, "verify-key", "", "(Optional) Location of the public key to verify each haul's signed checksums with... every chunk is checked before anything is loaded")
+	f.BoolVar(&o.VerifyChecksums, "verify-checksums", false, "(Optional) Check each haul against its checksums before loading it, without requiring a signature")
+	f.StringVar(&o.Policy, "policy", "", "(Optional) Location of an admission policy file to enforce (defaults to policy.yaml in the hauler directory, when present)")
 }
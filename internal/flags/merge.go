@@ -0,0 +1,23 @@
+package flags
+
+import "github.com/spf13/cobra"
+
+type MergeOpts struct {
+	*StoreRootOpts
+
+	From         []string
+	Conflict     string
+	OutputFormat string
+}
+
+func (o *MergeOpts) AddFlags(cmd *cobra.Command) {
+	f := cmd.Flags()
+
+	f.StringSliceVar(&o.From, "from", []string{}, "Path or StoreID of a store to merge into the target store (can be repeated)")
+	f.StringVar(&o.Conflict, "conflict", "fail", "(Optional) What to do when a reference is already stored with a different digest (fail | keep-existing | overwrite | rename)")
+	f.StringVarP(&o.OutputFormat, "output", "o", "table", "(Optional) Specify the output format (table | json)")
+
+	if err := cmd.MarkFlagRequired("from"); err != nil {
+		panic(err)
+	}
+}
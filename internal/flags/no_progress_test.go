@@ -80,3 +80,26 @@ func TestAddChartOpts_NoProgressFlag(t *testing.T) {
 		})
 	}
 }
+
+// TestCopyOpts_NoProgressFlag proves --no-progress registered by
+// CopyOpts.AddFlags sets o.NoProgress, and defaults to false when unset.
+func TestCopyOpts_NoProgressFlag(t *testing.T) {
+	for _, tt := range []struct {
+		args []string
+		want bool
+	}{
+		{args: []string{}, want: false},
+		{args: []string{"--no-progress"}, want: true},
+	} {
+		o := &CopyOpts{StoreRootOpts: &StoreRootOpts{}}
+		cmd := &cobra.Command{Use: "copy"}
+		o.AddFlags(cmd)
+
+		if err := cmd.ParseFlags(tt.args); err != nil {
+			t.Fatalf("ParseFlags(%v): %v", tt.args, err)
+		}
+		if o.NoProgress != tt.want {
+			t.Errorf("ParseFlags(%v): o.NoProgress = %v, want %v", tt.args, o.NoProgress, tt.want)
+		}
+	}
+}
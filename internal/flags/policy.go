@@ -0,0 +1,26 @@
+package flags
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+
+	"hauler.dev/go/hauler/v2/pkg/consts"
+)
+
+// ResolvePolicyPath returns the admission policy to enforce: policy when set
+// via --policy, else policy.yaml in the hauler directory when it exists, else
+// "" for no policy at all.
+func ResolvePolicyPath(ro *CliRootOpts, policy string) (string, error) {
+	if policy != "" {
+		return policy, nil
+	}
+	path := filepath.Join(resolveHaulerDir(ro), consts.DefaultPolicyFileName)
+	if _, err := os.Stat(path); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", nil
+		}
+		return "", err
+	}
+	return path, nil
+}
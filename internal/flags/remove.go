@@ -3,9 +3,13 @@ package flags
 import "github.com/spf13/cobra"
 
 type RemoveOpts struct {
-	Force bool // skip remove confirmation
+	Force  bool // skip remove confirmation
+	Select string
+	DryRun bool
 }
 
 func (o *RemoveOpts) AddFlags(cmd *cobra.Command) {
 	cmd.Flags().BoolVarP(&o.Force, "force", "f", false, "(Optional) Remove artifact(s) without confirmation")
+	cmd.Flags().StringVar(&o.Select, "select", "", selectUsage)
+	cmd.Flags().BoolVar(&o.DryRun, "dry-run", false, "(Optional) List the artifact(s) that would be removed without removing anything")
 }
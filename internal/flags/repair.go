@@ -0,0 +1,23 @@
+package flags
+
+import "github.com/spf13/cobra"
+
+type RepairOpts struct {
+	*StoreRootOpts
+
+	Mirror                string
+	Hauls                 []string
+	OutputFormat          string
+	CaFile                string
+	InsecureSkipTLSVerify bool
+}
+
+func (o *RepairOpts) AddFlags(cmd *cobra.Command) {
+	f := cmd.Flags()
+
+	f.StringVar(&o.Mirror, "mirror", "", "(Optional) Re-pull blobs from this registry instead of each artifact's original registry... i.e. registry.example.com")
+	f.StringSliceVar(&o.Hauls, "haul", []string{}, "(Optional) Location of a haul archive or store directory to copy blobs from before trying any registry (can be repeated)")
+	f.StringVarP(&o.OutputFormat, "output", "o", "table", "(Optional) Specify the output format (table | json)")
+	f.StringVar(&o.CaFile, "ca-file", "", "(Optional) Location of CA Bundle to enable certification verification")
+	f.BoolVar(&o.InsecureSkipTLSVerify, "insecure-skip-tls-verify", false, "(Optional) Skip TLS certificate verification")
+}
@@ -11,14 +11,32 @@ type SaveOpts struct {
 	Platform                string
 	ContainerdCompatibility bool
 	ChunkSize               string
+	Since                   string
+	ContentList             string
+	Select                  string
+	DryRun                  bool
+	SignKey                 string
+	Compression             string
+	CompressionLevel        int
+	Threads                 int
+	Format                  string
 }
 
 func (o *SaveOpts) AddFlags(cmd *cobra.Command) {
 	f := cmd.Flags()
 
-	f.StringVarP(&o.FileName, "filename", "f", consts.DefaultHaulerArchiveName, "(Optional) Specify the name of outputted haul")
+	f.StringVarP(&o.FileName, "filename", "f", consts.DefaultHaulerArchiveName, "(Optional) Specify the name of outputted haul... - writes it to stdout")
 	f.StringVarP(&o.Platform, "platform", "p", "", "(Optional) Specify the platform for runtime imports... i.e. linux/amd64 (unspecified implies all)")
 	f.BoolVar(&o.ContainerdCompatibility, "containerd", false, "(Optional) Enable import compatibility with containerd... removes oci-layout from the haul")
 	f.StringVar(&o.ChunkSize, "chunk-size", "", "(Optional) Split the output archive into chunks of the specified size (e.g. 1G, 500M, 2048M)")
+	f.StringVar(&o.Since, "since", "", "(Optional) Save only the content missing from the specified baseline haul or content list... writes a delta haul")
+	f.StringVar(&o.ContentList, "content-list", "", "(Optional) Write the content list of the saved haul to the specified file... usable as a baseline for --since")
+	f.StringVar(&o.Select, "select", "", selectUsage)
+	f.BoolVar(&o.DryRun, "dry-run", false, "(Optional) List the artifact(s) that would be saved without writing a haul")
+	f.StringVar(&o.SignKey, "sign-key", "", "(Optional) Location of the private key to sign the haul's checksums with (a cosign key is decrypted with $COSIGN_PASSWORD)")
+	f.StringVar(&o.Compression, "compression", "", "(Optional) Compression of the haul (zst | gz | bz2 | xz | lz4 | br | none)... inferred from the filename extension, else zst")
+	f.IntVar(&o.CompressionLevel, "compression-level", 0, "(Optional) Compression level (zst 1-22 | gz, bz2, lz4 1-9 | br 0-11)... 0 uses the compression's default")
+	f.IntVar(&o.Threads, "threads", 0, "(Optional) Number of threads to compress zst with, or 1 to compress gz on a single thread... 0 uses every core, and other compressions only run on one")
+	f.StringVar(&o.Format, "format", "", "(Optional) Archive format of the haul (tar | zip)... inferred from the filename extension, else tar")
 
 }
@@ -0,0 +1,5 @@
+package flags
+
+// selectUsage is the --select help shared by every command that takes a selector.
+const selectUsage = "(Optional) Only act on the artifacts matching a selector... comma-separated terms that must all match, i.e. 'registry=docker.io,type=image,added<30d' " +
+	"(keys: ref | type | kind | platform | registry | digest | size | added | label.<name>; operators: = != ~ !~ < <= > >=; prefix a term with ! to negate it)"
@@ -0,0 +1,20 @@
+package flags
+
+import "github.com/spf13/cobra"
+
+type SignOpts struct {
+	*StoreRootOpts
+	Key      string
+	Referrer bool
+}
+
+func (o *SignOpts) AddFlags(cmd *cobra.Command) {
+	f := cmd.Flags()
+
+	f.StringVarP(&o.Key, "key", "k", "", "Location of the private key to sign with (a cosign key is decrypted with $COSIGN_PASSWORD)")
+	f.BoolVar(&o.Referrer, "referrer", false, "(Optional) Also attach an OCI 1.1 referrer carrying a sigstore bundle, for verifiers that expect the new bundle format")
+
+	if err := cmd.MarkFlagRequired("key"); err != nil {
+		panic(err)
+	}
+}
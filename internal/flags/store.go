@@ -7,6 +7,7 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"time"
 
 	"github.com/spf13/cobra"
 	"hauler.dev/go/hauler/v2/pkg/consts"
@@ -31,6 +32,15 @@ type StoreRootOpts struct {
 	// HAULER_BLOB_CONCURRENCY, so subcommands with no PreRunE still honor
 	// the env var).
 	BlobConcurrency int
+
+	// LockTimeout is how long a command waits on another hauler process
+	// holding the store's lock before giving up (0 fails immediately).
+	LockTimeout time.Duration
+
+	// HistoryLimit is how many generations of index.json a store keeps for
+	// `hauler store rollback` (0 records none). Generations already kept are
+	// only dropped by `hauler store history --trim`.
+	HistoryLimit int
 }
 
 func (o *StoreRootOpts) AddFlags(cmd *cobra.Command) {
@@ -39,6 +49,8 @@ func (o *StoreRootOpts) AddFlags(cmd *cobra.Command) {
 	pf.IntVarP(&o.Retries, "retries", "r", 0, fmt.Sprintf("Set the number of retries for operations (0 uses HAULER_RETRIES, otherwise defaults to %d)", consts.DefaultRetries))
 	pf.StringVarP(&o.TempOverride, "tempdir", "t", "", "(Optional) Override the default temporary directory determined by the OS")
 	pf.IntVar(&o.BlobConcurrency, "blob-concurrency", 0, fmt.Sprintf("(Optional) Override the maximum number of concurrent blob writes (0 auto-derives from --concurrency where set, otherwise defaults to %d)", consts.DefaultBlobConcurrency))
+	pf.DurationVar(&o.LockTimeout, "lock-timeout", consts.DefaultLockTimeout, "(Optional) Set how long to wait for another hauler process to release the store (0 fails immediately)")
+	pf.IntVar(&o.HistoryLimit, "history-limit", consts.DefaultStoreHistoryLimit, "(Optional) Set how many generations of the store index to keep for 'hauler store rollback' (0 records none... generations already kept stay until 'hauler store history --trim')")
 }
 
 // ResolveStoreDir turns storeDir into an absolute path without opening a store for it --
@@ -121,7 +133,11 @@ func (o *StoreRootOpts) Store(ctx context.Context, ro *CliRootOpts) (*store.Layo
 	}
 	o.Retries = retries
 
-	opts := []store.Options{store.WithHaulerDir(resolveHaulerDir(ro))}
+	if o.HistoryLimit < 0 {
+		return nil, fmt.Errorf("invalid --history-limit %d: must be 0 or more", o.HistoryLimit)
+	}
+
+	opts := []store.Options{store.WithHaulerDir(resolveHaulerDir(ro)), store.WithHistoryLimit(o.HistoryLimit)}
 	if o.BlobConcurrency > 0 {
 		opts = append(opts, store.WithBlobConcurrency(o.BlobConcurrency))
 	}
@@ -133,6 +149,24 @@ func (o *StoreRootOpts) Store(ctx context.Context, ro *CliRootOpts) (*store.Layo
 	return s, nil
 }
 
+// LockShared takes s's cross-process lock for a command that only reads the
+// store (info, copy, serve, save...), waiting up to --lock-timeout.
+func (o *StoreRootOpts) LockShared(ctx context.Context, s *store.Layout, command string) error {
+	return o.lock(ctx, s, store.LockShared, command)
+}
+
+// LockExclusive takes s's cross-process lock for a command that writes
+// index.json or blobs (add, sync, remove, load...), waiting up to --lock-timeout.
+func (o *StoreRootOpts) LockExclusive(ctx context.Context, s *store.Layout, command string) error {
+	return o.lock(ctx, s, store.LockExclusive, command)
+}
+
+func (o *StoreRootOpts) lock(ctx context.Context, s *store.Layout, mode store.LockMode, command string) error {
+	l := log.FromContext(ctx)
+	l.Debugf("taking [%s] lock on store [%s]", mode, s.Root)
+	return s.Lock(ctx, mode, o.LockTimeout, command)
+}
+
 // resolveHaulerDir mirrors other variable detection, but duplicated to avoid an import cycle
 func resolveHaulerDir(ro *CliRootOpts) string {
 	if ro != nil && ro.HaulerDir != "" {
@@ -27,6 +27,13 @@ type SyncOpts struct {
 	NoProgress                   bool
 	CaFile                       string
 	InsecureSkipTLSVerify        bool
+	Lock                         bool
+	Locked                       bool
+	Lockfile                     string
+	Prune                        bool
+	Report                       string
+	ReportFormat                 string
+	Policy                       string
 
 	// Whether each of these flags was explicitly set on the CLI, captured in
 	// sync's PreRunE. A plain bool (and a resolved store/retries value) has no
@@ -56,9 +63,19 @@ func (o *SyncOpts) AddFlags(cmd *cobra.Command) {
 	f.StringVarP(&o.ProductRegistry, "product-registry", "c", "", "(Optional) Specify the product registry. Defaults to RGS Carbide Registry (rgcrprod.azurecr.us)")
 	f.BoolVar(&o.Tlog, "use-tlog-verify", false, "(Optional) Allow transparency log verification (defaults to false)")
 	f.BoolVar(&o.ExcludeExtras, "exclude-extras", false, "(Optional) Exclude cosign signatures, attestations, SBOMs, and OCI referrers when pulling images")
-	f.BoolVar(&o.DryRun, "dry-run", false, "(Optional) Output product manifest content to stdout instead of processing it (requires --products)")
+	f.BoolVar(&o.DryRun, "dry-run", false, "(Optional) Output product manifest content to stdout instead of processing it (requires --products); with --prune, only list what would be pruned, without syncing anything")
 	f.IntVarP(&o.Concurrency, "concurrency", "j", consts.DefaultConcurrency, "(Optional) Maximum number of artifacts to fetch and store concurrently (1 = serial; also via HAULER_CONCURRENCY, explicit flag wins)")
 	f.BoolVar(&o.NoProgress, "no-progress", false, "(Optional) Disable the live progress display")
 	f.StringVar(&o.CaFile, "ca-file", "", "(Optional) Location of CA Bundle to enable certification verification")
 	f.BoolVar(&o.InsecureSkipTLSVerify, "insecure-skip-tls-verify", false, "(Optional) Skip TLS certificate verification")
+	f.BoolVar(&o.Lock, "lock", false, "(Optional) Write a lockfile pinning every image, chart, and file to the content that was fetched")
+	f.BoolVar(&o.Locked, "locked", false, "(Optional) Fetch exactly the content pinned in the lockfile... fails if upstream content has drifted")
+	f.StringVar(&o.Lockfile, "lockfile", consts.DefaultLockfileName, "(Optional) Specify the lockfile read by --locked and written by --lock")
+
+	f.BoolVar(&o.Prune, "prune", false, "(Optional) Remove anything from the store (including its signatures, attestations, SBOMs, and referrers) that the manifests, products, or image.txt files no longer produce")
+	f.StringVar(&o.Report, "report", "", "(Optional) Write a report of every image, chart, and file job (reference, digest, store, status, attempts, bytes, duration) to this path")
+	f.StringVar(&o.ReportFormat, "report-format", "json", "(Optional) Format of the --report... one of [json, junit]")
+	f.StringVar(&o.Policy, "policy", "", "(Optional) Location of an admission policy file to enforce (defaults to policy.yaml in the hauler directory, when present)")
+
+	cmd.MarkFlagsMutuallyExclusive("lock", "locked")
 }
@@ -0,0 +1,17 @@
+package flags
+
+import "github.com/spf13/cobra"
+
+type TagOpts struct {
+	*StoreRootOpts
+
+	Move bool
+	File string
+}
+
+func (o *TagOpts) AddFlags(cmd *cobra.Command) {
+	f := cmd.Flags()
+
+	f.BoolVar(&o.Move, "move", false, "(Optional) Remove the source reference once the artifact is tagged, instead of keeping both")
+	f.StringVarP(&o.File, "file", "f", "", "(Optional) Location of a mapping file with one '<src-ref> <dst-ref>' pair per line, to tag many artifacts at once")
+}
@@ -0,0 +1,490 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/handlers"
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+
+	"hauler.dev/go/hauler/v2/internal/flags"
+	"hauler.dev/go/hauler/v2/pkg/consts"
+	"hauler.dev/go/hauler/v2/pkg/store"
+)
+
+// NewStoreRegistry returns a read-only OCI Distribution server that answers
+// straight out of the store's OCI layout, with no copy into a separate
+// registry root first.
+func NewStoreRegistry(ctx context.Context, s *store.Layout, cfg flags.ServeRegistryOpts) (Server, error) {
+	if cfg.Port == 0 {
+		cfg.Port = consts.DefaultRegistryPort
+	}
+
+	srv := &http.Server{
+		Handler: handlers.LoggingHandler(os.Stdout, NewStoreRegistryHandler(s)),
+		Addr:    fmt.Sprintf(":%d", cfg.Port),
+		// no Read/WriteTimeout: a multi-gigabyte layer can legitimately take
+		// longer than any fixed bound to stream, so only the headers are capped
+		ReadHeaderTimeout: time.Duration(consts.DefaultFileserverTimeout) * time.Second,
+	}
+
+	return srv, nil
+}
+
+// storeRegistry implements the read side of the OCI Distribution API over a
+// store.Layout: manifests are resolved through index.json annotations, and
+// blobs are served from blobs/sha256 as-is.
+type storeRegistry struct {
+	s *store.Layout
+
+	// mu guards repos and the index.json stat they were built from, so the
+	// catalog is rebuilt only when the index on disk actually changes.
+	mu       sync.Mutex
+	repos    map[string]*storeRepo
+	modTime  time.Time
+	fileSize int64
+}
+
+// storeRepo is a single repository's view of the store: tags resolve to the
+// root descriptor recorded in index.json, manifests holds every manifest
+// reachable from this repository by digest (roots, index children, cosign
+// artifacts, and referrers), and referrers lists the stored OCI 1.1 referrers
+// by subject digest.
+type storeRepo struct {
+	tags      map[string]ocispec.Descriptor
+	manifests map[digest.Digest]ocispec.Descriptor
+	referrers map[digest.Digest][]ocispec.Descriptor
+}
+
+// NewStoreRegistryHandler returns the http.Handler behind NewStoreRegistry,
+// split out so it can be mounted or tested without binding a port.
+func NewStoreRegistryHandler(s *store.Layout) http.Handler {
+	return &storeRegistry{s: s}
+}
+
+var fileExcludeRegexp = regexp.MustCompile(consts.FileExcludePattern)
+
+func (r *storeRegistry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Docker-Distribution-API-Version", "registry/2.0")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		writeRegistryError(w, http.StatusMethodNotAllowed, "UNSUPPORTED", "the registry is served read-only from the store")
+		return
+	}
+
+	path := req.URL.Path
+	switch {
+	case path == "/v2" || path == "/v2/":
+		w.WriteHeader(http.StatusOK)
+		return
+	case path == "/v2/_catalog":
+		r.serveCatalog(w, req)
+		return
+	case !strings.HasPrefix(path, "/v2/"):
+		writeRegistryError(w, http.StatusNotFound, "NOT_FOUND", "unknown route")
+		return
+	}
+
+	rest := strings.TrimPrefix(path, "/v2/")
+	if name, ok := strings.CutSuffix(rest, "/tags/list"); ok {
+		r.serveTags(w, req, name)
+		return
+	}
+	if i := strings.LastIndex(rest, "/referrers/"); i > 0 {
+		r.serveReferrers(w, req, rest[:i], rest[i+len("/referrers/"):])
+		return
+	}
+	if i := strings.LastIndex(rest, "/manifests/"); i > 0 {
+		r.serveManifest(w, req, rest[:i], rest[i+len("/manifests/"):])
+		return
+	}
+	if i := strings.LastIndex(rest, "/blobs/"); i > 0 {
+		r.serveBlob(w, req, rest[:i], rest[i+len("/blobs/"):])
+		return
+	}
+	writeRegistryError(w, http.StatusNotFound, "NOT_FOUND", "unknown route")
+}
+
+func (r *storeRegistry) serveCatalog(w http.ResponseWriter, req *http.Request) {
+	repos, err := r.catalog()
+	if err != nil {
+		writeRegistryError(w, http.StatusInternalServerError, "UNKNOWN", err.Error())
+		return
+	}
+
+	names := make([]string, 0, len(repos))
+	for name := range repos {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	writeRegistryJSON(w, req, struct {
+		Repositories []string `json:"repositories"`
+	}{Repositories: paginate(w, req, names)})
+}
+
+func (r *storeRegistry) serveTags(w http.ResponseWriter, req *http.Request, name string) {
+	repo, ok := r.repo(w, name)
+	if !ok {
+		return
+	}
+
+	tags := make([]string, 0, len(repo.tags))
+	for tag := range repo.tags {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+
+	writeRegistryJSON(w, req, struct {
+		Name string   `json:"name"`
+		Tags []string `json:"tags"`
+	}{Name: name, Tags: paginate(w, req, tags)})
+}
+
+func (r *storeRegistry) serveManifest(w http.ResponseWriter, req *http.Request, name, ref string) {
+	repo, ok := r.repo(w, name)
+	if !ok {
+		return
+	}
+
+	var desc ocispec.Descriptor
+	if d, err := digest.Parse(ref); err == nil {
+		desc, ok = repo.manifests[d]
+	} else {
+		desc, ok = repo.tags[ref]
+	}
+	if !ok {
+		writeRegistryError(w, http.StatusNotFound, "MANIFEST_UNKNOWN", fmt.Sprintf("manifest unknown to repository [%s]: %s", name, ref))
+		return
+	}
+
+	r.serveContent(w, req, desc, desc.MediaType, "MANIFEST_UNKNOWN")
+}
+
+// serveReferrers answers the OCI 1.1 referrers API from the referrer
+// manifests saved alongside each image, so sigstore bundles and other
+// attached artifacts resolve by subject exactly as they did upstream.
+func (r *storeRegistry) serveReferrers(w http.ResponseWriter, req *http.Request, name, ref string) {
+	repo, ok := r.repo(w, name)
+	if !ok {
+		return
+	}
+
+	d, err := digest.Parse(ref)
+	if err != nil {
+		writeRegistryError(w, http.StatusBadRequest, "DIGEST_INVALID", err.Error())
+		return
+	}
+
+	manifests := []ocispec.Descriptor{}
+	artifactType := req.URL.Query().Get("artifactType")
+	for _, desc := range repo.referrers[d] {
+		if artifactType == "" || desc.ArtifactType == artifactType {
+			manifests = append(manifests, desc)
+		}
+	}
+	if artifactType != "" {
+		w.Header().Set("OCI-Filters-Applied", "artifactType")
+	}
+
+	idx := ocispec.Index{
+		MediaType: ocispec.MediaTypeImageIndex,
+		Manifests: manifests,
+	}
+	idx.SchemaVersion = 2
+	data, err := json.Marshal(idx)
+	if err != nil {
+		writeRegistryError(w, http.StatusInternalServerError, "UNKNOWN", err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", ocispec.MediaTypeImageIndex)
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	if req.Method == http.MethodHead {
+		return
+	}
+	w.Write(data) //nolint:errcheck
+}
+
+func (r *storeRegistry) serveBlob(w http.ResponseWriter, req *http.Request, name, ref string) {
+	if _, ok := r.repo(w, name); !ok {
+		return
+	}
+
+	d, err := digest.Parse(ref)
+	if err != nil {
+		writeRegistryError(w, http.StatusBadRequest, "DIGEST_INVALID", err.Error())
+		return
+	}
+
+	r.serveContent(w, req, ocispec.Descriptor{Digest: d}, "application/octet-stream", "BLOB_UNKNOWN")
+}
+
+// serveContent streams desc's blob from the layout. Blobs are *os.File under
+// the hood, so http.ServeContent gets a seeker and handles Range and HEAD.
+func (r *storeRegistry) serveContent(w http.ResponseWriter, req *http.Request, desc ocispec.Descriptor, mediaType, unknownCode string) {
+	rc, err := r.s.Fetch(req.Context(), desc)
+	if err != nil {
+		if os.IsNotExist(err) {
+			writeRegistryError(w, http.StatusNotFound, unknownCode, fmt.Sprintf("%s not found in store", desc.Digest))
+			return
+		}
+		writeRegistryError(w, http.StatusInternalServerError, "UNKNOWN", err.Error())
+		return
+	}
+	defer rc.Close()
+
+	w.Header().Set("Content-Type", mediaType)
+	w.Header().Set("Docker-Content-Digest", desc.Digest.String())
+	w.Header().Set("Etag", `"`+desc.Digest.String()+`"`)
+
+	if rs, ok := rc.(io.ReadSeeker); ok {
+		http.ServeContent(w, req, "", time.Time{}, rs)
+		return
+	}
+	if req.Method == http.MethodHead {
+		return
+	}
+	io.Copy(w, rc) //nolint:errcheck // the client has already been sent a 200
+}
+
+// repo resolves name, writing NAME_UNKNOWN (or the catalog error) on failure.
+func (r *storeRegistry) repo(w http.ResponseWriter, name string) (*storeRepo, bool) {
+	repos, err := r.catalog()
+	if err != nil {
+		writeRegistryError(w, http.StatusInternalServerError, "UNKNOWN", err.Error())
+		return nil, false
+	}
+	repo, ok := repos[name]
+	if !ok {
+		writeRegistryError(w, http.StatusNotFound, "NAME_UNKNOWN", fmt.Sprintf("repository name not known to registry: %s", name))
+		return nil, false
+	}
+	return repo, true
+}
+
+// catalog returns the repository view of the store, built from index.json on
+// the first request and rebuilt only if the file has changed since. serve
+// holds the store's shared lock while it runs, so no other hauler process
+// writes the index under it; the check only guards against the file being
+// replaced by hand.
+func (r *storeRegistry) catalog() (map[string]*storeRepo, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	indexPath := r.s.OCI.ResolvePath(ocispec.ImageIndexFile)
+	info, err := os.Stat(indexPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return map[string]*storeRepo{}, nil
+		}
+		return nil, err
+	}
+	if r.repos != nil && info.ModTime().Equal(r.modTime) && info.Size() == r.fileSize {
+		return r.repos, nil
+	}
+
+	data, err := os.ReadFile(indexPath)
+	if err != nil {
+		return nil, err
+	}
+	var idx ocispec.Index
+	if err := json.Unmarshal(data, &idx); err != nil {
+		return nil, fmt.Errorf("failed to decode store index: %w", err)
+	}
+
+	repos := r.buildRepos(idx.Manifests)
+	r.repos, r.modTime, r.fileSize = repos, info.ModTime(), info.Size()
+	return repos, nil
+}
+
+// buildRepos groups index.json's descriptors into repositories, using the
+// same routing `store copy registry://` applies when pushing: cosign
+// artifacts are tagged sha256-<hex>.sig/.att/.sbom after their parent image,
+// referrers and digest-only references are reachable by digest only.
+func (r *storeRegistry) buildRepos(descs []ocispec.Descriptor) map[string]*storeRepo {
+	repos := make(map[string]*storeRepo)
+	get := func(name string) *storeRepo {
+		repo, ok := repos[name]
+		if !ok {
+			repo = &storeRepo{
+				tags:      make(map[string]ocispec.Descriptor),
+				manifests: make(map[digest.Digest]ocispec.Descriptor),
+				referrers: make(map[digest.Digest][]ocispec.Descriptor),
+			}
+			repos[name] = repo
+		}
+		return repo
+	}
+
+	// parent image digests by stored ref, for routing cosign artifacts
+	refDigest := make(map[string]digest.Digest)
+	for _, desc := range descs {
+		kind := consts.NormalizeLegacyKind(desc.Annotations[consts.KindAnnotationName])
+		if kind == "" || kind == consts.KindAnnotationImage || kind == consts.KindAnnotationIndex {
+			refDigest[desc.Annotations[ocispec.AnnotationRefName]] = desc.Digest
+		}
+	}
+
+	sigExts := map[string]string{
+		consts.KindAnnotationSigs:  ".sig",
+		consts.KindAnnotationAtts:  ".att",
+		consts.KindAnnotationSboms: ".sbom",
+	}
+
+	for _, desc := range descs {
+		baseRef := desc.Annotations[ocispec.AnnotationRefName]
+		if baseRef == "" || fileExcludeRegexp.MatchString(baseRef) || desc.Digest.Validate() != nil {
+			continue
+		}
+		name, tag := splitStoreRef(baseRef)
+		if name == "" {
+			continue
+		}
+		repo := get(name)
+
+		kind := consts.NormalizeLegacyKind(desc.Annotations[consts.KindAnnotationName])
+		switch {
+		case sigExts[kind] != "":
+			if parent, ok := refDigest[baseRef]; ok {
+				repo.tags[strings.ReplaceAll(parent.String(), ":", "-")+sigExts[kind]] = desc
+			}
+		case strings.HasPrefix(kind, consts.KindAnnotationReferrers):
+			// digest-only... reachable through the referrers API by subject
+			r.addReferrer(repo, desc)
+		case tag != "":
+			repo.tags[tag] = desc
+		}
+		r.addManifest(repo, desc)
+	}
+	return repos
+}
+
+// addManifest records desc and, for an index, every child manifest beneath
+// it, so clients can pull a platform manifest by digest after resolving the
+// index by tag.
+func (r *storeRegistry) addManifest(repo *storeRepo, desc ocispec.Descriptor) {
+	if _, seen := repo.manifests[desc.Digest]; seen {
+		return
+	}
+	repo.manifests[desc.Digest] = desc
+
+	switch desc.MediaType {
+	case ocispec.MediaTypeImageIndex, consts.DockerManifestListSchema2:
+	default:
+		return
+	}
+
+	rc, err := r.s.Fetch(context.Background(), desc)
+	if err != nil {
+		return
+	}
+	defer rc.Close()
+
+	var idx ocispec.Index
+	if err := json.NewDecoder(rc).Decode(&idx); err != nil {
+		return
+	}
+	for _, child := range idx.Manifests {
+		if child.Digest.Validate() == nil {
+			r.addManifest(repo, child)
+		}
+	}
+}
+
+// addReferrer lists desc under the subject its manifest names, carrying the
+// artifactType and annotations the referrers API reports.
+func (r *storeRegistry) addReferrer(repo *storeRepo, desc ocispec.Descriptor) {
+	rc, err := r.s.Fetch(context.Background(), desc)
+	if err != nil {
+		return
+	}
+	defer rc.Close()
+
+	var m ocispec.Manifest
+	if err := json.NewDecoder(rc).Decode(&m); err != nil || m.Subject == nil {
+		return
+	}
+	artifactType := m.ArtifactType
+	if artifactType == "" {
+		artifactType = m.Config.MediaType
+	}
+	repo.referrers[m.Subject.Digest] = append(repo.referrers[m.Subject.Digest], ocispec.Descriptor{
+		MediaType:    desc.MediaType,
+		Digest:       desc.Digest,
+		Size:         desc.Size,
+		ArtifactType: artifactType,
+		Annotations:  m.Annotations,
+	})
+}
+
+// splitStoreRef splits a stored AnnotationRefName into repository and tag.
+// The tag is empty for digest-only references (repo@sha256:...).
+func splitStoreRef(ref string) (string, string) {
+	if at := strings.Index(ref, "@"); at != -1 {
+		return ref[:at], ""
+	}
+	if colon := strings.LastIndex(ref, ":"); colon > strings.LastIndex(ref, "/") {
+		return ref[:colon], ref[colon+1:]
+	}
+	return ref, consts.DefaultTag
+}
+
+// paginate applies the Distribution spec's n/last query parameters to a
+// sorted list, setting the Link header when more results remain.
+func paginate(w http.ResponseWriter, req *http.Request, items []string) []string {
+	q := req.URL.Query()
+	if last := q.Get("last"); last != "" {
+		i, _ := slices.BinarySearch(items, last)
+		for i < len(items) && items[i] <= last {
+			i++
+		}
+		items = items[i:]
+	}
+	n, err := strconv.Atoi(q.Get("n"))
+	if err != nil || n <= 0 || n >= len(items) {
+		return items
+	}
+	items = items[:n]
+
+	next := *req.URL
+	nq := next.Query()
+	nq.Set("last", items[len(items)-1])
+	next.RawQuery = nq.Encode()
+	w.Header().Set("Link", fmt.Sprintf(`<%s>; rel="next"`, next.RequestURI()))
+	return items
+}
+
+func writeRegistryJSON(w http.ResponseWriter, req *http.Request, v any) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		writeRegistryError(w, http.StatusInternalServerError, "UNKNOWN", err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	w.WriteHeader(http.StatusOK)
+	if req.Method != http.MethodHead {
+		w.Write(data) //nolint:errcheck
+	}
+}
+
+func writeRegistryError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]any{ //nolint:errcheck
+		"errors": []map[string]string{{"code": code, "message": message}},
+	})
+}
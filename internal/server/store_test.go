@@ -0,0 +1,305 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	gcrv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+
+	"hauler.dev/go/hauler/v2/pkg/store"
+)
+
+// newServedStore fills a fresh store from an in-memory upstream registry and
+// serves it through NewStoreRegistryHandler, returning the store, the served
+// host, and remote options routed through the test server's transport.
+func newServedStore(t *testing.T) (*store.Layout, string, []remote.Option) {
+	t.Helper()
+
+	upstream := httptest.NewServer(registry.New())
+	t.Cleanup(upstream.Close)
+	upHost := strings.TrimPrefix(upstream.URL, "http://")
+	upOpts := []remote.Option{remote.WithTransport(upstream.Client().Transport)}
+
+	s, err := store.NewLayout(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewLayout: %v", err)
+	}
+
+	img, err := random.Image(1024, 2)
+	if err != nil {
+		t.Fatalf("random.Image: %v", err)
+	}
+	imgRef, _ := name.NewTag(upHost+"/myorg/app:v1", name.Insecure)
+	if err := remote.Write(imgRef, img, upOpts...); err != nil {
+		t.Fatalf("remote.Write: %v", err)
+	}
+
+	child, err := random.Image(256, 1)
+	if err != nil {
+		t.Fatalf("random.Image: %v", err)
+	}
+	idx := mutate.AppendManifests(empty.Index, mutate.IndexAddendum{
+		Add:        child,
+		Descriptor: gcrv1.Descriptor{Platform: &gcrv1.Platform{OS: "linux", Architecture: "amd64"}},
+	})
+	idxRef, _ := name.NewTag(upHost+"/myorg/multi:v2", name.Insecure)
+	if err := remote.WriteIndex(idxRef, idx, upOpts...); err != nil {
+		t.Fatalf("remote.WriteIndex: %v", err)
+	}
+
+	ctx := context.Background()
+	for _, ref := range []string{imgRef.Name(), idxRef.Name()} {
+		if _, err := s.AddImage(ctx, ref, "", true, "", false, "", upOpts...); err != nil {
+			t.Fatalf("AddImage %s: %v", ref, err)
+		}
+	}
+
+	srv := httptest.NewServer(NewStoreRegistryHandler(s))
+	t.Cleanup(srv.Close)
+	return s, strings.TrimPrefix(srv.URL, "http://"), []remote.Option{remote.WithTransport(srv.Client().Transport)}
+}
+
+func TestStoreRegistry_PullByTagAndDigest(t *testing.T) {
+	_, host, opts := newServedStore(t)
+
+	ref, _ := name.ParseReference(host+"/myorg/app:v1", name.Insecure)
+	img, err := remote.Image(ref, opts...)
+	if err != nil {
+		t.Fatalf("remote.Image by tag: %v", err)
+	}
+	// Layers() alone doesn't touch the blobs; reading them does
+	layers, err := img.Layers()
+	if err != nil {
+		t.Fatalf("Layers: %v", err)
+	}
+	for _, lyr := range layers {
+		rc, err := lyr.Compressed()
+		if err != nil {
+			t.Fatalf("Compressed: %v", err)
+		}
+		if _, err := io.Copy(io.Discard, rc); err != nil {
+			t.Fatalf("reading layer: %v", err)
+		}
+		rc.Close()
+	}
+
+	d, err := img.Digest()
+	if err != nil {
+		t.Fatalf("Digest: %v", err)
+	}
+	byDigest, _ := name.ParseReference(host+"/myorg/app@"+d.String(), name.Insecure)
+	if _, err := remote.Head(byDigest, opts...); err != nil {
+		t.Errorf("HEAD by digest: %v", err)
+	}
+}
+
+func TestStoreRegistry_IndexChildrenByDigest(t *testing.T) {
+	_, host, opts := newServedStore(t)
+
+	ref, _ := name.ParseReference(host+"/myorg/multi:v2", name.Insecure)
+	idx, err := remote.Index(ref, opts...)
+	if err != nil {
+		t.Fatalf("remote.Index: %v", err)
+	}
+	im, err := idx.IndexManifest()
+	if err != nil {
+		t.Fatalf("IndexManifest: %v", err)
+	}
+	if len(im.Manifests) != 1 {
+		t.Fatalf("expected 1 child manifest, got %d", len(im.Manifests))
+	}
+	child, _ := name.ParseReference(host+"/myorg/multi@"+im.Manifests[0].Digest.String(), name.Insecure)
+	if _, err := remote.Image(child, opts...); err != nil {
+		t.Errorf("fetching child manifest by digest: %v", err)
+	}
+}
+
+func TestStoreRegistry_CatalogAndTags(t *testing.T) {
+	_, host, opts := newServedStore(t)
+
+	reg, _ := name.NewRegistry(host, name.Insecure)
+	repos, err := remote.Catalog(context.Background(), reg, opts...)
+	if err != nil {
+		t.Fatalf("remote.Catalog: %v", err)
+	}
+	if strings.Join(repos, ",") != "myorg/app,myorg/multi" {
+		t.Errorf("catalog = %v, want [myorg/app myorg/multi]", repos)
+	}
+
+	repo, _ := name.NewRepository(host+"/myorg/app", name.Insecure)
+	tags, err := remote.List(repo, opts...)
+	if err != nil {
+		t.Fatalf("remote.List: %v", err)
+	}
+	if len(tags) != 1 || tags[0] != "v1" {
+		t.Errorf("tags = %v, want [v1]", tags)
+	}
+}
+
+func TestStoreRegistry_BlobRange(t *testing.T) {
+	_, host, _ := newServedStore(t)
+	base := "http://" + host
+
+	resp, err := http.Get(base + "/v2/myorg/app/manifests/v1")
+	if err != nil {
+		t.Fatalf("GET manifest: %v", err)
+	}
+	var m struct {
+		Layers []struct {
+			Digest string `json:"digest"`
+			Size   int64  `json:"size"`
+		} `json:"layers"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&m)
+	resp.Body.Close()
+	if err != nil || len(m.Layers) == 0 {
+		t.Fatalf("decoding manifest: %v (%d layers)", err, len(m.Layers))
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, base+"/v2/myorg/app/blobs/"+m.Layers[0].Digest, nil)
+	req.Header.Set("Range", "bytes=0-9")
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("ranged GET: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusPartialContent {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusPartialContent)
+	}
+	if len(body) != 10 {
+		t.Errorf("ranged body length = %d, want 10", len(body))
+	}
+	if got := resp.Header.Get("Docker-Content-Digest"); got != m.Layers[0].Digest {
+		t.Errorf("Docker-Content-Digest = %q, want %q", got, m.Layers[0].Digest)
+	}
+}
+
+func TestStoreRegistry_Errors(t *testing.T) {
+	_, host, _ := newServedStore(t)
+	base := "http://" + host
+
+	tests := []struct {
+		method, path string
+		status       int
+		code         string
+	}{
+		{http.MethodGet, "/v2/myorg/missing/tags/list", http.StatusNotFound, "NAME_UNKNOWN"},
+		{http.MethodGet, "/v2/myorg/app/manifests/nope", http.StatusNotFound, "MANIFEST_UNKNOWN"},
+		{http.MethodGet, "/v2/myorg/app/blobs/sha256:" + strings.Repeat("0", 64), http.StatusNotFound, "BLOB_UNKNOWN"},
+		{http.MethodPut, "/v2/myorg/app/manifests/v9", http.StatusMethodNotAllowed, "UNSUPPORTED"},
+		{http.MethodDelete, "/v2/myorg/app/manifests/v1", http.StatusMethodNotAllowed, "UNSUPPORTED"},
+	}
+	for _, tt := range tests {
+		req, _ := http.NewRequest(tt.method, base+tt.path, nil)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s %s: %v", tt.method, tt.path, err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != tt.status {
+			t.Errorf("%s %s: status = %d, want %d", tt.method, tt.path, resp.StatusCode, tt.status)
+		}
+		if !strings.Contains(string(body), tt.code) {
+			t.Errorf("%s %s: body %q missing code %s", tt.method, tt.path, body, tt.code)
+		}
+	}
+}
+
+// a store changed underneath a running server must be picked up without a
+// restart, including removals
+func TestStoreRegistry_ReloadsIndex(t *testing.T) {
+	s, host, opts := newServedStore(t)
+
+	ref, _ := name.ParseReference(host+"/myorg/app:v1", name.Insecure)
+	if _, err := remote.Head(ref, opts...); err != nil {
+		t.Fatalf("HEAD before remove: %v", err)
+	}
+
+	if err := s.RemoveArtifact(context.Background(), "myorg/app:v1-dev.hauler/image", ocispec.Descriptor{}); err != nil {
+		t.Fatalf("RemoveArtifact: %v", err)
+	}
+
+	if _, err := remote.Head(ref, opts...); err == nil {
+		t.Error("expected HEAD to fail after the artifact was removed")
+	}
+}
+
+func TestStoreRegistry_Referrers(t *testing.T) {
+	upstream := httptest.NewServer(registry.New(registry.WithReferrersSupport(true)))
+	t.Cleanup(upstream.Close)
+	upHost := strings.TrimPrefix(upstream.URL, "http://")
+	upOpts := []remote.Option{remote.WithTransport(upstream.Client().Transport)}
+
+	img, err := random.Image(256, 1)
+	if err != nil {
+		t.Fatalf("random.Image: %v", err)
+	}
+	imgRef, _ := name.NewTag(upHost+"/myorg/app:v1", name.Insecure)
+	if err := remote.Write(imgRef, img, upOpts...); err != nil {
+		t.Fatalf("remote.Write: %v", err)
+	}
+	subject, err := remote.Head(imgRef, upOpts...)
+	if err != nil {
+		t.Fatalf("remote.Head: %v", err)
+	}
+
+	art, err := random.Image(64, 1)
+	if err != nil {
+		t.Fatalf("random.Image: %v", err)
+	}
+	art = mutate.ConfigMediaType(art, "application/vnd.example.test+json")
+	art = mutate.Subject(art, *subject).(gcrv1.Image)
+	artDigest, _ := art.Digest()
+	artRef, _ := name.NewDigest(upHost+"/myorg/app@"+artDigest.String(), name.Insecure)
+	if err := remote.Write(artRef, art, upOpts...); err != nil {
+		t.Fatalf("remote.Write referrer: %v", err)
+	}
+
+	s, err := store.NewLayout(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewLayout: %v", err)
+	}
+	if _, err := s.AddImage(context.Background(), imgRef.Name(), "", false, "", false, "", upOpts...); err != nil {
+		t.Fatalf("AddImage: %v", err)
+	}
+
+	srv := httptest.NewServer(NewStoreRegistryHandler(s))
+	t.Cleanup(srv.Close)
+	host := strings.TrimPrefix(srv.URL, "http://")
+	opts := []remote.Option{remote.WithTransport(srv.Client().Transport)}
+
+	d, _ := name.NewDigest(host+"/myorg/app@"+subject.Digest.String(), name.Insecure)
+	idx, err := remote.Referrers(d, opts...)
+	if err != nil {
+		t.Fatalf("remote.Referrers: %v", err)
+	}
+	im, err := idx.IndexManifest()
+	if err != nil {
+		t.Fatalf("IndexManifest: %v", err)
+	}
+	if len(im.Manifests) != 1 || im.Manifests[0].Digest != artDigest || im.Manifests[0].ArtifactType != "application/vnd.example.test+json" {
+		t.Fatalf("referrers = %+v, want the one stored referrer", im.Manifests)
+	}
+
+	filtered, err := remote.Referrers(d, append(opts, remote.WithFilter("artifactType", "application/other"))...)
+	if err != nil {
+		t.Fatalf("remote.Referrers (filtered): %v", err)
+	}
+	if fm, _ := filtered.IndexManifest(); len(fm.Manifests) != 0 {
+		t.Errorf("filtered referrers = %+v, want none", fm.Manifests)
+	}
+}
@@ -43,4 +43,8 @@ type Chart struct {
 	CaFile                string `json:"caFile,omitempty"`
 	InsecureSkipTLSVerify bool   `json:"insecureSkipTLSVerify,omitempty"`
 	PlainHTTP             bool   `json:"plainHTTP,omitempty"`
+
+	// Labels are user metadata stored with the chart, and with the
+	// dependencies and images added along with it
+	Labels map[string]string `json:"labels,omitempty"`
 }
@@ -27,4 +27,7 @@ type File struct {
 	// If not specified, the default system CA bundle will be used.
 	CaFile                string `json:"ca-file"`
 	InsecureSkipTLSVerify bool   `json:"insecure-skip-tls-verify"`
+
+	// Labels are user metadata stored with the file, i.e. team or ticket
+	Labels map[string]string `json:"labels,omitempty"`
 }
@@ -44,4 +44,7 @@ type Image struct {
 	// TLS options for verifying the image signature.  If not specified, the default system CA bundle will be used.
 	CaFile                string `json:"ca-file"`
 	InsecureSkipTLSVerify bool   `json:"insecure-skip-tls-verify"`
+
+	// Labels are user metadata stored with the image, i.e. team or ticket
+	Labels map[string]string `json:"labels,omitempty"`
 }
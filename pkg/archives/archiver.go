@@ -5,9 +5,12 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path"
 	"path/filepath"
+	"strings"
 
 	"github.com/mholt/archives"
+	"hauler.dev/go/hauler/v2/pkg/consts"
 	"hauler.dev/go/hauler/v2/pkg/log"
 )
 
@@ -27,6 +30,40 @@ var ArchivalMap = map[string]archives.Archival{
 	"zip": archives.Zip{},
 }
 
+// withoutLockFiles drops the store lock and its holder record from files: they
+// describe the process saving the haul, not its content. The store's index
+// history goes too; it is this store's undo log, not something to ship.
+func withoutLockFiles(files []archives.FileInfo, root string) []archives.FileInfo {
+	skip := map[string]bool{
+		path.Join(root, consts.DefaultStoreLockName):     true,
+		path.Join(root, consts.DefaultStoreLockInfoName): true,
+	}
+	history := path.Join(root, consts.DefaultStoreHistoryDir)
+	kept := files[:0]
+	for _, f := range files {
+		if skip[f.NameInArchive] || f.NameInArchive == history || strings.HasPrefix(f.NameInArchive, history+"/") {
+			continue
+		}
+		kept = append(kept, f)
+	}
+	return kept
+}
+
+// withContents puts contents, the haul's header, at the front of files, so
+// ReadContents finds it without reading past anything else. A header left on
+// disk by an older save is dropped in its favor.
+func withContents(files []archives.FileInfo, root string, contents []byte) []archives.FileInfo {
+	name := path.Join(root, consts.DefaultHaulContentsName)
+	out := make([]archives.FileInfo, 0, len(files)+1)
+	out = append(out, contentsFile(name, contents))
+	for _, f := range files {
+		if f.NameInArchive != name {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
 // check if a path exists
 func isExist(path string) bool {
 	_, statErr := os.Stat(path)
@@ -56,22 +93,6 @@ func Archive(ctx context.Context, dir, outfile string, compression archives.Comp
 		return errMsg
 	}
 
-	// map files on disk to their paths in the archive
-	l.Debugf("mapping files in directory [%s]", dir)
-	archiveDirName := filepath.Base(filepath.Clean(dir))
-	if dir == "." {
-		archiveDirName = ""
-	}
-	files, err := archives.FilesFromDisk(context.Background(), nil, map[string]string{
-		dir: archiveDirName,
-	})
-	if err != nil {
-		errMsg := fmt.Errorf("error mapping files from directory [%s]: %w", dir, err)
-		l.Debugf("%s", errMsg.Error())
-		return errMsg
-	}
-	l.Debugf("successfully mapped files for directory [%s]", dir)
-
 	// create the output file we'll write to
 	l.Debugf("creating output file [%s]", outfile)
 	outf, err := os.Create(outfile)
@@ -85,17 +106,8 @@ func Archive(ctx context.Context, dir, outfile string, compression archives.Comp
 		outf.Close()
 	}()
 
-	// define the archive format
-	l.Debugf("defining the archive format: [%T]/[%T]", archival, compression)
-	format := archives.CompressedArchive{
-		Compression: compression,
-		Archival:    archival,
-	}
-
-	// create the archive
 	l.Debugf("starting archive for [%s]", outfile)
-	err = format.Archive(context.Background(), outf, files)
-	if err != nil {
+	if err := ArchiveTo(ctx, dir, outf, compression, archival, nil); err != nil {
 		errMsg := fmt.Errorf("error during archive creation for output file [%s]: %w", outfile, err)
 		l.Debugf("%s", errMsg.Error())
 		return errMsg
@@ -104,83 +116,45 @@ func Archive(ctx context.Context, dir, outfile string, compression archives.Comp
 	return nil
 }
 
-// splits an existing archive into chunks of at most maxBytes each, named
-// <archivePath>.001, .002, ... and removes the original archive afterward.
-func SplitArchive(ctx context.Context, archivePath string, maxBytes int64) ([]string, error) {
+// archives the files in a directory to w as they're read, for a haul written
+// somewhere other than a single file: a stream, or a set of chunks. contents,
+// when not nil, is written first as the haul's contents header.
+func ArchiveTo(ctx context.Context, dir string, w io.Writer, compression archives.Compression, archival archives.Archival, contents []byte) error {
 	l := log.FromContext(ctx)
 
-	if maxBytes <= 0 {
-		return nil, fmt.Errorf("maxBytes must be greater than zero, received %d", maxBytes)
+	if !isExist(dir) {
+		return fmt.Errorf("directory [%s] does not exist, cannot proceed with archival", dir)
 	}
 
-	f, err := os.Open(archivePath)
+	// map files on disk to their paths in the archive
+	l.Debugf("mapping files in directory [%s]", dir)
+	archiveDirName := filepath.Base(filepath.Clean(dir))
+	rootOnDisk := dir
+	if dir == "." {
+		// mapped as "./" so only its contents are archived... a bare "." is
+		// trimmed from the front of every name, turning .hauler.lock into
+		// hauler.lock
+		archiveDirName = ""
+		rootOnDisk = "." + string(filepath.Separator)
+	}
+	files, err := archives.FilesFromDisk(context.Background(), nil, map[string]string{
+		rootOnDisk: archiveDirName,
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to open archive for splitting: %w", err)
+		return fmt.Errorf("error mapping files from directory [%s]: %w", dir, err)
 	}
-
-	var chunks []string
-	buf := make([]byte, 32*1024)
-	chunkIdx := 1
-	var written int64
-	var outf *os.File
-
-	for {
-		remaining := maxBytes - written
-		readSize := int64(len(buf))
-		if readSize > remaining {
-			readSize = remaining
-		}
-
-		n, readErr := f.Read(buf[:readSize])
-		if n > 0 {
-			// chunk files are only created once there's real data to write,
-			// so an archive size that's an exact multiple of maxBytes never
-			// leaves a trailing empty chunk behind.
-			if outf == nil {
-				chunkPath := fmt.Sprintf("%s.%03d", archivePath, chunkIdx)
-				outf, err = os.Create(chunkPath)
-				if err != nil {
-					f.Close()
-					return nil, fmt.Errorf("failed to create chunk %d: %w", chunkIdx, err)
-				}
-				chunks = append(chunks, chunkPath)
-				l.Debugf("creating chunk [%s]", chunkPath)
-				chunkIdx++
-			}
-			if _, writeErr := outf.Write(buf[:n]); writeErr != nil {
-				outf.Close()
-				f.Close()
-				return nil, fmt.Errorf("failed to write to chunk: %w", writeErr)
-			}
-			written += int64(n)
-		}
-
-		if readErr == io.EOF {
-			if outf != nil {
-				outf.Close()
-			}
-			break
-		}
-		if readErr != nil {
-			if outf != nil {
-				outf.Close()
-			}
-			f.Close()
-			return nil, fmt.Errorf("failed to read archive: %w", readErr)
-		}
-
-		if written >= maxBytes {
-			outf.Close()
-			outf = nil
-			written = 0
-		}
+	files = withoutLockFiles(files, archiveDirName)
+	if contents != nil {
+		files = withContents(files, archiveDirName, contents)
 	}
+	l.Debugf("successfully mapped files for directory [%s]", dir)
 
-	f.Close()
-	if err := os.Remove(archivePath); err != nil {
-		return nil, fmt.Errorf("failed to remove original archive after splitting: %w", err)
+	// define the archive format
+	l.Debugf("defining the archive format: [%T]/[%T]", archival, compression)
+	format := archives.CompressedArchive{
+		Compression: compression,
+		Archival:    archival,
 	}
 
-	l.Infof("split archive [%s] into %d chunk(s)", filepath.Base(archivePath), len(chunks))
-	return chunks, nil
+	return format.Archive(context.Background(), w, files)
 }
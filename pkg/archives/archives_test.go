@@ -1,17 +1,24 @@
 package archives
 
 import (
+	"archive/tar"
 	"bytes"
+	"compress/gzip"
 	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/mholt/archives"
+	"github.com/opencontainers/go-digest"
 	"github.com/rs/zerolog"
+
+	"hauler.dev/go/hauler/v2/pkg/consts"
 )
 
 func testContext(t *testing.T) context.Context {
@@ -72,6 +79,71 @@ func TestArchive_RoundTrip(t *testing.T) {
 	}
 }
 
+// A store saved while locked must not carry the lock, or its index history,
+// into the haul.
+func TestArchive_SkipsLockFiles(t *testing.T) {
+	ctx := testContext(t)
+
+	srcDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(srcDir, consts.DefaultStoreHistoryDir), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"index.json", consts.DefaultStoreLockName, consts.DefaultStoreLockInfoName, filepath.Join(consts.DefaultStoreHistoryDir, "1.json")} {
+		if err := os.WriteFile(filepath.Join(srcDir, name), []byte("{}"), 0o644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+
+	outFile := filepath.Join(t.TempDir(), "test.tar.zst")
+	if err := Archive(ctx, srcDir, outFile, archives.Zstd{}, archives.Tar{}); err != nil {
+		t.Fatalf("Archive() error: %v", err)
+	}
+	dstDir := t.TempDir()
+	if err := Unarchive(ctx, outFile, dstDir); err != nil {
+		t.Fatalf("Unarchive() error: %v", err)
+	}
+
+	root := filepath.Join(dstDir, filepath.Base(srcDir))
+	if _, err := os.Stat(filepath.Join(root, "index.json")); err != nil {
+		t.Errorf("index.json missing from archive: %v", err)
+	}
+	for _, name := range []string{consts.DefaultStoreLockName, consts.DefaultStoreLockInfoName, consts.DefaultStoreHistoryDir} {
+		if _, err := os.Stat(filepath.Join(root, name)); !os.IsNotExist(err) {
+			t.Errorf("%s was archived, want it skipped", name)
+		}
+	}
+}
+
+// TestArchive_SkipsLockFilesFromRoot archives from inside the store, as store
+// save does, where the lock files' names start with the root's "."
+func TestArchive_SkipsLockFilesFromRoot(t *testing.T) {
+	ctx := testContext(t)
+
+	srcDir := t.TempDir()
+	for _, name := range []string{"index.json", consts.DefaultStoreLockName, consts.DefaultStoreLockInfoName} {
+		if err := os.WriteFile(filepath.Join(srcDir, name), []byte("{}"), 0o644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+
+	outFile := filepath.Join(t.TempDir(), "test.tar.zst")
+	t.Chdir(srcDir)
+	if err := Archive(ctx, ".", outFile, archives.Zstd{}, archives.Tar{}); err != nil {
+		t.Fatalf("Archive() error: %v", err)
+	}
+
+	var names []string
+	if err := Walk(ctx, outFile, func(name string, _ archives.FileInfo) error {
+		names = append(names, name)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != 1 || names[0] != "index.json" {
+		t.Errorf("archived %v, want only index.json", names)
+	}
+}
+
 func TestArchive_NonExistentDir(t *testing.T) {
 	ctx := testContext(t)
 	nonExistent := filepath.Join(t.TempDir(), "does-not-exist")
@@ -81,6 +153,57 @@ func TestArchive_NonExistentDir(t *testing.T) {
 	}
 }
 
+func TestWalk(t *testing.T) {
+	ctx := testContext(t)
+
+	srcDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(srcDir, "blobs", "sha256"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(srcDir, "index.json"), []byte(`{}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(srcDir, "blobs", "sha256", "abc"), []byte("blob"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	outFile := filepath.Join(t.TempDir(), "walk.tar.zst")
+	cwd, _ := os.Getwd()
+	if err := os.Chdir(srcDir); err != nil {
+		t.Fatal(err)
+	}
+	err := Archive(ctx, ".", outFile, archives.Zstd{}, archives.Tar{})
+	os.Chdir(cwd)
+	if err != nil {
+		t.Fatalf("Archive() error: %v", err)
+	}
+
+	got := map[string]string{}
+	err = Walk(ctx, outFile, func(name string, f archives.FileInfo) error {
+		rc, err := f.Open()
+		if err != nil {
+			return err
+		}
+		defer rc.Close()
+		data, err := io.ReadAll(rc)
+		got[name] = string(data)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("Walk() error: %v", err)
+	}
+
+	want := map[string]string{"index.json": "{}", "blobs/sha256/abc": "blob"}
+	if len(got) != len(want) {
+		t.Fatalf("Walk() visited %v, want %v", got, want)
+	}
+	for name, content := range want {
+		if got[name] != content {
+			t.Errorf("entry %s = %q, want %q", name, got[name], content)
+		}
+	}
+}
+
 func TestUnarchive_ExistingHaul(t *testing.T) {
 	ctx := testContext(t)
 
@@ -241,56 +364,92 @@ func TestChunkInfo(t *testing.T) {
 }
 
 // --------------------------------------------------------------------------
-// SplitArchive
+// HaulWriter
 // --------------------------------------------------------------------------
 
-func TestSplitArchive(t *testing.T) {
-	ctx := testContext(t)
+// writeHaul writes data through a HaulWriter at archivePath, chunked at
+// maxBytes, and returns the closed writer.
+func writeHaul(t *testing.T, archivePath string, data []byte, maxBytes int64) *HaulWriter {
+	t.Helper()
+	w, err := NewHaulWriter(archivePath, maxBytes)
+	if err != nil {
+		t.Fatalf("NewHaulWriter() error = %v", err)
+	}
+	if _, err := w.Write(data); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	return w
+}
 
-	tests := []struct {
-		name     string
-		dataSize int
-		maxBytes int64
-	}{
-		{name: "splits into multiple chunks", dataSize: 100, maxBytes: 30},
-		{name: "single chunk when data fits", dataSize: 50, maxBytes: 100},
+// wantChecksums digests data as the haul at archivePath, split at maxBytes
+// when maxBytes is set.
+func wantChecksums(archivePath string, data []byte, maxBytes int64) *Checksums {
+	c := &Checksums{Archive: FileChecksum{
+		Name:   filepath.Base(archivePath),
+		Digest: digest.FromBytes(data),
+		Size:   int64(len(data)),
+	}}
+	if maxBytes <= 0 {
+		return c
 	}
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
+	for i := int64(0); i*maxBytes < int64(len(data)); i++ {
+		chunk := data[i*maxBytes : min((i+1)*maxBytes, int64(len(data)))]
+		c.Chunks = append(c.Chunks, FileChecksum{
+			Name:   fmt.Sprintf("%s.%03d", filepath.Base(archivePath), i+1),
+			Digest: digest.FromBytes(chunk),
+			Size:   int64(len(chunk)),
+		})
+	}
+	return c
+}
+
+func TestHaulWriter(t *testing.T) {
+	data := make([]byte, 100)
+	for i := range data {
+		data[i] = byte(i % 256)
+	}
+
+	t.Run("chunks as it writes", func(t *testing.T) {
+		for _, maxBytes := range []int64{30, 50, 100, 1000} {
 			dir := t.TempDir()
 			archivePath := filepath.Join(dir, "haul.tar.zst")
-			data := make([]byte, tt.dataSize)
-			for i := range data {
-				data[i] = byte(i % 256)
-			}
-			if err := os.WriteFile(archivePath, data, 0o644); err != nil {
+			// a chunk left by an earlier, longer save
+			stale := archivePath + ".009"
+			if err := os.WriteFile(stale, []byte("stale"), 0o644); err != nil {
 				t.Fatal(err)
 			}
 
-			chunks, err := SplitArchive(ctx, archivePath, tt.maxBytes)
+			w, err := NewHaulWriter(archivePath, maxBytes)
 			if err != nil {
-				t.Fatalf("SplitArchive() error = %v", err)
+				t.Fatalf("NewHaulWriter() error = %v", err)
 			}
-			if len(chunks) == 0 {
-				t.Fatal("SplitArchive() returned no chunks")
+			// uneven writes, so they straddle chunk boundaries
+			for _, part := range [][]byte{data[:7], data[7:52], data[52:]} {
+				if _, err := w.Write(part); err != nil {
+					t.Fatalf("Write() error = %v", err)
+				}
 			}
-
-			// original archive must be removed
-			if _, err := os.Stat(archivePath); !os.IsNotExist(err) {
-				t.Error("original archive should be removed after splitting")
+			if err := w.Close(); err != nil {
+				t.Fatalf("Close() error = %v", err)
 			}
 
-			// chunks must follow <archivePath>.NNN naming (3-digit, 1-based)
-			for i, chunk := range chunks {
-				expected := filepath.Join(dir, fmt.Sprintf("haul.tar.zst.%03d", i+1))
-				if chunk != expected {
-					t.Errorf("chunk[%d] = %s, want %s", i, chunk, expected)
-				}
+			wantChunks := (int64(len(data)) + maxBytes - 1) / maxBytes
+			chunks := w.Files()
+			if int64(len(chunks)) != wantChunks {
+				t.Fatalf("maxBytes %d: %d chunks, want %d... no empty trailing chunk", maxBytes, len(chunks), wantChunks)
+			}
+			if _, err := os.Stat(stale); !os.IsNotExist(err) {
+				t.Errorf("maxBytes %d: stale chunk was left behind", maxBytes)
 			}
 
-			// concatenating chunks must reproduce the original data
 			var combined []byte
-			for _, chunk := range chunks {
+			for i, chunk := range chunks {
+				if want := fmt.Sprintf("%s.%03d", archivePath, i+1); chunk != want {
+					t.Errorf("chunk[%d] = %s, want %s", i, chunk, want)
+				}
 				b, err := os.ReadFile(chunk)
 				if err != nil {
 					t.Fatal(err)
@@ -298,19 +457,59 @@ func TestSplitArchive(t *testing.T) {
 				combined = append(combined, b...)
 			}
 			if !bytes.Equal(combined, data) {
-				t.Error("combined chunks do not match original data")
+				t.Errorf("maxBytes %d: combined chunks do not match what was written", maxBytes)
 			}
-		})
-	}
-}
 
-func TestSplitArchive_MissingFile(t *testing.T) {
-	ctx := testContext(t)
-	dir := t.TempDir()
-	_, err := SplitArchive(ctx, filepath.Join(dir, "nonexistent.tar.zst"), 1<<30)
-	if err == nil {
-		t.Fatal("SplitArchive() expected error for missing file, got nil")
-	}
+			if got, want := w.Checksums(), wantChecksums(archivePath, data, maxBytes); !reflect.DeepEqual(got, want) {
+				t.Errorf("maxBytes %d: Checksums() = %+v, want %+v", maxBytes, got, want)
+			}
+		}
+	})
+
+	t.Run("single file", func(t *testing.T) {
+		archivePath := filepath.Join(t.TempDir(), "haul.tar.zst")
+		w := writeHaul(t, archivePath, data, 0)
+		if got, want := w.Checksums(), wantChecksums(archivePath, data, 0); !reflect.DeepEqual(got, want) {
+			t.Errorf("Checksums() = %+v, want %+v", got, want)
+		}
+	})
+
+	t.Run("stream", func(t *testing.T) {
+		var buf bytes.Buffer
+		w := NewHaulStreamWriter(&buf, "-")
+		if _, err := w.Write(data); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatalf("Close() error = %v", err)
+		}
+		if !bytes.Equal(buf.Bytes(), data) {
+			t.Error("stream does not match what was written")
+		}
+		if len(w.Files()) != 0 {
+			t.Errorf("Files() = %v, want none for a stream", w.Files())
+		}
+		if got, want := w.Checksums().Archive.Digest, digest.FromBytes(data); got != want {
+			t.Errorf("Checksums() digest = %s, want %s", got, want)
+		}
+	})
+
+	t.Run("discard", func(t *testing.T) {
+		archivePath := filepath.Join(t.TempDir(), "haul.tar.zst")
+		w, err := NewHaulWriter(archivePath, 30)
+		if err != nil {
+			t.Fatalf("NewHaulWriter() error = %v", err)
+		}
+		if _, err := w.Write(data); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+		if err := w.Discard(); err != nil {
+			t.Fatalf("Discard() error = %v", err)
+		}
+		if matches, _ := filepath.Glob(archivePath + "*"); len(matches) != 0 {
+			t.Errorf("Discard() left %v behind", matches)
+		}
+	})
 }
 
 // --------------------------------------------------------------------------
@@ -406,10 +605,10 @@ func TestJoinChunks(t *testing.T) {
 }
 
 // --------------------------------------------------------------------------
-// SplitArchive + JoinChunks round-trip
+// HaulWriter + JoinChunks round-trip
 // --------------------------------------------------------------------------
 
-func TestSplitJoinChunks_RoundTrip(t *testing.T) {
+func TestHaulWriterJoinChunks_RoundTrip(t *testing.T) {
 	ctx := testContext(t)
 
 	original := make([]byte, 1000)
@@ -417,18 +616,10 @@ func TestSplitJoinChunks_RoundTrip(t *testing.T) {
 		original[i] = byte(i % 256)
 	}
 
-	dir := t.TempDir()
-	archivePath := filepath.Join(dir, "haul.tar.zst")
-	if err := os.WriteFile(archivePath, original, 0o644); err != nil {
-		t.Fatal(err)
-	}
-
-	chunks, err := SplitArchive(ctx, archivePath, 100)
-	if err != nil {
-		t.Fatalf("SplitArchive() error = %v", err)
-	}
+	archivePath := filepath.Join(t.TempDir(), "haul.tar.zst")
+	chunks := writeHaul(t, archivePath, original, 100).Files()
 	if len(chunks) == 0 {
-		t.Fatal("SplitArchive() returned no chunks")
+		t.Fatal("HaulWriter wrote no chunks")
 	}
 
 	joined, err := JoinChunks(ctx, chunks[0], t.TempDir())
@@ -444,3 +635,486 @@ func TestSplitJoinChunks_RoundTrip(t *testing.T) {
 		t.Error("round-trip: joined data does not match original")
 	}
 }
+
+func TestHaulWriterOpenChunks_RoundTrip(t *testing.T) {
+	ctx := testContext(t)
+
+	original := make([]byte, 1000)
+	for i := range original {
+		original[i] = byte(i % 251)
+	}
+
+	archivePath := filepath.Join(t.TempDir(), "haul.tar.zst")
+	chunks := writeHaul(t, archivePath, original, 100).Files()
+
+	rc, name, err := OpenChunks(ctx, chunks[len(chunks)-1])
+	if err != nil {
+		t.Fatalf("OpenChunks() error = %v", err)
+	}
+	defer rc.Close()
+	if name != "haul.tar.zst" {
+		t.Errorf("OpenChunks() name = %q, want haul.tar.zst", name)
+	}
+	got, err := io.ReadAll(rc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, original) {
+		t.Error("round-trip: streamed chunks do not match original")
+	}
+
+	// a file that isn't a chunk is read as itself
+	single := filepath.Join(t.TempDir(), "haul.tar.zst")
+	if err := os.WriteFile(single, original, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	rc2, name, err := OpenChunks(ctx, single)
+	if err != nil {
+		t.Fatalf("OpenChunks(non-chunk) error = %v", err)
+	}
+	rc2.Close()
+	if name != "haul.tar.zst" {
+		t.Errorf("OpenChunks(non-chunk) name = %q, want haul.tar.zst", name)
+	}
+}
+
+// --------------------------------------------------------------------------
+// ApplyLayer
+// --------------------------------------------------------------------------
+
+type tarEntry struct {
+	name     string
+	typeflag byte
+	body     string
+	linkname string
+}
+
+func buildLayer(t *testing.T, gz bool, entries ...tarEntry) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	var w io.Writer = &buf
+	var zw *gzip.Writer
+	if gz {
+		zw = gzip.NewWriter(&buf)
+		w = zw
+	}
+	tw := tar.NewWriter(w)
+	for _, e := range entries {
+		hdr := &tar.Header{Name: e.name, Typeflag: e.typeflag, Linkname: e.linkname, Mode: 0o644}
+		if e.typeflag == tar.TypeDir {
+			hdr.Mode = 0o755
+		}
+		if e.typeflag == tar.TypeReg {
+			hdr.Size = int64(len(e.body))
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatalf("WriteHeader(%s): %v", e.name, err)
+		}
+		if e.typeflag == tar.TypeReg {
+			if _, err := tw.Write([]byte(e.body)); err != nil {
+				t.Fatalf("Write(%s): %v", e.name, err)
+			}
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("tar close: %v", err)
+	}
+	if zw != nil {
+		if err := zw.Close(); err != nil {
+			t.Fatalf("gzip close: %v", err)
+		}
+	}
+	return &buf
+}
+
+func TestApplyLayer_Whiteouts(t *testing.T) {
+	ctx := testContext(t)
+	dst := t.TempDir()
+
+	base := buildLayer(t, true,
+		tarEntry{name: "etc/", typeflag: tar.TypeDir},
+		tarEntry{name: "etc/keep", typeflag: tar.TypeReg, body: "keep"},
+		tarEntry{name: "etc/remove", typeflag: tar.TypeReg, body: "remove"},
+		tarEntry{name: "opt/", typeflag: tar.TypeDir},
+		tarEntry{name: "opt/old/", typeflag: tar.TypeDir},
+		tarEntry{name: "opt/old/file", typeflag: tar.TypeReg, body: "old"},
+		tarEntry{name: "opt/stale", typeflag: tar.TypeReg, body: "stale"},
+	)
+	if err := ApplyLayer(ctx, base, "application/vnd.oci.image.layer.v1.tar+gzip", dst, ""); err != nil {
+		t.Fatalf("ApplyLayer(base): %v", err)
+	}
+
+	// the new opt/fresh is written before the opaque marker... it belongs to
+	// this layer and has to survive it
+	upper := buildLayer(t, false,
+		tarEntry{name: "etc/.wh.remove", typeflag: tar.TypeReg},
+		tarEntry{name: "opt/fresh", typeflag: tar.TypeReg, body: "fresh"},
+		tarEntry{name: "opt/.wh..wh..opq", typeflag: tar.TypeReg},
+		tarEntry{name: "etc/keep", typeflag: tar.TypeReg, body: "updated"},
+	)
+	if err := ApplyLayer(ctx, upper, "application/vnd.oci.image.layer.v1.tar", dst, ""); err != nil {
+		t.Fatalf("ApplyLayer(upper): %v", err)
+	}
+
+	for p, want := range map[string]string{"etc/keep": "updated", "opt/fresh": "fresh"} {
+		got, err := os.ReadFile(filepath.Join(dst, p))
+		if err != nil {
+			t.Fatalf("ReadFile(%s): %v", p, err)
+		}
+		if string(got) != want {
+			t.Errorf("%s = %q, want %q", p, got, want)
+		}
+	}
+	for _, p := range []string{"etc/remove", "etc/.wh.remove", "opt/old", "opt/stale", "opt/.wh..wh..opq"} {
+		if _, err := os.Lstat(filepath.Join(dst, p)); !os.IsNotExist(err) {
+			t.Errorf("expected %s to be removed, got err=%v", p, err)
+		}
+	}
+}
+
+func TestApplyLayer_StaysInRoot(t *testing.T) {
+	ctx := testContext(t)
+	parent := t.TempDir()
+	dst := filepath.Join(parent, "rootfs")
+	if err := os.Mkdir(dst, 0o755); err != nil {
+		t.Fatalf("Mkdir: %v", err)
+	}
+
+	layer := buildLayer(t, false,
+		tarEntry{name: "../escape-dotdot", typeflag: tar.TypeReg, body: "x"},
+		tarEntry{name: "abs", typeflag: tar.TypeSymlink, linkname: "/"},
+		tarEntry{name: "rel", typeflag: tar.TypeSymlink, linkname: "../../.."},
+		tarEntry{name: "abs/escape-abs", typeflag: tar.TypeReg, body: "x"},
+		tarEntry{name: "rel/escape-rel", typeflag: tar.TypeReg, body: "x"},
+		tarEntry{name: "hard", typeflag: tar.TypeLink, linkname: "../escape-dotdot"},
+	)
+	if err := ApplyLayer(ctx, layer, "", dst, ""); err != nil {
+		t.Fatalf("ApplyLayer: %v", err)
+	}
+
+	entries, err := os.ReadDir(parent)
+	if err != nil {
+		t.Fatalf("ReadDir: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("expected only the rootfs dir in %s, got %d entries", parent, len(entries))
+	}
+	for _, p := range []string{"escape-dotdot", "escape-abs", "escape-rel", "hard"} {
+		if _, err := os.Lstat(filepath.Join(dst, p)); err != nil {
+			t.Errorf("expected %s inside the root: %v", p, err)
+		}
+	}
+}
+
+func TestApplyLayer_Path(t *testing.T) {
+	ctx := testContext(t)
+	dst := t.TempDir()
+
+	layer := buildLayer(t, false,
+		tarEntry{name: "bin/", typeflag: tar.TypeDir},
+		tarEntry{name: "bin/tool", typeflag: tar.TypeReg, body: "tool"},
+		tarEntry{name: "usr/", typeflag: tar.TypeDir},
+		tarEntry{name: "usr/local/", typeflag: tar.TypeDir},
+		tarEntry{name: "usr/local/bin/", typeflag: tar.TypeDir},
+		tarEntry{name: "usr/local/bin/app", typeflag: tar.TypeReg, body: "app"},
+		tarEntry{name: "usr/local/bin/app-link", typeflag: tar.TypeLink, linkname: "usr/local/bin/app"},
+		tarEntry{name: "usr/local/bin/tool-link", typeflag: tar.TypeLink, linkname: "bin/tool"},
+		tarEntry{name: "usr/share/doc", typeflag: tar.TypeReg, body: "doc"},
+	)
+	if err := ApplyLayer(ctx, layer, "", dst, "/usr/local/bin"); err != nil {
+		t.Fatalf("ApplyLayer: %v", err)
+	}
+
+	for _, p := range []string{"usr/local/bin/app", "usr/local/bin/app-link"} {
+		if _, err := os.Stat(filepath.Join(dst, p)); err != nil {
+			t.Errorf("expected %s: %v", p, err)
+		}
+	}
+	for _, p := range []string{"bin", "usr/share", "usr/local/bin/tool-link"} {
+		if _, err := os.Lstat(filepath.Join(dst, p)); !os.IsNotExist(err) {
+			t.Errorf("expected %s to be filtered out, got err=%v", p, err)
+		}
+	}
+}
+
+// --------------------------------------------------------------------------
+// Checksums
+// --------------------------------------------------------------------------
+
+func TestChecksums_Verify(t *testing.T) {
+	ctx := testContext(t)
+
+	original := make([]byte, 1000)
+	for i := range original {
+		original[i] = byte(i % 241)
+	}
+	split := func(t *testing.T) (string, []string, *Checksums) {
+		t.Helper()
+		archivePath := filepath.Join(t.TempDir(), "haul.tar.zst")
+		w := writeHaul(t, archivePath, original, 300)
+		return archivePath, w.Files(), w.Checksums()
+	}
+
+	t.Run("intact chunks", func(t *testing.T) {
+		archivePath, chunks, c := split(t)
+		if len(c.Chunks) != len(chunks) || c.Archive.Size != int64(len(original)) {
+			t.Fatalf("checksums = %+v, want %d chunks over %d bytes", c, len(chunks), len(original))
+		}
+		if got := ChecksumsPath(chunks[1]); got != archivePath+consts.DefaultHaulChecksumsExt {
+			t.Errorf("ChecksumsPath(chunk) = %s, want %s", got, archivePath+consts.DefaultHaulChecksumsExt)
+		}
+		if err := c.Verify(ctx, chunks[0]); err != nil {
+			t.Errorf("Verify() error = %v", err)
+		}
+	})
+
+	t.Run("corrupt chunk is named", func(t *testing.T) {
+		_, chunks, c := split(t)
+		if err := os.WriteFile(chunks[1], bytes.Repeat([]byte{0}, 300), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		err := c.Verify(ctx, chunks[0])
+		if err == nil || !strings.Contains(err.Error(), filepath.Base(chunks[1])) {
+			t.Errorf("Verify() error = %v, want one naming %s", err, filepath.Base(chunks[1]))
+		}
+	})
+
+	t.Run("missing chunk is named", func(t *testing.T) {
+		_, chunks, c := split(t)
+		if err := os.Remove(chunks[2]); err != nil {
+			t.Fatal(err)
+		}
+		err := c.Verify(ctx, chunks[0])
+		if err == nil || !strings.Contains(err.Error(), filepath.Base(chunks[2])) {
+			t.Errorf("Verify() error = %v, want one naming %s", err, filepath.Base(chunks[2]))
+		}
+	})
+
+	t.Run("chunks joined by hand", func(t *testing.T) {
+		_, chunks, c := split(t)
+		joined, err := JoinChunks(ctx, chunks[0], t.TempDir())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := c.Verify(ctx, joined); err != nil {
+			t.Errorf("Verify(joined) error = %v", err)
+		}
+	})
+
+	t.Run("round trip through the sidecar", func(t *testing.T) {
+		archivePath := filepath.Join(t.TempDir(), "haul.tar.zst")
+		c := writeHaul(t, archivePath, original, 0).Checksums()
+		if _, err := c.Write(ChecksumsPath(archivePath)); err != nil {
+			t.Fatal(err)
+		}
+		read, _, err := ReadChecksums(ChecksumsPath(archivePath))
+		if err != nil {
+			t.Fatalf("ReadChecksums() error = %v", err)
+		}
+		if err := read.Verify(ctx, archivePath); err != nil {
+			t.Errorf("Verify() error = %v", err)
+		}
+		if err := os.WriteFile(archivePath, append(original, 'x'), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := read.Verify(ctx, archivePath); err == nil {
+			t.Error("Verify() of a changed archive succeeded")
+		}
+	})
+}
+
+// --------------------------------------------------------------------------
+// Haul contents
+// --------------------------------------------------------------------------
+
+func TestReadContents(t *testing.T) {
+	ctx := testContext(t)
+
+	index := `{"schemaVersion":2,"manifests":[{"mediaType":"application/vnd.oci.image.manifest.v1+json","digest":"sha256:1adaa1f4cf58171cb74fadb24e5f133dffe8729cfefb57680a12905765a1a10c","size":472,"annotations":{"kind":"dev.hauler/image","org.opencontainers.image.ref.name":"library/busybox:1.36"}}]}`
+	layout := func(t *testing.T, header string) string {
+		t.Helper()
+		dir := t.TempDir()
+		files := map[string]string{
+			"index.json":        index,
+			"blobs/sha256/aaaa": "blob",
+		}
+		for name, content := range files {
+			full := filepath.Join(dir, name)
+			if err := os.MkdirAll(filepath.Dir(full), 0o755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(full, []byte(content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		// archived from inside the layout, the way store save does
+		out := filepath.Join(t.TempDir(), "haul.tar.zst")
+		t.Chdir(dir)
+		if header == "" {
+			if err := Archive(ctx, ".", out, archives.Zstd{}, archives.Tar{}); err != nil {
+				t.Fatalf("Archive() error = %v", err)
+			}
+			return out
+		}
+		f, err := os.Create(out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		if err := ArchiveTo(ctx, ".", f, archives.Zstd{}, archives.Tar{}, []byte(header)); err != nil {
+			t.Fatalf("ArchiveTo() error = %v", err)
+		}
+		return out
+	}
+	read := func(t *testing.T, haul string) *HaulContents {
+		t.Helper()
+		f, err := os.Open(haul)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		c, err := ReadContents(ctx, haul, f, "")
+		if err != nil {
+			t.Fatalf("ReadContents() error = %v", err)
+		}
+		return c
+	}
+
+	t.Run("header is the first entry", func(t *testing.T) {
+		header, _ := json.Marshal(HaulContents{
+			StoreID:   "store-1",
+			Artifacts: []HaulArtifact{{Reference: "docker.io/library/busybox:1.36", Type: "image", Platforms: []string{"linux/amd64"}, Size: 1234}},
+		})
+		haul := layout(t, string(header))
+
+		var first string
+		if err := Walk(ctx, haul, func(name string, _ archives.FileInfo) error {
+			if first == "" {
+				first = name
+			}
+			return nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+		if first != consts.DefaultHaulContentsName {
+			t.Errorf("first entry = %q, want %q", first, consts.DefaultHaulContentsName)
+		}
+
+		c := read(t, haul)
+		if c.Legacy || c.StoreID != "store-1" || len(c.Artifacts) != 1 || c.Artifacts[0].Platforms[0] != "linux/amd64" {
+			t.Errorf("ReadContents() = %+v, want the header as written", c)
+		}
+	})
+
+	t.Run("older hauls fall back to the index", func(t *testing.T) {
+		c := read(t, layout(t, ""))
+		if !c.Legacy {
+			t.Error("ReadContents() of a haul without a header isn't marked legacy")
+		}
+		if len(c.Artifacts) != 1 || c.Artifacts[0].Reference != "library/busybox:1.36" || c.Artifacts[0].Type != "image" || c.Artifacts[0].Size != 472 {
+			t.Errorf("ReadContents() artifacts = %+v", c.Artifacts)
+		}
+	})
+}
+
+// --------------------------------------------------------------------------
+// Formats
+// --------------------------------------------------------------------------
+
+func TestNewFormat(t *testing.T) {
+	tests := []struct {
+		name     string
+		filename string
+		opts     FormatOptions
+		wantComp string // extension of the compression, "" for none
+		wantArch string
+		wantErr  bool
+	}{
+		{name: "default name", filename: "haul.tar.zst", wantComp: ".zst", wantArch: ".tar"},
+		{name: "unknown extension", filename: "haul.bin", wantComp: ".zst", wantArch: ".tar"},
+		{name: "gz by extension", filename: "haul.tgz", wantComp: ".gz", wantArch: ".tar"},
+		{name: "uncompressed tar", filename: "haul.tar", wantComp: "", wantArch: ".tar"},
+		{name: "zip", filename: "HAUL.ZIP", wantComp: "", wantArch: ".zip"},
+		{name: "flag beats extension", filename: "haul.tar.zst", opts: FormatOptions{Compression: "xz"}, wantComp: ".xz", wantArch: ".tar"},
+		{name: "zst level", filename: "haul.tar.zst", opts: FormatOptions{Level: 19, Threads: 4}, wantComp: ".zst", wantArch: ".tar"},
+		{name: "zst level too high", filename: "haul.tar.zst", opts: FormatOptions{Level: 23}, wantErr: true},
+		{name: "xz has no levels", filename: "haul.tar.xz", opts: FormatOptions{Level: 3}, wantErr: true},
+		{name: "compressed zip", filename: "haul.zip", opts: FormatOptions{Compression: "gz"}, wantErr: true},
+		{name: "unknown compression", filename: "haul.tar", opts: FormatOptions{Compression: "rar"}, wantErr: true},
+		{name: "unknown format", filename: "haul.tar", opts: FormatOptions{Archival: "7z"}, wantErr: true},
+		{name: "negative threads", filename: "haul.tar.zst", opts: FormatOptions{Threads: -1}, wantErr: true},
+		{name: "gz threads", filename: "haul.tar.gz", opts: FormatOptions{Threads: 4}, wantComp: ".gz", wantArch: ".tar"},
+		{name: "one thread for xz", filename: "haul.tar.xz", opts: FormatOptions{Threads: 1}, wantComp: ".xz", wantArch: ".tar"},
+		{name: "xz threads", filename: "haul.tar.xz", opts: FormatOptions{Threads: 4}, wantErr: true},
+		{name: "bz2 threads", filename: "haul.tar.bz2", opts: FormatOptions{Threads: 2}, wantErr: true},
+		{name: "lz4 threads", filename: "haul.tar.lz4", opts: FormatOptions{Threads: 2}, wantErr: true},
+		{name: "br threads", filename: "haul.tar.br", opts: FormatOptions{Threads: 2}, wantErr: true},
+		{name: "uncompressed threads", filename: "haul.tar", opts: FormatOptions{Threads: 2}, wantErr: true},
+		{name: "zip threads", filename: "haul.zip", opts: FormatOptions{Threads: 2}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := NewFormat(tt.filename, tt.opts)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("NewFormat() succeeded, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NewFormat() error = %v", err)
+			}
+			var gotComp string
+			if f.Compression != nil {
+				gotComp = f.Compression.Extension()
+			}
+			if gotComp != tt.wantComp || f.Archival.Extension() != tt.wantArch {
+				t.Errorf("NewFormat() = %q/%q, want %q/%q", f.Archival.Extension(), gotComp, tt.wantArch, tt.wantComp)
+			}
+		})
+	}
+}
+
+// TestWalkReader_ZipStream walks a zip read from a plain stream, the way a
+// chunked or remote haul arrives, which zip can't read without seeking.
+func TestWalkReader_ZipStream(t *testing.T) {
+	ctx := testContext(t)
+
+	srcDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(srcDir, "index.json"), []byte(`{}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	f, err := NewFormat("haul.zip", FormatOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := filepath.Join(t.TempDir(), "haul.zip")
+	t.Chdir(srcDir)
+	if err := Archive(ctx, ".", out, f.Compression, f.Archival); err != nil {
+		t.Fatalf("Archive() error = %v", err)
+	}
+
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the spool goes to the temp dir it's given
+	spoolDir := t.TempDir()
+	var got []string
+	err = WalkReader(ctx, "haul.zip", io.MultiReader(bytes.NewReader(data)), spoolDir, func(name string, _ archives.FileInfo) error {
+		got = append(got, name)
+		if spools, _ := filepath.Glob(filepath.Join(spoolDir, "hauler-*.zip")); len(spools) != 1 {
+			t.Errorf("spools in the temp dir = %v, want one", spools)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("WalkReader() error = %v", err)
+	}
+	if len(got) != 1 || got[0] != "index.json" {
+		t.Errorf("WalkReader() entries = %v, want [index.json]", got)
+	}
+	if spools, _ := filepath.Glob(filepath.Join(spoolDir, "hauler-*.zip")); len(spools) != 0 {
+		t.Errorf("spool left behind: %v", spools)
+	}
+}
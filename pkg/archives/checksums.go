@@ -0,0 +1,136 @@
+package archives
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/opencontainers/go-digest"
+
+	"hauler.dev/go/hauler/v2/pkg/consts"
+	"hauler.dev/go/hauler/v2/pkg/log"
+)
+
+// Checksums is a haul's sidecar digest manifest: the digest of the whole
+// archive and, for a haul split into chunks, of every chunk in order. It
+// is written next to the haul as <archive>.checksums.json.
+type Checksums struct {
+	Archive FileChecksum   `json:"archive"`
+	Chunks  []FileChecksum `json:"chunks,omitempty"`
+}
+
+// FileChecksum is the digest and size of one file of a haul, by base name.
+type FileChecksum struct {
+	Name   string        `json:"name"`
+	Digest digest.Digest `json:"digest"`
+	Size   int64         `json:"size"`
+}
+
+// ChecksumsPath returns where the sidecar of the haul at archivePath lives:
+// next to it, named after the whole archive even when archivePath is one of
+// its chunks.
+func ChecksumsPath(archivePath string) string {
+	dir, name := filepath.Dir(archivePath), filepath.Base(archivePath)
+	if key, ok := ChunkGroupKey(name); ok {
+		name = key
+	}
+	return filepath.Join(dir, name+consts.DefaultHaulChecksumsExt)
+}
+
+// ReadChecksums reads the sidecar at path.
+func ReadChecksums(path string) (*Checksums, []byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	var c Checksums
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse haul checksums [%s]: %w", path, err)
+	}
+	if err := c.Archive.Digest.Validate(); err != nil {
+		return nil, nil, fmt.Errorf("haul checksums [%s] hold an invalid archive digest: %w", path, err)
+	}
+	return &c, data, nil
+}
+
+// Write writes c to path.
+func (c *Checksums) Write(path string) ([]byte, error) {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	data = append(data, '\n')
+	return data, os.WriteFile(path, data, 0o644)
+}
+
+// Verify checks the haul at archivePath against c before anything reads it:
+// every chunk of a chunked haul, in order, and the whole archive. The error
+// names the first file that doesn't match.
+func (c *Checksums) Verify(ctx context.Context, archivePath string) error {
+	l := log.FromContext(ctx)
+
+	// a haul whose chunks were joined by hand is still checked as a whole
+	files := []string{archivePath}
+	matches, _ := chunkSet(archivePath)
+	chunked := matches != nil
+	switch {
+	case !chunked:
+	case len(c.Chunks) == 0:
+		return fmt.Errorf("haul [%s] is chunked, but its checksums list no chunks", c.Archive.Name)
+	case len(matches) != len(c.Chunks):
+		present := map[string]bool{}
+		for _, m := range matches {
+			present[filepath.Base(m)] = true
+		}
+		for _, want := range c.Chunks {
+			if !present[want.Name] {
+				return fmt.Errorf("haul [%s] is missing chunk [%s]... found [%d] of [%d] chunks", c.Archive.Name, want.Name, len(matches), len(c.Chunks))
+			}
+		}
+		return fmt.Errorf("haul [%s] has [%d] chunks, its checksums list [%d]", c.Archive.Name, len(matches), len(c.Chunks))
+	default:
+		files = matches
+	}
+
+	whole := digest.Canonical.Digester()
+	for i, path := range files {
+		fc, err := checksumFile(path, whole.Hash())
+		if err != nil {
+			return err
+		}
+		if !chunked {
+			continue
+		}
+		want := c.Chunks[i]
+		if fc.Size != want.Size || fc.Digest != want.Digest {
+			return fmt.Errorf("chunk [%s] of haul [%s] is corrupt... has digest [%s] and size [%d], want [%s] and [%d]",
+				fc.Name, c.Archive.Name, fc.Digest, fc.Size, want.Digest, want.Size)
+		}
+		l.Debugf("verified chunk [%s] with digest [%s]", fc.Name, fc.Digest)
+	}
+
+	if got := whole.Digest(); got != c.Archive.Digest {
+		return fmt.Errorf("haul [%s] is corrupt... has digest [%s], want [%s]", c.Archive.Name, got, c.Archive.Digest)
+	}
+	l.Debugf("verified haul [%s] with digest [%s]", c.Archive.Name, c.Archive.Digest)
+	return nil
+}
+
+// checksumFile digests the file at path, also writing it through to w.
+func checksumFile(path string, w io.Writer) (FileChecksum, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return FileChecksum{}, err
+	}
+	defer f.Close()
+
+	d := digest.Canonical.Digester()
+	n, err := io.Copy(io.MultiWriter(d.Hash(), w), f)
+	if err != nil {
+		return FileChecksum{}, fmt.Errorf("failed to read [%s]: %w", path, err)
+	}
+	return FileChecksum{Name: filepath.Base(path), Digest: d.Digest(), Size: n}, nil
+}
@@ -0,0 +1,181 @@
+package archives
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mholt/archives"
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+
+	"hauler.dev/go/hauler/v2/pkg/consts"
+)
+
+// HaulContents is the bill of contents `hauler store save` writes as the
+// first entry of every haul, so what a haul holds can be read without
+// extracting it.
+type HaulContents struct {
+	StoreID       string `json:"store-id,omitempty" yaml:"store-id,omitempty"`
+	HaulerVersion string `json:"hauler-version,omitempty" yaml:"hauler-version,omitempty"`
+	Created       string `json:"created,omitempty" yaml:"created,omitempty"`
+	// Delta marks a haul saved with --since, which only loads on top of its
+	// baseline.
+	Delta     bool           `json:"delta,omitempty" yaml:"delta,omitempty"`
+	Artifacts []HaulArtifact `json:"artifacts" yaml:"artifacts"`
+
+	// Legacy is set when the haul predates the contents header, and its
+	// artifacts were read from the haul's index.json instead... they carry no
+	// platforms, and only the size of each manifest.
+	Legacy bool `json:"legacy,omitempty" yaml:"legacy,omitempty"`
+}
+
+// HaulArtifact is one index entry of a haul.
+type HaulArtifact struct {
+	Reference string        `json:"reference" yaml:"reference"`
+	Type      string        `json:"type" yaml:"type"`
+	Platforms []string      `json:"platforms,omitempty" yaml:"platforms,omitempty"`
+	Digest    digest.Digest `json:"digest" yaml:"digest"`
+	// Size is every blob the artifact references.
+	Size int64 `json:"size" yaml:"size"`
+}
+
+// contentsFile is the in-memory header store save adds to a haul as name.
+func contentsFile(name string, data []byte) archives.FileInfo {
+	info := contentsInfo{name: path.Base(name), size: int64(len(data)), modTime: time.Now()}
+	return archives.FileInfo{
+		FileInfo:      info,
+		NameInArchive: name,
+		Open: func() (fs.File, error) {
+			return &contentsReader{Reader: bytes.NewReader(data), info: info}, nil
+		},
+	}
+}
+
+type contentsInfo struct {
+	name    string
+	size    int64
+	modTime time.Time
+}
+
+func (i contentsInfo) Name() string       { return i.name }
+func (i contentsInfo) Size() int64        { return i.size }
+func (i contentsInfo) Mode() fs.FileMode  { return 0o644 }
+func (i contentsInfo) ModTime() time.Time { return i.modTime }
+func (i contentsInfo) IsDir() bool        { return false }
+func (i contentsInfo) Sys() any           { return nil }
+
+type contentsReader struct {
+	*bytes.Reader
+	info contentsInfo
+}
+
+func (r *contentsReader) Stat() (fs.FileInfo, error) { return r.info, nil }
+func (r *contentsReader) Close() error               { return nil }
+
+// errContentsRead stops a walk once the contents are read.
+var errContentsRead = errors.New("haul contents read")
+
+// ReadContents reads the contents of the haul streamed from r. The header is
+// the haul's first entry, so only its front is decompressed; a haul without
+// one is scanned for its index.json instead. A zip haul is spooled to tempDir
+// to be read.
+func ReadContents(ctx context.Context, name string, r io.Reader, tempDir string) (*HaulContents, error) {
+	var c *HaulContents
+	err := WalkReader(ctx, name, r, tempDir, func(entry string, f archives.FileInfo) error {
+		switch entry {
+		case consts.DefaultHaulContentsName:
+			data, err := readEntry(f)
+			if err != nil {
+				return err
+			}
+			var hc HaulContents
+			if err := json.Unmarshal(data, &hc); err != nil {
+				return fmt.Errorf("failed to parse haul contents: %w", err)
+			}
+			c = &hc
+			return errContentsRead
+		case ocispec.ImageIndexFile:
+			data, err := readEntry(f)
+			if err != nil {
+				return err
+			}
+			var idx ocispec.Index
+			if err := json.Unmarshal(data, &idx); err != nil {
+				return fmt.Errorf("failed to parse haul index: %w", err)
+			}
+			c = contentsFromIndex(idx)
+			return errContentsRead
+		}
+		return nil
+	})
+	if c != nil {
+		return c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return nil, fmt.Errorf("haul [%s] has no contents header or %s", name, ocispec.ImageIndexFile)
+}
+
+// contentsFromIndex describes a haul by its index alone, for hauls saved
+// before the contents header.
+func contentsFromIndex(idx ocispec.Index) *HaulContents {
+	c := &HaulContents{Legacy: true, Artifacts: []HaulArtifact{}}
+	for _, desc := range idx.Manifests {
+		ref := desc.Annotations[consts.ContainerdImageNameKey]
+		if ref == "" {
+			ref = desc.Annotations[ocispec.AnnotationRefName]
+		}
+		a := HaulArtifact{
+			Reference: ref,
+			Type:      indexEntryType(desc),
+			Digest:    desc.Digest,
+			Size:      desc.Size,
+		}
+		if p := desc.Platform; p != nil {
+			a.Platforms = []string{p.OS + "/" + p.Architecture}
+		}
+		c.Artifacts = append(c.Artifacts, a)
+	}
+	sort.SliceStable(c.Artifacts, func(i, j int) bool { return c.Artifacts[i].Reference < c.Artifacts[j].Reference })
+	return c
+}
+
+// indexEntryType is the best guess at an entry's content type without its
+// manifest: files and charts share a kind, so both show as "artifact".
+func indexEntryType(desc ocispec.Descriptor) string {
+	switch desc.Annotations[consts.KindAnnotationName] {
+	case consts.KindAnnotationImage, consts.KindAnnotationIndex:
+		return "image"
+	case consts.KindAnnotationSigs:
+		return "sigs"
+	case consts.KindAnnotationAtts:
+		return "atts"
+	case consts.KindAnnotationSboms:
+		return "sbom"
+	case "":
+		return "unknown"
+	}
+	if strings.HasPrefix(desc.Annotations[consts.KindAnnotationName], consts.KindAnnotationReferrers) {
+		return "referrer"
+	}
+	return "artifact"
+}
+
+func readEntry(f archives.FileInfo) ([]byte, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(rc)
+}
@@ -0,0 +1,179 @@
+package archives
+
+import (
+	"archive/zip"
+	"fmt"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/mholt/archives"
+	"github.com/pierrec/lz4/v4"
+)
+
+// CompressionNone writes a haul without compressing it as a whole, for stores
+// whose layers are compressed already.
+const CompressionNone = "none"
+
+// FormatOptions picks the format of a haul. Anything left unset is inferred
+// from the haul's file name, falling back to a zstd-compressed tar.
+type FormatOptions struct {
+	// Compression is a CompressionMap key or CompressionNone.
+	Compression string
+	// Archival is an ArchivalMap key.
+	Archival string
+	// Level is the compression level, or 0 for the compression's default:
+	// 1-22 for zst, 1-9 for gz, bz2, and lz4, and 0-11 for br.
+	Level int
+	// Threads is how many cores encode at once, or 0 for all of them. Only
+	// zst and gz encode in parallel; gz can only be turned off, with 1, and
+	// the rest refuse more than 1.
+	Threads int
+}
+
+// formatExtensions infers a haul's compression and archival from its name,
+// longest suffix first.
+var formatExtensions = []struct {
+	suffix, compression, archival string
+}{
+	{".tar.zst", "zst", "tar"},
+	{".tar.zstd", "zst", "tar"},
+	{".tar.gz", "gz", "tar"},
+	{".tar.bz2", "bz2", "tar"},
+	{".tar.xz", "xz", "tar"},
+	{".tar.lz4", "lz4", "tar"},
+	{".tar.br", "br", "tar"},
+	{".tzst", "zst", "tar"},
+	{".tgz", "gz", "tar"},
+	{".tbz2", "bz2", "tar"},
+	{".txz", "xz", "tar"},
+	{".tar", CompressionNone, "tar"},
+	{".zip", CompressionNone, "zip"},
+}
+
+// InferFormat returns the compression and archival the name of a haul
+// implies, or ok false when its extension isn't one of them.
+func InferFormat(filename string) (compression, archival string, ok bool) {
+	name := strings.ToLower(filename)
+	for _, e := range formatExtensions {
+		if strings.HasSuffix(name, e.suffix) {
+			return e.compression, e.archival, true
+		}
+	}
+	return "", "", false
+}
+
+// NewFormat returns the format to write the haul named filename in.
+func NewFormat(filename string, o FormatOptions) (archives.CompressedArchive, error) {
+	compression, archival, ok := InferFormat(filename)
+	if !ok {
+		compression, archival = "zst", "tar"
+	}
+	if o.Compression != "" {
+		compression = o.Compression
+	}
+	if o.Archival != "" {
+		archival = o.Archival
+	}
+	if o.Level < 0 {
+		return archives.CompressedArchive{}, fmt.Errorf("compression level must not be negative, received %d", o.Level)
+	}
+	if o.Threads < 0 {
+		return archives.CompressedArchive{}, fmt.Errorf("threads must not be negative, received %d", o.Threads)
+	}
+
+	var format archives.CompressedArchive
+	switch archival {
+	case "tar":
+		format.Archival = archives.Tar{}
+	case "zip":
+		// a zip compresses each file on its own, and leaves the ones that
+		// already are (most layers) stored as they are
+		if compression != CompressionNone {
+			return format, fmt.Errorf("a zip haul can't be %s-compressed as a whole... use --compression %s", compression, CompressionNone)
+		}
+		if o.Level != 0 {
+			return format, fmt.Errorf("--compression-level doesn't apply to a zip haul")
+		}
+		if o.Threads > 1 {
+			return format, fmt.Errorf("--threads doesn't apply to a zip haul, which compresses on one core")
+		}
+		format.Archival = archives.Zip{Compression: zip.Deflate, SelectiveCompression: true}
+		return format, nil
+	default:
+		return format, fmt.Errorf("unsupported archive format %q: must be one of tar, zip", archival)
+	}
+
+	c, err := newCompression(compression, o.Level, o.Threads)
+	if err != nil {
+		return format, err
+	}
+	format.Compression = c
+	return format, nil
+}
+
+// newCompression configures the compression named name.
+func newCompression(name string, level, threads int) (archives.Compression, error) {
+	inRange := func(lo, hi int) error {
+		if level != 0 && (level < lo || level > hi) {
+			return fmt.Errorf("%s compression level must be between %d and %d, received %d", name, lo, hi, level)
+		}
+		return nil
+	}
+	// everything but zst and gz encodes on one core
+	if threads > 1 && name != "zst" && name != "gz" {
+		if name == CompressionNone {
+			return nil, fmt.Errorf("--threads doesn't apply to an uncompressed haul")
+		}
+		return nil, fmt.Errorf("%s compression encodes on one core... --threads only applies to zst and gz", name)
+	}
+
+	switch name {
+	case CompressionNone:
+		if level != 0 {
+			return nil, fmt.Errorf("--compression-level doesn't apply to an uncompressed haul")
+		}
+		return nil, nil
+	case "zst":
+		if err := inRange(1, 22); err != nil {
+			return nil, err
+		}
+		var opts []zstd.EOption
+		if level != 0 {
+			opts = append(opts, zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(level)))
+		}
+		if threads != 0 {
+			opts = append(opts, zstd.WithEncoderConcurrency(threads))
+		}
+		return archives.Zstd{EncoderOptions: opts}, nil
+	case "gz":
+		if err := inRange(1, 9); err != nil {
+			return nil, err
+		}
+		return archives.Gz{CompressionLevel: level, Multithreaded: threads != 1}, nil
+	case "bz2":
+		if err := inRange(1, 9); err != nil {
+			return nil, err
+		}
+		return archives.Bz2{CompressionLevel: level}, nil
+	case "xz":
+		if level != 0 {
+			return nil, fmt.Errorf("xz compression has no levels to choose from")
+		}
+		return archives.Xz{}, nil
+	case "lz4":
+		if err := inRange(1, 9); err != nil {
+			return nil, err
+		}
+		lz := archives.Lz4{}
+		if level != 0 {
+			lz.CompressionLevel = int(lz4.Level1 << (level - 1))
+		}
+		return lz, nil
+	case "br":
+		if level > 11 {
+			return nil, fmt.Errorf("br compression level must be between 0 and 11, received %d", level)
+		}
+		return archives.Brotli{Quality: level}, nil
+	}
+	return nil, fmt.Errorf("unsupported compression %q: must be one of zst, gz, bz2, xz, lz4, br, %s", name, CompressionNone)
+}
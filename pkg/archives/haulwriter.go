@@ -0,0 +1,204 @@
+package archives
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/opencontainers/go-digest"
+)
+
+// HaulWriter takes a haul as ArchiveTo writes it: into the file at its path,
+// straight into chunks of at most maxBytes each (named <path>.001, .002, ...),
+// or on to a stream. The haul is digested on the way through, so its
+// checksums are ready once it's written, without reading it back.
+type HaulWriter struct {
+	path     string
+	maxBytes int64
+
+	stream io.Writer
+	f      *os.File
+	// written is what's gone into the current chunk
+	written int64
+
+	whole digest.Digester
+	chunk digest.Digester
+	sums  Checksums
+	files []string
+}
+
+// NewHaulWriter writes the haul at path, split into chunks of at most
+// maxBytes each when maxBytes is set. Whatever an earlier save left under the
+// same name is removed first, so no stale chunk is taken for part of the haul.
+func NewHaulWriter(path string, maxBytes int64) (*HaulWriter, error) {
+	if maxBytes < 0 {
+		return nil, fmt.Errorf("maxBytes must not be negative, received %d", maxBytes)
+	}
+	if err := removeHaul(path); err != nil {
+		return nil, err
+	}
+
+	w := newHaulWriter(filepath.Base(path))
+	w.path = path
+	w.maxBytes = maxBytes
+	if maxBytes == 0 {
+		// created up front, so an unwritable path fails before any archiving
+		if err := w.next(); err != nil {
+			return nil, err
+		}
+	}
+	return w, nil
+}
+
+// NewHaulStreamWriter passes the haul on to out, e.g. stdout, as it's
+// written. name is only what its checksums call it.
+func NewHaulStreamWriter(out io.Writer, name string) *HaulWriter {
+	w := newHaulWriter(name)
+	w.stream = out
+	return w
+}
+
+func newHaulWriter(name string) *HaulWriter {
+	return &HaulWriter{
+		whole: digest.Canonical.Digester(),
+		sums:  Checksums{Archive: FileChecksum{Name: name}},
+	}
+}
+
+// removeHaul removes the haul at path and any chunks of it.
+func removeHaul(path string) error {
+	if err := os.RemoveAll(path); err != nil {
+		return fmt.Errorf("failed to remove existing output file [%s]: %w", path, err)
+	}
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		chunk := filepath.Join(filepath.Dir(path), e.Name())
+		if base, _, ok := chunkInfo(chunk); !ok || base != filepath.Clean(path) {
+			continue
+		}
+		if err := os.Remove(chunk); err != nil {
+			return fmt.Errorf("failed to remove existing chunk [%s]: %w", chunk, err)
+		}
+	}
+	return nil
+}
+
+// Write writes p on, starting a new chunk whenever the current one is full.
+// Chunks are only created once there's data for them, so a haul whose size
+// is an exact multiple of maxBytes never ends on an empty chunk.
+func (w *HaulWriter) Write(p []byte) (int, error) {
+	var n int
+	for len(p) > 0 {
+		out := w.stream
+		if out == nil {
+			if w.f == nil {
+				if err := w.next(); err != nil {
+					return n, err
+				}
+			}
+			out = w.f
+		}
+
+		b := p
+		if w.maxBytes > 0 && int64(len(b)) > w.maxBytes-w.written {
+			b = b[:w.maxBytes-w.written]
+		}
+		m, err := out.Write(b)
+		w.whole.Hash().Write(b[:m])
+		if w.chunk != nil {
+			w.chunk.Hash().Write(b[:m])
+		}
+		w.written += int64(m)
+		w.sums.Archive.Size += int64(m)
+		n += m
+		if err != nil {
+			return n, err
+		}
+
+		if w.maxBytes > 0 && w.written == w.maxBytes {
+			if err := w.closeFile(); err != nil {
+				return n, err
+			}
+		}
+		p = p[m:]
+	}
+	return n, nil
+}
+
+// next opens the haul's file, or its next chunk.
+func (w *HaulWriter) next() error {
+	path := w.path
+	if w.maxBytes > 0 {
+		path = fmt.Sprintf("%s.%03d", w.path, len(w.files)+1)
+		w.chunk = digest.Canonical.Digester()
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		if w.maxBytes > 0 {
+			return fmt.Errorf("failed to create chunk %d: %w", len(w.files)+1, err)
+		}
+		return fmt.Errorf("error creating output file [%s]: %w", path, err)
+	}
+	w.f = f
+	w.files = append(w.files, path)
+	w.written = 0
+	return nil
+}
+
+// closeFile closes the file being written, recording its checksum when it's
+// a chunk.
+func (w *HaulWriter) closeFile() error {
+	err := w.f.Close()
+	if w.maxBytes > 0 {
+		w.sums.Chunks = append(w.sums.Chunks, FileChecksum{
+			Name:   filepath.Base(w.f.Name()),
+			Digest: w.chunk.Digest(),
+			Size:   w.written,
+		})
+		w.chunk = nil
+	}
+	w.f = nil
+	return err
+}
+
+// Close closes the file or chunk being written. A stream is left open.
+func (w *HaulWriter) Close() error {
+	if w.f == nil {
+		return nil
+	}
+	return w.closeFile()
+}
+
+// Discard closes the writer and removes everything it wrote, after a haul
+// that failed partway.
+func (w *HaulWriter) Discard() error {
+	if err := w.Close(); err != nil {
+		return err
+	}
+	for _, f := range w.files {
+		if err := os.Remove(f); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	w.files = nil
+	return nil
+}
+
+// Files returns the haul's file, or its chunks in order... nothing for a
+// stream.
+func (w *HaulWriter) Files() []string {
+	return w.files
+}
+
+// Checksums returns the checksums of everything written so far, which for a
+// closed writer is the whole haul.
+func (w *HaulWriter) Checksums() *Checksums {
+	c := w.sums
+	c.Chunks = append([]FileChecksum(nil), w.sums.Chunks...)
+	c.Archive.Digest = w.whole.Digest()
+	return &c
+}
@@ -0,0 +1,324 @@
+package archives
+
+import (
+	"archive/tar"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"hauler.dev/go/hauler/v2/pkg/log"
+)
+
+const (
+	whiteoutPrefix = ".wh."
+	whiteoutOpaque = ".wh..wh..opq"
+
+	maxSymlinkHops = 255 // same bound the kernel puts on a single path lookup
+)
+
+// ApplyLayer unpacks one image layer onto dst the way a container runtime
+// would. mediaType selects the decompressor (gzip, zstd, or a plain tar).
+// OCI whiteouts (.wh.<name>) remove what a lower layer put down and an opaque
+// marker (.wh..wh..opq) hides a directory's lower contents; neither touches
+// entries from the same layer. Every path is checked with securePath, and
+// symlinks from earlier entries are resolved as if dst were "/", so a link
+// can never carry a later write outside dst.
+//
+// When filter is set, only that path (a file or a subtree, relative to the
+// image root) is written, at its full path under dst. Device nodes and fifos
+// are skipped, ownership isn't restored, and directories are always kept
+// owner-writable so later layers can still write into them.
+func ApplyLayer(ctx context.Context, r io.Reader, mediaType string, dst string, filter string) error {
+	l := log.FromContext(ctx)
+
+	var stream io.Reader = r
+	if comp := layerCompression(mediaType); comp != "" {
+		rc, err := CompressionMap[comp].OpenReader(r)
+		if err != nil {
+			return fmt.Errorf("failed to open %s layer: %w", comp, err)
+		}
+		defer rc.Close()
+		stream = rc
+	}
+
+	filter = cleanLayerPath(filter)
+	a := &layerApplier{
+		log:       l,
+		root:      dst,
+		filter:    filter,
+		written:   map[string]bool{},
+		ancestors: map[string]bool{},
+	}
+
+	tr := tar.NewReader(stream)
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read layer: %w", err)
+		}
+		if err := a.apply(hdr, tr); err != nil {
+			return fmt.Errorf("failed to apply [%s]: %w", hdr.Name, err)
+		}
+		l.Debugf("applied layer entry [%s]", hdr.Name)
+	}
+}
+
+// picks the CompressionMap key for a layer media type... anything that isn't
+// gzip or zstd is treated as an uncompressed tar
+func layerCompression(mediaType string) string {
+	switch {
+	case strings.HasSuffix(mediaType, "gzip"):
+		return "gz"
+	case strings.HasSuffix(mediaType, "zstd"):
+		return "zst"
+	}
+	return ""
+}
+
+// normalizes a tar entry name (or a --path filter) to a slash-separated path
+// relative to the image root, with "" meaning the root itself
+func cleanLayerPath(p string) string {
+	p = path.Clean("/" + filepath.ToSlash(p))
+	return strings.TrimPrefix(p, "/")
+}
+
+type layerApplier struct {
+	log    log.Logger
+	root   string
+	filter string
+
+	// written holds every path this layer created, and ancestors every
+	// parent directory of one... whiteouts only apply to lower layers, so
+	// neither may be removed by a marker later in the same layer
+	written   map[string]bool
+	ancestors map[string]bool
+}
+
+func (a *layerApplier) apply(hdr *tar.Header, r io.Reader) error {
+	name := cleanLayerPath(hdr.Name)
+	if name == "" {
+		return nil
+	}
+	dir, base := path.Split(name)
+
+	parent, err := a.resolve(path.Clean("/" + dir))
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case base == whiteoutOpaque:
+		return a.removeLower(parent)
+	case strings.HasPrefix(base, whiteoutPrefix):
+		target := path.Join(parent, strings.TrimPrefix(base, whiteoutPrefix))
+		if a.written[target] || a.ancestors[target] {
+			return nil
+		}
+		p, err := securePath(a.root, target)
+		if err != nil {
+			return err
+		}
+		return os.RemoveAll(p)
+	}
+
+	rel := path.Join(parent, base)
+	if !a.included(name) && !a.included(rel) {
+		return nil
+	}
+	target, err := securePath(a.root, rel)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(target), dirPermissions); err != nil {
+		return err
+	}
+
+	switch hdr.Typeflag {
+	case tar.TypeDir:
+		if fi, err := os.Lstat(target); err == nil && !fi.IsDir() {
+			if err := os.Remove(target); err != nil {
+				return err
+			}
+		}
+		if err := os.MkdirAll(target, dirPermissions); err != nil {
+			return err
+		}
+		if err := os.Chmod(target, hdr.FileInfo().Mode().Perm()|0o700); err != nil {
+			return err
+		}
+
+	case tar.TypeReg:
+		if err := a.replace(target); err != nil {
+			return err
+		}
+		f, err := os.OpenFile(target, os.O_CREATE|os.O_EXCL|os.O_WRONLY, hdr.FileInfo().Mode().Perm())
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(f, r); err != nil {
+			f.Close()
+			return err
+		}
+		if err := f.Close(); err != nil {
+			return err
+		}
+		if err := os.Chtimes(target, hdr.ModTime, hdr.ModTime); err != nil {
+			return err
+		}
+
+	case tar.TypeSymlink:
+		if err := a.replace(target); err != nil {
+			return err
+		}
+		// the link is stored as-is... it's only ever followed through
+		// resolve, which keeps it inside the root
+		if err := os.Symlink(hdr.Linkname, target); err != nil {
+			return err
+		}
+
+	case tar.TypeLink:
+		linkRel, err := a.resolve(path.Clean("/" + hdr.Linkname))
+		if err != nil {
+			return err
+		}
+		src, err := securePath(a.root, linkRel)
+		if err != nil {
+			return err
+		}
+		if _, err := os.Lstat(src); errors.Is(err, os.ErrNotExist) && a.filter != "" {
+			// the link target sits outside the requested --path
+			a.log.Warnf("skipping hardlink [%s]: target [%s] is outside of the requested path", name, hdr.Linkname)
+			return nil
+		}
+		if err := a.replace(target); err != nil {
+			return err
+		}
+		if err := os.Link(src, target); err != nil {
+			return err
+		}
+
+	default:
+		// char/block devices and fifos need privileges and mean nothing
+		// outside a running container
+		a.log.Debugf("skipping [%s]: unsupported entry type [%c]", name, hdr.Typeflag)
+		return nil
+	}
+
+	a.written[rel] = true
+	for p := path.Dir(rel); p != "." && p != "/"; p = path.Dir(p) {
+		a.ancestors[p] = true
+	}
+	return nil
+}
+
+// reports whether p is inside the requested --path, or on the way to it...
+// the parents of the filter are created as they're needed, not from entries
+func (a *layerApplier) included(p string) bool {
+	return a.filter == "" || p == a.filter || strings.HasPrefix(p, a.filter+"/")
+}
+
+// clears whatever sits at target so a new entry can take its place. A
+// directory is only removed when something else replaces it.
+func (a *layerApplier) replace(target string) error {
+	fi, err := os.Lstat(target)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if fi.IsDir() {
+		return os.RemoveAll(target)
+	}
+	return os.Remove(target)
+}
+
+// removes the lower-layer contents of dir, keeping anything this layer has
+// already written into it
+func (a *layerApplier) removeLower(dir string) error {
+	p, err := securePath(a.root, dir)
+	if err != nil {
+		return err
+	}
+	entries, err := os.ReadDir(p)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		child := path.Join(dir, e.Name())
+		if a.written[child] || a.ancestors[child] {
+			if e.IsDir() {
+				if err := a.removeLower(child); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(p, e.Name())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resolve walks p (an absolute, slash-separated path in the image) one
+// component at a time against what's already on disk, following symlinks as
+// if root were "/". The result is relative to root and never climbs out of
+// it: ".." stops at the root and absolute link targets restart from it.
+func (a *layerApplier) resolve(p string) (string, error) {
+	resolved := ""
+	parts := strings.Split(strings.TrimPrefix(p, "/"), "/")
+	hops := 0
+
+	for len(parts) > 0 {
+		part := parts[0]
+		parts = parts[1:]
+
+		switch part {
+		case "", ".":
+			continue
+		case "..":
+			resolved = strings.TrimPrefix(path.Dir("/"+resolved), "/")
+			continue
+		}
+
+		next := path.Join(resolved, part)
+		full, err := securePath(a.root, next)
+		if err != nil {
+			return "", err
+		}
+		fi, err := os.Lstat(full)
+		if err != nil || fi.Mode()&os.ModeSymlink == 0 {
+			resolved = next
+			continue
+		}
+
+		hops++
+		if hops > maxSymlinkHops {
+			return "", fmt.Errorf("too many levels of symbolic links resolving [%s]", p)
+		}
+		link, err := os.Readlink(full)
+		if err != nil {
+			return "", err
+		}
+		if path.IsAbs(link) {
+			resolved = ""
+		}
+		parts = append(strings.Split(link, "/"), parts...)
+	}
+	return resolved, nil
+}
@@ -160,6 +160,72 @@ func Unarchive(ctx context.Context, tarball, dst string) error {
 	return nil
 }
 
+// walks the entries of an archive without extracting it to disk... fn is called
+// with the cleaned name of every regular file and may read it via f.Open
+func Walk(ctx context.Context, tarball string, fn func(name string, f archives.FileInfo) error) error {
+	archiveFile, openErr := os.Open(tarball)
+	if openErr != nil {
+		return fmt.Errorf("failed to open tarball %s: %w", tarball, openErr)
+	}
+	defer archiveFile.Close()
+
+	return WalkReader(ctx, tarball, archiveFile, "", fn)
+}
+
+// WalkReader is Walk over an archive read from r, such as a download or a set
+// of chunks, in a single pass. name only helps identify the archive's format.
+// Entries arrive in archive order, and each has to be read within fn, before
+// the next one is reached. A zip, which can't be read as a stream, is spooled
+// to tempDir first ("" uses the OS default).
+func WalkReader(ctx context.Context, name string, r io.Reader, tempDir string, fn func(name string, f archives.FileInfo) error) error {
+	format, input, identifyErr := archives.Identify(ctx, name, r)
+	if identifyErr != nil {
+		return fmt.Errorf("failed to identify format: %w", identifyErr)
+	}
+
+	extractor, ok := format.(archives.Extractor)
+	if !ok {
+		return fmt.Errorf("unsupported format for extraction")
+	}
+
+	// a zip is read from its central directory at the end, so a zip haul
+	// streamed from chunks or a download is spooled to disk first
+	if _, isZip := format.(archives.Zip); isZip {
+		if _, seekable := input.(interface {
+			io.ReaderAt
+			io.Seeker
+		}); !seekable {
+			spool, err := os.CreateTemp(tempDir, "hauler-*.zip")
+			if err != nil {
+				return err
+			}
+			defer os.Remove(spool.Name())
+			defer spool.Close()
+			log.FromContext(ctx).Debugf("spooling zip archive [%s] to [%s]", name, spool.Name())
+			if _, err := io.Copy(spool, input); err != nil {
+				return fmt.Errorf("failed to spool zip archive: %w", err)
+			}
+			if _, err := spool.Seek(0, io.SeekStart); err != nil {
+				return err
+			}
+			input = spool
+		}
+	}
+
+	handler := func(ctx context.Context, f archives.FileInfo) error {
+		if f.IsDir() || f.LinkTarget != "" {
+			return nil
+		}
+		name := strings.TrimPrefix(filepath.ToSlash(filepath.Clean("/"+f.NameInArchive)), "/")
+		return fn(name, f)
+	}
+
+	if extractErr := extractor.Extract(ctx, input, handler); extractErr != nil {
+		return fmt.Errorf("failed to read archive: %w", extractErr)
+	}
+	return nil
+}
+
 var chunkSuffixRe = regexp.MustCompile(`^(.+)\.(\d{3,})$`)
 
 // checks whether archivePath matches the <name>.NNN chunk naming pattern
@@ -228,12 +294,38 @@ func ChunkGroupKey(name string) (key string, ok bool) {
 // pre-v2.1 chunk sets that were never at risk of misdetection don't need to
 // be manually renamed.
 func JoinChunks(ctx context.Context, archivePath, tempDir string) (string, error) {
+	matches, joinedName := chunkSet(archivePath)
+	if matches == nil {
+		return archivePath, nil
+	}
+	return joinFiles(ctx, matches, tempDir, joinedName)
+}
+
+// OpenChunks opens archivePath for reading as JoinChunks would join it, but
+// without writing the joined copy: the chunks of a set are read one after the
+// other, in order. name is what the joined file would be called, so the
+// archive format can still be identified by its extension.
+func OpenChunks(ctx context.Context, archivePath string) (rc io.ReadCloser, name string, err error) {
+	matches, joinedName := chunkSet(archivePath)
+	if matches == nil {
+		f, err := os.Open(archivePath)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to open tarball %s: %w", archivePath, err)
+		}
+		return f, filepath.Base(archivePath), nil
+	}
+	log.FromContext(ctx).Debugf("streaming %d chunk(s) as [%s]", len(matches), joinedName)
+	return &chunkReader{paths: matches}, joinedName, nil
+}
+
+// chunkSet returns every chunk in archivePath's set in numeric order, and the
+// name the joined archive goes by, or nil when archivePath isn't a chunk.
+func chunkSet(archivePath string) (matches []string, joinedName string) {
 	if base, _, ok := chunkInfo(archivePath); ok {
 		all, err := filepath.Glob(base + ".*")
 		if err != nil {
-			return archivePath, nil
+			return nil, ""
 		}
-		var matches []string
 		for _, m := range all {
 			// the glob is a string-prefix match, so it can also catch siblings
 			// like <base>.old.001 whose own base differs from ours
@@ -242,22 +334,21 @@ func JoinChunks(ctx context.Context, archivePath, tempDir string) (string, error
 			}
 		}
 		if len(matches) == 0 {
-			return archivePath, nil
+			return nil, ""
 		}
 		sort.Slice(matches, func(i, j int) bool {
 			_, idxI, _ := chunkInfo(matches[i])
 			_, idxJ, _ := chunkInfo(matches[j])
 			return idxI < idxJ
 		})
-		return joinFiles(ctx, matches, tempDir, filepath.Base(base))
+		return matches, filepath.Base(base)
 	}
 
 	if base, ext, _, ok := legacyChunkInfo(archivePath); ok {
 		all, err := filepath.Glob(base + "_*" + ext)
 		if err != nil {
-			return archivePath, nil
+			return nil, ""
 		}
-		var matches []string
 		for _, m := range all {
 			// same prefix-collision guard as above, applied to the legacy shape
 			if mBase, mExt, _, ok := legacyChunkInfo(m); ok && mBase == base && mExt == ext {
@@ -265,17 +356,58 @@ func JoinChunks(ctx context.Context, archivePath, tempDir string) (string, error
 			}
 		}
 		if len(matches) == 0 {
-			return archivePath, nil
+			return nil, ""
 		}
 		sort.Slice(matches, func(i, j int) bool {
 			_, _, idxI, _ := legacyChunkInfo(matches[i])
 			_, _, idxJ, _ := legacyChunkInfo(matches[j])
 			return idxI < idxJ
 		})
-		return joinFiles(ctx, matches, tempDir, filepath.Base(base)+ext)
+		return matches, filepath.Base(base) + ext
+	}
+
+	return nil, ""
+}
+
+// chunkReader reads a set of chunk files back to back, holding only the one
+// being read open.
+type chunkReader struct {
+	paths []string
+	cur   *os.File
+}
+
+func (c *chunkReader) Read(p []byte) (int, error) {
+	for {
+		if c.cur == nil {
+			if len(c.paths) == 0 {
+				return 0, io.EOF
+			}
+			f, err := os.Open(c.paths[0])
+			if err != nil {
+				return 0, fmt.Errorf("failed to open chunk [%s]: %w", c.paths[0], err)
+			}
+			c.cur, c.paths = f, c.paths[1:]
+		}
+		n, err := c.cur.Read(p)
+		if err == io.EOF {
+			c.cur.Close()
+			c.cur = nil
+			if n > 0 {
+				return n, nil
+			}
+			continue
+		}
+		return n, err
 	}
+}
 
-	return archivePath, nil
+func (c *chunkReader) Close() error {
+	if c.cur == nil {
+		return nil
+	}
+	err := c.cur.Close()
+	c.cur = nil
+	return err
 }
 
 // concatenates matches in order into a new file named joinedName in tempDir.
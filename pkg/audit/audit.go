@@ -66,6 +66,10 @@ type Entry struct {
 	Global    *GlobalEntry   `json:"global,omitempty"`
 	Flags     map[string]any `json:"flags,omitempty"`
 
+	// Rejected is the admission policy's reason for refusing an artifact; an
+	// entry carrying it records content that was never stored
+	Rejected string `json:"rejected,omitempty"`
+
 	// PortableReference replaces Reference in the store audit log
 	PortableReference string `json:"-"`
 }
@@ -79,6 +83,7 @@ type portableEntry struct {
 	Type      string `json:"type,omitempty"`
 	Reference string `json:"reference,omitempty"`
 	Digest    string `json:"digest,omitempty"`
+	Rejected  string `json:"rejected,omitempty"`
 }
 
 // ShortFileRef returns a portable-safe short name for a local path or URL
@@ -191,6 +196,7 @@ func Append(haulerDir string, e Entry) error {
 			Type:      e.Type,
 			Reference: reference,
 			Digest:    e.Digest,
+			Rejected:  e.Rejected,
 		}
 		if err := appendLine(e.Store, pe); err != nil {
 			if globalErr != nil {
@@ -260,6 +266,66 @@ func MergeStoreLog(tempDir, destDir string) error {
 	return err
 }
 
+// MergeNewStoreLog appends the entries of srcDir's audit.log that destDir's
+// does not already hold, keyed on timestamp and event, so merging stores
+// that share history (or merging the same store twice) doesn't repeat them.
+func MergeNewStoreLog(srcDir, destDir string) error {
+	data, err := os.ReadFile(filepath.Join(srcDir, LogFileName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("audit: read source log: %w", err)
+	}
+
+	appendMu.Lock()
+	defer appendMu.Unlock()
+
+	seen := map[string]bool{}
+	existing, err := os.ReadFile(filepath.Join(destDir, LogFileName))
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("audit: read log: %w", err)
+	}
+	for _, line := range strings.Split(string(existing), "\n") {
+		seen[entryKey(line)] = true
+	}
+
+	var out strings.Builder
+	for _, line := range strings.Split(string(data), "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if key := entryKey(line); !seen[key] {
+			seen[key] = true
+			out.WriteString(line + "\n")
+		}
+	}
+	if out.Len() == 0 {
+		return nil
+	}
+
+	if err := os.MkdirAll(destDir, 0o755); err != nil {
+		return fmt.Errorf("audit: ensure dir: %w", err)
+	}
+	f, err := os.OpenFile(filepath.Join(destDir, LogFileName), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("audit: open log: %w", err)
+	}
+	defer f.Close()
+	_, err = f.WriteString(out.String())
+	return err
+}
+
+// entryKey identifies the event a store log line records: when it happened,
+// the command, and what it acted on. A line that isn't an entry is its own key.
+func entryKey(line string) string {
+	var e portableEntry
+	if err := json.Unmarshal([]byte(line), &e); err != nil || e.Timestamp == "" {
+		return strings.TrimSpace(line)
+	}
+	return strings.Join([]string{e.Timestamp, e.AuditID, e.Command, e.Type, e.Reference, e.Digest}, "|")
+}
+
 func resolveDir(haulerDir string) string {
 	if haulerDir != "" {
 		return haulerDir
@@ -242,6 +242,33 @@ func TestMergeStoreLog_NoopWhenTempHasNoLog(t *testing.T) {
 	}
 }
 
+func TestMergeNewStoreLog_SkipsExisting(t *testing.T) {
+	src, dest := t.TempDir(), t.TempDir()
+
+	shared := `{"audit-id":"a","timestamp":"2024-01-01T00:00:00Z","command":"store add image","reference":"app:v1"}`
+	other := `{"audit-id":"b","timestamp":"2024-01-02T00:00:00Z","command":"store add file","reference":"f.txt"}`
+	if err := os.WriteFile(filepath.Join(dest, LogFileName), []byte(shared+"\n"), 0o644); err != nil {
+		t.Fatalf("seed dest log: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(src, LogFileName), []byte(shared+"\n"+other+"\n"), 0o644); err != nil {
+		t.Fatalf("seed source log: %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := MergeNewStoreLog(src, dest); err != nil {
+			t.Fatalf("MergeNewStoreLog: %v", err)
+		}
+	}
+
+	data, err := os.ReadFile(filepath.Join(dest, LogFileName))
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	if want := shared + "\n" + other + "\n"; string(data) != want {
+		t.Fatalf("dest log = %q, want %q", data, want)
+	}
+}
+
 func TestShortFileRef(t *testing.T) {
 	tests := []struct {
 		name string
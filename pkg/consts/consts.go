@@ -1,6 +1,9 @@
 package consts
 
-import "fmt"
+import (
+	"fmt"
+	"time"
+)
 
 const (
 	// container media types
@@ -83,6 +86,10 @@ const (
 	// URL or absolute local path.
 	OriginalRefAnnotation = "hauler.dev/original-ref"
 
+	// LabelAnnotationPrefix prefixes the user labels set on an index entry,
+	// one annotation per label (e.g. "hauler.dev/label.team")
+	LabelAnnotationPrefix = "hauler.dev/label."
+
 	// cosign keyless validation options
 	ImageAnnotationCertIdentity                 = "hauler.dev/certificate-identity"
 	ImageAnnotationCertIdentityRegexp           = "hauler.dev/certificate-identity-regexp"
@@ -136,9 +143,21 @@ const (
 	DefaultFileserverPort     = 8080
 	DefaultFileserverTimeout  = 60
 	DefaultHaulerArchiveName  = "haul.tar.zst"
+	DefaultHaulChecksumsExt   = ".checksums.json"
+	DefaultHaulSignatureExt   = ".sig"
+	DefaultHaulContentsName   = "haul-contents.json"
 	DefaultHaulerManifestName = "hauler-manifest.yaml"
 	DefaultStoreMetadataName  = "store.json"
 	DefaultStoreInventoryName = "stores.json"
+	DefaultDeltaManifestName  = "delta.json"
+	DefaultLockfileName       = "hauler-lock.yaml"
+	DefaultPolicyFileName     = "policy.yaml"
+	DefaultStoreLockName      = ".hauler.lock"
+	DefaultStoreLockInfoName  = ".hauler.lock.json"
+	DefaultStoreHistoryDir    = "history"
+	DefaultStoreHistoryLimit  = 20
+	DefaultLockTimeout        = 5 * time.Minute
+	DefaultGCTempGrace        = time.Hour
 	DefaultRetries            = 3
 	RetriesInterval           = 5
 	DefaultConcurrency        = 5
@@ -0,0 +1,91 @@
+package content
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	ccontent "github.com/containerd/containerd/v2/core/content"
+	"github.com/containerd/containerd/v2/core/remotes"
+	"github.com/containerd/errdefs"
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// LimitTarget wraps t so every blob pushed to it holds one of this store's
+// blobSem permits from Push through Close. `store copy registry://` builds a
+// target per artifact and runs several artifacts at once; routing all of
+// them through the source store's semaphore is what gives the copy one
+// shared blob ceiling -- and the same IOStats accounting a sync gets --
+// instead of one unbounded fan-out per artifact.
+//
+// Only pushes are gated. Resolve and Fetcher pass straight through.
+func (o *OCI) LimitTarget(t Target) Target {
+	return &limitedTarget{Target: t, oci: o}
+}
+
+type limitedTarget struct {
+	Target
+	oci *OCI
+}
+
+func (t *limitedTarget) Pusher(ctx context.Context, ref string) (remotes.Pusher, error) {
+	p, err := t.Target.Pusher(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	return &limitedPusher{inner: p, oci: t.oci}, nil
+}
+
+type limitedPusher struct {
+	inner remotes.Pusher
+	oci   *OCI
+}
+
+// Push acquires a permit before calling the wrapped pusher, since a
+// registry pusher starts the upload session inside Push itself. A blob the
+// target already has returns the permit immediately and counts as cached.
+func (p *limitedPusher) Push(ctx context.Context, d ocispec.Descriptor) (ccontent.Writer, error) {
+	start := time.Now()
+	if err := p.oci.blobSem.Acquire(ctx, 1); err != nil {
+		return nil, err
+	}
+	p.oci.stats.addSemWait(time.Since(start))
+	p.oci.stats.enterBlob()
+	release := sync.OnceFunc(func() {
+		p.oci.stats.exitBlob()
+		p.oci.blobSem.Release(1)
+	})
+
+	w, err := p.inner.Push(ctx, d)
+	if err != nil {
+		release()
+		if errdefs.IsAlreadyExists(err) {
+			p.oci.stats.BlobsCached.Add(1)
+		}
+		return nil, err
+	}
+	return &limitedWriter{Writer: w, oci: p.oci, release: release}, nil
+}
+
+type limitedWriter struct {
+	ccontent.Writer
+	oci     *OCI
+	release func()
+}
+
+func (w *limitedWriter) Commit(ctx context.Context, size int64, expected digest.Digest, opts ...ccontent.Opt) error {
+	if err := w.Writer.Commit(ctx, size, expected, opts...); err != nil {
+		return err
+	}
+	w.oci.stats.BlobsWritten.Add(1)
+	w.oci.stats.BlobBytesWritten.Add(size)
+	return nil
+}
+
+// Close releases the permit on every path, including a writer that was
+// never committed.
+func (w *limitedWriter) Close() error {
+	defer w.release()
+	return w.Writer.Close()
+}
@@ -0,0 +1,75 @@
+package content
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+func TestLimitTarget_SharesBlobCeiling(t *testing.T) {
+	ctx := context.Background()
+
+	src, err := NewOCI(t.TempDir(), WithBlobConcurrency(2))
+	if err != nil {
+		t.Fatalf("NewOCI src: %v", err)
+	}
+	dst, err := NewOCI(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewOCI dst: %v", err)
+	}
+	target := src.LimitTarget(dst)
+
+	// every push gets its own pusher, the way copy builds one target per
+	// artifact, so the only thing bounding them is src's semaphore
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			body := fmt.Sprintf("blob-%d", i)
+			desc := ocispec.Descriptor{
+				MediaType: "application/octet-stream",
+				Digest:    digest.FromString(body),
+				Size:      int64(len(body)),
+			}
+			p, err := target.Pusher(ctx, "")
+			if err != nil {
+				t.Errorf("Pusher: %v", err)
+				return
+			}
+			w, err := p.Push(ctx, desc)
+			if err != nil {
+				t.Errorf("Push: %v", err)
+				return
+			}
+			defer w.Close()
+			time.Sleep(5 * time.Millisecond)
+			if _, err := w.Write([]byte(body)); err != nil {
+				t.Errorf("Write: %v", err)
+				return
+			}
+			if err := w.Commit(ctx, desc.Size, desc.Digest); err != nil {
+				t.Errorf("Commit: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	st := src.Stats().Snapshot()
+	if st.BlobPeakInFlight < 1 || st.BlobPeakInFlight > 2 {
+		t.Errorf("BlobPeakInFlight = %d, want 1..2", st.BlobPeakInFlight)
+	}
+	if st.BlobsWritten != 8 {
+		t.Errorf("BlobsWritten = %d, want 8", st.BlobsWritten)
+	}
+
+	// every permit must have been handed back on Close
+	if !src.blobSem.TryAcquire(2) {
+		t.Error("blob permits leaked after all writers closed")
+	}
+}
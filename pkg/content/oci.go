@@ -157,29 +157,11 @@ func (o *OCI) BlobConcurrency() int {
 func (o *OCI) AddIndex(desc ocispec.Descriptor) error {
 	// Pure validation/parsing -- doesn't touch shared state -- stays outside
 	// the lock.
-	if _, ok := desc.Annotations[ocispec.AnnotationRefName]; !ok {
-		return fmt.Errorf("descriptor must contain a reference from the annotation: %s", ocispec.AnnotationRefName)
-	}
-
-	key, err := reference.Parse(desc.Annotations[ocispec.AnnotationRefName])
-	if err != nil {
+	mapKey, err := IndexKey(desc)
+	if err != nil || mapKey == "" {
 		return err
 	}
 
-	if strings.TrimSpace(key.String()) == "--" {
-		return nil
-	}
-
-	var mapKey string
-	switch key.(type) {
-	case name.Digest:
-		mapKey = fmt.Sprintf("%s-%s", key.Context().String(), desc.Annotations[consts.KindAnnotationName])
-	case name.Tag:
-		mapKey = fmt.Sprintf("%s-%s", key.String(), desc.Annotations[consts.KindAnnotationName])
-	default:
-		return nil
-	}
-
 	o.lock()
 	defer o.mu.Unlock()
 
@@ -203,6 +185,32 @@ func (o *OCI) AddIndex(desc ocispec.Descriptor) error {
 	return o.saveIndexCheckpointLocked()
 }
 
+// IndexKey returns the key AddIndex files desc under, the same key Walk
+// hands back: its reference (the repository alone for a digest reference)
+// and its kind. An empty key means AddIndex would ignore desc.
+func IndexKey(desc ocispec.Descriptor) (string, error) {
+	if _, ok := desc.Annotations[ocispec.AnnotationRefName]; !ok {
+		return "", fmt.Errorf("descriptor must contain a reference from the annotation: %s", ocispec.AnnotationRefName)
+	}
+
+	key, err := reference.Parse(desc.Annotations[ocispec.AnnotationRefName])
+	if err != nil {
+		return "", err
+	}
+
+	if strings.TrimSpace(key.String()) == "--" {
+		return "", nil
+	}
+
+	switch key.(type) {
+	case name.Digest:
+		return fmt.Sprintf("%s-%s", key.Context().String(), desc.Annotations[consts.KindAnnotationName]), nil
+	case name.Tag:
+		return fmt.Sprintf("%s-%s", key.String(), desc.Annotations[consts.KindAnnotationName]), nil
+	}
+	return "", nil
+}
+
 // descriptorsEqual reports whether two descriptors are equal in every field
 // AddIndex's callers in this codebase populate: MediaType, Digest, Size,
 // URLs, ArtifactType, Platform, Data, and Annotations (compared by
@@ -0,0 +1,49 @@
+package cosign
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/sigstore/sigstore/pkg/cryptoutils"
+	"github.com/sigstore/sigstore/pkg/signature"
+	"github.com/sigstore/sigstore/pkg/signature/options"
+)
+
+// SignBlob signs data the way `cosign sign-blob --key` does, returning the
+// base64 signature it writes with --output-signature, so `cosign verify-blob`
+// checks it as readily as VerifyBlob does.
+func (s *Signer) SignBlob(ctx context.Context, data []byte) ([]byte, error) {
+	raw, err := s.sv.SignMessage(bytes.NewReader(data), options.WithContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("signing blob: %w", err)
+	}
+	return []byte(base64.StdEncoding.EncodeToString(raw)), nil
+}
+
+// VerifyBlob checks sig, a base64 signature as SignBlob and `cosign
+// sign-blob` write it, over data against the PEM-encoded public key at
+// keyPath (an ECDSA, RSA, or ed25519 key, such as cosign.pub).
+func VerifyBlob(ctx context.Context, keyPath string, data, sig []byte) error {
+	raw, err := os.ReadFile(keyPath)
+	if err != nil {
+		return fmt.Errorf("reading verification key: %w", err)
+	}
+	pub, err := cryptoutils.UnmarshalPEMToPublicKey(raw)
+	if err != nil {
+		return fmt.Errorf("loading verification key: %w", err)
+	}
+	v, err := signature.LoadDefaultVerifier(pub)
+	if err != nil {
+		return fmt.Errorf("loading verification key: %w", err)
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(sig)))
+	if err != nil {
+		return fmt.Errorf("decoding signature: %w", err)
+	}
+	return v.VerifySignature(bytes.NewReader(decoded), bytes.NewReader(data), options.WithContext(ctx))
+}
@@ -0,0 +1,270 @@
+package cosign
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	gname "github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	gmutate "github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/partial"
+	gstatic "github.com/google/go-containerregistry/pkg/v1/static"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	intotov1 "github.com/in-toto/attestation/go/v1"
+	cosignpkg "github.com/sigstore/cosign/v3/pkg/cosign"
+	"github.com/sigstore/cosign/v3/pkg/oci"
+	"github.com/sigstore/cosign/v3/pkg/oci/empty"
+	"github.com/sigstore/cosign/v3/pkg/oci/static"
+	cosigntypes "github.com/sigstore/cosign/v3/pkg/types"
+	protocommon "github.com/sigstore/protobuf-specs/gen/pb-go/common/v1"
+	sgbundle "github.com/sigstore/sigstore-go/pkg/bundle"
+	"github.com/sigstore/sigstore-go/pkg/sign"
+	"github.com/sigstore/sigstore/pkg/cryptoutils"
+	"github.com/sigstore/sigstore/pkg/signature"
+	"github.com/sigstore/sigstore/pkg/signature/options"
+	"github.com/sigstore/sigstore/pkg/signature/payload"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// PasswordEnv holds the password of an encrypted cosign private key, the
+// same variable cosign itself reads.
+const PasswordEnv = "COSIGN_PASSWORD"
+
+// emptyJSON is the OCI empty descriptor's content, the config of every
+// referrer manifest Signer builds.
+var emptyJSON = []byte("{}")
+
+// Signer produces cosign-compatible signatures with a local private key,
+// without contacting Fulcio, Rekor, or a timestamp authority -- inside the
+// airgap there is nothing else to sign with. Verifiers check its signatures
+// with the matching public key and transparency log checks turned off.
+type Signer struct {
+	sv   signature.SignerVerifier
+	alg  signature.AlgorithmDetails
+	hint []byte
+}
+
+// NewSigner loads the private key at keyPath: a cosign key (as written by
+// `cosign generate-key-pair`, decrypted with $COSIGN_PASSWORD) or an
+// unencrypted PEM-encoded ECDSA, RSA, or ed25519 key.
+func NewSigner(keyPath string) (*Signer, error) {
+	raw, err := os.ReadFile(keyPath)
+	if err != nil {
+		return nil, fmt.Errorf("reading signing key: %w", err)
+	}
+
+	var sv signature.SignerVerifier
+	if block, _ := pem.Decode(raw); block != nil && strings.HasPrefix(block.Type, "ENCRYPTED") {
+		sv, err = cosignpkg.LoadPrivateKey(raw, []byte(os.Getenv(PasswordEnv)), nil)
+		if err != nil {
+			return nil, fmt.Errorf("loading cosign key (is %s set?): %w", PasswordEnv, err)
+		}
+	} else {
+		priv, err := cryptoutils.UnmarshalPEMToPrivateKey(raw, cryptoutils.SkipPassword)
+		if err != nil {
+			return nil, fmt.Errorf("loading signing key: %w", err)
+		}
+		if sv, err = signature.LoadDefaultSignerVerifier(priv); err != nil {
+			return nil, fmt.Errorf("loading signing key: %w", err)
+		}
+	}
+
+	pub, err := sv.PublicKey()
+	if err != nil {
+		return nil, fmt.Errorf("getting public key: %w", err)
+	}
+	alg, err := signature.GetDefaultAlgorithmDetails(pub)
+	if err != nil {
+		return nil, fmt.Errorf("getting signing algorithm: %w", err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return nil, fmt.Errorf("marshalling public key: %w", err)
+	}
+	sum := sha256.Sum256(der)
+
+	return &Signer{sv: sv, alg: alg, hint: []byte(base64.StdEncoding.EncodeToString(sum[:]))}, nil
+}
+
+// Signature signs the simple-signing payload `cosign sign` would produce for
+// ref, which must name the image by digest.
+func (s *Signer) Signature(ctx context.Context, ref gname.Digest) (oci.Signature, error) {
+	pl, err := payload.Cosign{Image: ref}.MarshalJSON()
+	if err != nil {
+		return nil, fmt.Errorf("building payload: %w", err)
+	}
+	raw, err := s.sv.SignMessage(bytes.NewReader(pl), options.WithContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("signing payload: %w", err)
+	}
+	return static.NewSignature(pl, base64.StdEncoding.EncodeToString(raw))
+}
+
+// AppendSignature returns the signature manifest sigs with sig added as one
+// more layer, the way cosign attaches a second signature to an image. A nil
+// sigs starts a new manifest.
+func AppendSignature(sigs v1.Image, sig oci.Signature) (v1.Image, error) {
+	if sigs == nil {
+		sigs = empty.Signatures()
+	}
+	ann, err := sig.Annotations()
+	if err != nil {
+		return nil, fmt.Errorf("reading signature annotations: %w", err)
+	}
+	return gmutate.Append(sigs, gmutate.Addendum{
+		Layer:       sig,
+		Annotations: ann,
+		MediaType:   types.MediaType(cosigntypes.SimpleSigningMediaType),
+	})
+}
+
+// Bundle returns an OCI 1.1 referrer manifest for subject carrying a sigstore
+// bundle, the shape `cosign sign --new-bundle-format` pushes: a DSSE-wrapped
+// in-toto statement naming the image's digest, signed with s's key.
+func (s *Signer) Bundle(ctx context.Context, subject v1.Descriptor) (v1.Image, error) {
+	statement, err := protojson.Marshal(&intotov1.Statement{
+		Type: intotov1.StatementTypeUri,
+		Subject: []*intotov1.ResourceDescriptor{{
+			Digest: map[string]string{subject.Digest.Algorithm: subject.Digest.Hex},
+		}},
+		PredicateType: cosigntypes.CosignSignPredicateType,
+		Predicate:     &structpb.Struct{},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("building statement: %w", err)
+	}
+
+	b, err := sign.Bundle(&sign.DSSEData{Data: statement, PayloadType: "application/vnd.in-toto+json"}, s, sign.BundleOptions{Context: ctx})
+	if err != nil {
+		return nil, fmt.Errorf("signing bundle: %w", err)
+	}
+	bundleJSON, err := protojson.Marshal(b)
+	if err != nil {
+		return nil, fmt.Errorf("marshalling bundle: %w", err)
+	}
+	bundleMediaType, err := sgbundle.MediaTypeString("0.3")
+	if err != nil {
+		return nil, err
+	}
+
+	layer := gstatic.NewLayer(bundleJSON, types.MediaType(bundleMediaType))
+	layerDigest, err := layer.Digest()
+	if err != nil {
+		return nil, err
+	}
+	configDigest, configSize, err := v1.SHA256(bytes.NewReader(emptyJSON))
+	if err != nil {
+		return nil, err
+	}
+
+	m := v1.Manifest{
+		SchemaVersion: 2,
+		MediaType:     types.OCIManifestSchema1,
+		ArtifactType:  bundleMediaType,
+		Config: v1.Descriptor{
+			MediaType: types.MediaType("application/vnd.oci.empty.v1+json"),
+			Digest:    configDigest,
+			Size:      configSize,
+		},
+		Layers: []v1.Descriptor{{
+			MediaType: types.MediaType(bundleMediaType),
+			Digest:    layerDigest,
+			Size:      int64(len(bundleJSON)),
+		}},
+		Subject: &v1.Descriptor{
+			MediaType: subject.MediaType,
+			Digest:    subject.Digest,
+			Size:      subject.Size,
+		},
+		Annotations: map[string]string{
+			"org.opencontainers.image.created":  time.Now().UTC().Format(time.RFC3339),
+			"dev.sigstore.bundle.content":       "dsse-envelope",
+			"dev.sigstore.bundle.predicateType": cosigntypes.CosignSignPredicateType,
+		},
+	}
+	raw, err := json.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	return partial.CompressedToImage(&referrerImage{manifest: raw, layer: layer})
+}
+
+// referrerImage is the minimal image core partial needs to treat a referrer
+// manifest built by hand as a v1.Image.
+type referrerImage struct {
+	manifest []byte
+	layer    v1.Layer
+}
+
+func (r *referrerImage) RawConfigFile() ([]byte, error) { return emptyJSON, nil }
+
+func (r *referrerImage) MediaType() (types.MediaType, error) { return types.OCIManifestSchema1, nil }
+
+func (r *referrerImage) RawManifest() ([]byte, error) { return r.manifest, nil }
+
+func (r *referrerImage) LayerByDigest(h v1.Hash) (partial.CompressedLayer, error) {
+	if d, err := r.layer.Digest(); err == nil && d == h {
+		return r.layer, nil
+	}
+	return nil, fmt.Errorf("blob %s not found", h)
+}
+
+// Signer is its own sign.Keypair, so sigstore-go signs bundles with the
+// loaded key rather than an ephemeral one.
+
+func (s *Signer) GetHashAlgorithm() protocommon.HashAlgorithm { return s.alg.GetProtoHashType() }
+
+func (s *Signer) GetSigningAlgorithm() protocommon.PublicKeyDetails {
+	return s.alg.GetSignatureAlgorithm()
+}
+
+func (s *Signer) GetHint() []byte { return s.hint }
+
+func (s *Signer) GetKeyAlgorithm() string {
+	switch s.alg.GetKeyType() {
+	case signature.ECDSA:
+		return "ECDSA"
+	case signature.RSA:
+		return "RSA"
+	default:
+		return "ED25519"
+	}
+}
+
+func (s *Signer) GetPublicKey() crypto.PublicKey {
+	pub, _ := s.sv.PublicKey()
+	return pub
+}
+
+func (s *Signer) GetPublicKeyPem() (string, error) {
+	pub, err := s.sv.PublicKey()
+	if err != nil {
+		return "", err
+	}
+	p, err := cryptoutils.MarshalPublicKeyToPEM(pub)
+	return string(p), err
+}
+
+func (s *Signer) SignData(ctx context.Context, data []byte) ([]byte, []byte, error) {
+	opts := []signature.SignOption{options.WithContext(ctx)}
+	var digest []byte
+	if h := s.alg.GetHashType(); h != 0 {
+		hh := h.New()
+		hh.Write(data)
+		digest = hh.Sum(nil)
+		opts = append(opts, options.WithDigest(digest))
+	}
+	sig, err := s.sv.SignMessage(bytes.NewReader(data), opts...)
+	return sig, digest, err
+}
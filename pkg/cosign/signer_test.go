@@ -0,0 +1,79 @@
+package cosign
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	gname "github.com/google/go-containerregistry/pkg/name"
+	cosignpkg "github.com/sigstore/cosign/v3/pkg/cosign"
+)
+
+func TestNewSigner_EncryptedCosignKey(t *testing.T) {
+	keys, err := cosignpkg.GenerateKeyPair(func(bool) ([]byte, error) { return []byte("hunter2"), nil })
+	if err != nil {
+		t.Fatalf("GenerateKeyPair: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "cosign.key")
+	if err := os.WriteFile(path, keys.PrivateBytes, 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	t.Setenv(PasswordEnv, "wrong")
+	if _, err := NewSigner(path); err == nil {
+		t.Fatal("NewSigner with the wrong password succeeded")
+	}
+
+	t.Setenv(PasswordEnv, "hunter2")
+	s, err := NewSigner(path)
+	if err != nil {
+		t.Fatalf("NewSigner: %v", err)
+	}
+	ref, _ := gname.NewDigest("registry.example.com/app@sha256:" + "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef")
+	sig, err := s.Signature(context.Background(), ref)
+	if err != nil {
+		t.Fatalf("Signature: %v", err)
+	}
+	if b64, err := sig.Base64Signature(); err != nil || b64 == "" {
+		t.Errorf("Base64Signature = %q, %v", b64, err)
+	}
+}
+
+func TestSignBlob_VerifyBlob(t *testing.T) {
+	ctx := context.Background()
+	keys, err := cosignpkg.GenerateKeyPair(func(bool) ([]byte, error) { return []byte("hunter2"), nil })
+	if err != nil {
+		t.Fatalf("GenerateKeyPair: %v", err)
+	}
+	dir := t.TempDir()
+	priv, pub := filepath.Join(dir, "cosign.key"), filepath.Join(dir, "cosign.pub")
+	if err := os.WriteFile(priv, keys.PrivateBytes, 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	if err := os.WriteFile(pub, keys.PublicBytes, 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	t.Setenv(PasswordEnv, "hunter2")
+	s, err := NewSigner(priv)
+	if err != nil {
+		t.Fatalf("NewSigner: %v", err)
+	}
+	data := []byte(`{"archive":{"name":"haul.tar.zst"}}`)
+	sig, err := s.SignBlob(ctx, data)
+	if err != nil {
+		t.Fatalf("SignBlob: %v", err)
+	}
+
+	// a trailing newline, as `cosign sign-blob` output often carries, is fine
+	if err := VerifyBlob(ctx, pub, data, append(sig, '\n')); err != nil {
+		t.Errorf("VerifyBlob: %v", err)
+	}
+	if err := VerifyBlob(ctx, pub, append(data, ' '), sig); err == nil {
+		t.Error("VerifyBlob of tampered data succeeded")
+	}
+	if err := VerifyBlob(ctx, writeTestPubKey(t), data, sig); err == nil {
+		t.Error("VerifyBlob with the wrong key succeeded")
+	}
+}
@@ -40,6 +40,13 @@ type Config struct {
 	// CaFile -- see NewVerifier.
 	InsecureSkipTLSVerify bool
 	CaFile                string
+
+	// TrustedRoot is a sigstore trusted_root.json to verify certificates and
+	// transparency log proofs against instead of fetching one via TUF.
+	// Offline additionally forbids every online lookup: tlog entries must be
+	// proven by the bundle carried on the signature itself.
+	TrustedRoot string
+	Offline     bool
 }
 
 // Empty reports whether cfg requests no verification at all.
@@ -61,7 +68,14 @@ func (c Config) Keyless() bool { return c.Key == "" }
 // narrower Key+CertIdentity case as KeyAndIdentityParseError
 // (cmd/cosign/cli/verify/verify.go:102); the extra fields fail identically, so
 // they are guarded identically.
+//
+// It also rejects Offline wherever trust material would otherwise come from
+// TUF: keyless certificates and tlog proofs need a TrustedRoot, and only a key
+// without Tlog needs no trust material at all.
 func (c Config) validate() error {
+	if c.Offline && c.TrustedRoot == "" && (c.Keyless() || c.Tlog) {
+		return fmt.Errorf("offline verification needs a trusted root when verifying keyless signatures or transparency log entries")
+	}
 	if c.Keyless() {
 		return nil
 	}
@@ -151,6 +165,7 @@ func NewVerifier(ctx context.Context, cfg Config, rso *flags.StoreRootOpts, ro *
 		Identities:                   identities,
 		CertGithubWorkflowRepository: cfg.CertGithubWorkflowRepository,
 		IgnoreTlog:                   ignoreTlog,
+		Offline:                      cfg.Offline,
 		MaxWorkers:                   defaultMaxWorkers,
 		// Must stay false: VerifyImageSignatures rejects a true value outright
 		// with "bundle support for image signatures is not yet implemented"
@@ -166,7 +181,7 @@ func NewVerifier(ctx context.Context, cfg Config, rso *flags.StoreRootOpts, ro *
 	// Order is load-bearing and copied from Exec: trust material first, then
 	// legacy clients, then the verifier -- LoadVerifierFromKeyOrCert validates
 	// a certificate chain against the trust material and must see it populated.
-	if err := verify.SetTrustedMaterial(ctx, "", "", "", "", "", offlineWithKey, co); err != nil {
+	if err := verify.SetTrustedMaterial(ctx, cfg.TrustedRoot, "", "", "", "", offlineWithKey, co); err != nil {
 		return nil, fmt.Errorf("setting trusted material: %w", err)
 	}
 
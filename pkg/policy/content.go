@@ -0,0 +1,94 @@
+package policy
+
+import (
+	"fmt"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/partial"
+)
+
+// Content is what storing an image writes, as far as CheckContent cares.
+type Content struct {
+	// Size is the total bytes of every manifest's config and layers. Blobs
+	// shared between platforms are counted once per platform, which is what a
+	// transfer without deduplication would move.
+	Size int64
+
+	// Platforms lists os/arch[/variant] of each image, skipping the
+	// unknown/unknown entries buildkit uses for attestation manifests.
+	Platforms []string
+}
+
+// InspectImage returns the Content of a single-platform image.
+func InspectImage(img v1.Image) (Content, error) {
+	size, err := imageSize(img)
+	if err != nil {
+		return Content{}, err
+	}
+	cf, err := img.ConfigFile()
+	if err != nil {
+		return Content{}, fmt.Errorf("reading image config: %w", err)
+	}
+	c := Content{Size: size}
+	if p := cf.Platform(); p != nil && p.OS != "" && p.OS != "unknown" {
+		c.Platforms = append(c.Platforms, p.String())
+	}
+	return c, nil
+}
+
+// InspectIndex returns the Content of every image in a multi-platform index.
+func InspectIndex(idx v1.ImageIndex) (Content, error) {
+	im, err := idx.IndexManifest()
+	if err != nil {
+		return Content{}, fmt.Errorf("reading index manifest: %w", err)
+	}
+	var c Content
+	for _, m := range im.Manifests {
+		if m.Platform != nil && m.Platform.OS == "unknown" {
+			continue
+		}
+		switch {
+		case m.MediaType.IsImage():
+			img, err := idx.Image(m.Digest)
+			if err != nil {
+				return Content{}, err
+			}
+			size, err := imageSize(img)
+			if err != nil {
+				return Content{}, err
+			}
+			c.Size += size
+			if m.Platform != nil {
+				c.Platforms = append(c.Platforms, m.Platform.String())
+			}
+		case m.MediaType.IsIndex():
+			child, err := idx.ImageIndex(m.Digest)
+			if err != nil {
+				return Content{}, err
+			}
+			cc, err := InspectIndex(child)
+			if err != nil {
+				return Content{}, err
+			}
+			c.Size += cc.Size
+			c.Platforms = append(c.Platforms, cc.Platforms...)
+		}
+	}
+	return c, nil
+}
+
+func imageSize(img v1.Image) (int64, error) {
+	m, err := img.Manifest()
+	if err != nil {
+		return 0, fmt.Errorf("reading image manifest: %w", err)
+	}
+	size, err := partial.Size(img)
+	if err != nil {
+		return 0, err
+	}
+	size += m.Config.Size
+	for _, l := range m.Layers {
+		size += l.Size
+	}
+	return size, nil
+}
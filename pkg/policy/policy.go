@@ -0,0 +1,284 @@
+// Package policy implements the admission policy hauler enforces on content
+// entering a store through `store add image`, `store sync`, and `store load`.
+//
+// A policy is a YAML file:
+//
+//	images:
+//	  allow: [ghcr.io/myorg, docker.io/rancher/*]
+//	  deny: [docker.io/rancher/*-dev]
+//	  signatures:
+//	    - match: ghcr.io/myorg
+//	      key: /etc/hauler/myorg.pub
+//	    - match: registry.rancher.com
+//	      certificateIdentityRegexp: ^https://github.com/rancher/
+//	      certificateOidcIssuer: https://token.actions.githubusercontent.com
+//	  maxSize: 2GiB
+//	  platforms: [linux/amd64, linux/arm64]
+//	files:
+//	  allowedHosts: [github.com, "*.example.com"]
+//
+// Image patterns are globs over <registry>/<repository> (docker.io for Docker
+// Hub): * and ? stay within one path segment, ** crosses segments, and a
+// pattern also covers everything beneath it, so ghcr.io/myorg admits
+// ghcr.io/myorg/app. Every unset section admits everything.
+package policy
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/dustin/go-humanize"
+	"github.com/google/go-containerregistry/pkg/name"
+	"gopkg.in/yaml.v3"
+
+	"hauler.dev/go/hauler/v2/pkg/cosign"
+)
+
+// go-containerregistry spells Docker Hub index.docker.io; policies spell it
+// docker.io, the way users write image references.
+const (
+	ggcrDockerHub   = "index.docker.io"
+	policyDockerHub = "docker.io"
+)
+
+type Policy struct {
+	Images ImagePolicy `yaml:"images,omitempty"`
+	Files  FilePolicy  `yaml:"files,omitempty"`
+
+	maxSize int64
+}
+
+type ImagePolicy struct {
+	Allow      []string        `yaml:"allow,omitempty"`
+	Deny       []string        `yaml:"deny,omitempty"`
+	Signatures []SignatureRule `yaml:"signatures,omitempty"`
+	MaxSize    string          `yaml:"maxSize,omitempty"`
+	Platforms  []string        `yaml:"platforms,omitempty"`
+}
+
+// SignatureRule requires every image matching Match to carry a signature that
+// verifies against Key, or keylessly against the certificate identity fields.
+// TrustedRoot replaces the sigstore root fetched via TUF; `store load` checks
+// a haul's signatures offline, so keyless rules need one there.
+type SignatureRule struct {
+	Match                       string `yaml:"match"`
+	Key                         string `yaml:"key,omitempty"`
+	Tlog                        bool   `yaml:"useTlogVerify,omitempty"`
+	CertificateIdentity         string `yaml:"certificateIdentity,omitempty"`
+	CertificateIdentityRegexp   string `yaml:"certificateIdentityRegexp,omitempty"`
+	CertificateOidcIssuer       string `yaml:"certificateOidcIssuer,omitempty"`
+	CertificateOidcIssuerRegexp string `yaml:"certificateOidcIssuerRegexp,omitempty"`
+	TrustedRoot                 string `yaml:"trustedRoot,omitempty"`
+}
+
+type FilePolicy struct {
+	AllowedHosts []string `yaml:"allowedHosts,omitempty"`
+}
+
+// Violation is a policy rule an artifact broke. Callers reject the artifact
+// outright: unlike a failed fetch, --ignore-errors never stores it anyway.
+type Violation struct {
+	Reason string
+}
+
+func (v *Violation) Error() string { return "policy violation: " + v.Reason }
+
+// IsViolation reports whether err is (or wraps) a *Violation.
+func IsViolation(err error) bool {
+	var v *Violation
+	return errors.As(err, &v)
+}
+
+func violationf(format string, args ...any) error {
+	return &Violation{Reason: fmt.Sprintf(format, args...)}
+}
+
+// Load reads and validates the policy file at path. Unknown fields are an
+// error: a misspelled rule in a security policy must not silently admit
+// everything it was meant to stop.
+func Load(path string) (*Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading policy: %w", err)
+	}
+
+	var p Policy
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&p); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("parsing policy [%s]: %w", path, err)
+	}
+	if err := p.validate(); err != nil {
+		return nil, fmt.Errorf("invalid policy [%s]: %w", path, err)
+	}
+	return &p, nil
+}
+
+func (p *Policy) validate() error {
+	for _, patterns := range [][]string{p.Images.Allow, p.Images.Deny, p.Images.Platforms, p.Files.AllowedHosts} {
+		for _, pat := range patterns {
+			if strings.TrimSpace(pat) == "" {
+				return fmt.Errorf("empty pattern")
+			}
+		}
+	}
+	for i, r := range p.Images.Signatures {
+		if r.Match == "" {
+			return fmt.Errorf("signature rule [%d] has no match pattern", i)
+		}
+		if r.Key == "" && r.CertificateIdentity == "" && r.CertificateIdentityRegexp == "" {
+			return fmt.Errorf("signature rule [%s] needs a key or a certificate identity", r.Match)
+		}
+	}
+	if p.Images.MaxSize != "" {
+		n, err := humanize.ParseBytes(p.Images.MaxSize)
+		if err != nil {
+			return fmt.Errorf("maxSize: %w", err)
+		}
+		p.maxSize = int64(n)
+	}
+	return nil
+}
+
+// RepositoryName returns ref's <registry>/<repository>, the string image
+// patterns match against.
+func RepositoryName(ref name.Reference) string {
+	reg := ref.Context().RegistryStr()
+	if reg == ggcrDockerHub {
+		reg = policyDockerHub
+	}
+	return reg + "/" + ref.Context().RepositoryStr()
+}
+
+// CheckImage applies the allow and deny lists to ref. Deny wins over allow.
+func (p *Policy) CheckImage(ref name.Reference) error {
+	if p == nil {
+		return nil
+	}
+	repo := RepositoryName(ref)
+	for _, pat := range p.Images.Deny {
+		if matchGlob(pat, repo) {
+			return violationf("image [%s] matches denied pattern [%s]", repo, pat)
+		}
+	}
+	if len(p.Images.Allow) == 0 {
+		return nil
+	}
+	for _, pat := range p.Images.Allow {
+		if matchGlob(pat, repo) {
+			return nil
+		}
+	}
+	return violationf("image [%s] matches no allowed pattern %v", repo, p.Images.Allow)
+}
+
+// SignatureRule returns the first rule requiring a signature of ref, or nil.
+func (p *Policy) SignatureRule(ref name.Reference) *SignatureRule {
+	if p == nil {
+		return nil
+	}
+	repo := RepositoryName(ref)
+	for i := range p.Images.Signatures {
+		if matchGlob(p.Images.Signatures[i].Match, repo) {
+			return &p.Images.Signatures[i]
+		}
+	}
+	return nil
+}
+
+// Config returns the verification r requires. A key wins over an identity,
+// as it does for `store add image`.
+func (r *SignatureRule) Config() cosign.Config {
+	if r.Key != "" {
+		return cosign.Config{Key: r.Key, Tlog: r.Tlog, TrustedRoot: r.TrustedRoot}
+	}
+	return cosign.Config{
+		CertIdentity:         r.CertificateIdentity,
+		CertIdentityRegexp:   r.CertificateIdentityRegexp,
+		CertOidcIssuer:       r.CertificateOidcIssuer,
+		CertOidcIssuerRegexp: r.CertificateOidcIssuerRegexp,
+		TrustedRoot:          r.TrustedRoot,
+	}
+}
+
+// NeedsContent reports whether CheckContent has anything to check, so callers
+// can skip inspecting an image's manifests when it doesn't.
+func (p *Policy) NeedsContent() bool {
+	return p != nil && (p.maxSize > 0 || len(p.Images.Platforms) > 0)
+}
+
+// CheckContent applies the size limit and platform list to what storing an
+// image would write.
+func (p *Policy) CheckContent(ref name.Reference, c Content) error {
+	if p == nil {
+		return nil
+	}
+	if p.maxSize > 0 && c.Size > p.maxSize {
+		return violationf("image [%s] is %s, over the maximum size of %s", ref.Name(), humanize.IBytes(uint64(c.Size)), humanize.IBytes(uint64(p.maxSize)))
+	}
+	if len(p.Images.Platforms) == 0 {
+		return nil
+	}
+	for _, plat := range c.Platforms {
+		allowed := false
+		for _, pat := range p.Images.Platforms {
+			if matchGlob(pat, plat) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return violationf("image [%s] holds platform [%s], not in the allowed platforms %v (use --platform to store one platform)", ref.Name(), plat, p.Images.Platforms)
+		}
+	}
+	return nil
+}
+
+// CheckFile applies the allowed host list to a file fetched from a URL. Local
+// paths name no host and are not covered by the list.
+func (p *Policy) CheckFile(path string) error {
+	if p == nil || len(p.Files.AllowedHosts) == 0 {
+		return nil
+	}
+	if !strings.HasPrefix(path, "http://") && !strings.HasPrefix(path, "https://") {
+		return nil
+	}
+	u, err := url.Parse(path)
+	if err != nil {
+		return violationf("file URL cannot be parsed: %v", err)
+	}
+	for _, pat := range p.Files.AllowedHosts {
+		if matchGlob(pat, u.Hostname()) || matchGlob(pat, u.Host) {
+			return nil
+		}
+	}
+	return violationf("file host [%s] is not in the allowed hosts %v", u.Host, p.Files.AllowedHosts)
+}
+
+// matchGlob reports whether pattern matches s or one of its parent paths.
+func matchGlob(pattern, s string) bool {
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; {
+		case c == '*' && i+1 < len(pattern) && pattern[i+1] == '*':
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("(/.*)?$")
+	re, err := regexp.Compile(b.String())
+	return err == nil && re.MatchString(s)
+}
@@ -0,0 +1,186 @@
+package policy
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+)
+
+func writePolicy(t *testing.T, content string) string {
+	t.Helper()
+	p := filepath.Join(t.TempDir(), "policy.yaml")
+	if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	return p
+}
+
+func mustLoad(t *testing.T, content string) *Policy {
+	t.Helper()
+	p, err := Load(writePolicy(t, content))
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	return p
+}
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pattern, s string
+		want       bool
+	}{
+		{"ghcr.io/myorg", "ghcr.io/myorg/app", true},
+		{"ghcr.io/myorg", "ghcr.io/myorg", true},
+		{"ghcr.io/myorg", "ghcr.io/myorgx/app", false},
+		{"docker.io/rancher/*", "docker.io/rancher/rancher", true},
+		{"docker.io/rancher/*-dev", "docker.io/rancher/rancher-dev", true},
+		{"docker.io/rancher/*-dev", "docker.io/rancher/rancher", false},
+		{"*.example.com", "files.example.com", true},
+		{"*.example.com", "example.com", false},
+		{"ghcr.io/**/app", "ghcr.io/a/b/app", true},
+		{"ghcr.io/*/app", "ghcr.io/a/b/app", false},
+		{"linux/*", "linux/arm64/v8", true},
+	}
+	for _, tc := range tests {
+		if got := matchGlob(tc.pattern, tc.s); got != tc.want {
+			t.Errorf("matchGlob(%q, %q) = %v, want %v", tc.pattern, tc.s, got, tc.want)
+		}
+	}
+}
+
+func TestCheckImage(t *testing.T) {
+	p := mustLoad(t, `
+images:
+  allow: [docker.io/rancher, ghcr.io/myorg]
+  deny: [docker.io/rancher/*-dev]
+`)
+	tests := []struct {
+		ref     string
+		allowed bool
+	}{
+		{"rancher/rancher:v2.10.1", true},
+		{"index.docker.io/rancher/fleet:v1", true},
+		{"rancher/rancher-dev:latest", false},
+		{"ghcr.io/myorg/app@sha256:" + strings.Repeat("a", 64), true},
+		{"quay.io/other/app:v1", false},
+		{"nginx:latest", false},
+	}
+	for _, tc := range tests {
+		r, err := name.ParseReference(tc.ref)
+		if err != nil {
+			t.Fatalf("ParseReference(%s): %v", tc.ref, err)
+		}
+		err = p.CheckImage(r)
+		if tc.allowed && err != nil {
+			t.Errorf("CheckImage(%s) = %v, want admitted", tc.ref, err)
+		}
+		if !tc.allowed && !IsViolation(err) {
+			t.Errorf("CheckImage(%s) = %v, want a violation", tc.ref, err)
+		}
+	}
+}
+
+func TestLoad_Invalid(t *testing.T) {
+	tests := map[string]string{
+		"unknown field":      "images:\n  alow: [ghcr.io]\n",
+		"bad size":           "images:\n  maxSize: lots\n",
+		"rule without match": "images:\n  signatures:\n    - key: cosign.pub\n",
+		"rule without trust": "images:\n  signatures:\n    - match: ghcr.io\n",
+	}
+	for name, content := range tests {
+		if _, err := Load(writePolicy(t, content)); err == nil {
+			t.Errorf("%s: Load succeeded, want an error", name)
+		}
+	}
+
+	if p, err := Load(writePolicy(t, "")); err != nil || p.NeedsContent() {
+		t.Errorf("empty policy: Load = %v, NeedsContent = %v", err, p != nil && p.NeedsContent())
+	}
+}
+
+func TestSignatureRule(t *testing.T) {
+	p := mustLoad(t, `
+images:
+  signatures:
+    - match: ghcr.io/myorg
+      key: /etc/hauler/myorg.pub
+    - match: registry.rancher.com
+      certificateIdentityRegexp: ^https://github.com/rancher/
+      certificateOidcIssuer: https://token.actions.githubusercontent.com
+`)
+	r := name.MustParseReference("ghcr.io/myorg/app:v1")
+	rule := p.SignatureRule(r)
+	if rule == nil || rule.Config().Key != "/etc/hauler/myorg.pub" {
+		t.Fatalf("SignatureRule(%s) = %+v, want the key rule", r, rule)
+	}
+	r = name.MustParseReference("registry.rancher.com/rancher/rancher:v2")
+	if rule = p.SignatureRule(r); rule == nil || !rule.Config().Keyless() {
+		t.Fatalf("SignatureRule(%s) = %+v, want the keyless rule", r, rule)
+	}
+	if rule = p.SignatureRule(name.MustParseReference("docker.io/library/nginx")); rule != nil {
+		t.Errorf("SignatureRule(nginx) = %+v, want none", rule)
+	}
+}
+
+func TestCheckContent(t *testing.T) {
+	p := mustLoad(t, `
+images:
+  maxSize: 1KiB
+  platforms: [linux/amd64, linux/arm64*]
+`)
+	r := name.MustParseReference("ghcr.io/myorg/app:v1")
+
+	if err := p.CheckContent(r, Content{Size: 512, Platforms: []string{"linux/amd64", "linux/arm64/v8"}}); err != nil {
+		t.Errorf("CheckContent(admitted) = %v", err)
+	}
+	if err := p.CheckContent(r, Content{Size: 2048}); !IsViolation(err) || !strings.Contains(err.Error(), "maximum size") {
+		t.Errorf("CheckContent(oversized) = %v, want a size violation", err)
+	}
+	if err := p.CheckContent(r, Content{Size: 1, Platforms: []string{"windows/amd64"}}); !IsViolation(err) || !strings.Contains(err.Error(), "windows/amd64") {
+		t.Errorf("CheckContent(windows) = %v, want a platform violation", err)
+	}
+}
+
+func TestCheckFile(t *testing.T) {
+	p := mustLoad(t, `
+files:
+  allowedHosts: [github.com, "*.example.com"]
+`)
+	tests := []struct {
+		path    string
+		allowed bool
+	}{
+		{"https://github.com/rancher/rke2/releases/download/install.sh", true},
+		{"https://files.example.com/a.tar.gz", true},
+		{"https://files.example.com:8443/a.tar.gz", true},
+		{"http://evil.test/a.tar.gz", false},
+		{"/tmp/local-file.txt", true},
+	}
+	for _, tc := range tests {
+		err := p.CheckFile(tc.path)
+		if tc.allowed && err != nil {
+			t.Errorf("CheckFile(%s) = %v, want admitted", tc.path, err)
+		}
+		if !tc.allowed && !IsViolation(err) {
+			t.Errorf("CheckFile(%s) = %v, want a violation", tc.path, err)
+		}
+	}
+}
+
+func TestInspectIndex(t *testing.T) {
+	idx, err := random.Index(256, 1, 2)
+	if err != nil {
+		t.Fatalf("random.Index: %v", err)
+	}
+	c, err := InspectIndex(idx)
+	if err != nil {
+		t.Fatalf("InspectIndex: %v", err)
+	}
+	if c.Size < 2*256 {
+		t.Errorf("InspectIndex size = %d, want at least the two layers", c.Size)
+	}
+}
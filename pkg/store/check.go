@@ -10,6 +10,7 @@ import (
 	"runtime"
 	"sync"
 
+	"github.com/opencontainers/go-digest"
 	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
 	"golang.org/x/sync/errgroup"
 )
@@ -310,3 +311,36 @@ func (c *Checker) Check(ctx context.Context, desc ocispec.Descriptor) CheckResul
 
 	return result
 }
+
+// RepairBlob replaces the damaged blob d with the content open returns, which
+// WriteBlob checks against d before committing it. The damaged file is
+// removed first: WriteBlob trusts any existing file of the right size, and a
+// same-length corruption is exactly that. open is called before anything is
+// removed, so a source that can't serve d leaves the store as it found it.
+func (l *Layout) RepairBlob(ctx context.Context, d digest.Digest, open func() (io.ReadCloser, error)) error {
+	rc, err := open()
+	if err != nil {
+		return err
+	}
+
+	blobFile := filepath.Join(l.Root, ocispec.ImageBlobsDir, d.Algorithm().String(), d.Hex())
+	if err := os.Remove(blobFile); err != nil && !os.IsNotExist(err) {
+		rc.Close()
+		return err
+	}
+
+	// hand the already-open reader to the first call; a retry reopens
+	first := rc
+	err = l.OCI.WriteBlob(ctx, d, 0, func() (io.ReadCloser, error) {
+		if first != nil {
+			r := first
+			first = nil
+			return r, nil
+		}
+		return open()
+	})
+	if first != nil {
+		first.Close()
+	}
+	return err
+}
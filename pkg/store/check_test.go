@@ -1,8 +1,10 @@
 package store_test
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"io"
 	"net/http/httptest"
 	"os"
 	"strings"
@@ -364,3 +366,39 @@ func TestCheck_SharedLayerMemoizedAcrossImages(t *testing.T) {
 		t.Errorf("shared layer HashCount = %d, want 1 (memoization must prevent re-hashing across images)", got)
 	}
 }
+
+// TestRepairBlob checks that a same-length corruption, which WriteBlob's size
+// fast path alone would keep, is replaced, and that content hashing to the
+// wrong digest is refused.
+func TestRepairBlob(t *testing.T) {
+	s := newCheckTestStore(t)
+	host, opts := newCheckTestRegistry(t)
+	ctx := context.Background()
+
+	desc := pushAndAddImage(t, s, host, "test/repair", "v1", opts)
+	victim := readManifestBlob(t, s.Root, desc.Digest).Layers[0]
+	path := blobPath(s.Root, victim.Digest)
+
+	orig, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read original blob: %v", err)
+	}
+	corrupted := append([]byte(nil), orig...)
+	corrupted[0] ^= 0xFF
+	if err := os.WriteFile(path, corrupted, 0o644); err != nil {
+		t.Fatalf("corrupt blob: %v", err)
+	}
+
+	wrong := func() (io.ReadCloser, error) { return io.NopCloser(bytes.NewReader(corrupted)), nil }
+	if err := s.RepairBlob(ctx, victim.Digest, wrong); err == nil {
+		t.Error("RepairBlob accepted content that does not match the digest")
+	}
+
+	right := func() (io.ReadCloser, error) { return io.NopCloser(bytes.NewReader(orig)), nil }
+	if err := s.RepairBlob(ctx, victim.Digest, right); err != nil {
+		t.Fatalf("RepairBlob: %v", err)
+	}
+	if res := s.NewChecker().Check(ctx, desc); !res.OK {
+		t.Errorf("artifact still damaged after RepairBlob: %+v", res.Problems)
+	}
+}
@@ -0,0 +1,348 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+
+	"hauler.dev/go/hauler/v2/pkg/consts"
+)
+
+// GCOptions controls a garbage collection pass.
+type GCOptions struct {
+	// DryRun reports what would be deleted without deleting anything.
+	DryRun bool
+	// TempGrace is how old a temp file must be before it is swept, so a
+	// write still in flight in some other process keeps its temp file.
+	TempGrace time.Duration
+}
+
+// GCOrphan is a signature, attestation, sbom, or referrer index entry whose
+// subject image is no longer in the store.
+type GCOrphan struct {
+	Reference string `json:"reference"`
+	Kind      string `json:"kind"`
+	// Type is the kind the way `store info` shows it.
+	Type   string `json:"type"`
+	Digest string `json:"digest"`
+	Size   int64  `json:"size"`
+	// Subject is the digest the orphan was made for, when its manifest
+	// records one; orphans saved by tag convention name no subject.
+	Subject string `json:"subject,omitempty"`
+
+	key string
+}
+
+// GCBlob is a blob no index entry references.
+type GCBlob struct {
+	Digest string `json:"digest"`
+	Size   int64  `json:"size"`
+}
+
+// GCTemp is a temp file left behind by an interrupted write.
+type GCTemp struct {
+	Path    string    `json:"path"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"modified"`
+}
+
+// GCResult is what a GC pass deleted, or would delete on a dry run.
+type GCResult struct {
+	Orphans []GCOrphan `json:"orphans"`
+	Blobs   []GCBlob   `json:"blobs"`
+	Temps   []GCTemp   `json:"temps"`
+	// Held are blobs no index entry references that were kept only because
+	// a retained generation of the store's history does.
+	Held []GCBlob `json:"held"`
+}
+
+// HeldSize is the number of bytes the store's history keeps from collection.
+func (r *GCResult) HeldSize() int64 {
+	var n int64
+	for _, b := range r.Held {
+		n += b.Size
+	}
+	return n
+}
+
+// Freed is the number of bytes the pass deleted (or would delete). An
+// orphan's manifest is counted among Blobs, not again here.
+func (r *GCResult) Freed() int64 {
+	var n int64
+	for _, b := range r.Blobs {
+		n += b.Size
+	}
+	for _, t := range r.Temps {
+		n += t.Size
+	}
+	return n
+}
+
+// indexTempPattern matches the temp files saveIndexLocked renames over
+// index.json.
+var indexTempPattern = regexp.MustCompile(`^index-\d+\.json$`)
+
+// GC removes orphaned related-artifact entries from the index, then deletes
+// every blob neither a remaining entry nor a retained generation of the
+// store's history reaches, and every temp file older than opts.TempGrace.
+//
+// A manifest that can't be read or decoded stops the pass before anything is
+// deleted: its children can't be known, and collecting them as unreferenced
+// would turn a repairable store into a broken one.
+func (l *Layout) GC(ctx context.Context, opts GCOptions) (*GCResult, error) {
+	type entry struct {
+		key  string
+		desc ocispec.Descriptor
+	}
+	var entries []entry
+	if err := l.OCI.Walk(func(key string, desc ocispec.Descriptor) error {
+		entries = append(entries, entry{key: key, desc: desc})
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("failed to walk artifacts: %w", err)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].key < entries[j].key })
+
+	res := &GCResult{Orphans: []GCOrphan{}, Blobs: []GCBlob{}, Temps: []GCTemp{}, Held: []GCBlob{}}
+	graphs := make(map[string]*manifestGraph, len(entries))
+	for _, e := range entries {
+		g, err := l.readGraph(ctx, e.desc)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read manifest [%s] of [%s]: %w... run `hauler store repair` or remove the artifact before collecting garbage",
+				e.desc.Digest, e.desc.Annotations[ocispec.AnnotationRefName], err)
+		}
+		graphs[e.key] = g
+	}
+
+	// Subjects are whatever the non-related entries hold. A related entry
+	// that survives can itself be a subject (an attestation's signature),
+	// so keep admitting until nothing changes.
+	live := map[digest.Digest]bool{}
+	refs := map[string]bool{}
+	pending := map[string]entry{}
+	for _, e := range entries {
+		if isRelatedKind(e.desc.Annotations[consts.KindAnnotationName]) {
+			pending[e.key] = e
+			continue
+		}
+		refs[e.desc.Annotations[ocispec.AnnotationRefName]] = true
+		for d := range graphs[e.key].manifests {
+			live[d] = true
+		}
+	}
+	for changed := true; changed; {
+		changed = false
+		for key, e := range pending {
+			g := graphs[key]
+			alive := refs[e.desc.Annotations[ocispec.AnnotationRefName]]
+			if g.subject != "" {
+				alive = live[g.subject]
+			}
+			if alive {
+				for d := range g.manifests {
+					live[d] = true
+				}
+				delete(pending, key)
+				changed = true
+			}
+		}
+	}
+
+	referenced := map[digest.Digest]bool{}
+	for _, e := range entries {
+		if _, orphaned := pending[e.key]; orphaned {
+			continue
+		}
+		for d := range graphs[e.key].blobs {
+			referenced[d] = true
+		}
+	}
+	for _, e := range entries {
+		if _, orphaned := pending[e.key]; !orphaned {
+			continue
+		}
+		res.Orphans = append(res.Orphans, GCOrphan{
+			Reference: e.desc.Annotations[ocispec.AnnotationRefName],
+			Kind:      e.desc.Annotations[consts.KindAnnotationName],
+			Type:      l.ArtifactType(ctx, e.desc),
+			Digest:    e.desc.Digest.String(),
+			Size:      e.desc.Size,
+			Subject:   graphs[e.key].subject.String(),
+			key:       e.key,
+		})
+	}
+
+	// a generation kept for `hauler store rollback` still needs its content
+	kept, err := l.historyBlobs(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read store history: %w", err)
+	}
+
+	if err := l.scanBlobs(referenced, kept, opts, res); err != nil {
+		return nil, err
+	}
+	if opts.DryRun {
+		return res, nil
+	}
+
+	if len(res.Orphans) > 0 {
+		for _, o := range res.Orphans {
+			l.OCI.RemoveFromIndex(o.key)
+		}
+		if err := l.OCI.SaveIndex(); err != nil {
+			return nil, fmt.Errorf("failed to save index: %w", err)
+		}
+	}
+	for _, b := range res.Blobs {
+		d := digest.Digest(b.Digest)
+		if err := os.Remove(filepath.Join(l.Root, ocispec.ImageBlobsDir, d.Algorithm().String(), d.Encoded())); err != nil && !os.IsNotExist(err) {
+			return res, fmt.Errorf("failed to remove blob %s: %w", b.Digest, err)
+		}
+	}
+	for _, t := range res.Temps {
+		if err := os.Remove(filepath.Join(l.Root, t.Path)); err != nil && !os.IsNotExist(err) {
+			return res, fmt.Errorf("failed to remove temp file %s: %w", t.Path, err)
+		}
+	}
+	return res, nil
+}
+
+// scanBlobs fills res with the blobs outside referenced, setting aside those
+// kept references, and the temp files past their grace period.
+func (l *Layout) scanBlobs(referenced, kept map[digest.Digest]bool, opts GCOptions, res *GCResult) error {
+	now := time.Now()
+	addTemp := func(rel string, info os.FileInfo) {
+		if now.Sub(info.ModTime()) >= opts.TempGrace {
+			res.Temps = append(res.Temps, GCTemp{Path: rel, Size: info.Size(), ModTime: info.ModTime()})
+		}
+	}
+
+	rootEntries, err := os.ReadDir(l.Root)
+	if err != nil {
+		return fmt.Errorf("failed to read store directory: %w", err)
+	}
+	for _, e := range rootEntries {
+		if e.Type().IsRegular() && indexTempPattern.MatchString(e.Name()) {
+			if info, err := e.Info(); err == nil {
+				addTemp(e.Name(), info)
+			}
+		}
+	}
+
+	blobsDir := filepath.Join(l.Root, ocispec.ImageBlobsDir)
+	algs, err := os.ReadDir(blobsDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to read blobs directory: %w", err)
+	}
+	for _, alg := range algs {
+		if !alg.IsDir() {
+			continue
+		}
+		files, err := os.ReadDir(filepath.Join(blobsDir, alg.Name()))
+		if err != nil {
+			return fmt.Errorf("failed to read blobs directory: %w", err)
+		}
+		for _, f := range files {
+			if f.IsDir() {
+				continue
+			}
+			info, err := f.Info()
+			if err != nil {
+				continue
+			}
+			if strings.Contains(f.Name(), ".tmp-") {
+				addTemp(filepath.Join(ocispec.ImageBlobsDir, alg.Name(), f.Name()), info)
+				continue
+			}
+			d := digest.NewDigestFromEncoded(digest.Algorithm(alg.Name()), f.Name())
+			if d.Validate() != nil {
+				// not a blob hauler wrote... leave it be
+				continue
+			}
+			switch {
+			case referenced[d]:
+			case kept[d]:
+				res.Held = append(res.Held, GCBlob{Digest: d.String(), Size: info.Size()})
+			default:
+				res.Blobs = append(res.Blobs, GCBlob{Digest: d.String(), Size: info.Size()})
+			}
+		}
+	}
+	return nil
+}
+
+// manifestGraph is every blob reachable from one index entry.
+type manifestGraph struct {
+	blobs     map[digest.Digest]int64 // sizes as their descriptors record them
+	manifests map[digest.Digest]bool  // the manifests among blobs
+	subject   digest.Digest           // the root manifest's subject, if any
+}
+
+// Blobs returns every blob desc reaches -- its manifest, any child manifests,
+// configs, and layers -- with the size each descriptor records.
+func (l *Layout) Blobs(ctx context.Context, desc ocispec.Descriptor) (map[digest.Digest]int64, error) {
+	blobs := map[digest.Digest]int64{}
+	err := l.WalkBlobs(ctx, desc, func(d ocispec.Descriptor) error {
+		if d.Digest.Validate() == nil {
+			blobs[d.Digest] = d.Size
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return blobs, nil
+}
+
+func (l *Layout) readGraph(ctx context.Context, desc ocispec.Descriptor) (*manifestGraph, error) {
+	// the root is decoded even when its media type is one WalkBlobs doesn't
+	// descend into, so an unreadable entry still stops the pass
+	var root struct {
+		Subject *ocispec.Descriptor `json:"subject,omitempty"`
+	}
+	if err := l.fetchJSON(ctx, desc, &root); err != nil {
+		return nil, fmt.Errorf("decoding %s: %w", desc.Digest, err)
+	}
+
+	g := &manifestGraph{blobs: map[digest.Digest]int64{}, manifests: map[digest.Digest]bool{desc.Digest: true}}
+	if root.Subject != nil {
+		g.subject = root.Subject.Digest
+	}
+	err := l.WalkBlobs(ctx, desc, func(d ocispec.Descriptor) error {
+		if d.Digest.Validate() != nil {
+			return nil
+		}
+		g.blobs[d.Digest] = d.Size
+		switch d.MediaType {
+		case ocispec.MediaTypeImageManifest, ocispec.MediaTypeImageIndex,
+			consts.DockerManifestSchema2, consts.DockerManifestListSchema2:
+			g.manifests[d.Digest] = true
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// isRelatedKind reports whether kind marks an artifact saved for some other
+// image: a cosign signature, attestation, or sbom, or an OCI referrer.
+func isRelatedKind(kind string) bool {
+	switch kind {
+	case consts.KindAnnotationSigs, consts.KindAnnotationAtts, consts.KindAnnotationSboms:
+		return true
+	}
+	return strings.HasPrefix(kind, consts.KindAnnotationReferrers)
+}
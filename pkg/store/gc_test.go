@@ -0,0 +1,211 @@
+package store_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	gcrv1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	digest "github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+
+	"hauler.dev/go/hauler/v2/pkg/consts"
+	"hauler.dev/go/hauler/v2/pkg/store"
+)
+
+func writeStrayFile(t *testing.T, path string, content string, age time.Duration) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("WriteFile(%s): %v", path, err)
+	}
+	mtime := time.Now().Add(-age)
+	if err := os.Chtimes(path, mtime, mtime); err != nil {
+		t.Fatalf("Chtimes(%s): %v", path, err)
+	}
+}
+
+func TestGC_UnreferencedBlobsAndTemps(t *testing.T) {
+	ctx := context.Background()
+	s := newCheckTestStore(t)
+	host, opts := newCheckTestRegistry(t)
+	desc := pushAndAddImage(t, s, host, "test/gc", "v1", opts)
+
+	stray := digest.FromString("stray")
+	writeStrayFile(t, blobPath(s.Root, stray), "stray", 0)
+	staleTemp := filepath.Join(s.Root, "blobs", "sha256", stray.Encoded()+".tmp-1")
+	freshTemp := filepath.Join(s.Root, "blobs", "sha256", stray.Encoded()+".tmp-2")
+	writeStrayFile(t, staleTemp, "partial", 2*time.Hour)
+	writeStrayFile(t, freshTemp, "partial", 0)
+
+	dry, err := s.GC(ctx, store.GCOptions{DryRun: true, TempGrace: time.Hour})
+	if err != nil {
+		t.Fatalf("GC(dry run): %v", err)
+	}
+	if len(dry.Blobs) != 1 || dry.Blobs[0].Digest != stray.String() || dry.Blobs[0].Size != 5 {
+		t.Errorf("dry run blobs = %+v, want only the stray blob", dry.Blobs)
+	}
+	if len(dry.Temps) != 1 || filepath.Base(dry.Temps[0].Path) != filepath.Base(staleTemp) {
+		t.Errorf("dry run temps = %+v, want only the stale temp file", dry.Temps)
+	}
+	if _, err := os.Stat(blobPath(s.Root, stray)); err != nil {
+		t.Errorf("dry run deleted the stray blob: %v", err)
+	}
+
+	res, err := s.GC(ctx, store.GCOptions{TempGrace: time.Hour})
+	if err != nil {
+		t.Fatalf("GC: %v", err)
+	}
+	if res.Freed() != dry.Freed() {
+		t.Errorf("GC freed %d bytes, dry run promised %d", res.Freed(), dry.Freed())
+	}
+	for _, p := range []string{blobPath(s.Root, stray), staleTemp} {
+		if _, err := os.Stat(p); !os.IsNotExist(err) {
+			t.Errorf("%s survived GC", p)
+		}
+	}
+	if _, err := os.Stat(freshTemp); err != nil {
+		t.Errorf("temp file inside its grace period was swept: %v", err)
+	}
+	if check := s.NewChecker().Check(ctx, desc); !check.OK {
+		t.Errorf("GC damaged a live image: %+v", check.Problems)
+	}
+}
+
+// A referrer whose subject was removed is dropped from the index along with
+// its blobs; one whose subject is still stored is kept.
+func TestGC_OrphanedReferrer(t *testing.T) {
+	ctx := context.Background()
+	s := newCheckTestStore(t)
+	host, opts := newCheckTestRegistry(t)
+	gone := pushAndAddImage(t, s, host, "test/gone", "v1", opts)
+	kept := pushAndAddImage(t, s, host, "test/kept", "v1", opts)
+
+	addReferrer := func(subject ocispec.Descriptor) ocispec.Descriptor {
+		base, err := random.Image(64, 1)
+		if err != nil {
+			t.Fatalf("random.Image: %v", err)
+		}
+		img := mutate.Subject(base, gcrv1.Descriptor{
+			MediaType: "application/vnd.oci.image.manifest.v1+json",
+			Digest:    gcrv1.Hash{Algorithm: "sha256", Hex: subject.Digest.Encoded()},
+			Size:      subject.Size,
+		}).(gcrv1.Image)
+		d, err := s.AddRelated(ctx, subject, img, consts.KindAnnotationReferrers+"/"+subject.Digest.Encoded())
+		if err != nil {
+			t.Fatalf("AddRelated: %v", err)
+		}
+		return d
+	}
+	orphan := addReferrer(gone)
+	addReferrer(kept)
+
+	var goneKey string
+	_ = s.OCI.Walk(func(key string, desc ocispec.Descriptor) error {
+		if desc.Digest == gone.Digest {
+			goneKey = key
+		}
+		return nil
+	})
+	if err := s.RemoveArtifact(ctx, goneKey, gone); err != nil {
+		t.Fatalf("RemoveArtifact: %v", err)
+	}
+
+	res, err := s.GC(ctx, store.GCOptions{TempGrace: time.Hour})
+	if err != nil {
+		t.Fatalf("GC: %v", err)
+	}
+	if len(res.Orphans) != 1 || res.Orphans[0].Digest != orphan.Digest.String() || res.Orphans[0].Subject != gone.Digest.String() {
+		t.Fatalf("orphans = %+v, want only the referrer of test/gone", res.Orphans)
+	}
+	if _, err := os.Stat(blobPath(s.Root, orphan.Digest)); !os.IsNotExist(err) {
+		t.Errorf("orphaned referrer manifest survived GC")
+	}
+
+	var entries int
+	_ = s.OCI.Walk(func(string, ocispec.Descriptor) error { entries++; return nil })
+	if entries != 2 {
+		t.Errorf("index holds %d entries after GC, want test/kept and its referrer", entries)
+	}
+}
+
+// An unreadable manifest hides which blobs it needs, so GC refuses to run
+// rather than collect them.
+func TestGC_UnreadableManifestStops(t *testing.T) {
+	ctx := context.Background()
+	s := newCheckTestStore(t)
+	host, opts := newCheckTestRegistry(t)
+	desc := pushAndAddImage(t, s, host, "test/broken", "v1", opts)
+	layer := readManifestBlob(t, s.Root, desc.Digest).Layers[0]
+
+	if err := os.Remove(blobPath(s.Root, desc.Digest)); err != nil {
+		t.Fatalf("remove manifest: %v", err)
+	}
+	if _, err := s.GC(ctx, store.GCOptions{}); err == nil {
+		t.Fatal("GC succeeded with a manifest missing")
+	}
+	if _, err := os.Stat(blobPath(s.Root, layer.Digest)); err != nil {
+		t.Errorf("GC deleted a layer of the unreadable manifest: %v", err)
+	}
+}
+
+// CleanUp, run by remove and sync --prune, only deletes unreferenced blobs:
+// an unreadable manifest is skipped rather than failing the pass, and
+// orphaned entries are left for `hauler store gc`.
+func TestCleanUp_BlobsOnly(t *testing.T) {
+	ctx := context.Background()
+	s := newCheckTestStore(t)
+	host, opts := newCheckTestRegistry(t)
+	gone := pushAndAddImage(t, s, host, "test/gone", "v1", opts)
+	broken := pushAndAddImage(t, s, host, "test/broken", "v1", opts)
+
+	base, err := random.Image(64, 1)
+	if err != nil {
+		t.Fatalf("random.Image: %v", err)
+	}
+	img := mutate.Subject(base, gcrv1.Descriptor{
+		MediaType: "application/vnd.oci.image.manifest.v1+json",
+		Digest:    gcrv1.Hash{Algorithm: "sha256", Hex: gone.Digest.Encoded()},
+		Size:      gone.Size,
+	}).(gcrv1.Image)
+	orphan, err := s.AddRelated(ctx, gone, img, consts.KindAnnotationReferrers+"/"+gone.Digest.Encoded())
+	if err != nil {
+		t.Fatalf("AddRelated: %v", err)
+	}
+
+	var goneKey string
+	_ = s.OCI.Walk(func(key string, desc ocispec.Descriptor) error {
+		if desc.Digest == gone.Digest {
+			goneKey = key
+		}
+		return nil
+	})
+	if err := s.RemoveArtifact(ctx, goneKey, gone); err != nil {
+		t.Fatalf("RemoveArtifact: %v", err)
+	}
+	if err := os.Remove(blobPath(s.Root, broken.Digest)); err != nil {
+		t.Fatalf("remove manifest: %v", err)
+	}
+
+	res, err := s.CleanUp(ctx)
+	if err != nil {
+		t.Fatalf("CleanUp: %v", err)
+	}
+	if len(res.Blobs) == 0 {
+		t.Error("CleanUp removed nothing, want the blobs of test/gone")
+	}
+	if _, err := os.Stat(blobPath(s.Root, gone.Digest)); !os.IsNotExist(err) {
+		t.Error("manifest of the removed image survived CleanUp")
+	}
+	if _, err := os.Stat(blobPath(s.Root, orphan.Digest)); err != nil {
+		t.Errorf("CleanUp deleted the orphaned referrer: %v", err)
+	}
+	var entries int
+	_ = s.OCI.Walk(func(string, ocispec.Descriptor) error { entries++; return nil })
+	if entries != 2 {
+		t.Errorf("index holds %d entries after CleanUp, want test/broken and the orphaned referrer", entries)
+	}
+}
@@ -0,0 +1,75 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+
+	"hauler.dev/go/hauler/v2/pkg/consts"
+)
+
+// WalkBlobs calls fn for desc and every descriptor reachable from it (configs,
+// layers, and the children of an index), following the same graph that Copy
+// traverses. Each digest is visited once, so blobs shared between children of
+// an index are only reported the first time they're seen.
+func (l *Layout) WalkBlobs(ctx context.Context, desc ocispec.Descriptor, fn func(ocispec.Descriptor) error) error {
+	return l.walkBlobs(ctx, desc, map[digest.Digest]bool{}, fn)
+}
+
+func (l *Layout) walkBlobs(ctx context.Context, desc ocispec.Descriptor, seen map[digest.Digest]bool, fn func(ocispec.Descriptor) error) error {
+	if seen[desc.Digest] {
+		return nil
+	}
+	seen[desc.Digest] = true
+
+	if err := fn(desc); err != nil {
+		return err
+	}
+
+	switch desc.MediaType {
+	case ocispec.MediaTypeImageManifest, consts.DockerManifestSchema2:
+		var manifest ocispec.Manifest
+		if err := l.fetchJSON(ctx, desc, &manifest); err != nil {
+			return fmt.Errorf("failed to read manifest %s: %w", desc.Digest, err)
+		}
+		if err := l.walkBlobs(ctx, manifest.Config, seen, fn); err != nil {
+			return err
+		}
+		for _, layer := range manifest.Layers {
+			if err := l.walkBlobs(ctx, layer, seen, fn); err != nil {
+				return err
+			}
+		}
+
+	case ocispec.MediaTypeImageIndex, consts.DockerManifestListSchema2:
+		var index ocispec.Index
+		if err := l.fetchJSON(ctx, desc, &index); err != nil {
+			return fmt.Errorf("failed to read index %s: %w", desc.Digest, err)
+		}
+		for _, child := range index.Manifests {
+			if err := l.walkBlobs(ctx, child, seen, fn); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (l *Layout) fetchJSON(ctx context.Context, desc ocispec.Descriptor, v any) error {
+	rc, err := l.OCI.Fetch(ctx, desc)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	data, err := io.ReadAll(rc)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
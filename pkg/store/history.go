@@ -0,0 +1,382 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+
+	"hauler.dev/go/hauler/v2/pkg/consts"
+	"hauler.dev/go/hauler/v2/pkg/content"
+)
+
+// HistoryEntry is one generation of a store's history: index.json as it
+// stood before a command that changed it.
+type HistoryEntry struct {
+	Generation int                  `json:"generation"`
+	Timestamp  time.Time            `json:"timestamp"`
+	Command    string               `json:"command"`
+	AuditID    string               `json:"audit-id,omitempty"`
+	Manifests  []ocispec.Descriptor `json:"manifests"`
+}
+
+type auditIDKey struct{}
+
+// ContextWithAuditID returns ctx carrying the audit id of the running
+// command, which an exclusive Lock records with the generation it takes.
+func ContextWithAuditID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, auditIDKey{}, id)
+}
+
+func auditIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(auditIDKey{}).(string)
+	return id
+}
+
+func (l *Layout) historyDir() string {
+	return filepath.Join(l.Root, consts.DefaultStoreHistoryDir)
+}
+
+func (l *Layout) historyPath(gen int) string {
+	return filepath.Join(l.historyDir(), strconv.Itoa(gen)+".json")
+}
+
+// beginSnapshot records the index as it stands when command takes the
+// exclusive lock, as the next generation. It is written before the command
+// runs, so a command that dies partway still leaves the way back. Nothing is
+// pruned until endSnapshot: the command may be a rollback to the oldest
+// generation. With history off, nothing is recorded, and the generations
+// already kept are left alone.
+func (l *Layout) beginSnapshot(ctx context.Context, command string) error {
+	if l.historyLimit <= 0 {
+		return nil
+	}
+
+	manifests, err := l.indexManifests()
+	if err != nil {
+		return fmt.Errorf("failed to snapshot index: %w", err)
+	}
+	gens, err := l.generations()
+	if err != nil {
+		return fmt.Errorf("failed to read store history: %w", err)
+	}
+	gen := 1
+	if len(gens) > 0 {
+		gen = gens[len(gens)-1] + 1
+	}
+
+	e := &HistoryEntry{
+		Generation: gen,
+		Timestamp:  time.Now().UTC(),
+		Command:    command,
+		AuditID:    auditIDFromContext(ctx),
+		Manifests:  manifests,
+	}
+	if err := l.writeHistoryEntry(e); err != nil {
+		return fmt.Errorf("failed to snapshot index: %w", err)
+	}
+	l.snapshot = e
+	return nil
+}
+
+// endSnapshot drops the generation beginSnapshot recorded when the index
+// came out of the command unchanged, and otherwise keeps it and prunes the
+// oldest generations beyond the limit, sparing any a rollback just restored.
+// A limit lower than the history already kept only stops it from growing:
+// the new generation pushes out one old one, and the rest are left for
+// TrimHistory.
+func (l *Layout) endSnapshot() error {
+	e := l.snapshot
+	restored := l.restored
+	l.snapshot = nil
+	l.restored = 0
+	if e == nil {
+		return nil
+	}
+
+	manifests, err := l.indexManifests()
+	if err != nil {
+		return err
+	}
+	if sameManifests(e.Manifests, manifests) {
+		if err := os.Remove(l.historyPath(e.Generation)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	gens, err := l.generations()
+	if err != nil {
+		return err
+	}
+	return l.pruneHistory(max(l.historyLimit, len(gens)-1), restored)
+}
+
+// TrimHistory drops all but the newest keep generations of the store's
+// history, returning how many it dropped. It is the only way generations
+// beyond a lowered --history-limit, or any at all with history off, leave
+// the store.
+func (l *Layout) TrimHistory(keep int) (int, error) {
+	entries, err := l.History()
+	if err != nil {
+		return 0, err
+	}
+	var errs []error
+	dropped := 0
+	for i := 0; i < len(entries)-keep; i++ {
+		if err := os.Remove(l.historyPath(entries[i].Generation)); err != nil && !os.IsNotExist(err) {
+			errs = append(errs, err)
+			continue
+		}
+		dropped++
+	}
+	return dropped, errors.Join(errs...)
+}
+
+// History returns the store's retained generations, oldest first.
+func (l *Layout) History() ([]HistoryEntry, error) {
+	gens, err := l.generations()
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]HistoryEntry, 0, len(gens))
+	for _, gen := range gens {
+		if l.snapshot != nil && gen == l.snapshot.Generation {
+			// taken by this process's own lock and not yet known to be kept
+			continue
+		}
+		e, err := l.HistoryEntry(gen)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, *e)
+	}
+	return entries, nil
+}
+
+// HistoryEntry returns generation gen of the store's history.
+func (l *Layout) HistoryEntry(gen int) (*HistoryEntry, error) {
+	data, err := os.ReadFile(l.historyPath(gen))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("generation [%d] not found in store history (use `hauler store history` to list generations)", gen)
+		}
+		return nil, err
+	}
+	var e HistoryEntry
+	if err := json.Unmarshal(data, &e); err != nil {
+		return nil, fmt.Errorf("failed to parse generation [%d]: %w", gen, err)
+	}
+	return &e, nil
+}
+
+// Rollback replaces the index with generation gen's. Every blob the
+// generation references -- manifests, configs, and layers -- must still be in
+// the store, or the index is left alone; garbage collection keeps them for as
+// long as the generation is retained. Rolling back is itself a
+// change to the index, so under an exclusive Lock it can be undone the same
+// way.
+func (l *Layout) Rollback(ctx context.Context, gen int) (*HistoryEntry, error) {
+	e, err := l.HistoryEntry(gen)
+	if err != nil {
+		return nil, err
+	}
+	l.restored = gen
+
+	var missing []string
+	for _, desc := range e.Manifests {
+		err := l.WalkBlobs(ctx, desc, func(d ocispec.Descriptor) error {
+			_, err := os.Stat(filepath.Join(l.Root, ocispec.ImageBlobsDir, d.Digest.Algorithm().String(), d.Digest.Encoded()))
+			return err
+		})
+		if err != nil {
+			missing = append(missing, fmt.Sprintf("%s (%s)", desc.Annotations[ocispec.AnnotationRefName], desc.Digest))
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("cannot roll back to generation [%d]... content is missing from the store for %s", gen, strings.Join(missing, ", "))
+	}
+
+	var keys []string
+	if err := l.OCI.Walk(func(key string, _ ocispec.Descriptor) error {
+		keys = append(keys, key)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	for _, key := range keys {
+		l.OCI.RemoveFromIndex(key)
+	}
+	for _, desc := range e.Manifests {
+		if err := l.OCI.AddIndex(desc); err != nil {
+			return nil, err
+		}
+	}
+	if err := l.OCI.SaveIndex(); err != nil {
+		return nil, fmt.Errorf("failed to save index: %w", err)
+	}
+	return e, nil
+}
+
+// historyBlobs returns every blob the retained generations reference that is
+// still in the store, so garbage collection can keep them.
+func (l *Layout) historyBlobs(ctx context.Context) (map[digest.Digest]bool, error) {
+	gens, err := l.generations()
+	if err != nil {
+		return nil, err
+	}
+	blobs := map[digest.Digest]bool{}
+	seen := map[digest.Digest]bool{}
+	for _, gen := range gens {
+		e, err := l.HistoryEntry(gen)
+		if err != nil {
+			return nil, err
+		}
+		for _, desc := range e.Manifests {
+			if seen[desc.Digest] {
+				continue
+			}
+			seen[desc.Digest] = true
+			g, err := l.readGraph(ctx, desc)
+			if err != nil {
+				// already gone... nothing left to keep
+				continue
+			}
+			for d := range g.blobs {
+				blobs[d] = true
+			}
+		}
+	}
+	return blobs, nil
+}
+
+// indexManifests returns the index's descriptors in key order.
+func (l *Layout) indexManifests() ([]ocispec.Descriptor, error) {
+	type entry struct {
+		key  string
+		desc ocispec.Descriptor
+	}
+	var entries []entry
+	if err := l.OCI.Walk(func(key string, desc ocispec.Descriptor) error {
+		entries = append(entries, entry{key: key, desc: desc})
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].key < entries[j].key })
+
+	manifests := make([]ocispec.Descriptor, 0, len(entries))
+	for _, e := range entries {
+		manifests = append(manifests, e.desc)
+	}
+	return manifests, nil
+}
+
+func sameManifests(a, b []ocispec.Descriptor) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		ka, _ := content.IndexKey(a[i])
+		kb, _ := content.IndexKey(b[i])
+		if ka != kb {
+			return false
+		}
+		ja, err := json.Marshal(a[i])
+		if err != nil {
+			return false
+		}
+		jb, err := json.Marshal(b[i])
+		if err != nil {
+			return false
+		}
+		if string(ja) != string(jb) {
+			return false
+		}
+	}
+	return true
+}
+
+// generations lists the generation numbers under history/, oldest first.
+func (l *Layout) generations() ([]int, error) {
+	dirEntries, err := os.ReadDir(l.historyDir())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var gens []int
+	for _, de := range dirEntries {
+		n, ok := strings.CutSuffix(de.Name(), ".json")
+		if !ok || de.IsDir() {
+			continue
+		}
+		gen, err := strconv.Atoi(n)
+		if err != nil || gen <= 0 {
+			continue
+		}
+		gens = append(gens, gen)
+	}
+	sort.Ints(gens)
+	return gens, nil
+}
+
+func (l *Layout) writeHistoryEntry(e *HistoryEntry) error {
+	if err := os.MkdirAll(l.historyDir(), 0o755); err != nil {
+		return err
+	}
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(l.historyDir(), "generation-*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), l.historyPath(e.Generation))
+}
+
+// pruneHistory drops the oldest generations beyond keep, other than spare.
+func (l *Layout) pruneHistory(keep, spare int) error {
+	gens, err := l.generations()
+	if err != nil {
+		return err
+	}
+	var errs []error
+	excess := len(gens) - keep
+	for _, gen := range gens {
+		if excess <= 0 {
+			break
+		}
+		if gen == spare {
+			continue
+		}
+		if err := os.Remove(l.historyPath(gen)); err != nil && !os.IsNotExist(err) {
+			errs = append(errs, err)
+		}
+		excess--
+	}
+	return errors.Join(errs...)
+}
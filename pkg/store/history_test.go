@@ -0,0 +1,208 @@
+package store_test
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+
+	"hauler.dev/go/hauler/v2/pkg/content"
+	"hauler.dev/go/hauler/v2/pkg/store"
+)
+
+// locked runs fn under an exclusive lock on s, the way every mutating
+// command does.
+func locked(t *testing.T, ctx context.Context, s *store.Layout, command string, fn func()) {
+	t.Helper()
+	if err := s.Lock(ctx, store.LockExclusive, 0, command); err != nil {
+		t.Fatalf("Lock: %v", err)
+	}
+	fn()
+	if err := s.Unlock(); err != nil {
+		t.Fatalf("Unlock: %v", err)
+	}
+}
+
+func TestHistory_Rollback(t *testing.T) {
+	ctx := store.ContextWithAuditID(context.Background(), "audit-1")
+	host, opts := newCheckTestRegistry(t)
+	s := newCheckTestStore(t)
+
+	var app ocispec.Descriptor
+	locked(t, ctx, s, "hauler store add image", func() {
+		app = pushAndAddImage(t, s, host, "history/app", "v1", opts)
+	})
+	// a command that leaves the index alone records nothing
+	locked(t, ctx, s, "hauler store info", func() {})
+	locked(t, ctx, s, "hauler store remove", func() {
+		key, err := content.IndexKey(app)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := s.RemoveArtifact(ctx, key, app); err != nil {
+			t.Fatalf("RemoveArtifact: %v", err)
+		}
+	})
+
+	history, err := s.History()
+	if err != nil {
+		t.Fatalf("History: %v", err)
+	}
+	if len(history) != 2 {
+		t.Fatalf("History has %d generations, want 2", len(history))
+	}
+	if h := history[0]; h.Generation != 1 || h.Command != "hauler store add image" || h.AuditID != "audit-1" || len(h.Manifests) != 0 {
+		t.Errorf("generation 1 = %+v, want the empty index before the add", h)
+	}
+	if h := history[1]; h.Generation != 2 || h.Command != "hauler store remove" || len(h.Manifests) != 1 {
+		t.Errorf("generation 2 = %+v, want the index holding the image before the remove", h)
+	}
+
+	// the removed image's blobs outlive gc while generation 2 is kept
+	locked(t, ctx, s, "hauler store gc", func() {
+		res, err := s.GC(ctx, store.GCOptions{})
+		if err != nil {
+			t.Fatalf("GC: %v", err)
+		}
+		if len(res.Blobs) != 0 {
+			t.Errorf("GC collected %d blobs a kept generation references", len(res.Blobs))
+		}
+		if len(res.Held) == 0 {
+			t.Error("GC reported no blobs held by the history")
+		}
+	})
+
+	locked(t, ctx, s, "hauler store rollback", func() {
+		if _, err := s.Rollback(ctx, 2); err != nil {
+			t.Fatalf("Rollback: %v", err)
+		}
+	})
+	var refs []string
+	if err := s.Walk(func(_ string, desc ocispec.Descriptor) error {
+		refs = append(refs, desc.Annotations[ocispec.AnnotationRefName])
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if len(refs) != 1 || refs[0] != "history/app:v1" {
+		t.Errorf("after rollback the index holds %v, want history/app:v1", refs)
+	}
+
+	// the rollback is a generation of its own
+	if history, _ = s.History(); len(history) != 3 || history[2].Command != "hauler store rollback" {
+		t.Errorf("after rollback history is %+v, want a third generation for it", history)
+	}
+
+	if _, err := s.Rollback(ctx, 99); err == nil {
+		t.Errorf("Rollback(99) = nil error, want one")
+	}
+}
+
+func TestHistory_Limit(t *testing.T) {
+	ctx := context.Background()
+	host, opts := newCheckTestRegistry(t)
+	s, err := store.NewLayout(t.TempDir(), store.WithHistoryLimit(2))
+	if err != nil {
+		t.Fatalf("NewLayout: %v", err)
+	}
+
+	for _, tag := range []string{"v1", "v2", "v3", "v4"} {
+		locked(t, ctx, s, "hauler store add image", func() {
+			pushAndAddImage(t, s, host, "history/app", tag, opts)
+		})
+	}
+	history, err := s.History()
+	if err != nil {
+		t.Fatalf("History: %v", err)
+	}
+	if len(history) != 2 || history[0].Generation != 3 || history[1].Generation != 4 {
+		t.Errorf("History = %+v, want only generations 3 and 4", history)
+	}
+
+	// rolling back to the oldest generation spares it, though the rollback
+	// records a generation of its own
+	locked(t, ctx, s, "hauler store rollback", func() {
+		if _, err := s.Rollback(ctx, 3); err != nil {
+			t.Fatalf("Rollback(3): %v", err)
+		}
+	})
+	history, err = s.History()
+	if err != nil {
+		t.Fatalf("History: %v", err)
+	}
+	if len(history) != 2 || history[0].Generation != 3 || history[1].Generation != 5 {
+		t.Errorf("History after rollback = %+v, want generations 3 and 5", history)
+	}
+
+	// a lower limit, or none, stops the history from growing but keeps what
+	// it holds
+	s1, err := store.NewLayout(s.Root, store.WithHistoryLimit(1))
+	if err != nil {
+		t.Fatalf("NewLayout: %v", err)
+	}
+	locked(t, ctx, s1, "hauler store add image", func() {
+		pushAndAddImage(t, s1, host, "history/app", "v5", opts)
+	})
+	if history, _ := s1.History(); len(history) != 2 || history[0].Generation != 5 || history[1].Generation != 6 {
+		t.Errorf("History with a lowered limit = %+v, want generations 5 and 6", history)
+	}
+	s0, err := store.NewLayout(s.Root, store.WithHistoryLimit(0))
+	if err != nil {
+		t.Fatalf("NewLayout: %v", err)
+	}
+	locked(t, ctx, s0, "hauler store add image", func() {
+		pushAndAddImage(t, s0, host, "history/app", "v6", opts)
+	})
+	if history, _ := s0.History(); len(history) != 2 {
+		t.Errorf("History with a limit of 0 = %+v, want the 2 generations already kept", history)
+	}
+
+	// only trimming drops them
+	locked(t, ctx, s0, "hauler store history", func() {
+		if dropped, err := s0.TrimHistory(0); err != nil || dropped != 2 {
+			t.Errorf("TrimHistory(0) = %d, %v, want 2 dropped", dropped, err)
+		}
+	})
+	if history, _ := s0.History(); len(history) != 0 {
+		t.Errorf("History after TrimHistory(0) = %+v, want none", history)
+	}
+}
+
+// A generation whose layers were collected can't be rolled back to, even
+// though its manifests are still stored.
+func TestHistory_RollbackMissingLayer(t *testing.T) {
+	ctx := context.Background()
+	host, opts := newCheckTestRegistry(t)
+	s := newCheckTestStore(t)
+
+	var app ocispec.Descriptor
+	locked(t, ctx, s, "hauler store add image", func() {
+		app = pushAndAddImage(t, s, host, "history/app", "v1", opts)
+	})
+	locked(t, ctx, s, "hauler store remove", func() {
+		key, err := content.IndexKey(app)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := s.RemoveArtifact(ctx, key, app); err != nil {
+			t.Fatalf("RemoveArtifact: %v", err)
+		}
+	})
+
+	layer := readManifestBlob(t, s.Root, app.Digest).Layers[0]
+	if err := os.Remove(blobPath(s.Root, layer.Digest)); err != nil {
+		t.Fatalf("remove layer: %v", err)
+	}
+
+	locked(t, ctx, s, "hauler store rollback", func() {
+		if _, err := s.Rollback(ctx, 2); err == nil {
+			t.Error("Rollback succeeded with a layer of the generation missing")
+		}
+	})
+	var entries int
+	_ = s.Walk(func(string, ocispec.Descriptor) error { entries++; return nil })
+	if entries != 0 {
+		t.Errorf("failed rollback left %d entries in the index, want none", entries)
+	}
+}
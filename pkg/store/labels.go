@@ -0,0 +1,86 @@
+package store
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+
+	"hauler.dev/go/hauler/v2/pkg/consts"
+)
+
+// labelKeyPattern is what a label key may hold: anything a selector can name
+// as "label.<key>" without it reading as an operator or another term.
+var labelKeyPattern = regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9._/-]*[A-Za-z0-9])?$`)
+
+// ValidateLabelKey reports whether key can name a label.
+func ValidateLabelKey(key string) error {
+	if !labelKeyPattern.MatchString(key) {
+		return fmt.Errorf("invalid label key [%s]... keys are letters, digits, '.', '_', '-', and '/', starting and ending with a letter or digit", key)
+	}
+	return nil
+}
+
+// ParseLabels parses "key=value" pairs, as --label takes them, into a map.
+func ParseLabels(pairs []string) (map[string]string, error) {
+	labels := map[string]string{}
+	for _, pair := range pairs {
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid label [%s]... want key=value", pair)
+		}
+		if err := ValidateLabelKey(key); err != nil {
+			return nil, err
+		}
+		labels[key] = value
+	}
+	return labels, nil
+}
+
+// Labels returns the user labels set on desc.
+func Labels(desc ocispec.Descriptor) map[string]string {
+	labels := map[string]string{}
+	for k, v := range desc.Annotations {
+		if key, ok := strings.CutPrefix(k, consts.LabelAnnotationPrefix); ok {
+			labels[key] = v
+		}
+	}
+	return labels
+}
+
+// FormatLabels renders labels as sorted "key=value" pairs.
+func FormatLabels(labels map[string]string) []string {
+	pairs := make([]string, 0, len(labels))
+	for k, v := range labels {
+		pairs = append(pairs, k+"="+v)
+	}
+	sort.Strings(pairs)
+	return pairs
+}
+
+// ApplyLabels sets labels and unsets remove in annotations, in place.
+func ApplyLabels(annotations map[string]string, labels map[string]string, remove []string) {
+	for _, k := range remove {
+		delete(annotations, consts.LabelAnnotationPrefix+k)
+	}
+	for k, v := range labels {
+		annotations[consts.LabelAnnotationPrefix+k] = v
+	}
+}
+
+// SetLabels sets labels, and unsets the keys in remove, on the artifact
+// stored as refName -- not on the signatures, attestations, sboms, and
+// referrers saved for it, which a Selector matches by their artifact's
+// labels. It returns the number of index entries changed.
+func (l *Layout) SetLabels(refName string, labels map[string]string, remove []string) (int, error) {
+	return l.OCI.UpdateAnnotations(
+		func(desc ocispec.Descriptor) bool {
+			return desc.Annotations[ocispec.AnnotationRefName] == refName && !isRelatedKind(desc.Annotations[consts.KindAnnotationName])
+		},
+		func(a map[string]string) {
+			ApplyLabels(a, labels, remove)
+		},
+	)
+}
@@ -0,0 +1,29 @@
+package store_test
+
+import (
+	"testing"
+
+	"hauler.dev/go/hauler/v2/pkg/store"
+)
+
+func TestParseLabels(t *testing.T) {
+	got, err := store.ParseLabels([]string{"team=platform", "hauler.dev/ticket=OPS-1=a", "empty="})
+	if err != nil {
+		t.Fatalf("ParseLabels: %v", err)
+	}
+	want := map[string]string{"team": "platform", "hauler.dev/ticket": "OPS-1=a", "empty": ""}
+	if len(got) != len(want) {
+		t.Fatalf("ParseLabels = %v, want %v", got, want)
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("label [%s] = %q, want %q", k, got[k], v)
+		}
+	}
+
+	for _, pair := range []string{"team", "=x", "bad key=x", "team-=x", "a,b=x", "a~b=x", "-a=x"} {
+		if _, err := store.ParseLabels([]string{pair}); err == nil {
+			t.Errorf("ParseLabels(%q) = nil error, want one", pair)
+		}
+	}
+}
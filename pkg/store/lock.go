@@ -0,0 +1,174 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/gofrs/flock"
+
+	"hauler.dev/go/hauler/v2/pkg/consts"
+	"hauler.dev/go/hauler/v2/pkg/log"
+)
+
+// LockMode is how a command holds the store: many readers (info, copy, serve,
+// save) can share it, while a writer (add, sync, remove, load) holds it alone.
+// content.OCI's mutex only serializes one process; this lock is what keeps a
+// second hauler process from rewriting index.json or sweeping blobs out from
+// under the first.
+type LockMode int
+
+const (
+	LockShared LockMode = iota
+	LockExclusive
+)
+
+func (m LockMode) String() string {
+	if m == LockExclusive {
+		return "exclusive"
+	}
+	return "shared"
+}
+
+// lockRetryDelay is how often a waiting process retries the lock.
+const lockRetryDelay = 250 * time.Millisecond
+
+// lockHolder describes the process that last took the lock, so a process
+// left waiting can say who it is waiting on.
+type lockHolder struct {
+	PID     int       `json:"pid"`
+	Command string    `json:"command"`
+	Mode    string    `json:"mode"`
+	Since   time.Time `json:"since"`
+}
+
+// Lock takes the store's advisory lock in mode, waiting up to timeout for any
+// conflicting holder to let go (a timeout of 0 means don't wait). command is
+// recorded as the holder, for the error another process reports while it
+// waits. The lock is held until Unlock or process exit; the OS releases it
+// even when the process dies without unlocking.
+func (l *Layout) Lock(ctx context.Context, mode LockMode, timeout time.Duration, command string) error {
+	if l.lock != nil {
+		return fmt.Errorf("store [%s] is already locked by this process", l.Root)
+	}
+	path := filepath.Join(l.Root, consts.DefaultStoreLockName)
+
+	// a reader of a read-only store (a mounted haul, a shared cache) can't
+	// create the lock file, and nothing can write to the store under it
+	// anyway, so it goes ahead unlocked; any other failure, and a writer,
+	// always needs the lock
+	if mode == LockShared {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_RDONLY, 0o600)
+		if err != nil {
+			if !errors.Is(err, syscall.EROFS) && !errors.Is(err, syscall.EACCES) {
+				return fmt.Errorf("failed to lock store [%s]: %w", l.Root, err)
+			}
+			log.FromContext(ctx).Warnf("store [%s] is read-only, continuing without a lock: %v", l.Root, err)
+			return nil
+		}
+		f.Close()
+	}
+
+	fl := flock.New(path)
+
+	try := fl.TryRLock
+	tryCtx := fl.TryRLockContext
+	if mode == LockExclusive {
+		try, tryCtx = fl.TryLock, fl.TryLockContext
+	}
+
+	var ok bool
+	var err error
+	if timeout <= 0 {
+		ok, err = try()
+	} else {
+		wctx, cancel := context.WithTimeout(ctx, timeout)
+		ok, err = tryCtx(wctx, lockRetryDelay)
+		cancel()
+		if errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil {
+			err = nil
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to lock store [%s]: %w", l.Root, err)
+	}
+	if !ok {
+		return l.lockedError(mode, timeout)
+	}
+
+	l.lock = fl
+	l.writeLockHolder(mode, command)
+
+	if mode == LockExclusive {
+		if err := l.beginSnapshot(ctx, command); err != nil {
+			l.clearLockHolder()
+			_ = fl.Unlock()
+			l.lock = nil
+			return err
+		}
+	}
+	return nil
+}
+
+// Unlock releases the lock taken by Lock. It is a no-op on an unlocked store.
+// Under an exclusive lock it first keeps the index.json snapshot Lock took,
+// when the index changed, as a new generation of the store's history.
+func (l *Layout) Unlock() error {
+	if l.lock == nil {
+		return nil
+	}
+	herr := l.endSnapshot()
+	l.clearLockHolder()
+	err := l.lock.Unlock()
+	l.lock = nil
+	return errors.Join(herr, err)
+}
+
+func (l *Layout) lockedError(mode LockMode, timeout time.Duration) error {
+	waited := "without waiting"
+	if timeout > 0 {
+		waited = "after waiting " + timeout.String()
+	}
+
+	h, err := l.readLockHolder()
+	if err != nil {
+		return fmt.Errorf("store [%s] is locked by another hauler process; could not take a [%s] lock %s", l.Root, mode, waited)
+	}
+	return fmt.Errorf("store [%s] is locked by PID [%d] running [%s] (%s lock since %s); could not take a [%s] lock %s",
+		l.Root, h.PID, h.Command, h.Mode, h.Since.Format(consts.CustomTimeFormat), mode, waited)
+}
+
+// The holder is kept beside the lock file rather than in it: on Windows a
+// locked file can't be read by anyone else, which is exactly who needs it.
+// With several readers sharing the lock, it names the latest of them.
+
+func (l *Layout) writeLockHolder(mode LockMode, command string) {
+	data, err := json.Marshal(lockHolder{PID: os.Getpid(), Command: command, Mode: mode.String(), Since: time.Now()})
+	if err != nil {
+		return
+	}
+	_ = os.WriteFile(filepath.Join(l.Root, consts.DefaultStoreLockInfoName), data, 0o644)
+}
+
+func (l *Layout) readLockHolder() (lockHolder, error) {
+	var h lockHolder
+	data, err := os.ReadFile(filepath.Join(l.Root, consts.DefaultStoreLockInfoName))
+	if err != nil {
+		return h, err
+	}
+	err = json.Unmarshal(data, &h)
+	return h, err
+}
+
+// clearLockHolder removes the holder record if it still names this process,
+// leaving one written by another reader in place.
+func (l *Layout) clearLockHolder() {
+	if h, err := l.readLockHolder(); err == nil && h.PID == os.Getpid() {
+		_ = os.Remove(filepath.Join(l.Root, consts.DefaultStoreLockInfoName))
+	}
+}
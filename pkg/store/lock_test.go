@@ -0,0 +1,153 @@
+package store_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"hauler.dev/go/hauler/v2/pkg/consts"
+	"hauler.dev/go/hauler/v2/pkg/store"
+)
+
+// openTwice opens the same store root as two Layouts. flock conflicts between
+// open file descriptions, so two Layouts in one process stand in for two
+// hauler processes.
+func openTwice(t *testing.T) (*store.Layout, *store.Layout) {
+	t.Helper()
+	dir := t.TempDir()
+	a, err := store.NewLayout(dir)
+	if err != nil {
+		t.Fatalf("NewLayout: %v", err)
+	}
+	b, err := store.NewLayout(dir)
+	if err != nil {
+		t.Fatalf("NewLayout: %v", err)
+	}
+	return a, b
+}
+
+func TestLayout_LockExclusive(t *testing.T) {
+	ctx := context.Background()
+	a, b := openTwice(t)
+
+	if err := a.Lock(ctx, store.LockExclusive, 0, "hauler store sync"); err != nil {
+		t.Fatalf("Lock(a): %v", err)
+	}
+	err := b.Lock(ctx, store.LockShared, 100*time.Millisecond, "hauler store info")
+	if err == nil {
+		t.Fatal("Lock(b) succeeded while a held the store exclusively")
+	}
+	for _, want := range []string{fmt.Sprintf("PID [%d]", os.Getpid()), "hauler store sync", "after waiting 100ms"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Lock(b) error %q does not mention %q", err, want)
+		}
+	}
+
+	if err := a.Unlock(); err != nil {
+		t.Fatalf("Unlock(a): %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(a.Root, consts.DefaultStoreLockInfoName)); !os.IsNotExist(err) {
+		t.Errorf("holder record left behind after Unlock: %v", err)
+	}
+	if err := b.Lock(ctx, store.LockExclusive, 0, "hauler store add image"); err != nil {
+		t.Fatalf("Lock(b) after Unlock(a): %v", err)
+	}
+	b.Unlock()
+}
+
+func TestLayout_LockShared(t *testing.T) {
+	ctx := context.Background()
+	a, b := openTwice(t)
+
+	if err := a.Lock(ctx, store.LockShared, 0, "hauler store serve registry"); err != nil {
+		t.Fatalf("Lock(a): %v", err)
+	}
+	defer a.Unlock()
+	if err := b.Lock(ctx, store.LockShared, 0, "hauler store info"); err != nil {
+		t.Fatalf("second shared Lock: %v", err)
+	}
+	defer b.Unlock()
+
+	c, err := store.NewLayout(a.Root)
+	if err != nil {
+		t.Fatalf("NewLayout: %v", err)
+	}
+	if err := c.Lock(ctx, store.LockExclusive, 0, "hauler store remove"); err == nil || !strings.Contains(err.Error(), "without waiting") {
+		t.Errorf("exclusive Lock under readers = %v, want an immediate failure", err)
+	}
+}
+
+// A writer waiting within its timeout gets the store once the holder lets go.
+func TestLayout_LockWaits(t *testing.T) {
+	ctx := context.Background()
+	a, b := openTwice(t)
+
+	if err := a.Lock(ctx, store.LockExclusive, 0, "hauler store load"); err != nil {
+		t.Fatalf("Lock(a): %v", err)
+	}
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		a.Unlock()
+	}()
+	if err := b.Lock(ctx, store.LockExclusive, 5*time.Second, "hauler store sync"); err != nil {
+		t.Fatalf("Lock(b) = %v, want it to wait out a", err)
+	}
+	b.Unlock()
+}
+
+// A reader of a store it can't create the lock file in goes ahead unlocked; a
+// writer does not. The lock file here is a dangling symlink, so opening it
+// with O_CREATE fails the way it does on a read-only store.
+func TestLayout_LockUnopenable(t *testing.T) {
+	ctx := context.Background()
+	s, err := store.NewLayout(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewLayout: %v", err)
+	}
+	lockPath := filepath.Join(s.Root, consts.DefaultStoreLockName)
+	os.Remove(lockPath)
+	if err := os.Symlink(filepath.Join(t.TempDir(), "missing", "lock"), lockPath); err != nil {
+		t.Skipf("Symlink: %v", err)
+	}
+
+	// only a read-only store lets a reader go ahead unlocked
+	if err := s.Lock(ctx, store.LockShared, 0, "hauler store info"); err == nil {
+		s.Unlock()
+		t.Fatal("shared Lock succeeded without a lock file on a writable store")
+	}
+	if err := s.Lock(ctx, store.LockExclusive, 0, "hauler store sync"); err == nil {
+		s.Unlock()
+		t.Fatal("exclusive Lock succeeded without a lock file")
+	}
+}
+
+func TestLayout_LockReadOnly(t *testing.T) {
+	if os.Getuid() == 0 {
+		t.Skip("root can create the lock file in a read-only directory")
+	}
+	ctx := context.Background()
+	s, err := store.NewLayout(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewLayout: %v", err)
+	}
+	os.Remove(filepath.Join(s.Root, consts.DefaultStoreLockName))
+	if err := os.Chmod(s.Root, 0o555); err != nil {
+		t.Fatalf("Chmod: %v", err)
+	}
+	t.Cleanup(func() { os.Chmod(s.Root, 0o755) })
+
+	if err := s.Lock(ctx, store.LockShared, 0, "hauler store info"); err != nil {
+		t.Fatalf("shared Lock = %v, want it to continue unlocked", err)
+	}
+	if err := s.Unlock(); err != nil {
+		t.Fatalf("Unlock: %v", err)
+	}
+	if err := s.Lock(ctx, store.LockExclusive, 0, "hauler store sync"); err == nil {
+		s.Unlock()
+		t.Fatal("exclusive Lock succeeded on a read-only store")
+	}
+}
@@ -0,0 +1,345 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	gname "github.com/google/go-containerregistry/pkg/name"
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+
+	"hauler.dev/go/hauler/v2/pkg/consts"
+	"hauler.dev/go/hauler/v2/pkg/content"
+	"hauler.dev/go/hauler/v2/pkg/reference"
+)
+
+// MergeConflictPolicy decides what Merge does when a source holds a
+// reference the target already has under a different digest.
+type MergeConflictPolicy string
+
+const (
+	// MergeFail refuses the whole merge, before anything is written.
+	MergeFail MergeConflictPolicy = "fail"
+	// MergeKeepExisting leaves the target's artifact in place.
+	MergeKeepExisting MergeConflictPolicy = "keep-existing"
+	// MergeOverwrite replaces the target's artifact with the source's.
+	MergeOverwrite MergeConflictPolicy = "overwrite"
+	// MergeRename stores the source's artifact under its tag suffixed with
+	// the source's short StoreID.
+	MergeRename MergeConflictPolicy = "rename"
+)
+
+// MergeConflictPolicies lists every MergeConflictPolicy, in the order help
+// text names them.
+var MergeConflictPolicies = []MergeConflictPolicy{MergeFail, MergeKeepExisting, MergeOverwrite, MergeRename}
+
+// MergeOptions controls a merge.
+type MergeOptions struct {
+	Conflict MergeConflictPolicy
+}
+
+// Actions a merge takes on a source's index entry.
+const (
+	MergeAdded       = "added"
+	MergeUnchanged   = "unchanged"
+	MergeKept        = "kept-existing"
+	MergeOverwritten = "overwritten"
+	MergeRenamed     = "renamed"
+)
+
+// MergeEntry is one source index entry and what the merge did with it.
+type MergeEntry struct {
+	Reference string `json:"reference"`
+	Kind      string `json:"kind"`
+	Digest    string `json:"digest"`
+	Action    string `json:"action"`
+	// RenamedTo is the reference a renamed entry was stored under.
+	RenamedTo string `json:"renamed-to,omitempty"`
+	// Existing is the digest the target held for a conflicting reference.
+	Existing string `json:"existing,omitempty"`
+}
+
+// MergeResult is what merging one source store did.
+type MergeResult struct {
+	Source   string       `json:"source"`
+	SourceID string       `json:"source-id"`
+	Entries  []MergeEntry `json:"entries"`
+	// Blobs and Bytes count the blobs copied into the target; blobs it
+	// already held aren't copied again.
+	Blobs int   `json:"blobs"`
+	Bytes int64 `json:"bytes"`
+}
+
+// MergeConflictError lists the references a MergeFail merge refused to
+// overwrite.
+type MergeConflictError struct {
+	Conflicts []MergeEntry
+}
+
+func (e *MergeConflictError) Error() string {
+	refs := make([]string, 0, len(e.Conflicts))
+	for _, c := range e.Conflicts {
+		refs = append(refs, fmt.Sprintf("%s (%s != %s)", c.Reference, c.Digest, c.Existing))
+	}
+	return fmt.Sprintf("[%d] reference(s) already stored with a different digest: %s", len(e.Conflicts), strings.Join(refs, ", "))
+}
+
+// mergeItem is a planned write of one source entry into the target.
+type mergeItem struct {
+	src   *Layout
+	desc  ocispec.Descriptor // as it will be indexed in the target
+	blobs map[digest.Digest]int64
+	entry int // index into its result's Entries
+}
+
+// Merge copies every index entry of sources, and the blobs each reaches,
+// into l, in order: a later source sees what an earlier one added. An entry
+// and everything saved for it under the same reference (signatures,
+// attestations, sboms, referrers) are merged as one, so a conflict on any of
+// them applies opts.Conflict to all of them.
+//
+// Every source is planned before anything is written, so a MergeFail merge
+// that finds a conflict returns a *MergeConflictError and leaves l as it was.
+func (l *Layout) Merge(ctx context.Context, sources []*Layout, opts MergeOptions) ([]*MergeResult, error) {
+	target := map[string]ocispec.Descriptor{}
+	if err := l.OCI.Walk(func(key string, desc ocispec.Descriptor) error {
+		target[key] = desc
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("failed to walk artifacts: %w", err)
+	}
+
+	var results []*MergeResult
+	var items []mergeItem
+	var conflicts []MergeEntry
+	for _, src := range sources {
+		if src.Root == l.Root {
+			return nil, fmt.Errorf("cannot merge store [%s] into itself", src.Root)
+		}
+		res := &MergeResult{Source: src.Root, SourceID: src.StoreID, Entries: []MergeEntry{}}
+		results = append(results, res)
+
+		groups, err := mergeGroups(src)
+		if err != nil {
+			return nil, err
+		}
+		for _, group := range groups {
+			conflict := false
+			for _, desc := range group {
+				key, _ := content.IndexKey(desc)
+				if existing, ok := target[key]; ok && existing.Digest != desc.Digest {
+					conflict = true
+					if opts.Conflict == MergeFail {
+						conflicts = append(conflicts, MergeEntry{
+							Reference: desc.Annotations[ocispec.AnnotationRefName],
+							Kind:      desc.Annotations[consts.KindAnnotationName],
+							Digest:    desc.Digest.String(),
+							Existing:  existing.Digest.String(),
+						})
+					}
+				}
+			}
+
+			renamed := ""
+			if conflict && opts.Conflict == MergeRename {
+				renamed, err = mergeRename(group, src.StoreID, target)
+				if err != nil {
+					return nil, err
+				}
+			}
+
+			for _, desc := range group {
+				key, _ := content.IndexKey(desc)
+				existing, exists := target[key]
+				e := MergeEntry{
+					Reference: desc.Annotations[ocispec.AnnotationRefName],
+					Kind:      desc.Annotations[consts.KindAnnotationName],
+					Digest:    desc.Digest.String(),
+					Action:    MergeAdded,
+				}
+				switch {
+				case conflict && opts.Conflict == MergeKeepExisting:
+					e.Action = MergeKept
+				case conflict && opts.Conflict == MergeRename:
+					e.Action = MergeRenamed
+					e.RenamedTo = renamed
+					desc = renameDescriptor(desc, renamed)
+					key, _ = content.IndexKey(desc)
+				case exists && existing.Digest == desc.Digest:
+					e.Action = MergeUnchanged
+				case exists:
+					e.Action = MergeOverwritten
+				}
+				if exists && existing.Digest != desc.Digest {
+					e.Existing = existing.Digest.String()
+				}
+				res.Entries = append(res.Entries, e)
+
+				if e.Action == MergeKept || e.Action == MergeUnchanged {
+					continue
+				}
+				blobs, err := src.Blobs(ctx, desc)
+				if err != nil {
+					return nil, fmt.Errorf("cannot read manifest [%s] of [%s] in [%s]: %w", desc.Digest, e.Reference, src.Root, err)
+				}
+				target[key] = desc
+				items = append(items, mergeItem{src: src, desc: desc, blobs: blobs, entry: len(res.Entries) - 1})
+			}
+		}
+	}
+	if len(conflicts) > 0 {
+		return results, &MergeConflictError{Conflicts: conflicts}
+	}
+
+	for i, src := range sources {
+		res := results[i]
+		for _, item := range items {
+			if item.src != src {
+				continue
+			}
+			for d, size := range item.blobs {
+				copied, err := l.mergeBlob(ctx, src, d, size)
+				if err != nil {
+					return results, fmt.Errorf("failed to copy blob [%s] of [%s]: %w", d, res.Entries[item.entry].Reference, err)
+				}
+				if copied {
+					res.Blobs++
+					res.Bytes += size
+				}
+			}
+			// blobs first, so an interrupted merge never indexes an entry
+			// whose content isn't all there
+			if err := l.OCI.AddIndex(item.desc); err != nil {
+				return results, err
+			}
+		}
+	}
+	if err := l.OCI.SaveIndex(); err != nil {
+		return results, fmt.Errorf("failed to save index: %w", err)
+	}
+	return results, nil
+}
+
+// mergeGroups returns src's index entries grouped by reference, each
+// group's image (or chart, or file) first, in reference order.
+func mergeGroups(src *Layout) ([][]ocispec.Descriptor, error) {
+	byRef := map[string][]ocispec.Descriptor{}
+	if err := src.OCI.Walk(func(_ string, desc ocispec.Descriptor) error {
+		if refName := desc.Annotations[ocispec.AnnotationRefName]; refName != "" {
+			byRef[refName] = append(byRef[refName], desc)
+		}
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("failed to walk artifacts in [%s]: %w", src.Root, err)
+	}
+
+	refs := make([]string, 0, len(byRef))
+	for ref := range byRef {
+		refs = append(refs, ref)
+	}
+	sort.Strings(refs)
+
+	groups := make([][]ocispec.Descriptor, 0, len(refs))
+	for _, ref := range refs {
+		group := byRef[ref]
+		SortRelatedLast(group)
+		groups = append(groups, group)
+	}
+	return groups, nil
+}
+
+// SortRelatedLast orders the index entries stored under one reference with
+// the artifact itself first and its signatures, attestations, sboms, and
+// referrers after it, by kind.
+func SortRelatedLast(group []ocispec.Descriptor) {
+	sort.Slice(group, func(i, j int) bool {
+		ri := isRelatedKind(group[i].Annotations[consts.KindAnnotationName])
+		rj := isRelatedKind(group[j].Annotations[consts.KindAnnotationName])
+		if ri != rj {
+			return !ri
+		}
+		return group[i].Annotations[consts.KindAnnotationName] < group[j].Annotations[consts.KindAnnotationName]
+	})
+}
+
+// mergeRename picks the reference a conflicting group is stored under: its
+// tag suffixed with the source's short StoreID, then a counter if that's
+// taken too.
+func mergeRename(group []ocispec.Descriptor, storeID string, target map[string]ocispec.Descriptor) (string, error) {
+	refName := group[0].Annotations[ocispec.AnnotationRefName]
+	ref, err := reference.Parse(refName)
+	if err != nil {
+		return "", fmt.Errorf("cannot rename [%s]: %w", refName, err)
+	}
+	if _, ok := ref.(gname.Tag); !ok {
+		return "", fmt.Errorf("cannot rename [%s]: a digest reference has no tag to rename", refName)
+	}
+
+	short := storeID
+	if len(short) > 8 {
+		short = short[:8]
+	}
+	for n := 1; ; n++ {
+		suffix := short
+		if n > 1 {
+			suffix = fmt.Sprintf("%s-%d", short, n)
+		}
+		renamed := refName + "-" + suffix
+		free := true
+		for _, desc := range group {
+			key, _ := content.IndexKey(renameDescriptor(desc, renamed))
+			if _, taken := target[key]; taken {
+				free = false
+				break
+			}
+		}
+		if free {
+			return renamed, nil
+		}
+	}
+}
+
+// renameDescriptor returns a copy of desc indexed under refName, with its
+// registry-qualified name following.
+func renameDescriptor(desc ocispec.Descriptor, refName string) ocispec.Descriptor {
+	old := desc.Annotations[ocispec.AnnotationRefName]
+	var fullName string
+	if full := desc.Annotations[consts.ContainerdImageNameKey]; strings.HasSuffix(full, old) {
+		fullName = strings.TrimSuffix(full, old) + refName
+	}
+	return RenameDescriptor(desc, refName, fullName)
+}
+
+// RenameDescriptor returns a copy of desc indexed under refName, and under
+// fullName too if desc carries a full image name and fullName isn't empty.
+// The original reference annotation is kept.
+func RenameDescriptor(desc ocispec.Descriptor, refName, fullName string) ocispec.Descriptor {
+	annotations := make(map[string]string, len(desc.Annotations))
+	for k, v := range desc.Annotations {
+		annotations[k] = v
+	}
+	annotations[ocispec.AnnotationRefName] = refName
+	if _, ok := annotations[consts.ContainerdImageNameKey]; ok && fullName != "" {
+		annotations[consts.ContainerdImageNameKey] = fullName
+	}
+	desc.Annotations = annotations
+	return desc
+}
+
+// mergeBlob copies blob d from src into l, reporting whether it had to:
+// WriteBlob skips a blob l already holds.
+func (l *Layout) mergeBlob(ctx context.Context, src *Layout, d digest.Digest, size int64) (bool, error) {
+	dst := filepath.Join(l.Root, ocispec.ImageBlobsDir, d.Algorithm().String(), d.Encoded())
+	if info, err := os.Stat(dst); err == nil && info.Size() == size {
+		return false, nil
+	}
+	err := l.OCI.WriteBlob(ctx, d, size, func() (io.ReadCloser, error) {
+		return os.Open(filepath.Join(src.Root, ocispec.ImageBlobsDir, d.Algorithm().String(), d.Encoded()))
+	})
+	return err == nil, err
+}
@@ -0,0 +1,88 @@
+package store_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+
+	"hauler.dev/go/hauler/v2/pkg/store"
+)
+
+// indexedDigests maps each reference in s's index to its digest.
+func indexedDigests(t *testing.T, s *store.Layout) map[string]string {
+	t.Helper()
+	refs := map[string]string{}
+	if err := s.OCI.Walk(func(_ string, desc ocispec.Descriptor) error {
+		refs[desc.Annotations[ocispec.AnnotationRefName]] = desc.Digest.String()
+		return nil
+	}); err != nil {
+		t.Fatalf("Walk: %v", err)
+	}
+	return refs
+}
+
+func TestMerge(t *testing.T) {
+	ctx := context.Background()
+	host, opts := newCheckTestRegistry(t)
+
+	a := newCheckTestStore(t)
+	only := pushAndAddImage(t, a, host, "merge/only", "v1", opts)
+	shared := pushAndAddImage(t, a, host, "merge/shared", "v1", opts)
+
+	target := newCheckTestStore(t)
+	pushAndAddImage(t, target, host, "merge/shared", "v1", opts) // re-pushed, so same tag, new digest
+	pushAndAddImage(t, target, host, "merge/local", "v1", opts)
+	before := indexedDigests(t, target)
+
+	var conflictErr *store.MergeConflictError
+	if _, err := target.Merge(ctx, []*store.Layout{a}, store.MergeOptions{Conflict: store.MergeFail}); !errors.As(err, &conflictErr) {
+		t.Fatalf("Merge(fail) = %v, want a conflict on merge/shared:v1", err)
+	}
+	if after := indexedDigests(t, target); len(after) != len(before) {
+		t.Errorf("a refused merge changed the index: %v", after)
+	}
+	if _, err := os.Stat(blobPath(target.Root, only.Digest)); !os.IsNotExist(err) {
+		t.Errorf("a refused merge copied blobs")
+	}
+
+	res, err := target.Merge(ctx, []*store.Layout{a}, store.MergeOptions{Conflict: store.MergeKeepExisting})
+	if err != nil {
+		t.Fatalf("Merge(keep-existing): %v", err)
+	}
+	after := indexedDigests(t, target)
+	if after["merge/shared:v1"] != before["merge/shared:v1"] || after["merge/only:v1"] != only.Digest.String() {
+		t.Errorf("keep-existing index = %v", after)
+	}
+	// a manifest, a config, and two layers
+	if res[0].Blobs != 4 {
+		t.Errorf("copied %d blobs, want the 4 of merge/only", res[0].Blobs)
+	}
+	if check := target.NewChecker().Check(ctx, only); !check.OK {
+		t.Errorf("merged image is damaged: %+v", check.Problems)
+	}
+
+	res, err = target.Merge(ctx, []*store.Layout{a}, store.MergeOptions{Conflict: store.MergeRename})
+	if err != nil {
+		t.Fatalf("Merge(rename): %v", err)
+	}
+	renamed := "merge/shared:v1-" + a.StoreID[:8]
+	after = indexedDigests(t, target)
+	if after[renamed] != shared.Digest.String() || after["merge/shared:v1"] != before["merge/shared:v1"] {
+		t.Errorf("rename index = %v, want merge/shared:v1 kept and %s added", after, renamed)
+	}
+	for _, e := range res[0].Entries {
+		if e.Reference == "merge/only:v1" && e.Action != store.MergeUnchanged {
+			t.Errorf("merge/only:v1 action = %s on a second merge, want unchanged", e.Action)
+		}
+	}
+
+	if _, err := target.Merge(ctx, []*store.Layout{a}, store.MergeOptions{Conflict: store.MergeOverwrite}); err != nil {
+		t.Fatalf("Merge(overwrite): %v", err)
+	}
+	if got := indexedDigests(t, target)["merge/shared:v1"]; got != shared.Digest.String() {
+		t.Errorf("overwrite left merge/shared:v1 at %s, want %s", got, shared.Digest)
+	}
+}
@@ -0,0 +1,680 @@
+package store
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	gname "github.com/google/go-containerregistry/pkg/name"
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+
+	"hauler.dev/go/hauler/v2/pkg/consts"
+)
+
+// Selector keys. A label is selected with its own key, "label.<name>".
+const (
+	SelectRef      = "ref"
+	SelectType     = "type"
+	SelectKind     = "kind"
+	SelectPlatform = "platform"
+	SelectRegistry = "registry"
+	SelectDigest   = "digest"
+	SelectSize     = "size"
+	SelectAdded    = "added"
+	SelectLabel    = "label."
+)
+
+// ArtifactTypes lists the content types ArtifactType gives, in the order help
+// text names them.
+var ArtifactTypes = []string{"image", "chart", "file", "sigs", "atts", "sbom", "referrer"}
+
+// selector operators, longest first so "!=" isn't read as "!" then "="
+var selectorOps = []string{"!=", "!~", "<=", ">=", "=", "~", "<", ">"}
+
+// Selector picks index entries out of a store. It is a comma-separated list
+// of terms that must all match, each a key, an operator, and a value:
+//
+//	ref=*/nginx:*            glob on the stored or full image name
+//	ref~^library/            regex on the same
+//	type=image               image | chart | file | sigs | atts | sbom | referrer
+//	kind=*/imageIndex        glob on the kind annotation
+//	platform=linux/arm*      glob on any platform of an image or index
+//	registry=docker.io       glob on the registry the artifact was added from
+//	digest=sha256:4b1c       digest prefix, with or without the algorithm
+//	size>1G                  total size of every blob the entry reaches
+//	added<30d                added before 30 days ago, or before a date (2006-01-02)
+//	label.team=infra         glob on a user label; "label.team" alone checks it's set
+//
+// "!=" and "!~" negate a glob or a regex, and a leading "!" negates any term.
+// A comma inside a value is written "\,". An artifact whose added time isn't
+// known -- nothing in the store's audit log or its annotations records it --
+// never matches an added term, so "!added<30d" selects it.
+type Selector struct {
+	expr  string
+	terms []selectorTerm
+}
+
+type selectorTerm struct {
+	key    string
+	op     string
+	negate bool
+	value  string
+
+	re   *regexp.Regexp
+	size int64
+	when time.Time
+}
+
+// ParseSelector parses expr, taking durations in added terms back from now.
+func ParseSelector(expr string) (*Selector, error) {
+	return parseSelector(expr, time.Now())
+}
+
+func parseSelector(expr string, now time.Time) (*Selector, error) {
+	sel := &Selector{expr: expr}
+	for _, raw := range splitSelector(expr) {
+		raw = strings.TrimSpace(raw)
+		if raw == "" {
+			continue
+		}
+		t, err := parseSelectorTerm(raw, now)
+		if err != nil {
+			return nil, fmt.Errorf("invalid selector term [%s]: %w", raw, err)
+		}
+		sel.terms = append(sel.terms, t)
+	}
+	if len(sel.terms) == 0 {
+		return nil, fmt.Errorf("empty selector [%s]", expr)
+	}
+	return sel, nil
+}
+
+// splitSelector splits expr on the commas that aren't escaped.
+func splitSelector(expr string) []string {
+	var terms []string
+	var b strings.Builder
+	for i := 0; i < len(expr); i++ {
+		switch {
+		case expr[i] == '\\' && i+1 < len(expr) && expr[i+1] == ',':
+			b.WriteByte(',')
+			i++
+		case expr[i] == ',':
+			terms = append(terms, b.String())
+			b.Reset()
+		default:
+			b.WriteByte(expr[i])
+		}
+	}
+	return append(terms, b.String())
+}
+
+func parseSelectorTerm(raw string, now time.Time) (selectorTerm, error) {
+	var t selectorTerm
+	if strings.HasPrefix(raw, "!") {
+		t.negate = true
+		raw = raw[1:]
+	}
+
+	i := strings.IndexAny(raw, "=!~<>")
+	if i == -1 {
+		// a bare label key checks that the label is set
+		if key := selectorKey(raw); strings.HasPrefix(key, SelectLabel) && len(key) > len(SelectLabel) {
+			t.key = key
+			return t, nil
+		}
+		return t, fmt.Errorf("missing operator... want <key><op><value>")
+	}
+	t.key = selectorKey(raw[:i])
+	for _, op := range selectorOps {
+		if strings.HasPrefix(raw[i:], op) {
+			t.op = op
+			break
+		}
+	}
+	if t.op == "" {
+		return t, fmt.Errorf("unknown operator at [%s]", raw[i:])
+	}
+	t.value = strings.TrimSpace(raw[i+len(t.op):])
+	if t.value == "" {
+		return t, fmt.Errorf("missing value")
+	}
+	if t.op == "!=" || t.op == "!~" {
+		t.negate = !t.negate
+		t.op = t.op[1:]
+	}
+
+	switch key := t.key; {
+	case key == SelectRef || key == SelectKind || key == SelectPlatform ||
+		(strings.HasPrefix(key, SelectLabel) && len(key) > len(SelectLabel)):
+		return t, t.compileMatch()
+
+	case key == SelectRegistry:
+		if t.op == "=" {
+			t.value = normalizeRegistry(t.value)
+		}
+		return t, t.compileMatch()
+
+	case key == SelectType:
+		if !strings.ContainsAny(t.value, "*?") && !slices.Contains(ArtifactTypes, t.value) {
+			return t, fmt.Errorf("type must be one of %v", ArtifactTypes)
+		}
+		return t, t.compileMatch()
+
+	case key == SelectDigest:
+		if t.op != "=" {
+			return t, fmt.Errorf("digest takes = or != a digest prefix")
+		}
+		t.value = strings.ToLower(t.value)
+		return t, nil
+
+	case key == SelectSize:
+		if t.op == "~" {
+			return t, fmt.Errorf("size takes =, !=, <, <=, >, or >= a size")
+		}
+		size, err := parseSelectorSize(t.value)
+		if err != nil {
+			return t, err
+		}
+		t.size = size
+		return t, nil
+
+	case key == SelectAdded:
+		if t.op == "=" || t.op == "~" {
+			return t, fmt.Errorf("added takes <, <=, >, or >= a duration (30d, 12h) or a date (2006-01-02)")
+		}
+		when, err := parseSelectorTime(t.value, now)
+		if err != nil {
+			return t, err
+		}
+		t.when = when
+		return t, nil
+
+	default:
+		return t, fmt.Errorf("unknown key [%s]... want ref, type, kind, platform, registry, digest, size, added, or label.<name>", key)
+	}
+}
+
+// selectorKey normalizes a term's key. Keys are case-insensitive, but the
+// name in "label.<name>" is kept as written: label keys are case-sensitive.
+func selectorKey(raw string) string {
+	key := strings.TrimSpace(raw)
+	if len(key) > len(SelectLabel) && strings.EqualFold(key[:len(SelectLabel)], SelectLabel) {
+		return SelectLabel + key[len(SelectLabel):]
+	}
+	return strings.ToLower(key)
+}
+
+// compileMatch compiles a glob or regex term. Globs are anchored and their
+// "*" crosses "/", so "ref=*nginx*" matches "library/nginx:1.25".
+func (t *selectorTerm) compileMatch() error {
+	if t.op == "~" {
+		re, err := regexp.Compile(t.value)
+		if err != nil {
+			return err
+		}
+		t.re = re
+		return nil
+	}
+	if t.op != "=" {
+		return fmt.Errorf("%s takes =, !=, ~, or !~", t.key)
+	}
+
+	var b strings.Builder
+	b.WriteString("^")
+	for _, r := range t.value {
+		switch r {
+		case '*':
+			b.WriteString(".*")
+		case '?':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	b.WriteString("$")
+	t.re = regexp.MustCompile(b.String())
+	return nil
+}
+
+// parseSelectorSize parses a byte count with an optional K, M, G, or T
+// suffix (with or without a trailing B), in powers of 1024.
+func parseSelectorSize(s string) (int64, error) {
+	units := []struct {
+		suffix string
+		mult   float64
+	}{
+		{"KB", 1 << 10}, {"MB", 1 << 20}, {"GB", 1 << 30}, {"TB", 1 << 40},
+		{"K", 1 << 10}, {"M", 1 << 20}, {"G", 1 << 30}, {"T", 1 << 40},
+		{"B", 1},
+	}
+	s = strings.ToUpper(strings.TrimSpace(s))
+	mult := 1.0
+	for _, u := range units {
+		if strings.HasSuffix(s, u.suffix) {
+			s = strings.TrimSuffix(s, u.suffix)
+			mult = u.mult
+			break
+		}
+	}
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size [%s]", s)
+	}
+	return int64(n * mult), nil
+}
+
+// parseSelectorTime parses a duration back from now (30d, 2w, 12h, 90m) or a
+// date (2006-01-02) or timestamp (RFC 3339).
+func parseSelectorTime(s string, now time.Time) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.DateOnly, s); err == nil {
+		return t, nil
+	}
+
+	units := map[byte]time.Duration{'d': 24 * time.Hour, 'w': 7 * 24 * time.Hour}
+	if mult, ok := units[s[len(s)-1]]; ok {
+		n, err := strconv.ParseFloat(s[:len(s)-1], 64)
+		if err != nil || n < 0 {
+			return time.Time{}, fmt.Errorf("invalid duration [%s]", s)
+		}
+		return now.Add(-time.Duration(n * float64(mult))), nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return time.Time{}, fmt.Errorf("invalid duration or date [%s]... want e.g. 30d, 12h, or 2006-01-02", s)
+	}
+	return now.Add(-d), nil
+}
+
+func (sel *Selector) String() string { return sel.expr }
+
+// uses reports whether any term of sel has key.
+func (sel *Selector) uses(key string) bool {
+	for _, t := range sel.terms {
+		if t.key == key || (key == SelectLabel && strings.HasPrefix(t.key, SelectLabel)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Selected is one index entry a Selector picked.
+type Selected struct {
+	// Key is the entry's index key, the reference Walk passes.
+	Key  string
+	Desc ocispec.Descriptor
+	// Type is the entry's content type, as ArtifactType gives it.
+	Type string
+}
+
+// SelectOptions controls Select.
+type SelectOptions struct {
+	// Related also selects the signatures, attestations, sboms, and referrers
+	// saved under every selected artifact's reference, so removing or copying
+	// an image doesn't leave them behind.
+	Related bool
+}
+
+// artifactFacts is what a Selector matches an index entry against. Related
+// artifacts take the registry, added time, and labels of the artifact they
+// were saved for when they don't carry their own.
+type artifactFacts struct {
+	names     []string
+	typ       string
+	kind      string
+	digest    string
+	platforms []string
+	registry  string
+	size      int64
+	added     time.Time
+	labels    map[string]string
+}
+
+// Select returns every index entry of l that sel matches, grouped by
+// reference with each group's artifact first. A nil sel selects everything.
+func (l *Layout) Select(ctx context.Context, sel *Selector, opts SelectOptions) ([]Selected, error) {
+	type entry struct {
+		Selected
+		facts artifactFacts
+	}
+	var entries []*entry
+	if err := l.OCI.Walk(func(key string, desc ocispec.Descriptor) error {
+		entries = append(entries, &entry{Selected: Selected{Key: key, Desc: desc}})
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("failed to walk artifacts: %w", err)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		ri, rj := entries[i].Desc.Annotations[ocispec.AnnotationRefName], entries[j].Desc.Annotations[ocispec.AnnotationRefName]
+		if ri != rj {
+			return ri < rj
+		}
+		ki, kj := entries[i].Desc.Annotations[consts.KindAnnotationName], entries[j].Desc.Annotations[consts.KindAnnotationName]
+		if isRelatedKind(ki) != isRelatedKind(kj) {
+			return !isRelatedKind(ki)
+		}
+		return entries[i].Key < entries[j].Key
+	})
+
+	var added map[digest.Digest]time.Time
+	if sel != nil && sel.uses(SelectAdded) {
+		var err error
+		if added, err = l.addedTimes(); err != nil {
+			return nil, err
+		}
+	}
+
+	subjects := map[string]*artifactFacts{}
+	for _, e := range entries {
+		e.Type = l.ArtifactType(ctx, e.Desc)
+		e.facts = l.facts(ctx, sel, e.Desc, e.Type, added)
+		refName := e.Desc.Annotations[ocispec.AnnotationRefName]
+		if !isRelatedKind(e.Desc.Annotations[consts.KindAnnotationName]) {
+			subjects[refName] = &e.facts
+			continue
+		}
+		if subject, ok := subjects[refName]; ok {
+			if e.facts.registry == "" {
+				e.facts.registry = subject.registry
+			}
+			if e.facts.added.IsZero() {
+				e.facts.added = subject.added
+			}
+			for k, v := range subject.labels {
+				if _, ok := e.facts.labels[k]; !ok {
+					e.facts.labels[k] = v
+				}
+			}
+		}
+	}
+
+	matched := map[string]bool{}
+	for _, e := range entries {
+		if sel == nil || sel.match(&e.facts) {
+			matched[e.Key] = true
+			if opts.Related && !isRelatedKind(e.Desc.Annotations[consts.KindAnnotationName]) {
+				matched["ref|"+e.Desc.Annotations[ocispec.AnnotationRefName]] = true
+			}
+		}
+	}
+
+	var selected []Selected
+	for _, e := range entries {
+		related := isRelatedKind(e.Desc.Annotations[consts.KindAnnotationName]) && matched["ref|"+e.Desc.Annotations[ocispec.AnnotationRefName]]
+		if matched[e.Key] || related {
+			selected = append(selected, e.Selected)
+		}
+	}
+	return selected, nil
+}
+
+// facts gathers what sel needs to know about desc; the platform and size,
+// which mean reading manifests, only when sel asks for them.
+func (l *Layout) facts(ctx context.Context, sel *Selector, desc ocispec.Descriptor, typ string, added map[digest.Digest]time.Time) artifactFacts {
+	f := artifactFacts{
+		typ:    typ,
+		kind:   desc.Annotations[consts.KindAnnotationName],
+		digest: desc.Digest.String(),
+		labels: Labels(desc),
+	}
+	for _, k := range []string{ocispec.AnnotationRefName, consts.ContainerdImageNameKey} {
+		if v := desc.Annotations[k]; v != "" {
+			f.names = append(f.names, v)
+		}
+	}
+	f.registry = artifactRegistry(desc)
+
+	if sel == nil {
+		return f
+	}
+	if sel.uses(SelectAdded) {
+		f.added = added[desc.Digest]
+		if f.added.IsZero() {
+			f.added, _ = time.Parse(time.RFC3339, desc.Annotations[ocispec.AnnotationCreated])
+		}
+	}
+	if sel.uses(SelectPlatform) {
+		f.platforms = l.Platforms(ctx, desc)
+	}
+	if sel.uses(SelectSize) {
+		if blobs, err := l.Blobs(ctx, desc); err == nil {
+			for _, size := range blobs {
+				f.size += size
+			}
+		}
+	}
+	return f
+}
+
+// artifactRegistry is the registry (or, for charts and files, the host) an
+// artifact was added from, or "" for local content.
+func artifactRegistry(desc ocispec.Descriptor) string {
+	orig := desc.Annotations[consts.OriginalRefAnnotation]
+	if orig == "" {
+		orig = desc.Annotations[consts.ContainerdImageNameKey]
+	}
+	if orig == "" {
+		return ""
+	}
+
+	// charts record "repoURL|repo:tag"
+	if repoURL, _, ok := strings.Cut(orig, "|"); ok {
+		orig = repoURL
+	}
+	if strings.Contains(orig, "://") {
+		u, err := url.Parse(orig)
+		if err != nil {
+			return ""
+		}
+		return normalizeRegistry(u.Host)
+	}
+	if _, ok := desc.Annotations[consts.ContainerdImageNameKey]; !ok || filepath.IsAbs(orig) {
+		return ""
+	}
+	ref, err := gname.ParseReference(orig)
+	if err != nil {
+		return ""
+	}
+	return normalizeRegistry(ref.Context().RegistryStr())
+}
+
+// normalizeRegistry names Docker Hub the way users write it.
+func normalizeRegistry(registry string) string {
+	if registry == gname.DefaultRegistry {
+		return "docker.io"
+	}
+	return registry
+}
+
+// Platforms lists the os/arch[/variant] of every image desc holds: each
+// child of an index, or the image itself.
+func (l *Layout) Platforms(ctx context.Context, desc ocispec.Descriptor) []string {
+	var platforms []string
+	switch desc.MediaType {
+	case ocispec.MediaTypeImageIndex, consts.DockerManifestListSchema2:
+		var idx ocispec.Index
+		if err := l.fetchJSON(ctx, desc, &idx); err != nil {
+			return nil
+		}
+		for _, child := range idx.Manifests {
+			if child.Platform != nil {
+				platforms = append(platforms, platformString(*child.Platform))
+			}
+		}
+	default:
+		var m ocispec.Manifest
+		if err := l.fetchJSON(ctx, desc, &m); err != nil {
+			return nil
+		}
+		if m.Config.MediaType != ocispec.MediaTypeImageConfig && m.Config.MediaType != consts.DockerConfigJSON {
+			return nil
+		}
+		var p ocispec.Platform
+		if err := l.fetchJSON(ctx, m.Config, &p); err == nil && p.OS != "" {
+			platforms = append(platforms, platformString(p))
+		}
+	}
+	return platforms
+}
+
+func platformString(p ocispec.Platform) string {
+	s := p.OS + "/" + p.Architecture
+	if p.Variant != "" {
+		s += "/" + p.Variant
+	}
+	return s
+}
+
+// auditLogName is the store's audit log, audit.LogFileName; pkg/audit can't
+// be imported here without a cycle.
+const auditLogName = "audit.log"
+
+// addedTimes reads when each digest was added from the store's audit log:
+// the first entry recording it since it was last removed.
+func (l *Layout) addedTimes() (map[digest.Digest]time.Time, error) {
+	added := map[digest.Digest]time.Time{}
+	fi, err := os.Open(filepath.Join(l.Root, auditLogName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return added, nil
+		}
+		return nil, err
+	}
+	defer fi.Close()
+
+	scanner := bufio.NewScanner(fi)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var e struct {
+			Timestamp string `json:"timestamp"`
+			Command   string `json:"command"`
+			Digest    string `json:"digest"`
+			Rejected  string `json:"rejected"`
+		}
+		if json.Unmarshal(scanner.Bytes(), &e) != nil || e.Digest == "" || e.Rejected != "" {
+			continue
+		}
+		d := digest.Digest(e.Digest)
+		if e.Command == "store remove" {
+			delete(added, d)
+			continue
+		}
+		if _, ok := added[d]; ok {
+			continue
+		}
+		if ts, err := time.Parse(time.RFC3339, e.Timestamp); err == nil {
+			added[d] = ts
+		}
+	}
+	return added, scanner.Err()
+}
+
+// match reports whether every term of sel matches f.
+func (sel *Selector) match(f *artifactFacts) bool {
+	for _, t := range sel.terms {
+		if t.match(f) == t.negate {
+			return false
+		}
+	}
+	return true
+}
+
+func (t selectorTerm) match(f *artifactFacts) bool {
+	switch {
+	case t.key == SelectRef:
+		return t.matchAny(f.names)
+	case t.key == SelectType:
+		return t.re.MatchString(f.typ)
+	case t.key == SelectKind:
+		return t.re.MatchString(f.kind)
+	case t.key == SelectPlatform:
+		return t.matchAny(f.platforms)
+	case t.key == SelectRegistry:
+		return f.registry != "" && t.re.MatchString(f.registry)
+	case t.key == SelectDigest:
+		if strings.Contains(t.value, ":") {
+			return strings.HasPrefix(f.digest, t.value)
+		}
+		_, hex, _ := strings.Cut(f.digest, ":")
+		return strings.HasPrefix(hex, t.value)
+	case t.key == SelectSize:
+		return compare(t.op, f.size, t.size)
+	case t.key == SelectAdded:
+		if f.added.IsZero() {
+			return false
+		}
+		return compare(t.op, f.added.Unix(), t.when.Unix())
+	case strings.HasPrefix(t.key, SelectLabel):
+		v, ok := f.labels[strings.TrimPrefix(t.key, SelectLabel)]
+		if t.op == "" || !ok {
+			return ok
+		}
+		return t.re.MatchString(v)
+	}
+	return false
+}
+
+func (t selectorTerm) matchAny(values []string) bool {
+	for _, v := range values {
+		if t.re.MatchString(v) {
+			return true
+		}
+	}
+	return false
+}
+
+func compare(op string, a, b int64) bool {
+	switch op {
+	case "<":
+		return a < b
+	case "<=":
+		return a <= b
+	case ">":
+		return a > b
+	case ">=":
+		return a >= b
+	default:
+		return a == b
+	}
+}
+
+// ArtifactType is the content type `store info` shows for desc: the related
+// kinds by their annotation, everything else by its manifest's config media
+// type, since files and charts are stored under the same kind.
+func (l *Layout) ArtifactType(ctx context.Context, desc ocispec.Descriptor) string {
+	switch kind := desc.Annotations[consts.KindAnnotationName]; {
+	case kind == consts.KindAnnotationSigs:
+		return "sigs"
+	case kind == consts.KindAnnotationAtts:
+		return "atts"
+	case kind == consts.KindAnnotationSboms:
+		return "sbom"
+	case strings.HasPrefix(kind, consts.KindAnnotationReferrers):
+		return "referrer"
+	}
+	if desc.MediaType == ocispec.MediaTypeImageIndex || desc.MediaType == consts.DockerManifestListSchema2 {
+		return "image"
+	}
+
+	switch l.Identify(ctx, desc) {
+	case consts.ChartConfigMediaType:
+		return "chart"
+	case consts.FileLocalConfigMediaType, consts.FileHttpConfigMediaType, consts.FileDirectoryConfigMediaType:
+		return "file"
+	default:
+		return "image"
+	}
+}
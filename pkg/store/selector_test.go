@@ -0,0 +1,166 @@
+package store_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+
+	"hauler.dev/go/hauler/v2/pkg/consts"
+	"hauler.dev/go/hauler/v2/pkg/store"
+)
+
+func TestParseSelector_Invalid(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"nginx",
+		"owner=me",
+		"type=imgae",
+		"ref~(",
+		"size~1G",
+		"size>lots",
+		"added=30d",
+		"added<yesterday",
+		"digest>sha256:ab",
+		"ref=",
+	} {
+		if _, err := store.ParseSelector(expr); err == nil {
+			t.Errorf("ParseSelector(%q) = nil error, want one", expr)
+		}
+	}
+}
+
+func TestSelect(t *testing.T) {
+	ctx := context.Background()
+	host, opts := newCheckTestRegistry(t)
+	s := newCheckTestStore(t)
+
+	img, err := random.Image(256, 2)
+	if err != nil {
+		t.Fatalf("random.Image: %v", err)
+	}
+	cf, err := img.ConfigFile()
+	if err != nil {
+		t.Fatalf("ConfigFile: %v", err)
+	}
+	cf = cf.DeepCopy()
+	cf.OS, cf.Architecture, cf.Variant = "linux", "arm64", "v8"
+	if img, err = mutate.ConfigFile(img, cf); err != nil {
+		t.Fatalf("mutate.ConfigFile: %v", err)
+	}
+	app := pushAndAddExistingImage(t, s, host, "select/app", "v1", img, opts)
+	tool := pushAndAddImage(t, s, host, "select/tool", "v1", opts)
+
+	if _, err := s.OCI.UpdateAnnotations(func(desc ocispec.Descriptor) bool {
+		return desc.Digest == tool.Digest
+	}, func(a map[string]string) {
+		a[consts.LabelAnnotationPrefix+"team"] = "infra"
+		a[consts.LabelAnnotationPrefix+"Owner"] = "ops"
+	}); err != nil {
+		t.Fatalf("UpdateAnnotations: %v", err)
+	}
+
+	// the store's audit log is what records when each was added
+	log := fmt.Sprintf(`{"timestamp":"2026-01-01T00:00:00Z","command":"store add image","digest":%q}
+{"timestamp":"2026-06-01T00:00:00Z","command":"store add image","digest":%q}
+`, app.Digest, tool.Digest)
+	if err := os.WriteFile(filepath.Join(s.Root, "audit.log"), []byte(log), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		expr string
+		want []string
+	}{
+		{"ref=select/app:*", []string{"select/app:v1"}},
+		{"ref=*tool*", []string{"select/tool:v1"}},
+		{"ref~^select/", []string{"select/app:v1", "select/tool:v1"}},
+		{"ref!~app", []string{"select/tool:v1"}},
+		{"!ref=*app*", []string{"select/tool:v1"}},
+		{"type=image", []string{"select/app:v1", "select/tool:v1"}},
+		{"type=chart", nil},
+		{"registry=" + host, []string{"select/app:v1", "select/tool:v1"}},
+		{"registry=docker.io", nil},
+		{"digest=" + app.Digest.Encoded()[:12], []string{"select/app:v1"}},
+		{"digest=" + tool.Digest.String()[:19], []string{"select/tool:v1"}},
+		{"platform=linux/arm64*", []string{"select/app:v1"}},
+		{"platform!=linux/arm64/v8", []string{"select/tool:v1"}},
+		{"size>1K", []string{"select/app:v1", "select/tool:v1"}},
+		{"size<1K", nil},
+		{"added<2026-03-01", []string{"select/app:v1"}},
+		{"added>=2026-03-01", []string{"select/tool:v1"}},
+		{"label.team=inf*", []string{"select/tool:v1"}},
+		{"label.team", []string{"select/tool:v1"}},
+		{"!label.team", []string{"select/app:v1"}},
+		{"LABEL.Owner=ops", []string{"select/tool:v1"}},
+		{"Label.Owner", []string{"select/tool:v1"}},
+		{"label.owner", nil},
+		{"label.owner=ops", nil},
+		{"type=image,registry=" + host + ",added<2026-03-01", []string{"select/app:v1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			sel, err := store.ParseSelector(tt.expr)
+			if err != nil {
+				t.Fatalf("ParseSelector: %v", err)
+			}
+			selected, err := s.Select(ctx, sel, store.SelectOptions{})
+			if err != nil {
+				t.Fatalf("Select: %v", err)
+			}
+			var got []string
+			for _, sa := range selected {
+				got = append(got, sa.Desc.Annotations[ocispec.AnnotationRefName])
+			}
+			sort.Strings(got)
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("selected %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSelect_Related(t *testing.T) {
+	ctx := context.Background()
+	host, opts := newCheckTestRegistry(t)
+	s := newCheckTestStore(t)
+
+	app := pushAndAddImage(t, s, host, "select/app", "v1", opts)
+	sig, err := random.Image(64, 1)
+	if err != nil {
+		t.Fatalf("random.Image: %v", err)
+	}
+	if _, err := s.AddRelated(ctx, app, sig, consts.KindAnnotationSigs); err != nil {
+		t.Fatalf("AddRelated: %v", err)
+	}
+
+	sel, err := store.ParseSelector("type=image")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		related bool
+		want    []string
+	}{
+		{false, []string{"image"}},
+		{true, []string{"image", "sigs"}},
+	} {
+		selected, err := s.Select(ctx, sel, store.SelectOptions{Related: tt.related})
+		if err != nil {
+			t.Fatalf("Select: %v", err)
+		}
+		var got []string
+		for _, sa := range selected {
+			got = append(got, sa.Type)
+		}
+		if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+			t.Errorf("Related=%v selected %v, want %v", tt.related, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,201 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+
+	"github.com/mholt/archives"
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+
+	"hauler.dev/go/hauler/v2/pkg/consts"
+)
+
+const (
+	// sizeSampleBytes is how much of an uncompressed blob is compressed to
+	// estimate its ratio in a haul.
+	sizeSampleBytes = 1 << 20
+
+	// tarBlockSize is the unit a tar archive pads each entry to, and the size
+	// of the header before it.
+	tarBlockSize = 512
+)
+
+// ArtifactSize is one artifact's share of the store's blobs.
+type ArtifactSize struct {
+	// Full is every blob the artifact references, shared or not.
+	Full int64 `json:"full"`
+	// Unique is the blobs no other artifact references... what removing the
+	// artifact would free.
+	Unique int64 `json:"unique"`
+	// Shared is the blobs the artifact references along with others.
+	Shared int64 `json:"shared"`
+}
+
+// SizeReport accounts for the store's blobs with sharing taken into account.
+type SizeReport struct {
+	// Artifacts is keyed by the digest of each single-platform manifest: the
+	// platform manifests of a multi-arch index, and every other manifest in
+	// the index as itself. Tags that point at the same digest share an entry.
+	Artifacts map[digest.Digest]ArtifactSize
+	// Footprint is every blob the index references, each counted once.
+	Footprint int64
+	// EstimatedHaul is the footprint as a zstd-compressed haul would hold it.
+	EstimatedHaul int64
+}
+
+// Sizes reports the size of every artifact in the index, the bytes unique to
+// it, and the bytes it shares, along with the store's deduplicated footprint
+// and an estimate of the compressed haul `hauler store save` would write.
+//
+// A multi-arch index's own manifest is counted as part of each of its
+// platforms, so it shows as shared between them.
+func (l *Layout) Sizes(ctx context.Context) (*SizeReport, error) {
+	owners := map[digest.Digest]map[digest.Digest]ocispec.Descriptor{}
+
+	addOwner := func(owner, desc ocispec.Descriptor, extra ...ocispec.Descriptor) error {
+		blobs, ok := owners[owner.Digest]
+		if !ok {
+			blobs = map[digest.Digest]ocispec.Descriptor{}
+			owners[owner.Digest] = blobs
+		}
+		for _, e := range extra {
+			blobs[e.Digest] = e
+		}
+		return l.WalkBlobs(ctx, desc, func(d ocispec.Descriptor) error {
+			blobs[d.Digest] = d
+			return nil
+		})
+	}
+
+	if err := l.OCI.Walk(func(_ string, desc ocispec.Descriptor) error {
+		if desc.MediaType != ocispec.MediaTypeImageIndex && desc.MediaType != consts.DockerManifestListSchema2 {
+			return addOwner(desc, desc)
+		}
+		var index ocispec.Index
+		if err := l.fetchJSON(ctx, desc, &index); err != nil {
+			return err
+		}
+		for _, child := range index.Manifests {
+			if err := addOwner(child, child, desc); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	// how many artifacts reference each blob
+	refs := map[digest.Digest]int{}
+	all := map[digest.Digest]ocispec.Descriptor{}
+	for _, blobs := range owners {
+		for d, desc := range blobs {
+			refs[d]++
+			all[d] = desc
+		}
+	}
+
+	r := &SizeReport{Artifacts: make(map[digest.Digest]ArtifactSize, len(owners))}
+	for owner, blobs := range owners {
+		var size ArtifactSize
+		for d, desc := range blobs {
+			size.Full += desc.Size
+			if refs[d] == 1 {
+				size.Unique += desc.Size
+			} else {
+				size.Shared += desc.Size
+			}
+		}
+		r.Artifacts[owner] = size
+	}
+
+	for _, desc := range all {
+		r.Footprint += desc.Size
+		compressed, err := l.compressedSize(ctx, desc)
+		if err != nil {
+			return nil, err
+		}
+		r.EstimatedHaul += compressed + tarEntryOverhead(desc.Size)
+	}
+	return r, nil
+}
+
+// compressedSize estimates how large desc is once a haul compresses it.
+// Blobs that are compressed already are counted as they are; for the rest, a
+// sample from the front of the blob is compressed and the ratio applied to
+// the whole.
+func (l *Layout) compressedSize(ctx context.Context, desc ocispec.Descriptor) (int64, error) {
+	if desc.Size == 0 || isCompressedMediaType(desc.MediaType) {
+		return desc.Size, nil
+	}
+
+	rc, err := l.OCI.Fetch(ctx, desc)
+	if err != nil {
+		return 0, err
+	}
+	defer rc.Close()
+
+	sample, err := io.ReadAll(io.LimitReader(rc, sizeSampleBytes))
+	if err != nil {
+		return 0, err
+	}
+	if len(sample) == 0 || isCompressedData(sample) {
+		return desc.Size, nil
+	}
+
+	var buf bytes.Buffer
+	w, err := archives.Zstd{}.OpenWriter(&buf)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := w.Write(sample); err != nil {
+		w.Close()
+		return 0, err
+	}
+	if err := w.Close(); err != nil {
+		return 0, err
+	}
+
+	// compression can grow incompressible data a little... the haul is never
+	// smarter than storing it as is
+	ratio := float64(buf.Len()) / float64(len(sample))
+	if ratio > 1 {
+		ratio = 1
+	}
+	return int64(float64(desc.Size) * ratio), nil
+}
+
+// isCompressedMediaType reports whether blobs of mediaType are stored
+// compressed: gzip and zstd layers, and helm chart archives.
+func isCompressedMediaType(mediaType string) bool {
+	return strings.HasSuffix(mediaType, "gzip") || strings.HasSuffix(mediaType, "zstd")
+}
+
+// isCompressedData sniffs the magic numbers of common compressed formats, for
+// file blobs whose media type doesn't say.
+func isCompressedData(b []byte) bool {
+	for _, magic := range [][]byte{
+		{0x1f, 0x8b},                       // gzip
+		{0x28, 0xb5, 0x2f, 0xfd},           // zstd
+		{0xfd, '7', 'z', 'X', 'Z', 0x00},   // xz
+		{'B', 'Z', 'h'},                    // bzip2
+		{'P', 'K', 0x03, 0x04},             // zip
+		{0x04, 0x22, 0x4d, 0x18},           // lz4
+		{'7', 'z', 0xbc, 0xaf, 0x27, 0x1c}, // 7z
+	} {
+		if bytes.HasPrefix(b, magic) {
+			return true
+		}
+	}
+	return false
+}
+
+// tarEntryOverhead is the header and padding a tar archive adds to a file of
+// size bytes.
+func tarEntryOverhead(size int64) int64 {
+	pad := (tarBlockSize - size%tarBlockSize) % tarBlockSize
+	return tarBlockSize + pad
+}
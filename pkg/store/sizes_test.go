@@ -0,0 +1,81 @@
+package store_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/opencontainers/go-digest"
+)
+
+func TestSizes_SharedLayer(t *testing.T) {
+	ctx := context.Background()
+	host, opts := newCheckTestRegistry(t)
+	s := newCheckTestStore(t)
+
+	base, err := random.Image(4096, 1)
+	if err != nil {
+		t.Fatalf("random.Image: %v", err)
+	}
+	layers, err := base.Layers()
+	if err != nil {
+		t.Fatal(err)
+	}
+	shared, err := layers[0].Size()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// two apps built on the same base layer, each with one layer of its own
+	var apps []digest.Digest
+	for _, repo := range []string{"sizes/one", "sizes/two"} {
+		extra, err := random.Layer(1024, "application/vnd.oci.image.layer.v1.tar")
+		if err != nil {
+			t.Fatalf("random.Layer: %v", err)
+		}
+		img, err := mutate.AppendLayers(base, extra)
+		if err != nil {
+			t.Fatalf("AppendLayers: %v", err)
+		}
+		desc := pushAndAddExistingImage(t, s, host, repo, "v1", img, opts)
+		apps = append(apps, desc.Digest)
+	}
+
+	report, err := s.Sizes(ctx)
+	if err != nil {
+		t.Fatalf("Sizes: %v", err)
+	}
+	if len(report.Artifacts) != 2 {
+		t.Fatalf("Sizes reported %d artifacts, want 2", len(report.Artifacts))
+	}
+
+	var sumFull, sumUnique int64
+	for d, size := range report.Artifacts {
+		if size.Shared != shared {
+			t.Errorf("%s shares %d bytes, want the base layer's %d", d, size.Shared, shared)
+		}
+		if size.Full != size.Unique+size.Shared {
+			t.Errorf("%s full %d != unique %d + shared %d", d, size.Full, size.Unique, size.Shared)
+		}
+		sumFull += size.Full
+		sumUnique += size.Unique
+	}
+
+	// the base layer is counted once in the footprint, not once per image
+	if want := sumUnique + shared; report.Footprint != want {
+		t.Errorf("Footprint = %d, want %d", report.Footprint, want)
+	}
+	if report.Footprint >= sumFull {
+		t.Errorf("Footprint %d is not below the naive total %d", report.Footprint, sumFull)
+	}
+	if report.EstimatedHaul <= 0 {
+		t.Errorf("EstimatedHaul = %d, want a positive estimate", report.EstimatedHaul)
+	}
+
+	for _, d := range apps {
+		if _, ok := report.Artifacts[d]; !ok {
+			t.Errorf("Sizes has no entry for %s", d)
+		}
+	}
+}
@@ -12,6 +12,7 @@ import (
 
 	"github.com/containerd/containerd/v2/core/remotes"
 	"github.com/containerd/errdefs"
+	"github.com/gofrs/flock"
 	"github.com/google/go-containerregistry/pkg/authn"
 	gname "github.com/google/go-containerregistry/pkg/name"
 	v1 "github.com/google/go-containerregistry/pkg/v1"
@@ -44,6 +45,19 @@ type Layout struct {
 	// WithBlobConcurrency; must be known before content.NewOCI is called, so
 	// NewLayout applies opts before constructing the OCI store.
 	blobConcurrency int
+
+	// lock is the cross-process store lock taken by Lock, nil when unheld.
+	lock *flock.Flock
+
+	// historyLimit is how many generations of index.json the store keeps
+	// under history/ (0 records none). Set via WithHistoryLimit.
+	historyLimit int
+	// snapshot is the generation an exclusive Lock recorded, kept by
+	// Unlock only if the index changed while the lock was held.
+	snapshot *HistoryEntry
+	// restored is the generation a Rollback under the lock restored, which
+	// Unlock's pruning spares.
+	restored int
 }
 
 type Options func(*Layout)
@@ -71,10 +85,19 @@ func WithBlobConcurrency(n int) Options {
 	}
 }
 
+// WithHistoryLimit sets how many generations of index.json the store keeps
+// for `hauler store rollback`; 0 records none.
+func WithHistoryLimit(n int) Options {
+	return func(l *Layout) {
+		l.historyLimit = n
+	}
+}
+
 func NewLayout(rootdir string, opts ...Options) (*Layout, error) {
 	l := &Layout{
-		Root:    rootdir,
-		StoreID: loadOrCreateStoreID(rootdir),
+		Root:         rootdir,
+		StoreID:      loadOrCreateStoreID(rootdir),
+		historyLimit: consts.DefaultStoreHistoryLimit,
 	}
 
 	for _, opt := range opts {
@@ -340,13 +363,9 @@ func (l *Layout) AddLocalImage(ctx context.Context, ref string) (string, error)
 		return "", fmt.Errorf("parsing reference %q: %w", ref, err)
 	}
 
-	if err := ensureDockerHost(); err != nil {
-		return "", fmt.Errorf("failed to locate Docker daemon socket: %w -- is the Docker daemon running?", err)
-	}
-
-	img, err := daemon.Image(parsedRef, daemon.WithContext(ctx))
+	img, err := LocalImage(ctx, parsedRef)
 	if err != nil {
-		return "", fmt.Errorf("failed to fetch image from Docker daemon: %w -- is the Docker daemon running?", err)
+		return "", err
 	}
 
 	d, err := img.Digest()
@@ -360,6 +379,19 @@ func (l *Layout) AddLocalImage(ctx context.Context, ref string) (string, error)
 	return d.String(), nil
 }
 
+// LocalImage returns ref as the local Docker daemon holds it.
+func LocalImage(ctx context.Context, ref gname.Reference) (v1.Image, error) {
+	if err := ensureDockerHost(); err != nil {
+		return nil, fmt.Errorf("failed to locate Docker daemon socket: %w -- is the Docker daemon running?", err)
+	}
+
+	img, err := daemon.Image(ref, daemon.WithContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch image from Docker daemon: %w -- is the Docker daemon running?", err)
+	}
+	return img, nil
+}
+
 // ensureDockerHost sets DOCKER_HOST if it is not already set and the default
 // socket (/var/run/docker.sock) does not exist. Docker Desktop on macOS places
 // its socket at ~/.docker/run/docker.sock instead of the default path.
@@ -477,6 +509,39 @@ func (l *Layout) writeImage(ctx context.Context, annotationRef gname.Reference,
 	return l.OCI.AddIndex(desc)
 }
 
+// AddRelated writes img -- a signature, attestation, SBOM, or referrer manifest made for an image
+// already in the store -- and indexes it under subject's reference with the given kind, the same
+// shape saveRelatedArtifacts and saveReferrers give the artifacts pulled along with an image. Like
+// any index entry, it replaces an existing entry of the same kind for that reference.
+func (l *Layout) AddRelated(ctx context.Context, subject ocispec.Descriptor, img v1.Image, kind string) (ocispec.Descriptor, error) {
+	if err := l.writeImageBlobs(ctx, img); err != nil {
+		return ocispec.Descriptor{}, err
+	}
+
+	mt, err := img.MediaType()
+	if err != nil {
+		return ocispec.Descriptor{}, fmt.Errorf("getting media type: %w", err)
+	}
+	raw, err := img.RawManifest()
+	if err != nil {
+		return ocispec.Descriptor{}, fmt.Errorf("getting manifest: %w", err)
+	}
+
+	annotations := map[string]string{consts.KindAnnotationName: kind}
+	for _, k := range []string{ocispec.AnnotationRefName, consts.ContainerdImageNameKey, consts.OriginalRefAnnotation} {
+		if v, ok := subject.Annotations[k]; ok {
+			annotations[k] = v
+		}
+	}
+	desc := ocispec.Descriptor{
+		MediaType:   string(mt),
+		Digest:      digest.FromBytes(raw),
+		Size:        int64(len(raw)),
+		Annotations: annotations,
+	}
+	return desc, l.OCI.AddIndex(desc)
+}
+
 // writeIndexBlobs recursively writes all child image blobs for an image index to the store's blob
 // directory. It does not write the top-level index manifest or add index entries.
 func (l *Layout) writeIndexBlobs(ctx context.Context, idx v1.ImageIndex) error {
@@ -742,16 +807,35 @@ func (l *Layout) copyDescriptorGraph(ctx context.Context, desc ocispec.Descripto
 			return fmt.Errorf("failed to unmarshal manifest: %w", err)
 		}
 
-		// Copy config blob
-		if err := l.copyDescriptor(ctx, manifest.Config, fetcher, pusher); err != nil {
-			return fmt.Errorf("failed to copy config: %w", err)
-		}
-
-		// Copy all layer blobs
+		// Copy the config and layer blobs concurrently. The local bound only
+		// caps open source files; the real ceiling is whichever blob
+		// semaphore the pusher draws on (the target store's, or this store's
+		// via content.OCI.LimitTarget), which is shared across artifacts.
+		g, gctx := errgroup.WithContext(ctx)
+		g.SetLimit(l.OCI.BlobConcurrency())
+		g.Go(func() error {
+			if err := l.copyDescriptor(gctx, manifest.Config, fetcher, pusher); err != nil {
+				return fmt.Errorf("failed to copy config: %w", err)
+			}
+			return nil
+		})
+		// a blob listed twice (e.g. an empty config reused as a layer) must
+		// only be pushed once, or two uploads of one digest race each other
+		scheduled := map[digest.Digest]bool{manifest.Config.Digest: true}
 		for _, layer := range manifest.Layers {
-			if err := l.copyDescriptor(ctx, layer, fetcher, pusher); err != nil {
-				return fmt.Errorf("failed to copy layer: %w", err)
+			if scheduled[layer.Digest] {
+				continue
 			}
+			scheduled[layer.Digest] = true
+			g.Go(func() error {
+				if err := l.copyDescriptor(gctx, layer, fetcher, pusher); err != nil {
+					return fmt.Errorf("failed to copy layer: %w", err)
+				}
+				return nil
+			})
+		}
+		if err := g.Wait(); err != nil {
+			return err
 		}
 
 		// Push the manifest itself using the already-fetched data to avoid double-fetching
@@ -974,11 +1058,15 @@ func (l *Layout) RemoveArtifact(ctx context.Context, reference string, desc ocis
 	return l.OCI.SaveIndex()
 }
 
-func (l *Layout) CleanUp(ctx context.Context) (int, int64, error) {
+// CleanUp deletes the blobs no index entry references, other than those a
+// retained generation of the store's history still needs, which it reports
+// as Held. Unlike GC it leaves orphaned entries and temp files alone, and
+// skips a manifest it can't read.
+func (l *Layout) CleanUp(ctx context.Context) (*GCResult, error) {
 	referencedDigests := make(map[string]bool)
 
 	if err := l.OCI.LoadIndex(); err != nil {
-		return 0, 0, fmt.Errorf("failed to load index: %w", err)
+		return nil, fmt.Errorf("failed to load index: %w", err)
 	}
 
 	var processManifest func(desc ocispec.Descriptor) error
@@ -1052,19 +1140,27 @@ func (l *Layout) CleanUp(ctx context.Context) (int, int64, error) {
 	if err := l.OCI.Walk(func(reference string, desc ocispec.Descriptor) error {
 		return processManifest(desc)
 	}); err != nil {
-		return 0, 0, fmt.Errorf("failed to walk artifacts: %w", err)
+		return nil, fmt.Errorf("failed to walk artifacts: %w", err)
+	}
+
+	// a generation kept for `hauler store rollback` still needs its content
+	kept, err := l.historyBlobs(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read store history: %w", err)
+	}
+	heldDigests := make(map[string]bool, len(kept))
+	for d := range kept {
+		heldDigests[d.Encoded()] = true
 	}
 
 	// read all entries
 	blobsPath := filepath.Join(l.Root, ocispec.ImageBlobsDir, digest.Canonical.String())
 	entries, err := os.ReadDir(blobsPath)
 	if err != nil {
-		return 0, 0, fmt.Errorf("failed to read blobs directory: %w", err)
+		return nil, fmt.Errorf("failed to read blobs directory: %w", err)
 	}
 
-	// track count and size of deletions
-	deletedCount := 0
-	var deletedSize int64
+	res := &GCResult{Orphans: []GCOrphan{}, Blobs: []GCBlob{}, Temps: []GCTemp{}, Held: []GCBlob{}}
 
 	// scan blobs
 	for _, entry := range entries {
@@ -1073,19 +1169,25 @@ func (l *Layout) CleanUp(ctx context.Context) (int, int64, error) {
 		}
 
 		digest := entry.Name()
+		if referencedDigests[digest] {
+			continue
+		}
 
-		if !referencedDigests[digest] {
-			blobPath := filepath.Join(blobsPath, digest)
-			if info, err := entry.Info(); err == nil {
-				deletedSize += info.Size()
-			}
+		var size int64
+		if info, err := entry.Info(); err == nil {
+			size = info.Size()
+		}
+		blob := GCBlob{Digest: "sha256:" + digest, Size: size}
+		if heldDigests[digest] {
+			res.Held = append(res.Held, blob)
+			continue
+		}
 
-			if err := os.Remove(blobPath); err != nil {
-				return deletedCount, deletedSize, fmt.Errorf("failed to remove blob %s: %w", digest, err)
-			}
-			deletedCount++
+		if err := os.Remove(filepath.Join(blobsPath, digest)); err != nil {
+			return res, fmt.Errorf("failed to remove blob %s: %w", digest, err)
 		}
+		res.Blobs = append(res.Blobs, blob)
 	}
 
-	return deletedCount, deletedSize, nil
+	return res, nil
 }